@@ -1,13 +1,20 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/mmvergara/gosss/internal/api"
+	"github.com/mmvergara/gosss/internal/api/handlers"
+	"github.com/mmvergara/gosss/internal/audit"
 	"github.com/mmvergara/gosss/internal/config"
 	"github.com/mmvergara/gosss/internal/storage"
+	"github.com/mmvergara/gosss/internal/tracing"
 )
 
 func main() {
@@ -17,16 +24,124 @@ func main() {
 		log.Fatalf("Failed to initialize configuration: %v", err)
 	}
 
+	// Route both slog and the standard logger through a leveled handler
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: cfg.LogLevel})))
+
+	// Content types for uploads without a Content-Type shouldn't depend on
+	// the host's mime.types
+	if err := cfg.RegisterMimeTypes(); err != nil {
+		log.Fatalf("Failed to load MIME types: %v", err)
+	}
+
 	// Initialize storage backend
-	store := storage.New(cfg.StoragePath)
+	local := storage.New(cfg.StoragePath, storage.Options{
+		SoftDelete:     cfg.SoftDelete,
+		TrashRetention: cfg.TrashRetention,
+
+		MaxObjectsPerBucket: cfg.MaxObjectsPerBucket,
+
+		DirMode:  cfg.DirMode,
+		FileMode: cfg.FileMode,
+
+		DurableWrites:    cfg.DurableWrites,
+		ContentAddressed: cfg.ContentAddressed,
+
+		ETagHistoryLimit: cfg.ETagHistoryLimit,
+		ShardKeys:        cfg.ShardKeys,
+	})
+	// Objects stored in the other layout would all look missing
+	if err := local.CheckLayout(); err != nil {
+		log.Fatalf("Failed to check storage layout of %s: %v", cfg.StoragePath, err)
+	}
+
+	// Single-bucket deployments can have their bucket created for them
+	if cfg.DefaultBucket != "" {
+		if err := handlers.ValidateBucketName(cfg.DefaultBucket); err != nil {
+			log.Fatalf("Invalid DEFAULT_BUCKET: %v", err)
+		}
+		exists, err := local.BucketExists(context.Background(), cfg.DefaultBucket)
+		if err != nil {
+			log.Fatalf("Failed to check default bucket: %v", err)
+		}
+		if !exists {
+			if err := local.CreateBucket(context.Background(), cfg.DefaultBucket); err != nil {
+				log.Fatalf("Failed to create default bucket: %v", err)
+			}
+			slog.Info("Created default bucket", "bucket", cfg.DefaultBucket)
+		}
+	}
+
+	// Purge expired trash in the background
+	if cfg.SoftDelete {
+		go local.RunTrashSweeper(context.Background(), time.Hour)
+	}
+
+	// Abort abandoned resumable uploads in the background
+	if cfg.UploadExpiry > 0 {
+		go local.RunUploadSweeper(context.Background(), time.Hour, cfg.UploadExpiry)
+	}
+
+	// Optionally time every storage operation, and cache and coalesce reads
+	// of small objects
+	var store storage.Storage = local
+	if cfg.StorageMetrics {
+		store = storage.NewMetered(local, storage.NewExpvarRecorder())
+	}
+	// The cache sits outside the metrics so they keep measuring disk access
+	if cfg.CacheMaxObjectSize > 0 || cfg.CoalesceMaxObjectSize > 0 {
+		store = storage.NewCaching(store, storage.CacheOptions{
+			MaxObjectSize:         cfg.CacheMaxObjectSize,
+			MaxBytes:              cfg.CacheMaxBytes,
+			TTL:                   cfg.CacheTTL,
+			CoalesceMaxObjectSize: cfg.CoalesceMaxObjectSize,
+		})
+	}
+
+	// Trace requests and storage calls when an OTLP endpoint is configured
+	if cfg.Tracing {
+		if err := tracing.Setup(context.Background()); err != nil {
+			log.Fatalf("Failed to initialize tracing: %v", err)
+		}
+		store = tracing.NewStorage(store)
+	}
+
+	// Initialize audit log (disabled when AUDIT_LOG_PATH is unset)
+	auditLog, err := audit.New(cfg.AuditLogPath, cfg.AuditLogMaxSize)
+	if err != nil {
+		log.Fatalf("Failed to initialize audit log: %v", err)
+	}
 
 	// Setup API handlers
-	router := api.NewRouter(store, cfg)
+	router := api.NewRouter(store, cfg, auditLog)
+
+	// Profiling gets its own loopback listener so it is never public, and
+	// long CPU profiles aren't cut off by WRITE_TIMEOUT
+	pprofListener, err := api.ListenPprof(cfg)
+	if err != nil {
+		log.Fatalf("Failed to start pprof server: %v", err)
+	}
+	if pprofListener != nil {
+		slog.Info("Starting pprof server", "addr", pprofListener.Addr())
+		go func() {
+			if err := http.Serve(pprofListener, api.NewPprofHandler()); err != nil {
+				log.Fatalf("pprof server failed: %v", err)
+			}
+		}()
+	}
 
 	// Start server
 	addr := fmt.Sprintf(":%s", cfg.PORT)
-	log.Printf("Starting server on %s", addr)
-	if err := http.ListenAndServe(addr, router); err != nil {
+	slog.Info("Starting server", "addr", addr)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           router,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+	if err := server.ListenAndServe(); err != nil {
 		log.Fatalf("Server failed: %v", err)
 	}
 }
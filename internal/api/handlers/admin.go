@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+)
+
+// AdminBucketName is reserved for the /admin routes and can't name a bucket
+const AdminBucketName = "admin"
+
+// AdminPutBucket dispatches PUT /admin/{bucket} to the operation named in
+// the query: ?quota=N or ?max-object-size=N.
+func (h *Handler) AdminPutBucket(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Query().Has("quota"):
+		h.SetBucketQuota(w, r)
+	case r.URL.Query().Has("max-object-size"):
+		h.SetBucketMaxObjectSize(w, r)
+	default:
+		sendUnsupportedAdminOperation(w, r)
+	}
+}
+
+// AdminPostBucket dispatches POST /admin/{bucket}: ?recompute.
+func (h *Handler) AdminPostBucket(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Query().Has("recompute"):
+		h.RecomputeBucket(w, r)
+	default:
+		sendUnsupportedAdminOperation(w, r)
+	}
+}
+
+// AdminDeleteBucket dispatches DELETE /admin/{bucket}: ?cleanup.
+func (h *Handler) AdminDeleteBucket(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Query().Has("cleanup"):
+		h.CleanupBucket(w, r)
+	default:
+		sendUnsupportedAdminOperation(w, r)
+	}
+}
+
+// AdminPostObject dispatches POST /admin/{bucket}/*: ?recompute.
+func (h *Handler) AdminPostObject(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Query().Has("recompute"):
+		h.RecomputeObject(w, r)
+	default:
+		sendUnsupportedAdminOperation(w, r)
+	}
+}
+
+func sendUnsupportedAdminOperation(w http.ResponseWriter, r *http.Request) {
+	gosssError.SendGossError(w, http.StatusBadRequest, "Unsupported admin operation", adminResource(r))
+}
+
+// sendAdminOnly answers data-plane requests for operations that moved under
+// /admin. Falling through instead would be dangerous: DELETE /{bucket}
+// without ?cleanup deletes the bucket.
+func sendAdminOnly(w http.ResponseWriter, r *http.Request) {
+	msg := "Administrative operation, send it to /admin" + r.URL.Path + " with the admin API key"
+	gosssError.SendGossError(w, http.StatusForbidden, msg, adminResource(r))
+}
+
+func adminResource(r *http.Request) string {
+	resource := chi.URLParam(r, "bucket")
+	if key := chi.URLParam(r, "*"); key != "" {
+		resource += "/" + key
+	}
+	return resource
+}
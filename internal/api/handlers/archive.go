@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/storage"
+)
+
+// Restore status reported in X-Restore-Status for archive-class objects
+const (
+	RestoreStatusArchived = "ARCHIVED"
+	RestoreStatusRestored = "RESTORED"
+)
+
+// Bounds for the ?days= of an archive restore
+const (
+	DefaultRestoreDays = 1
+	MaxRestoreDays     = 365
+)
+
+// isArchiveClass reports whether objects of this class must be restored
+// before they can be read.
+func (h *Handler) isArchiveClass(metadata *model.ObjectMetadata) bool {
+	return slices.Contains(h.config.ArchiveClasses, storageClassOf(metadata))
+}
+
+// isArchived reports whether an object is in an archive class and has no
+// restore in effect, i.e. it cannot be read right now.
+func (h *Handler) isArchived(metadata *model.ObjectMetadata) bool {
+	if !h.isArchiveClass(metadata) {
+		return false
+	}
+	return metadata.RestoredUntil == nil || time.Now().After(*metadata.RestoredUntil)
+}
+
+// sendArchivedError rejects reads of an object that hasn't been restored.
+func sendArchivedError(w http.ResponseWriter, bucket, key string) {
+	gosssError.SendGossError(w, http.StatusForbidden, "InvalidObjectState: object is archived, restore it with POST ?restore first", bucket+"/"+key)
+}
+
+// setRestoreHeaders reports the restore state of archive-class objects.
+func (h *Handler) setRestoreHeaders(w http.ResponseWriter, metadata *model.ObjectMetadata) {
+	if !h.isArchiveClass(metadata) {
+		return
+	}
+	if h.isArchived(metadata) {
+		w.Header().Set("X-Restore-Status", RestoreStatusArchived)
+		return
+	}
+	w.Header().Set("X-Restore-Status", RestoreStatusRestored)
+	w.Header().Set("X-Restore-Expiry-Date", metadata.RestoredUntil.Format(http.TimeFormat))
+}
+
+// RestoreArchivedObject handles POST /{bucket}/*?restore[&days=N] for objects
+// of an archive class, making them readable for N days (default 1).
+// Restoring an already restored object extends or shortens the window.
+func (h *Handler) RestoreArchivedObject(w http.ResponseWriter, r *http.Request) {
+	bucket := chi.URLParam(r, "bucket")
+	key := chi.URLParam(r, "*")
+
+	days := DefaultRestoreDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxRestoreDays {
+			gosssError.SendGossError(w, http.StatusBadRequest, "days must be between 1 and 365", bucket+"/"+key)
+			return
+		}
+		days = n
+	}
+
+	until := time.Now().Add(time.Duration(days) * 24 * time.Hour)
+	metadata, err := h.store.RestoreArchivedObject(r.Context(), bucket, key, until)
+	if errors.Is(err, storage.ErrBucketNotFound) || errors.Is(err, storage.ErrObjectNotFound) {
+		sendObjectLookupError(w, err, bucket, key)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to restore archived object", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to restore object", bucket+"/"+key)
+		return
+	}
+
+	h.setRestoreHeaders(w, metadata)
+	w.WriteHeader(http.StatusOK)
+}
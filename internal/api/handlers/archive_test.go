@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"archive/tar"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestArchivedObjectRestore(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "cold")
+	ts.mustPut(t, "cold", "a.txt", "frozen", "X-Storage-Class", "GLACIER")
+
+	rec := ts.do(t, http.MethodGet, "/cold/a.txt", "")
+	expectStatus(t, rec, http.StatusForbidden)
+	rec = ts.do(t, http.MethodHead, "/cold/a.txt", "")
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("X-Restore-Status"); got != RestoreStatusArchived {
+		t.Errorf("X-Restore-Status = %q, want %q", got, RestoreStatusArchived)
+	}
+
+	expectStatus(t, ts.do(t, http.MethodPost, "/cold/a.txt?restore&days=0", ""), http.StatusBadRequest)
+	rec = ts.do(t, http.MethodPost, "/cold/a.txt?restore&days=2", "")
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("X-Restore-Status"); got != RestoreStatusRestored {
+		t.Errorf("X-Restore-Status after restore = %q, want %q", got, RestoreStatusRestored)
+	}
+
+	rec = ts.do(t, http.MethodGet, "/cold/a.txt", "")
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Body.String() != "frozen" {
+		t.Errorf("body = %q, want frozen", rec.Body.String())
+	}
+}
+
+func TestExportSkipsArchivedObjects(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "mixed")
+	ts.mustPut(t, "mixed", "hot.txt", "hot")
+	ts.mustPut(t, "mixed", "cold.txt", "cold", "X-Storage-Class", "DEEP_ARCHIVE")
+	ts.mustPut(t, "mixed", "thawed.txt", "thawed", "X-Storage-Class", "GLACIER")
+	expectStatus(t, ts.do(t, http.MethodPost, "/mixed/thawed.txt?restore", ""), http.StatusOK)
+
+	rec := ts.do(t, http.MethodGet, "/mixed?export&format=tar", "")
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("X-Archived-Objects-Skipped"); got != "1" {
+		t.Errorf("X-Archived-Objects-Skipped = %q, want 1", got)
+	}
+
+	entries := map[string]string{}
+	tr := tar.NewReader(rec.Body)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[hdr.Name] = string(data)
+	}
+	if len(entries) != 2 || entries["hot.txt"] != "hot" || entries["thawed.txt"] != "thawed" {
+		t.Errorf("exported %v, want hot.txt and thawed.txt only", entries)
+	}
+}
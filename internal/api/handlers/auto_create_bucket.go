@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	gosssError "github.com/mmvergara/gosss/internal/error"
+)
+
+// ensureBucket checks that the bucket of an upload exists before anything is
+// written, since storing an object would otherwise create the bucket's
+// directory as a side effect. A missing bucket gets 404, or is created when
+// AUTO_CREATE_BUCKETS is set, so clients can write without a prior
+// PUT /{bucket}. Callers validate the bucket name first. It reports whether
+// the request may go on; otherwise the error has been written.
+func (h *Handler) ensureBucket(w http.ResponseWriter, r *http.Request, bucket string) bool {
+	exists, err := h.store.BucketExists(r.Context(), bucket)
+	if err != nil {
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to check bucket", bucket)
+		return false
+	}
+	if exists {
+		return true
+	}
+
+	if !h.config.AutoCreateBuckets {
+		slog.Debug("Upload to a missing bucket", "bucket", bucket)
+		gosssError.SendGossError(w, http.StatusNotFound, "Bucket not found", bucket)
+		return false
+	}
+
+	if err := h.store.CreateBucket(r.Context(), bucket); err != nil {
+		slog.Error("Failed to auto-create bucket", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to create bucket", bucket)
+		return false
+	}
+	slog.Info("Auto-created bucket", "bucket", bucket)
+	return true
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUploadToMissingBucket(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "src")
+	ts.mustPut(t, "src", "a.txt", "hello")
+
+	uploads := []struct {
+		method, target string
+		headers        []string
+	}{
+		{http.MethodPut, "/missing/a.txt", nil},
+		{http.MethodPost, "/missing", nil},
+		{http.MethodPut, "/missing/copy.txt", []string{"X-Copy-Source", "src/a.txt"}},
+	}
+	for _, u := range uploads {
+		rec := ts.do(t, u.method, u.target, "hello", u.headers...)
+		expectStatus(t, rec, http.StatusNotFound)
+	}
+	if _, err := os.Stat(filepath.Join(ts.h.config.StoragePath, "missing")); !os.IsNotExist(err) {
+		t.Errorf("bucket directory created by a rejected upload: %v", err)
+	}
+	expectStatus(t, ts.do(t, http.MethodPut, "/Missing_/a.txt", "hello"), http.StatusBadRequest)
+}
+
+func TestUploadAutoCreatesBucket(t *testing.T) {
+	ts := newTestServer(t, "AUTO_CREATE_BUCKETS=true")
+	ts.mustCreateBucket(t, "src")
+	ts.mustPut(t, "src", "a.txt", "hello")
+
+	for _, u := range []struct {
+		bucket, method, target string
+		headers                []string
+	}{
+		{"put-bucket", http.MethodPut, "/put-bucket/a.txt", nil},
+		{"post-bucket", http.MethodPost, "/post-bucket", nil},
+		{"copy-bucket", http.MethodPut, "/copy-bucket/a.txt", []string{"X-Copy-Source", "src/a.txt"}},
+	} {
+		rec := ts.do(t, u.method, u.target, "hello", u.headers...)
+		if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+			t.Fatalf("%s %s: status %d: %s", u.method, u.target, rec.Code, rec.Body.String())
+		}
+		if exists, err := ts.store.BucketExists(context.Background(), u.bucket); err != nil || !exists {
+			t.Errorf("%s %s: bucket not created: %v", u.method, u.target, err)
+		}
+	}
+
+	// Names are still validated before anything is created
+	expectStatus(t, ts.do(t, http.MethodPut, "/Bad_Bucket/a.txt", "hello"), http.StatusBadRequest)
+	if exists, _ := ts.store.BucketExists(context.Background(), "Bad_Bucket"); exists {
+		t.Error("invalid bucket name auto-created")
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/response"
+	"github.com/mmvergara/gosss/internal/storage"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Per-key errors reported by a batch metadata request
+const (
+	BatchErrorNotFound = "NotFound"
+	BatchErrorInternal = "InternalError"
+)
+
+// BatchObjectMetadata handles POST /{bucket}?metadata with a JSON body of
+// {"keys": [...]}, answering with the metadata of every listed object in one
+// response instead of a HEAD per key. Keys that don't exist are reported with
+// an error of "NotFound" rather than failing the request.
+func (h *Handler) BatchObjectMetadata(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucket := chi.URLParam(r, "bucket")
+
+	var req model.BatchMetadataRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, MaxJSONUploadSize)).Decode(&req); err != nil {
+		slog.Debug("Invalid batch metadata body", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusBadRequest, "Body must be a JSON object with a keys array", bucket)
+		return
+	}
+	if len(req.Keys) > h.config.MaxBatchMetadataKeys {
+		msg := fmt.Sprintf("At most %d keys may be requested at once", h.config.MaxBatchMetadataKeys)
+		gosssError.SendGossError(w, http.StatusBadRequest, msg, bucket)
+		return
+	}
+
+	exists, err := h.store.BucketExists(ctx, bucket)
+	if err != nil {
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to check bucket", bucket)
+		return
+	}
+	if !exists {
+		gosssError.SendGossError(w, http.StatusNotFound, "Bucket not found", bucket)
+		return
+	}
+
+	result := model.BatchMetadataResult{
+		Bucket:  bucket,
+		Objects: make(map[string]model.BatchMetadataEntry, len(req.Keys)),
+	}
+	for _, key := range req.Keys {
+		if _, seen := result.Objects[key]; seen {
+			continue
+		}
+
+		lookupKey := trimKeyWhitespace(key, h.config)
+		if h.config.NormalizeKeys {
+			lookupKey = norm.NFC.String(lookupKey)
+		}
+
+		// Keys that could never have been stored can't exist
+		if ok, _ := isValidObjectKey(lookupKey, h.config); !ok {
+			result.Objects[key] = model.BatchMetadataEntry{Error: BatchErrorNotFound}
+			continue
+		}
+
+		metadata, err := h.store.HeadObject(ctx, bucket, lookupKey)
+		switch {
+		case errors.Is(err, storage.ErrObjectNotFound):
+			result.Objects[key] = model.BatchMetadataEntry{Error: BatchErrorNotFound}
+		case err != nil:
+			slog.Error("Failed to read object metadata", "bucket", bucket, "key", lookupKey, "error", err)
+			result.Objects[key] = model.BatchMetadataEntry{Error: BatchErrorInternal}
+		default:
+			result.Objects[key] = model.BatchMetadataEntry{Metadata: metadata}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := response.Encode(w, result); err != nil {
+		slog.Error("Failed to encode batch metadata", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Internal server error", bucket)
+		return
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/storage"
+)
+
+// SetBucketMaxObjectSize handles PUT /{bucket}?max-object-size=N, limiting
+// uploads to the bucket to N bytes instead of MaxFileSize, lower or higher.
+// 0 restores MaxFileSize.
+func (h *Handler) SetBucketMaxObjectSize(w http.ResponseWriter, r *http.Request) {
+	bucket := chi.URLParam(r, "bucket")
+
+	size, err := strconv.ParseInt(r.URL.Query().Get("max-object-size"), 10, 64)
+	if err != nil || size < 0 {
+		gosssError.SendGossError(w, http.StatusBadRequest, "max-object-size must be a non-negative number of bytes", bucket)
+		return
+	}
+
+	err = h.store.SetBucketMaxObjectSize(r.Context(), bucket, size)
+	if errors.Is(err, storage.ErrBucketNotFound) {
+		gosssError.SendGossError(w, http.StatusNotFound, "Bucket not found", bucket)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to set bucket max object size", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to set bucket max object size", bucket)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// maxObjectSize returns the upload limit of bucket: its own max object size
+// when one is set, MaxFileSize otherwise. A missing bucket gets MaxFileSize,
+// leaving the upload to report it.
+func (h *Handler) maxObjectSize(ctx context.Context, bucket string) (int64, error) {
+	size, err := h.store.BucketMaxObjectSize(ctx, bucket)
+	if errors.Is(err, storage.ErrBucketNotFound) {
+		return MaxFileSize, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if size == 0 {
+		return MaxFileSize, nil
+	}
+	return size, nil
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/storage"
+)
+
+// SetBucketQuota handles PUT /{bucket}?quota=N, capping the total size of
+// the bucket's objects at N bytes. 0 removes the cap.
+func (h *Handler) SetBucketQuota(w http.ResponseWriter, r *http.Request) {
+	bucket := chi.URLParam(r, "bucket")
+
+	quota, err := strconv.ParseInt(r.URL.Query().Get("quota"), 10, 64)
+	if err != nil || quota < 0 {
+		gosssError.SendGossError(w, http.StatusBadRequest, "quota must be a non-negative number of bytes", bucket)
+		return
+	}
+
+	err = h.store.SetBucketQuota(r.Context(), bucket, quota)
+	if errors.Is(err, storage.ErrBucketNotFound) {
+		gosssError.SendGossError(w, http.StatusNotFound, "Bucket not found", bucket)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to set bucket quota", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to set bucket quota", bucket)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/storage"
+)
+
+// MaxCacheControlLength caps the Cache-Control stored for an object or bucket
+const MaxCacheControlLength = 1024
+
+// parseCacheControl reads the Cache-Control an upload should be served with.
+// It is kept verbatim, so it must be a valid header value.
+func parseCacheControl(r *http.Request) (string, bool) {
+	value := r.Header.Get("Cache-Control")
+	return value, isValidCacheControl(value)
+}
+
+func isValidCacheControl(value string) bool {
+	if len(value) > MaxCacheControlLength {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; (c < ' ' && c != '\t') || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// setCacheControl sets the Cache-Control of a GET or HEAD response: the one
+// the object was uploaded with, or else its bucket's default.
+func (h *Handler) setCacheControl(w http.ResponseWriter, r *http.Request, bucket string, metadata *model.ObjectMetadata) {
+	value := metadata.CacheControl
+	if value == "" {
+		var err error
+		value, err = h.store.BucketDefaultCacheControl(r.Context(), bucket)
+		if err != nil {
+			slog.Warn("Failed to read default Cache-Control", "bucket", bucket, "error", err)
+			return
+		}
+	}
+	if value != "" {
+		w.Header().Set("Cache-Control", value)
+	}
+}
+
+// SetBucketDefaultCacheControl handles PUT /{bucket}?default-cache-control=
+// value, the Cache-Control sent with objects of the bucket uploaded without
+// one. An empty value removes it.
+func (h *Handler) SetBucketDefaultCacheControl(w http.ResponseWriter, r *http.Request) {
+	bucket := chi.URLParam(r, "bucket")
+	value := r.URL.Query().Get("default-cache-control")
+
+	if !isValidCacheControl(value) {
+		gosssError.SendGossError(w, http.StatusBadRequest, "Invalid Cache-Control", bucket)
+		return
+	}
+
+	err := h.store.SetBucketDefaultCacheControl(r.Context(), bucket, value)
+	if errors.Is(err, storage.ErrBucketNotFound) {
+		gosssError.SendGossError(w, http.StatusNotFound, "Bucket not found", bucket)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to set default Cache-Control", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to set default Cache-Control", bucket)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
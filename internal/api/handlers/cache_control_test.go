@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestCacheControl(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+	ts.mustPut(t, "docs", "plain.txt", "plain")
+	ts.mustPut(t, "docs", "own.txt", "own", "Cache-Control", "no-store")
+
+	expectCacheControl := func(key, want string) {
+		t.Helper()
+		for _, method := range []string{http.MethodGet, http.MethodHead} {
+			rec := ts.do(t, method, "/docs/"+key, "")
+			expectStatus(t, rec, http.StatusOK)
+			if got := rec.Header().Get("Cache-Control"); got != want {
+				t.Errorf("%s %s: Cache-Control = %q, want %q", method, key, got, want)
+			}
+		}
+	}
+	expectCacheControl("plain.txt", "")
+	expectCacheControl("own.txt", "no-store")
+
+	// The bucket default fills in for objects without their own
+	expectStatus(t, ts.do(t, http.MethodPut, "/docs?default-cache-control="+url.QueryEscape("public, max-age=60"), ""), http.StatusOK)
+	expectCacheControl("plain.txt", "public, max-age=60")
+	expectCacheControl("own.txt", "no-store")
+
+	// Changing and removing the default takes effect at once
+	expectStatus(t, ts.do(t, http.MethodPut, "/docs?default-cache-control=max-age=5", ""), http.StatusOK)
+	expectCacheControl("plain.txt", "max-age=5")
+	expectStatus(t, ts.do(t, http.MethodPut, "/docs?default-cache-control=", ""), http.StatusOK)
+	expectCacheControl("plain.txt", "")
+
+	expectStatus(t, ts.do(t, http.MethodPut, "/missing?default-cache-control=max-age=5", ""), http.StatusNotFound)
+	expectStatus(t, ts.do(t, http.MethodPut, "/docs?default-cache-control="+url.QueryEscape("a\nb"), ""), http.StatusBadRequest)
+}
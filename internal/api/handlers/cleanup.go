@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/response"
+)
+
+// CleanupBucket handles DELETE /{bucket}?cleanup, removing temp files left
+// behind by interrupted uploads that are older than TEMP_FILE_MAX_AGE.
+func (h *Handler) CleanupBucket(w http.ResponseWriter, r *http.Request) {
+	bucket := chi.URLParam(r, "bucket")
+
+	exists, err := h.store.BucketExists(r.Context(), bucket)
+	if err != nil {
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to check bucket", bucket)
+		return
+	}
+	if !exists {
+		gosssError.SendGossError(w, http.StatusNotFound, "Bucket not found", bucket)
+		return
+	}
+
+	count, err := h.store.CleanupTempFiles(r.Context(), bucket, h.config.TempFileMaxAge)
+	if err != nil {
+		slog.Error("Failed to clean up bucket", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to clean up bucket", bucket)
+		return
+	}
+	if count > 0 {
+		slog.Info("Removed stale temp files", "bucket", bucket, "count", count)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := response.Encode(w, model.CleanupResult{Bucket: bucket, Removed: count}); err != nil {
+		slog.Error("Failed to encode cleanup result", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Internal server error", bucket)
+		return
+	}
+}
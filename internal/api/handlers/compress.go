@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// shouldGzip reports whether a response body of size bytes should be gzip
+// compressed: compression must be enabled, the client must accept gzip and
+// the body must be at least GzipMinSize, since compressing tiny bodies costs
+// CPU and can even make them larger. Range requests are answered from the
+// stored bytes, so resumed downloads line up with what was already fetched.
+func (h *Handler) shouldGzip(r *http.Request, size int64) bool {
+	if !h.config.GzipResponses || size < h.config.GzipMinSize || r.Header.Get("Range") != "" {
+		return false
+	}
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+		if strings.EqualFold(strings.TrimSpace(name), "gzip") && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// setVaryEncoding tells caches that a body of size bytes depends on the
+// request's Accept-Encoding, which is the case whenever it may be gzipped.
+func (h *Handler) setVaryEncoding(w http.ResponseWriter, size int64) {
+	if h.config.GzipResponses && size >= h.config.GzipMinSize {
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+}
+
+// gzipETag derives the ETag of the gzip-encoded body from the stored one,
+// e.g. "abc" becomes "abc-gzip".
+func gzipETag(etag string) string {
+	if strings.HasSuffix(etag, `"`) {
+		return strings.TrimSuffix(etag, `"`) + `-gzip"`
+	}
+	return etag + "-gzip"
+}
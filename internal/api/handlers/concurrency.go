@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+
+	gosssError "github.com/mmvergara/gosss/internal/error"
+)
+
+// newSlots makes a pool of n concurrency slots. Zero means unlimited and
+// gives a nil pool.
+func newSlots(n int) chan struct{} {
+	if n == 0 {
+		return nil
+	}
+	return make(chan struct{}, n)
+}
+
+// acquireSlot takes a slot from slots, answering 429 when every slot is in
+// use. Uploads and downloads draw from separate pools, so a flood of one
+// can't starve the other. Callers must call release once done.
+func acquireSlot(w http.ResponseWriter, slots chan struct{}, operation string) (release func(), ok bool) {
+	if slots == nil {
+		return func() {}, true
+	}
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, true
+	default:
+		gosssError.SendGossError(w, http.StatusTooManyRequests, "Too many concurrent "+operation, "")
+		return nil, false
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForSlots waits until n of slots are taken
+func waitForSlots(t *testing.T, slots chan struct{}, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(slots) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d slots taken, want %d", len(slots), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Uploads that fill their pool are throttled without holding up downloads
+func TestWritesThrottledWhileReadsSucceed(t *testing.T) {
+	ts := newTestServer(t, "MAX_CONCURRENT_WRITES=2", "MAX_CONCURRENT_READS=4")
+	ts.mustCreateBucket(t, "docs")
+	ts.mustPut(t, "docs", "a.txt", "hello")
+
+	// Two uploads whose bodies are still arriving hold every write slot
+	var wg sync.WaitGroup
+	writers := make([]*io.PipeWriter, 2)
+	codes := make([]int, 2)
+	for i := range writers {
+		pr, pw := io.Pipe()
+		writers[i] = pw
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/docs/slow%d.txt", i), pr)
+		req.ContentLength = -1
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			ts.router.ServeHTTP(rec, req)
+			codes[i] = rec.Code
+		}(i)
+	}
+	waitForSlots(t, ts.h.writeSlots, 2)
+
+	var mixed sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		mixed.Add(2)
+		go func() {
+			defer mixed.Done()
+			rec := ts.do(t, http.MethodPut, "/docs/b.txt", "more")
+			if rec.Code != http.StatusTooManyRequests {
+				t.Errorf("upload with writes saturated: status %d, want 429", rec.Code)
+			}
+		}()
+		go func() {
+			defer mixed.Done()
+			rec := ts.do(t, http.MethodGet, "/docs/a.txt", "")
+			if rec.Code != http.StatusOK || rec.Body.String() != "hello" {
+				t.Errorf("download with writes saturated: %d %q, want the object", rec.Code, rec.Body.String())
+			}
+		}()
+	}
+	mixed.Wait()
+
+	for _, pw := range writers {
+		pw.Write([]byte("data"))
+		pw.Close()
+	}
+	wg.Wait()
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("slow upload %d: status %d", i, code)
+		}
+	}
+	waitForSlots(t, ts.h.writeSlots, 0)
+	ts.mustPut(t, "docs", "b.txt", "more")
+}
+
+// blockingWriter is a ResponseWriter whose body writes wait for release
+type blockingWriter struct {
+	*httptest.ResponseRecorder
+	release chan struct{}
+}
+
+func (bw *blockingWriter) Write(p []byte) (int, error) {
+	<-bw.release
+	return bw.ResponseRecorder.Write(p)
+}
+
+// and downloads that fill theirs leave uploads alone
+func TestReadsThrottledWhileWritesSucceed(t *testing.T) {
+	ts := newTestServer(t, "MAX_CONCURRENT_WRITES=4", "MAX_CONCURRENT_READS=1")
+	ts.mustCreateBucket(t, "docs")
+	ts.mustPut(t, "docs", "a.txt", "hello")
+
+	bw := &blockingWriter{ResponseRecorder: httptest.NewRecorder(), release: make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ts.router.ServeHTTP(bw, httptest.NewRequest(http.MethodGet, "/docs/a.txt", nil))
+	}()
+	waitForSlots(t, ts.h.readSlots, 1)
+
+	expectStatus(t, ts.do(t, http.MethodGet, "/docs/a.txt", ""), http.StatusTooManyRequests)
+	ts.mustPut(t, "docs", "b.txt", "written")
+
+	close(bw.release)
+	<-done
+	if bw.Code != http.StatusOK || bw.Body.String() != "hello" {
+		t.Errorf("blocked download = %d %q", bw.Code, bw.Body.String())
+	}
+	expectStatus(t, ts.do(t, http.MethodGet, "/docs/b.txt", ""), http.StatusOK)
+}
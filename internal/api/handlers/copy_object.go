@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/response"
+	"github.com/mmvergara/gosss/internal/storage"
+)
+
+const (
+	MetadataDirectiveCopy    = "COPY"
+	MetadataDirectiveReplace = "REPLACE"
+)
+
+// CopyObject handles PUT /{bucket}/* with an X-Copy-Source: srcbucket/srckey
+// header. X-Metadata-Directive selects whether the source's metadata is kept
+// (COPY, the default) or replaced with the Content-Type, X-Storage-Class and
+// X-Redirect-Location of this request (REPLACE).
+func (h *Handler) CopyObject(w http.ResponseWriter, r *http.Request) {
+	bucket := chi.URLParam(r, "bucket")
+	key := chi.URLParam(r, "*")
+
+	srcBucket, srcKey, ok := strings.Cut(strings.TrimPrefix(r.Header.Get("X-Copy-Source"), "/"), "/")
+	srcBucket = bucketName(srcBucket, h.config)
+	srcKey = trimKeyWhitespace(srcKey, h.config)
+	if !ok || srcBucket == "" || srcKey == "" {
+		gosssError.SendGossError(w, http.StatusBadRequest, "X-Copy-Source must be in the form bucket/key", bucket+"/"+key)
+		return
+	}
+	// The source names a path on disk just like the destination does
+	if !h.validObjectTarget(w, srcBucket, srcKey) {
+		return
+	}
+
+	var override *model.ObjectMetadata
+	switch directive := strings.ToUpper(r.Header.Get("X-Metadata-Directive")); directive {
+	case "", MetadataDirectiveCopy:
+	case MetadataDirectiveReplace:
+		storageClass, ok := parseStorageClass(r)
+		if !ok {
+			gosssError.SendGossError(w, http.StatusBadRequest, "Invalid storage class", bucket+"/"+key)
+			return
+		}
+		redirectLocation, ok := parseRedirectLocation(r)
+		if !ok {
+			gosssError.SendGossError(w, http.StatusBadRequest, "Redirect location must be a path starting with / or an http(s) URL", bucket+"/"+key)
+			return
+		}
+		cacheControl, ok := parseCacheControl(r)
+		if !ok {
+			gosssError.SendGossError(w, http.StatusBadRequest, "Invalid Cache-Control", bucket+"/"+key)
+			return
+		}
+		override = &model.ObjectMetadata{ContentType: r.Header.Get("Content-Type"), StorageClass: storageClass, RedirectLocation: redirectLocation, CacheControl: cacheControl}
+	default:
+		gosssError.SendGossError(w, http.StatusBadRequest, "X-Metadata-Directive must be COPY or REPLACE", bucket+"/"+key)
+		return
+	}
+
+	// Validate destination bucket name
+	isValidBuckName, verr := isValidBucketName(bucket)
+	if !isValidBuckName {
+		slog.Debug("Invalid bucket name", "bucket", bucket, "reason", verr.Message)
+		sendValidationError(w, verr, bucket)
+		return
+	}
+
+	// Validate destination object key
+	isValidObjKey, verr := isValidObjectKey(key, h.config)
+	if !isValidObjKey {
+		slog.Debug("Invalid object key", "key", key, "reason", verr.Message)
+		sendValidationError(w, verr, bucket+"/"+key)
+		return
+	}
+
+	if pattern, blocked := blockedKeyPattern(key, h.config); blocked {
+		sendBlockedKeyError(w, bucket, key, pattern)
+		return
+	}
+
+	if !h.allowMutation(w, r, bucket, key) {
+		return
+	}
+	if !h.ensureBucket(w, r, bucket) {
+		return
+	}
+
+	// A missing source is left for CopyObject to report
+	if src, err := h.store.HeadObject(r.Context(), srcBucket, srcKey); err == nil {
+		// Archived objects can't be read, so they can't be copied either
+		if h.isArchived(src) {
+			sendArchivedError(w, srcBucket, srcKey)
+			return
+		}
+		// The copy is an upload to the destination, so its limit applies
+		maxSize, err := h.maxObjectSize(r.Context(), bucket)
+		if err != nil {
+			slog.Error("Failed to read bucket max object size", "bucket", bucket, "error", err)
+			gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to check bucket", bucket+"/"+key)
+			return
+		}
+		if src.Size > maxSize {
+			slog.Debug("Copy source exceeds the maximum allowed size", "size", src.Size, "max", maxSize)
+			gosssError.SendGossError(w, http.StatusRequestEntityTooLarge, "Object exceeds the maximum allowed size", bucket+"/"+key)
+			return
+		}
+	}
+
+	metadata, err := h.store.CopyObject(r.Context(), srcBucket, srcKey, bucket, key, override)
+	if errors.Is(err, storage.ErrBucketNotFound) || errors.Is(err, storage.ErrObjectNotFound) {
+		sendObjectLookupError(w, err, srcBucket, srcKey)
+		return
+	}
+	if errors.Is(err, storage.ErrTooManyObjects) {
+		gosssError.SendGossError(w, http.StatusConflict, "Bucket has reached its maximum number of objects", bucket+"/"+key)
+		return
+	}
+	if errors.Is(err, storage.ErrQuotaExceeded) {
+		gosssError.SendGossError(w, http.StatusInsufficientStorage, "Bucket quota exceeded", bucket+"/"+key)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to copy object", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to copy object", bucket+"/"+key)
+		return
+	}
+	h.objectCreated(bucket, metadata)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := response.Encode(w, metadata); err != nil {
+		slog.Error("Failed to encode metadata", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to encode metadata", bucket+"/"+key)
+		return
+	}
+}
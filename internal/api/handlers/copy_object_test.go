@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCopyObject(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "src")
+	ts.mustCreateBucket(t, "dst")
+	ts.mustPut(t, "src", "a.txt", "hello")
+
+	rec := ts.do(t, http.MethodPut, "/dst/b.txt", "", "X-Copy-Source", "src/a.txt")
+	expectStatus(t, rec, http.StatusOK)
+	rec = ts.do(t, http.MethodGet, "/dst/b.txt", "")
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Body.String() != "hello" {
+		t.Fatalf("copy = %q, want hello", rec.Body.String())
+	}
+}
+
+func TestCopyObjectValidatesSource(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "dst")
+
+	for _, source := range []string{
+		"src/../../etc/passwd",
+		"src/a/./b",
+		"../etc/passwd",
+		"Bad_Bucket/a.txt",
+		"src/.trash/a.txt",
+	} {
+		rec := ts.do(t, http.MethodPut, "/dst/b.txt", "", "X-Copy-Source", source)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("X-Copy-Source %q: status %d, want 400", source, rec.Code)
+		}
+	}
+}
+
+func TestCopyObjectBucketMaxObjectSize(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "small")
+	ts.mustCreateBucket(t, "large")
+	if err := ts.store.SetBucketMaxObjectSize(context.Background(), "small", 100); err != nil {
+		t.Fatal(err)
+	}
+	ts.mustPut(t, "large", "big.txt", strings.Repeat("x", 200))
+	ts.mustPut(t, "small", "little.txt", strings.Repeat("x", 50))
+
+	// The destination's limit applies, not the source's
+	rec := ts.do(t, http.MethodPut, "/small/big.txt", "", "X-Copy-Source", "large/big.txt")
+	expectStatus(t, rec, http.StatusRequestEntityTooLarge)
+	expectStatus(t, ts.do(t, http.MethodHead, "/small/big.txt", ""), http.StatusNotFound)
+	expectStatus(t, ts.do(t, http.MethodPut, "/small/copy.txt", "", "X-Copy-Source", "small/little.txt"), http.StatusOK)
+	expectStatus(t, ts.do(t, http.MethodPut, "/large/little.txt", "", "X-Copy-Source", "small/little.txt"), http.StatusOK)
+
+	// A raised limit lets the copy through
+	if err := ts.store.SetBucketMaxObjectSize(context.Background(), "small", 1000); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, ts.do(t, http.MethodPut, "/small/big.txt", "", "X-Copy-Source", "large/big.txt"), http.StatusOK)
+}
@@ -1,7 +1,7 @@
 package handlers
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -9,6 +9,19 @@ import (
 )
 
 func (h *Handler) CreateBucket(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Has("quota") || r.URL.Query().Has("max-object-size") {
+		sendAdminOnly(w, r)
+		return
+	}
+	if r.URL.Query().Has("default-object") {
+		h.SetBucketDefaultObject(w, r)
+		return
+	}
+	if r.URL.Query().Has("default-cache-control") {
+		h.SetBucketDefaultCacheControl(w, r)
+		return
+	}
+
 	bucket := chi.URLParam(r, "bucket")
 
 	// Check if bucket already exists
@@ -23,15 +36,15 @@ func (h *Handler) CreateBucket(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate bucket name
-	isValidBuckName, msg := isValidBucketName(bucket)
+	isValidBuckName, verr := isValidBucketName(bucket)
 	if !isValidBuckName {
-		log.Printf("Invalid bucket name: %s (%s)", bucket, msg)
-		gosssError.SendGossError(w, http.StatusBadRequest, msg, bucket)
+		slog.Debug("Invalid bucket name", "bucket", bucket, "reason", verr.Message)
+		sendValidationError(w, verr, bucket)
 		return
 	}
 
 	if err := h.store.CreateBucket(r.Context(), bucket); err != nil {
-		log.Println(err)
+		slog.Error("Failed to create bucket", "bucket", bucket, "error", err)
 		gosssError.SendGossError(w, http.StatusInternalServerError, err.Error(), "")
 		return
 	}
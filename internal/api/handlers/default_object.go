@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/storage"
+)
+
+// SetBucketDefaultObject handles PUT /{bucket}?default-object=key, making
+// GET /{bucket} serve that object instead of a listing. An empty key turns
+// listings back on.
+func (h *Handler) SetBucketDefaultObject(w http.ResponseWriter, r *http.Request) {
+	bucket := chi.URLParam(r, "bucket")
+	key := r.URL.Query().Get("default-object")
+
+	if key != "" {
+		isValidObjKey, verr := isValidObjectKey(key, h.config)
+		if !isValidObjKey {
+			slog.Debug("Invalid object key", "key", key, "reason", verr.Message)
+			verr.Field = "default-object"
+			sendValidationError(w, verr, bucket+"/"+key)
+			return
+		}
+	}
+
+	err := h.store.SetBucketDefaultObject(r.Context(), bucket, key)
+	if errors.Is(err, storage.ErrBucketNotFound) {
+		gosssError.SendGossError(w, http.StatusNotFound, "Bucket not found", bucket)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to set default object", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to set default object", bucket)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// defaultObject returns the key GET /{bucket} should serve instead of a
+// listing, or "" to list. Requests with ?list or any listing parameter are
+// always listings, as are buckets whose default object doesn't exist.
+func (h *Handler) defaultObject(r *http.Request, bucket string) string {
+	query := r.URL.Query()
+	for _, param := range []string{"list", "prefix", "start-after", "tag", "content-type"} {
+		if query.Has(param) {
+			return ""
+		}
+	}
+
+	key, err := h.store.BucketDefaultObject(r.Context(), bucket)
+	if err != nil || key == "" {
+		return ""
+	}
+	if _, err := h.store.HeadObject(r.Context(), bucket, key); err != nil {
+		slog.Debug("Default object is missing, listing instead", "bucket", bucket, "key", key, "error", err)
+		return ""
+	}
+	return key
+}
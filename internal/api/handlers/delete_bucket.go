@@ -1,15 +1,19 @@
 package handlers
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
-	"time"
 
 	"github.com/go-chi/chi/v5"
 	gosssError "github.com/mmvergara/gosss/internal/error"
 )
 
 func (h *Handler) DeleteBucket(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Has("cleanup") {
+		sendAdminOnly(w, r)
+		return
+	}
+
 	bucket := chi.URLParam(r, "bucket")
 
 	// Check if bucket exists
@@ -26,24 +30,18 @@ func (h *Handler) DeleteBucket(w http.ResponseWriter, r *http.Request) {
 	// List objects to ensure bucket is empty
 	hasObject, err := h.store.HasObject(r.Context(), bucket)
 	if err != nil {
-		log.Printf("Failed to check if bucket is empty: %v", err)
+		slog.Error("Failed to check if bucket is empty", "bucket", bucket, "error", err)
 		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to list objects", bucket)
 		return
 	}
 	if hasObject {
-		log.Printf("Bucket not empty: %s", bucket)
+		slog.Debug("Bucket not empty", "bucket", bucket)
 		gosssError.SendGossError(w, http.StatusConflict, "Bucket not empty", bucket)
+		return
 	}
 
-	// Delete bucket with retry
-	for i := 0; i < 3; i++ {
-		err = h.store.DeleteBucket(r.Context(), bucket)
-		if err == nil {
-			break
-		}
-		time.Sleep(time.Second * time.Duration(i+1))
-	}
-	if err != nil {
+	// Transient filesystem errors are retried inside the storage layer
+	if err := h.store.DeleteBucket(r.Context(), bucket); err != nil {
 		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to delete bucket", bucket)
 		return
 	}
@@ -1,7 +1,7 @@
 package handlers
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -9,14 +9,24 @@ import (
 )
 
 func (h *Handler) DeleteObject(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Has("tagging") {
+		h.DeleteObjectTagging(w, r)
+		return
+	}
+
 	bucket := chi.URLParam(r, "bucket")
 	key := chi.URLParam(r, "*")
 
+	if !h.allowMutation(w, r, bucket, key) {
+		return
+	}
+
 	if err := h.store.DeleteObject(r.Context(), bucket, key); err != nil {
-		log.Println(err)
+		slog.Error("Failed to delete object", "bucket", bucket, "key", key, "error", err)
 		gosssError.SendGossError(w, http.StatusInternalServerError, err.Error(), bucket+"/"+key)
 		return
 	}
+	h.objectRemoved(bucket, key)
 
 	w.WriteHeader(http.StatusNoContent)
 }
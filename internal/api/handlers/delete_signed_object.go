@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+)
+
+// DeleteSignedObject serves DELETE /presign/{bucket}/* for URLs signed with DELETE.
+func (h *Handler) DeleteSignedObject(w http.ResponseWriter, r *http.Request) {
+	if !h.verifySignedRequest(w, r) {
+		return
+	}
+
+	bucket := chi.URLParam(r, "bucket")
+	key := chi.URLParam(r, "*")
+
+	if !h.allowMutation(w, r, bucket, key) {
+		return
+	}
+
+	if err := h.store.DeleteObject(r.Context(), bucket, key); err != nil {
+		slog.Error("Failed to delete object", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, err.Error(), bucket+"/"+key)
+		return
+	}
+	h.objectRemoved(bucket, key)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// activeContentTypes are rendered by browsers as documents that can run
+// scripts, which makes serving untrusted uploads of them inline an XSS risk
+var activeContentTypes = map[string]bool{
+	"text/html":             true,
+	"application/xhtml+xml": true,
+	"image/svg+xml":         true,
+	"text/xml":              true,
+	"application/xml":       true,
+	"text/xsl":              true,
+}
+
+// setDownloadHeaders hardens the response to a download of untrusted
+// content. Browsers are told not to second-guess contentType, the type the
+// object is served as, and with FORCE_ATTACHMENT active content is only
+// offered as a file to save.
+func (h *Handler) setDownloadHeaders(w http.ResponseWriter, key, contentType string) {
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if h.config.ForceAttachment && isActiveContentType(contentType) {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(key)}))
+	}
+}
+
+// isActiveContentType reports whether a browser might render contentType as
+// a document. Unknown types count, since they may be sniffed as HTML.
+func isActiveContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return true
+	}
+	return activeContentTypes[mediaType] || strings.HasSuffix(mediaType, "+xml")
+}
+
+// servedContentType is the Content-Type an object is sent with: the stored
+// one, or else the one its key's extension maps to. Empty means it will be
+// sniffed from the content.
+func servedContentType(key, stored string) string {
+	if stored != "" {
+		return stored
+	}
+	return mime.TypeByExtension(path.Ext(key))
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"testing"
+)
+
+func TestIsActiveContentType(t *testing.T) {
+	for contentType, want := range map[string]bool{
+		"text/html":                true,
+		"text/html; charset=utf-8": true,
+		"image/svg+xml":            true,
+		"application/xml":          true,
+		"application/atom+xml":     true,
+		"":                         true,
+		"text/plain":               false,
+		"image/png":                false,
+		"application/json":         false,
+	} {
+		if got := isActiveContentType(contentType); got != want {
+			t.Errorf("isActiveContentType(%q) = %v, want %v", contentType, got, want)
+		}
+	}
+}
+
+func TestDownloadsAreNosniff(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+	ts.mustPut(t, "docs", "page.html", "<script>alert(1)</script>", "Content-Type", "text/html")
+
+	for _, target := range []string{"/docs/page.html", presignURL(sha256.New, "SHA256", http.MethodGet, "docs", "page.html", nil)} {
+		rec := ts.do(t, http.MethodGet, target, "")
+		expectStatus(t, rec, http.StatusOK)
+		if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+			t.Errorf("GET %s: X-Content-Type-Options = %q, want nosniff", target, got)
+		}
+		if got := rec.Header().Get("Content-Disposition"); got != "" {
+			t.Errorf("GET %s: Content-Disposition = %q without FORCE_ATTACHMENT", target, got)
+		}
+	}
+}
+
+func TestForceAttachment(t *testing.T) {
+	ts := newTestServer(t, "FORCE_ATTACHMENT=true")
+	ts.mustCreateBucket(t, "docs")
+	ts.mustPut(t, "docs", "site/page.html", "<html></html>", "Content-Type", "text/html")
+	ts.mustPut(t, "docs", "logo.svg", "<svg/>", "Content-Type", "image/svg+xml")
+	ts.mustPut(t, "docs", "photo.png", "png", "Content-Type", "image/png")
+
+	for key, want := range map[string]string{
+		"site/page.html": "attachment; filename=page.html",
+		"logo.svg":       "attachment; filename=logo.svg",
+		"photo.png":      "",
+	} {
+		for _, target := range []string{"/docs/" + key, presignURL(sha256.New, "SHA256", http.MethodGet, "docs", key, nil)} {
+			rec := ts.do(t, http.MethodGet, target, "")
+			expectStatus(t, rec, http.StatusOK)
+			if got := rec.Header().Get("Content-Disposition"); got != want {
+				t.Errorf("GET %s: Content-Disposition = %q, want %q", target, got, want)
+			}
+			if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("GET %s: X-Content-Type-Options = %q, want nosniff", target, got)
+			}
+		}
+	}
+}
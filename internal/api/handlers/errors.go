@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/storage"
+)
+
+// sendObjectLookupError reports a failed object read, telling a missing
+// bucket (NoSuchBucket) apart from a missing key (NoSuchKey) like S3 does.
+func sendObjectLookupError(w http.ResponseWriter, err error, bucket, key string) {
+	switch {
+	case errors.Is(err, storage.ErrBucketNotFound):
+		gosssError.SendGossErrorCode(w, http.StatusNotFound, "NoSuchBucket", "Bucket not found", bucket)
+	case errors.Is(err, storage.ErrObjectNotFound):
+		gosssError.SendGossErrorCode(w, http.StatusNotFound, "NoSuchKey", "Object not found", bucket+"/"+key)
+	default:
+		slog.Error("Failed to read object", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Internal server error", bucket+"/"+key)
+	}
+}
+
+// sendBlockedKeyError rejects a write to a key matching BLOCKED_KEYS.
+func sendBlockedKeyError(w http.ResponseWriter, bucket, key, pattern string) {
+	slog.Debug("Blocked object key", "bucket", bucket, "key", key, "pattern", pattern)
+	gosssError.SendGossError(w, http.StatusForbidden, fmt.Sprintf("key matches blocked pattern %s and cannot be stored", pattern), bucket+"/"+key)
+}
+
+// sendValidationError rejects a request whose bucket name or object key
+// failed validation, with the rule it broke in the response's details.
+func sendValidationError(w http.ResponseWriter, verr validationError, resource string) {
+	gosssError.SendGossErrorDetails(w, http.StatusBadRequest, verr.Message, resource, &gosssError.ErrorDetails{Field: verr.Field, Rule: verr.Rule})
+}
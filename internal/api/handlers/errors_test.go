@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	gosssError "github.com/mmvergara/gosss/internal/error"
+)
+
+func TestValidationErrorDetails(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "test")
+
+	tests := []struct {
+		method, target string
+		field, rule    string
+	}{
+		{http.MethodPut, "/ab", "bucket", "bucket_name_length"},
+		{http.MethodPut, "/test/a%5Cb.txt", "key", "key_sequence"},
+		{http.MethodPost, "/test?rename=my..photos", "rename", "bucket_name_adjacent_periods"},
+		{http.MethodPut, "/test?default-object=_index.html", "default-object", "key_prefix"},
+	}
+	for _, tt := range tests {
+		rec := ts.do(t, tt.method, tt.target, "x")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: status %d, want 400", tt.method, tt.target, rec.Code)
+			continue
+		}
+		var resp gosssError.ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s %s: %v", tt.method, tt.target, err)
+		}
+		if resp.Details == nil || resp.Details.Field != tt.field || resp.Details.Rule != tt.rule || resp.Message == "" {
+			t.Errorf("%s %s: message %q, details %+v, want field %s and rule %s", tt.method, tt.target, resp.Message, resp.Details, tt.field, tt.rule)
+		}
+	}
+
+	// Other errors carry no details
+	rec := ts.do(t, http.MethodGet, "/test/missing.txt", "")
+	expectStatus(t, rec, http.StatusNotFound)
+	var raw map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := raw["details"]; ok {
+		t.Errorf("404 body has details: %s", rec.Body.String())
+	}
+}
+
+func TestObjectLookupErrorCodes(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+
+	tests := []struct {
+		method, target string
+		code, resource string
+	}{
+		{http.MethodGet, "/missing/a.txt", "NoSuchBucket", "missing"},
+		{http.MethodGet, "/docs/a.txt", "NoSuchKey", "docs/a.txt"},
+		{http.MethodHead, "/missing/a.txt", "NoSuchBucket", "missing"},
+		{http.MethodHead, "/docs/a.txt", "NoSuchKey", "docs/a.txt"},
+		{http.MethodHead, "/missing/a.txt?exists", "NoSuchBucket", "missing"},
+		{http.MethodHead, "/docs/a.txt?exists", "NoSuchKey", "docs/a.txt"},
+	}
+	for _, tt := range tests {
+		rec := ts.do(t, tt.method, tt.target, "")
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.target, rec.Code, http.StatusNotFound)
+			continue
+		}
+		// HEAD responses lose their body on the wire, so the header is
+		// all a client has to go on
+		if got := rec.Header().Get("X-Error-Code"); got != tt.code {
+			t.Errorf("%s %s: X-Error-Code = %q, want %q", tt.method, tt.target, got, tt.code)
+		}
+		if tt.method == http.MethodHead {
+			continue
+		}
+		var body gosssError.ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s %s: %v", tt.method, tt.target, err)
+		}
+		if body.Code != tt.code || body.Resource != tt.resource {
+			t.Errorf("%s %s: code %q for %q, want %q for %q", tt.method, tt.target, body.Code, body.Resource, tt.code, tt.resource)
+		}
+	}
+}
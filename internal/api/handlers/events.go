@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/notify"
+)
+
+// objectCreated publishes an ObjectCreated event for a freshly stored object
+func (h *Handler) objectCreated(bucket string, metadata *model.ObjectMetadata) {
+	h.notifier.Publish(model.ObjectEvent{
+		EventType: notify.EventObjectCreated,
+		Bucket:    bucket,
+		Key:       metadata.Key,
+		Size:      metadata.Size,
+		ETag:      metadata.ETag,
+		Timestamp: time.Now().UTC(),
+	})
+}
+
+// objectRemoved publishes an ObjectRemoved event for a deleted object
+func (h *Handler) objectRemoved(bucket, key string) {
+	h.notifier.Publish(model.ObjectEvent{
+		EventType: notify.EventObjectRemoved,
+		Bucket:    bucket,
+		Key:       key,
+		Timestamp: time.Now().UTC(),
+	})
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/storage"
+)
+
+// ExportObjects handles GET /{bucket}?export&format=tar|zip, streaming every
+// object under ?prefix as a single archive with object keys as entry paths.
+func (h *Handler) ExportObjects(w http.ResponseWriter, r *http.Request) {
+	bucket := chi.URLParam(r, "bucket")
+	prefix := r.URL.Query().Get("prefix")
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "tar"
+	}
+	if format != "tar" && format != "zip" {
+		gosssError.SendGossError(w, http.StatusBadRequest, "Archive format must be tar or zip", bucket)
+		return
+	}
+
+	release, ok := acquireSlot(w, h.readSlots, "downloads")
+	if !ok {
+		return
+	}
+	defer release()
+
+	objects, err := h.store.ListObjects(r.Context(), bucket, prefix)
+	if err != nil {
+		slog.Warn("Failed to list objects", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Something went wrong or the bucket does not exist", bucket)
+		return
+	}
+
+	// Archived objects can't be read until they are restored, so they are
+	// left out and counted in X-Archived-Objects-Skipped
+	readable := objects[:0]
+	for i := range objects {
+		if !h.isArchived(&objects[i]) {
+			readable = append(readable, objects[i])
+		}
+	}
+	if skipped := len(objects) - len(readable); skipped > 0 {
+		w.Header().Set("X-Archived-Objects-Skipped", strconv.Itoa(skipped))
+	}
+	objects = readable
+
+	filename := bucket
+	if prefix != "" {
+		filename += "-" + strings.Trim(strings.ReplaceAll(prefix, "/", "-"), "-")
+	}
+	if format == "zip" {
+		w.Header().Set("Content-Type", "application/zip")
+	} else {
+		w.Header().Set("Content-Type", "application/x-tar")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+"."+format))
+
+	// Headers are sent with the first entry, so failures past this point
+	// can only be logged and the archive truncated.
+	if format == "zip" {
+		err = h.exportZip(r, bucket, objects, w)
+	} else {
+		err = h.exportTar(r, bucket, objects, w)
+	}
+	if err != nil && isClientDisconnect(r, err) {
+		slog.Debug("Client disconnected during export", "bucket", bucket, "error", err)
+	} else if err != nil {
+		slog.Error("Failed to export bucket", "bucket", bucket, "error", err)
+	}
+}
+
+// The listing only says which objects to export. Each entry's header is
+// taken from the metadata read together with its data, since an object may
+// have been replaced or deleted since it was listed; deleted ones are left
+// out.
+func (h *Handler) exportTar(r *http.Request, bucket string, objects []model.ObjectMetadata, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	for _, obj := range objects {
+		err := h.exportObject(r, bucket, obj.Key, func(meta *model.ObjectMetadata, body io.Reader) error {
+			hdr := &tar.Header{
+				Name:    meta.Key,
+				Mode:    0644,
+				Size:    meta.Size,
+				ModTime: meta.LastModified,
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			_, err := io.CopyN(tw, body, meta.Size)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func (h *Handler) exportZip(r *http.Request, bucket string, objects []model.ObjectMetadata, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, obj := range objects {
+		err := h.exportObject(r, bucket, obj.Key, func(meta *model.ObjectMetadata, body io.Reader) error {
+			hdr := &zip.FileHeader{
+				Name:     meta.Key,
+				Method:   zip.Deflate,
+				Modified: meta.LastModified,
+			}
+			fw, err := zw.CreateHeader(hdr)
+			if err != nil {
+				return err
+			}
+			_, err = io.CopyN(fw, body, meta.Size)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// exportObject opens an object and passes its current metadata and body to
+// write. Objects deleted or archived since the listing are skipped.
+func (h *Handler) exportObject(r *http.Request, bucket, key string, write func(*model.ObjectMetadata, io.Reader) error) error {
+	obj, meta, err := h.store.GetObject(r.Context(), bucket, key)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		slog.Debug("Object deleted during export", "bucket", bucket, "key", key)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	if h.isArchived(meta) {
+		slog.Debug("Object archived during export", "bucket", bucket, "key", key)
+		return nil
+	}
+
+	return write(meta, obj)
+}
@@ -1,33 +1,122 @@
 package handlers
 
 import (
+	"compress/gzip"
+	"errors"
 	"io"
-	"log"
 	"net/http"
+	"path"
 
 	"github.com/go-chi/chi/v5"
-	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/storage"
 )
 
 func (h *Handler) GetObject(w http.ResponseWriter, r *http.Request) {
-	bucket := chi.URLParam(r, "bucket")
-	key := chi.URLParam(r, "*")
+	if r.URL.Query().Has("metadata") {
+		h.GetObjectMetadata(w, r)
+		return
+	}
+	if r.URL.Query().Has("tagging") {
+		h.GetObjectTagging(w, r)
+		return
+	}
+	if r.URL.Query().Has("history") {
+		h.GetObjectHistory(w, r)
+		return
+	}
+
+	h.serveObject(w, r, chi.URLParam(r, "bucket"), chi.URLParam(r, "*"))
+}
+
+// serveObject streams an object along with its headers, honoring Range,
+// conditional requests, gzip and image transforms.
+func (h *Handler) serveObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	release, ok := acquireSlot(w, h.readSlots, "downloads")
+	if !ok {
+		return
+	}
+	defer release()
 
 	obj, metadata, err := h.store.GetObject(r.Context(), bucket, key)
+	if errors.Is(err, storage.ErrObjectNotFound) && h.origin != nil {
+		h.serveFromOrigin(w, r, bucket, key)
+		return
+	}
 	if err != nil {
-		log.Println(err)
-		gosssError.SendGossError(w, http.StatusNotFound, "Object not found", bucket+"/"+key)
+		sendObjectLookupError(w, err, bucket, key)
 		return
 	}
 	defer obj.Close()
 
-	w.Header().Set("Content-Type", metadata.ContentType)
+	if h.sendObjectRedirect(w, metadata) {
+		return
+	}
+
+	if h.isArchived(metadata) {
+		sendArchivedError(w, bucket, key)
+		return
+	}
+
+	if h.transformRequested(key, metadata) {
+		h.serveVariant(w, r, bucket, key, obj, metadata)
+		return
+	}
+
+	h.writeObject(w, r, bucket, key, obj, metadata)
+}
+
+// writeObject sends an object's headers and body. Every download carries the
+// validators and range support that caches and resuming clients rely on:
+// ETag, Last-Modified, Accept-Ranges and, when the body may be compressed,
+// Vary: Accept-Encoding.
+func (h *Handler) writeObject(w http.ResponseWriter, r *http.Request, bucket, key string, obj io.Reader, metadata *model.ObjectMetadata) {
+	// The stored content type and ETag are authoritative; the key's
+	// extension is only used when no content type was stored
+	if metadata.ContentType != "" {
+		w.Header().Set("Content-Type", metadata.ContentType)
+	}
 	w.Header().Set("ETag", metadata.ETag)
 	w.Header().Set("Last-Modified", metadata.LastModified.Format(http.TimeFormat))
+	w.Header().Set("X-Storage-Class", storageClassOf(metadata))
+	h.setCacheControl(w, r, bucket, metadata)
+	h.setDownloadHeaders(w, key, servedContentType(key, metadata.ContentType))
 
-	if _, err := io.Copy(w, obj); err != nil {
-		log.Println(err)
-		gosssError.SendGossError(w, http.StatusInternalServerError, "Internal server error", bucket+"/"+key)
+	h.setVaryEncoding(w, metadata.Size)
+
+	rs, seekable := obj.(io.ReadSeeker)
+	if seekable {
+		w.Header().Set("Accept-Ranges", "bytes")
+	} else {
+		w.Header().Set("Accept-Ranges", "none")
+	}
+
+	// ServeContent handles Range and conditional requests and lets the
+	// server use sendfile for the body
+	gzipped := h.shouldGzip(r, metadata.Size)
+	if seekable && !gzipped {
+		http.ServeContent(w, r, path.Base(key), metadata.LastModified, rs)
+		return
+	}
+
+	var body io.Writer = w
+	var gz *gzip.Writer
+	if gzipped {
+		// The compressed body is a different representation, so it can't
+		// share the stored bytes' strong ETag: If-Range with it must not
+		// resume into the uncompressed bytes
+		w.Header().Set("ETag", gzipETag(metadata.ETag))
+		w.Header().Set("Content-Encoding", "gzip")
+		gz = gzip.NewWriter(w)
+		body = gz
+	}
+
+	written, err := io.Copy(body, obj)
+	if err == nil && gz != nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		handleStreamError(w, r, err, written, bucket, key)
 		return
 	}
 }
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/response"
+)
+
+// GetObjectMetadata handles GET /{bucket}/*?metadata, returning the object's
+// metadata as JSON without streaming its body.
+func (h *Handler) GetObjectMetadata(w http.ResponseWriter, r *http.Request) {
+	bucket := chi.URLParam(r, "bucket")
+	key := chi.URLParam(r, "*")
+
+	metadata, err := h.store.HeadObject(r.Context(), bucket, key)
+	if err != nil {
+		sendObjectLookupError(w, err, bucket, key)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := response.Encode(w, metadata); err != nil {
+		slog.Error("Failed to encode metadata", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Internal server error", bucket+"/"+key)
+		return
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDownloadHeaders(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "media")
+	ts.mustPut(t, "media", "clip.bin", "0123456789")
+	head := ts.do(t, http.MethodHead, "/media/clip.bin", "")
+
+	for _, target := range []string{"/media/clip.bin", presignURL(sha256.New, "SHA256", http.MethodGet, "media", "clip.bin", nil)} {
+		rec := ts.do(t, http.MethodGet, target, "")
+		expectStatus(t, rec, http.StatusOK)
+		for name, want := range map[string]string{
+			"ETag":           head.Header().Get("ETag"),
+			"Last-Modified":  head.Header().Get("Last-Modified"),
+			"Accept-Ranges":  "bytes",
+			"Content-Length": "10",
+			"Vary":           "",
+		} {
+			if got := rec.Header().Get(name); got != want || (name != "Vary" && got == "") {
+				t.Errorf("GET %s: %s = %q, want %q", target, name, got, want)
+			}
+		}
+
+		// Resuming with the ETag continues where the download stopped
+		rec = ts.do(t, http.MethodGet, target, "", "Range", "bytes=4-", "If-Range", head.Header().Get("ETag"))
+		expectStatus(t, rec, http.StatusPartialContent)
+		if rec.Body.String() != "456789" || rec.Header().Get("ETag") != head.Header().Get("ETag") {
+			t.Errorf("GET %s resumed = %q with ETag %q", target, rec.Body.String(), rec.Header().Get("ETag"))
+		}
+		// but a changed object is sent whole
+		rec = ts.do(t, http.MethodGet, target, "", "Range", "bytes=4-", "If-Range", `"stale"`)
+		expectStatus(t, rec, http.StatusOK)
+		if rec.Body.String() != "0123456789" {
+			t.Errorf("GET %s with a stale If-Range = %q, want the whole object", target, rec.Body.String())
+		}
+	}
+}
+
+func TestDownloadHeadersWithGzip(t *testing.T) {
+	ts := newTestServer(t, "GZIP_RESPONSES=true", "GZIP_MIN_SIZE=16")
+	ts.mustCreateBucket(t, "media")
+	body := strings.Repeat("compressible ", 10)
+	ts.mustPut(t, "media", "big.txt", body)
+	ts.mustPut(t, "media", "tiny.txt", "tiny")
+	etag := ts.do(t, http.MethodHead, "/media/big.txt", "").Header().Get("ETag")
+
+	rec := ts.do(t, http.MethodGet, "/media/big.txt", "", "Accept-Encoding", "gzip")
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("gzipped GET: Content-Encoding %q, Vary %q", rec.Header().Get("Content-Encoding"), rec.Header().Get("Vary"))
+	}
+	if got := rec.Header().Get("ETag"); got == etag || !strings.HasSuffix(got, `-gzip"`) {
+		t.Errorf("gzipped GET: ETag %q, want a -gzip variant of %q", got, etag)
+	}
+	if rec.Header().Get("Last-Modified") == "" || rec.Header().Get("Accept-Ranges") != "bytes" {
+		t.Errorf("gzipped GET: Last-Modified %q, Accept-Ranges %q", rec.Header().Get("Last-Modified"), rec.Header().Get("Accept-Ranges"))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(zr); string(data) != body {
+		t.Errorf("gunzipped body = %q", data)
+	}
+
+	// The same object uncompressed still varies on Accept-Encoding
+	rec = ts.do(t, http.MethodGet, "/media/big.txt", "")
+	if rec.Header().Get("Content-Encoding") != "" || rec.Header().Get("Vary") != "Accept-Encoding" || rec.Header().Get("ETag") != etag {
+		t.Errorf("identity GET: Content-Encoding %q, Vary %q, ETag %q", rec.Header().Get("Content-Encoding"), rec.Header().Get("Vary"), rec.Header().Get("ETag"))
+	}
+
+	// Ranges are served from the stored bytes
+	rec = ts.do(t, http.MethodGet, "/media/big.txt", "", "Accept-Encoding", "gzip", "Range", "bytes=0-11")
+	expectStatus(t, rec, http.StatusPartialContent)
+	if rec.Body.String() != "compressible" || rec.Header().Get("Content-Encoding") != "" || rec.Header().Get("ETag") != etag {
+		t.Errorf("range GET = %q, Content-Encoding %q, ETag %q", rec.Body.String(), rec.Header().Get("Content-Encoding"), rec.Header().Get("ETag"))
+	}
+
+	// Bodies too small to compress don't vary
+	rec = ts.do(t, http.MethodGet, "/media/tiny.txt", "", "Accept-Encoding", "gzip")
+	if rec.Header().Get("Content-Encoding") != "" || rec.Header().Get("Vary") != "" {
+		t.Errorf("tiny GET: Content-Encoding %q, Vary %q", rec.Header().Get("Content-Encoding"), rec.Header().Get("Vary"))
+	}
+}
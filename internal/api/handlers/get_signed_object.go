@@ -3,9 +3,13 @@ package handlers
 import (
 	"crypto/hmac"
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
-	"io"
+	"fmt"
+	"hash"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -14,70 +18,180 @@ import (
 	gosssError "github.com/mmvergara/gosss/internal/error"
 )
 
-// generateSignature creates an HMAC-SHA256 signature for the given parameters
-func (h *Handler) generateSignature(expiration, bucket, key string) (string, error) {
+// MaxPresignNonceLength caps the nonce of a one-time presigned URL
+const MaxPresignNonceLength = 128
+
+// presignAlgorithms are the HMAC hashes presigned URLs may be signed with,
+// by the name used in PRESIGN_ALGORITHM and the ?algorithm parameter
+var presignAlgorithms = map[string]func() hash.Hash{
+	"SHA256": sha256.New,
+	"SHA512": sha512.New,
+}
+
+// generateSignature creates an HMAC signature for the given parameters.
+// The HTTP method is part of the string to sign so a URL signed for one
+// operation (e.g. GET) cannot be replayed for another (e.g. DELETE).
+// URLs naming their algorithm also sign it, so it can't be swapped; URLs
+// without one are signed with PRESIGN_ALGORITHM over the original string.
+// Any other query parameters are appended in canonical form (see
+// canonicalQuery), so none can be added, removed or changed after signing.
+func (h *Handler) generateSignature(algorithm, method, expiration, bucket, key string, query url.Values) (string, error) {
 	// Create string to sign in same format as client
-	stringToSign := strings.Join([]string{expiration, bucket, key}, ":")
+	parts := []string{method, expiration, bucket, key}
+	if canonical := canonicalQuery(query); canonical != "" {
+		parts = append(parts, canonical)
+	}
+	newHash := presignAlgorithms[h.config.PresignAlgorithm]
+	if algorithm != "" {
+		parts = append([]string{algorithm}, parts...)
+		newHash = presignAlgorithms[algorithm]
+	}
+	if newHash == nil {
+		return "", fmt.Errorf("unsupported signature algorithm %q", algorithm)
+	}
+	stringToSign := strings.Join(parts, ":")
 
-	mac := hmac.New(sha256.New, []byte(h.config.SecretKey))
+	mac := hmac.New(newHash, []byte(h.config.SecretKey))
 	mac.Write([]byte(stringToSign))
 	signature := hex.EncodeToString(mac.Sum(nil))
 	return signature, nil
 }
 
-func (h *Handler) GetSignedObject(w http.ResponseWriter, r *http.Request) {
+// presignReservedParams are the query parameters with a fixed place in the
+// string to sign, or that are the signature itself
+var presignReservedParams = map[string]bool{
+	"expiration": true,
+	"algorithm":  true,
+	"signature":  true,
+}
+
+// canonicalQuery renders the query parameters of a presigned URL other than
+// presignReservedParams as name=value pairs, sorted bytewise and joined by
+// "&". Names and values are percent-encoded as in RFC 3986: everything but
+// A-Z a-z 0-9 - _ . ~ becomes %XX, spaces included.
+func canonicalQuery(query url.Values) string {
+	var pairs []string
+	for name, values := range query {
+		if presignReservedParams[name] {
+			continue
+		}
+		for _, value := range values {
+			pairs = append(pairs, presignEscape(name)+"="+presignEscape(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// presignEscape percent-encodes s for canonicalQuery
+func presignEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// verifySignedRequest validates the expiration and signature query parameters
+// of a presigned request. It writes an error response and returns false when
+// the request must not proceed.
+func (h *Handler) verifySignedRequest(w http.ResponseWriter, r *http.Request) bool {
 	// Validate query parameters
 	expiration := r.URL.Query().Get("expiration")
 	signature := r.URL.Query().Get("signature")
+	algorithm := strings.ToUpper(r.URL.Query().Get("algorithm"))
 	bucket := chi.URLParam(r, "bucket")
 	key := chi.URLParam(r, "*")
 
 	if expiration == "" || signature == "" {
 		gosssError.SendGossError(w, http.StatusBadRequest, "Missing required query parameters", "expiration and signature required")
-		return
+		return false
+	}
+
+	if algorithm != "" && presignAlgorithms[algorithm] == nil {
+		gosssError.SendGossError(w, http.StatusBadRequest, "Unsupported signature algorithm", "algorithm must be SHA256 or SHA512")
+		return false
 	}
 
 	// Parse and validate expiration
 	exp, err := strconv.ParseInt(expiration, 10, 64)
 	if err != nil {
 		gosssError.SendGossError(w, http.StatusBadRequest, "Invalid expiration format", "")
-		return
+		return false
 	}
 
-	// Check if URL has expired
-	if time.Now().Unix() > exp {
+	// Check if URL has expired, allowing for a little clock skew
+	now := time.Now()
+	skew := int64(h.config.PresignClockSkew / time.Second)
+	if now.Unix() > exp+skew {
 		gosssError.SendGossError(w, http.StatusForbidden, "URL has expired", "")
-		return
+		return false
 	}
 
-	// Verify signature using bucket and key in the signature generation
-	expectedSignature, err := h.generateSignature(expiration, bucket, key)
+	// Reject URLs minted with a lifetime beyond the allowed maximum
+	if h.config.MaxPresignTTL > 0 {
+		maxExp := now.Add(h.config.MaxPresignTTL).Unix() + skew
+		if exp > maxExp {
+			gosssError.SendGossError(w, http.StatusForbidden, "URL expiration is too far in the future", "")
+			return false
+		}
+	}
+
+	// Verify signature using method, bucket and key in the signature generation
+	expectedSignature, err := h.generateSignature(algorithm, r.Method, expiration, bucket, key, r.URL.Query())
 	if err != nil {
 		gosssError.SendGossError(w, http.StatusInternalServerError, "Error verifying signature", "")
-		return
+		return false
 	}
 
 	if !hmac.Equal([]byte(signature), []byte(expectedSignature)) {
 		gosssError.SendGossError(w, http.StatusForbidden, "Invalid signature", "")
+		return false
+	}
+
+	// A nonce, signed like any other parameter, makes the URL single-use.
+	// It is used up by the first request, even if that request then fails.
+	if r.URL.Query().Has("nonce") {
+		nonce := r.URL.Query().Get("nonce")
+		if nonce == "" || len(nonce) > MaxPresignNonceLength {
+			gosssError.SendGossError(w, http.StatusBadRequest, "Invalid nonce", "nonce must be 1 to 128 characters")
+			return false
+		}
+		if !h.nonces.Use(nonce, time.Unix(exp+skew, 0)) {
+			gosssError.SendGossError(w, http.StatusForbidden, "URL has already been used", "")
+			return false
+		}
+	}
+
+	return true
+}
+
+func (h *Handler) GetSignedObject(w http.ResponseWriter, r *http.Request) {
+	if !h.verifySignedRequest(w, r) {
 		return
 	}
 
+	bucket := chi.URLParam(r, "bucket")
+	key := chi.URLParam(r, "*")
+
+	release, ok := acquireSlot(w, h.readSlots, "downloads")
+	if !ok {
+		return
+	}
+	defer release()
+
 	// If signature is valid, proceed with getting the object
 	obj, metadata, err := h.store.GetObject(r.Context(), bucket, key)
 	if err != nil {
-		gosssError.SendGossError(w, http.StatusNotFound, "Object not found", bucket+"/"+key)
+		sendObjectLookupError(w, err, bucket, key)
 		return
 	}
 	defer obj.Close()
 
-	// Set response headers
-	w.Header().Set("Content-Type", metadata.ContentType)
-	w.Header().Set("ETag", metadata.ETag)
-	w.Header().Set("Last-Modified", metadata.LastModified.Format(http.TimeFormat))
+	if h.sendObjectRedirect(w, metadata) {
+		return
+	}
 
-	// Stream the object to the response
-	if _, err := io.Copy(w, obj); err != nil {
-		gosssError.SendGossError(w, http.StatusInternalServerError, "Internal server error", bucket+"/"+key)
+	if h.isArchived(metadata) {
+		sendArchivedError(w, bucket, key)
 		return
 	}
+
+	h.writeObject(w, r, bucket, key, obj, metadata)
 }
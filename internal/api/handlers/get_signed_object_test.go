@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// presignURL signs a URL the way the SDKs do, with the test server's secret
+// key. algorithm is the name put in the URL, if any, and newHash the hash
+// actually signed with. extra parameters are signed with canonicalQuery.
+func presignURL(newHash func() hash.Hash, algorithm, method, bucket, key string, extra url.Values) string {
+	expiration := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	parts := []string{method, expiration, bucket, key}
+	if canonical := canonicalQuery(extra); canonical != "" {
+		parts = append(parts, canonical)
+	}
+	if algorithm != "" {
+		parts = append([]string{algorithm}, parts...)
+	}
+	mac := hmac.New(newHash, []byte("test-secret-key"))
+	mac.Write([]byte(strings.Join(parts, ":")))
+
+	query := url.Values{}
+	for name, values := range extra {
+		query[name] = values
+	}
+	query.Set("expiration", expiration)
+	query.Set("signature", hex.EncodeToString(mac.Sum(nil)))
+	if algorithm != "" {
+		query.Set("algorithm", algorithm)
+	}
+	return "/presign/" + bucket + "/" + key + "?" + query.Encode()
+}
+
+func TestPresignAlgorithms(t *testing.T) {
+	for _, tc := range []struct {
+		configured                string
+		configuredHash, otherHash func() hash.Hash
+	}{
+		{"SHA256", sha256.New, sha512.New},
+		{"SHA512", sha512.New, sha256.New},
+	} {
+		t.Run(tc.configured, func(t *testing.T) {
+			ts := newTestServer(t, "PRESIGN_ALGORITHM="+tc.configured)
+			ts.mustCreateBucket(t, "docs")
+			ts.mustPut(t, "docs", "a.txt", "signed")
+
+			// URLs naming their algorithm verify whatever is configured
+			for _, target := range []string{
+				presignURL(sha256.New, "SHA256", http.MethodGet, "docs", "a.txt", nil),
+				presignURL(sha512.New, "SHA512", http.MethodGet, "docs", "a.txt", nil),
+				presignURL(tc.configuredHash, "", http.MethodGet, "docs", "a.txt", nil),
+			} {
+				rec := ts.do(t, http.MethodGet, target, "")
+				expectStatus(t, rec, http.StatusOK)
+				if rec.Body.String() != "signed" {
+					t.Errorf("GET %s = %q, want signed", target, rec.Body.String())
+				}
+			}
+
+			// Unnamed URLs signed with the other hash, and URLs whose named
+			// algorithm isn't the one they were signed with, don't
+			expectStatus(t, ts.do(t, http.MethodGet, presignURL(tc.otherHash, "", http.MethodGet, "docs", "a.txt", nil), ""), http.StatusForbidden)
+			swapped := presignURL(sha256.New, "SHA256", http.MethodGet, "docs", "a.txt", nil)
+			swapped = strings.Replace(swapped, "algorithm=SHA256", "algorithm=SHA512", 1)
+			expectStatus(t, ts.do(t, http.MethodGet, swapped, ""), http.StatusForbidden)
+
+			expectStatus(t, ts.do(t, http.MethodGet, presignURL(sha256.New, "MD5", http.MethodGet, "docs", "a.txt", nil), ""), http.StatusBadRequest)
+		})
+	}
+}
+
+// Every query parameter is signed, so none can be changed, added or removed
+// once the URL is issued
+func TestPresignQueryTampering(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+	ts.mustPut(t, "docs", "a.txt", "signed")
+
+	signed := presignURL(sha256.New, "SHA256", http.MethodGet, "docs", "a.txt", url.Values{"response-content-type": {"text/plain"}})
+	expectStatus(t, ts.do(t, http.MethodGet, signed, ""), http.StatusOK)
+
+	for name, tamper := range map[string]func(url.Values){
+		"changed":  func(q url.Values) { q.Set("response-content-type", "text/html") },
+		"added":    func(q url.Values) { q.Set("download", "evil.html") },
+		"repeated": func(q url.Values) { q.Add("response-content-type", "text/html") },
+		"removed":  func(q url.Values) { q.Del("response-content-type") },
+		"expiration": func(q url.Values) {
+			exp, _ := strconv.ParseInt(q.Get("expiration"), 10, 64)
+			q.Set("expiration", strconv.FormatInt(exp-1, 10))
+		},
+	} {
+		target, err := url.Parse(signed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		query := target.Query()
+		tamper(query)
+		target.RawQuery = query.Encode()
+		if rec := ts.do(t, http.MethodGet, target.String(), ""); rec.Code != http.StatusForbidden {
+			t.Errorf("%s parameter: status %d, want 403", name, rec.Code)
+		}
+	}
+}
@@ -1,34 +1,93 @@
 package handlers
 
 import (
+	"net/http"
+	"runtime"
 	"sync"
 	"time"
 
 	"github.com/mmvergara/gosss/internal/config"
+	"github.com/mmvergara/gosss/internal/idempotency"
+	"github.com/mmvergara/gosss/internal/nonce"
+	"github.com/mmvergara/gosss/internal/notify"
 	"github.com/mmvergara/gosss/internal/storage"
+	"github.com/mmvergara/gosss/internal/transform"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
 	MaxFileSize = 10 * 1024 * 1024 * 1024 // 10GB
 
-	MaxConcurrent  = 100
-	RequestTimeout = 30 * time.Second
-)
+	// Filesystems commonly limit a single path component to 255 bytes
+	MaxKeySegmentLength = 255
+
+	// JSON uploads are decoded in memory, so they get a much smaller cap
+	MaxJSONUploadSize = 64 * 1024 * 1024 // 64MB
+
+	// Object tagging limits (same as S3)
+	MaxTagsPerObject   = 10
+	MaxTagKeyLength    = 128
+	MaxTagValueLength  = 256
+	MaxTaggingBodySize = 64 * 1024 // 64KB
 
-var (
-	semaphore = make(chan struct{}, MaxConcurrent)
+	// Archive entries larger than this are skipped during bulk import
+	MaxImportEntrySize = 1 * 1024 * 1024 * 1024 // 1GB
+
+	// Zip archives are spooled to disk before they are extracted, so the
+	// archive as a whole is capped too
+	MaxImportArchiveSize = 10 * 1024 * 1024 * 1024 // 10GB
+
+	// Images are decoded in memory to be resized, so larger ones aren't
+	MaxTransformSourceSize = 32 * 1024 * 1024 // 32MB
+
+	RequestTimeout = 30 * time.Second
 )
 
 type Handler struct {
-	store  storage.Storage
-	mutex  sync.RWMutex
-	config *config.Config
+	store    storage.Storage
+	mutex    sync.RWMutex
+	config   *config.Config
+	notifier *notify.Notifier
+
+	// idempotency is nil when Idempotency-Key support is disabled
+	idempotency *idempotency.Store
+
+	// origin is nil unless ORIGIN_URL is set
+	origin *http.Client
+
+	// nonces records the one-time presigned URLs already used
+	nonces *nonce.Store
+
+	// writeSlots and readSlots bound concurrent uploads and downloads; nil
+	// means unlimited
+	writeSlots chan struct{}
+	readSlots  chan struct{}
+
+	// transformer is nil unless IMAGE_TRANSFORMS is enabled; variants caches
+	// its output and is nil when that cache is disabled. transformSlots
+	// bounds the transforms running at once
+	transformer    transform.Transformer
+	variants       *transform.Cache
+	variantFlight  singleflight.Group
+	transformSlots chan struct{}
 }
 
 func NewHandler(store storage.Storage, config *config.Config) *Handler {
 	return &Handler{
-		store:  store,
-		config: config,
-		mutex:  sync.RWMutex{},
+		store:    store,
+		config:   config,
+		mutex:    sync.RWMutex{},
+		notifier: notify.New(config.WebhookURL, config.BucketWebhooks),
+
+		idempotency: idempotency.New(config.IdempotencyTTL),
+		origin:      originClient(config.OriginURL),
+		nonces:      nonce.New(),
+
+		writeSlots: newSlots(config.MaxConcurrentWrites),
+		readSlots:  newSlots(config.MaxConcurrentReads),
+
+		transformer:    newTransformer(config.ImageTransforms, config.ImageTransformMaxDimension),
+		variants:       transform.NewCache(config.ImageTransformCacheBytes),
+		transformSlots: newSlots(runtime.NumCPU()),
 	}
 }
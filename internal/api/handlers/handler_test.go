@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mmvergara/gosss/internal/config"
+	"github.com/mmvergara/gosss/internal/storage"
+)
+
+// testServer is a Handler over a LocalStorage in a temporary directory,
+// routed like the data-plane and admin routes of the real router but
+// without authentication.
+type testServer struct {
+	h      *Handler
+	store  *storage.LocalStorage
+	router chi.Router
+}
+
+// newTestServer builds a testServer. env holds extra NAME=value settings
+// applied before the configuration is loaded.
+func newTestServer(t testing.TB, env ...string) *testServer {
+	t.Helper()
+	t.Setenv("ACCESS_KEY_ID", "test-access-key")
+	t.Setenv("SECRET_ACCESS_KEY", "test-secret-key")
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		t.Setenv(name, value)
+	}
+	cfg, err := config.New()
+	if err != nil {
+		t.Fatalf("config.New: %v", err)
+	}
+	cfg.StoragePath = t.TempDir()
+
+	store := storage.New(cfg.StoragePath, storage.Options{
+		SoftDelete:          cfg.SoftDelete,
+		TrashRetention:      cfg.TrashRetention,
+		MaxObjectsPerBucket: cfg.MaxObjectsPerBucket,
+		ContentAddressed:    cfg.ContentAddressed,
+		ShardKeys:           cfg.ShardKeys,
+		ETagHistoryLimit:    cfg.ETagHistoryLimit,
+	})
+	return newTestServerWith(t, store, cfg)
+}
+
+func newTestServerWith(t testing.TB, store *storage.LocalStorage, cfg *config.Config) *testServer {
+	t.Helper()
+	h := NewHandler(store, cfg)
+
+	r := chi.NewRouter()
+	r.Get("/presign/{bucket}/*", h.GetSignedObject)
+	r.Head("/presign/{bucket}/*", h.HeadSignedObject)
+	r.Delete("/presign/{bucket}/*", h.DeleteSignedObject)
+
+	r.Put("/{bucket}", h.CreateBucket)
+	r.Post("/{bucket}", h.PostBucket)
+	r.Delete("/{bucket}", h.DeleteBucket)
+	r.Head("/{bucket}", h.HeadBucket)
+	r.Get("/{bucket}/*", h.GetObject)
+	r.Put("/{bucket}/*", h.PutObject)
+	r.Post("/{bucket}/*", h.PostObject)
+	r.Delete("/{bucket}/*", h.DeleteObject)
+	r.Get("/{bucket}", h.ListObjects)
+	r.Head("/{bucket}/*", h.HeadObject)
+
+	r.Route("/admin", func(r chi.Router) {
+		r.Put("/{bucket}", h.AdminPutBucket)
+		r.Post("/{bucket}", h.AdminPostBucket)
+		r.Delete("/{bucket}", h.AdminDeleteBucket)
+		r.Post("/{bucket}/*", h.AdminPostObject)
+	})
+	return &testServer{h: h, store: store, router: r}
+}
+
+// do serves a request with an optional body and headers given as
+// alternating names and values.
+func (ts *testServer) do(t testing.TB, method, target, body string, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	ts.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// mustCreateBucket creates bucket directly in storage
+func (ts *testServer) mustCreateBucket(t testing.TB, bucket string) {
+	t.Helper()
+	if err := ts.store.CreateBucket(context.Background(), bucket); err != nil {
+		t.Fatalf("CreateBucket(%s): %v", bucket, err)
+	}
+}
+
+// mustPut uploads an object through PutObject and fails the test unless it
+// is stored
+func (ts *testServer) mustPut(t *testing.T, bucket, key, body string, headers ...string) {
+	t.Helper()
+	rec := ts.do(t, http.MethodPut, "/"+bucket+"/"+key, body, headers...)
+	if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Fatalf("PUT %s/%s: status %d: %s", bucket, key, rec.Code, rec.Body.String())
+	}
+}
+
+// expectStatus fails the test if rec doesn't have the wanted status
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if rec.Code != want {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, want, rec.Body.String())
+	}
+}
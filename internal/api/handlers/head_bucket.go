@@ -1,26 +1,52 @@
 package handlers
 
 import (
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/storage"
 )
 
 func (h *Handler) HeadBucket(w http.ResponseWriter, r *http.Request) {
-	log.Println("HEAD /{bucket}")
 	bucket := chi.URLParam(r, "bucket")
 
-	exists, err := h.store.BucketExists(r.Context(), bucket)
+	stats, err := h.store.BucketStats(r.Context(), bucket)
+	if errors.Is(err, storage.ErrBucketNotFound) {
+		gosssError.SendGossError(w, http.StatusNotFound, "Bucket not found", bucket)
+		return
+	}
 	if err != nil {
+		slog.Error("Failed to get bucket stats", "bucket", bucket, "error", err)
 		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to check bucket", bucket)
 		return
 	}
-	if !exists {
-		gosssError.SendGossError(w, http.StatusNotFound, "Bucket not found", bucket)
+
+	// The stats are the bucket's state as HEAD reports it, so their weak
+	// ETag changes whenever a header would
+	etag, err := listingETag(stats)
+	if err != nil {
+		slog.Error("Failed to compute bucket ETag", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to check bucket", bucket)
+		return
+	}
+	if notModified(w, r, etag) {
 		return
 	}
 
+	w.Header().Set("X-Bucket-Object-Count", strconv.FormatInt(stats.ObjectCount, 10))
+	w.Header().Set("X-Bucket-Size-Bytes", strconv.FormatInt(stats.SizeBytes, 10))
+	if stats.Quota > 0 {
+		w.Header().Set("X-Bucket-Quota-Bytes", strconv.FormatInt(stats.Quota, 10))
+	}
+	if stats.MaxObjectSize > 0 {
+		w.Header().Set("X-Bucket-Max-Object-Size", strconv.FormatInt(stats.MaxObjectSize, 10))
+	}
+	if !stats.CreatedAt.IsZero() {
+		w.Header().Set("X-Bucket-Created-At", stats.CreatedAt.UTC().Format(http.TimeFormat))
+	}
 	w.WriteHeader(http.StatusOK)
 }
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHeadBucketIfNoneMatch(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+	ts.mustPut(t, "docs", "a.txt", "hello")
+
+	rec := ts.do(t, http.MethodHead, "/docs", "")
+	expectStatus(t, rec, http.StatusOK)
+	etag := rec.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("bucket ETag = %q, want a weak validator", etag)
+	}
+
+	rec = ts.do(t, http.MethodHead, "/docs", "", "If-None-Match", etag)
+	expectStatus(t, rec, http.StatusNotModified)
+	if rec.Header().Get("ETag") != etag || rec.Header().Get("X-Bucket-Object-Count") != "" {
+		t.Errorf("304 headers = %v, want only the ETag", rec.Header())
+	}
+
+	for _, match := range []string{"", `W/"other"`} {
+		rec = ts.do(t, http.MethodHead, "/docs", "", "If-None-Match", match)
+		expectStatus(t, rec, http.StatusOK)
+		if rec.Header().Get("X-Bucket-Object-Count") != "1" || rec.Header().Get("X-Bucket-Size-Bytes") != "5" {
+			t.Errorf("If-None-Match %q: headers %v, want the bucket stats", match, rec.Header())
+		}
+	}
+
+	// A new object changes the bucket's state and so its ETag
+	ts.mustPut(t, "docs", "b.txt", "world")
+	rec = ts.do(t, http.MethodHead, "/docs", "", "If-None-Match", etag)
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Header().Get("ETag") == etag {
+		t.Errorf("bucket ETag unchanged after an upload: %s", etag)
+	}
+}
@@ -2,28 +2,55 @@ package handlers
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
-	gosssError "github.com/mmvergara/gosss/internal/error"
 )
 
 func (h *Handler) HeadObject(w http.ResponseWriter, r *http.Request) {
 	bucket := chi.URLParam(r, "bucket")
 	key := chi.URLParam(r, "*")
 
+	if r.URL.Query().Has("exists") {
+		h.headObjectExists(w, r, bucket, key)
+		return
+	}
+
 	metadata, err := h.store.HeadObject(r.Context(), bucket, key)
 	if err != nil {
-		log.Println(err)
-		gosssError.SendGossError(w, http.StatusNotFound, "Object not found", bucket+"/"+key)
+		sendObjectLookupError(w, err, bucket, key)
+		return
+	}
+
+	if h.sendObjectRedirect(w, metadata) {
+		return
+	}
+	// Pollers revalidating with the ETag they hold get a bare 304
+	if notModified(w, r, metadata.ETag) {
 		return
 	}
 
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", metadata.Size))
 	w.Header().Set("Content-Type", metadata.ContentType)
-	w.Header().Set("ETag", metadata.ETag)
+	w.Header().Set("X-Storage-Class", storageClassOf(metadata))
+	h.setRestoreHeaders(w, metadata)
+	h.setCacheControl(w, r, bucket, metadata)
 	w.Header().Set("Last-Modified", metadata.LastModified.Format(http.TimeFormat))
 
+	h.writeHeadStatus(w, r, metadata)
+}
+
+// headObjectExists handles HEAD /{bucket}/*?exists, a cheap existence check
+// answering only 200 with Content-Length, or 404. The object's metadata is
+// not read, so none of the other headers of a HEAD are sent and redirects
+// and archived objects are reported as existing like any other.
+func (h *Handler) headObjectExists(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	size, err := h.store.StatObject(r.Context(), bucket, key)
+	if err != nil {
+		sendObjectLookupError(w, err, bucket, key)
+		return
+	}
+
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", size))
 	w.WriteHeader(http.StatusOK)
 }
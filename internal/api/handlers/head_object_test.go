@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestHeadObjectHeaders(t *testing.T) {
+	ts := newTestServer(t, "ETAG_HISTORY_LIMIT=20")
+	ts.mustCreateBucket(t, "docs")
+	for i := 0; i < 20; i++ {
+		ts.mustPut(t, "docs", "a.txt", fmt.Sprintf("version %d", i), "Content-Type", "text/plain")
+	}
+	ts.mustPut(t, "docs", "a.txt", "<p>latest</p>", "Content-Type", "text/html", "Cache-Control", "no-cache")
+
+	get := ts.do(t, http.MethodGet, "/docs/a.txt", "")
+	head := ts.do(t, http.MethodHead, "/docs/a.txt", "")
+	expectStatus(t, head, http.StatusOK)
+	for _, name := range []string{"ETag", "Content-Type", "Content-Length", "Last-Modified", "Cache-Control", "X-Storage-Class"} {
+		if head.Header().Get(name) == "" || head.Header().Get(name) != get.Header().Get(name) {
+			t.Errorf("HEAD %s = %q, GET has %q", name, head.Header().Get(name), get.Header().Get(name))
+		}
+	}
+
+	exists := ts.do(t, http.MethodHead, "/docs/a.txt?exists", "")
+	expectStatus(t, exists, http.StatusOK)
+	if exists.Header().Get("Content-Length") != head.Header().Get("Content-Length") {
+		t.Errorf("?exists Content-Length = %q, want %q", exists.Header().Get("Content-Length"), head.Header().Get("Content-Length"))
+	}
+	expectStatus(t, ts.do(t, http.MethodHead, "/docs/missing.txt", ""), http.StatusNotFound)
+	expectStatus(t, ts.do(t, http.MethodHead, "/docs/missing.txt?exists", ""), http.StatusNotFound)
+}
+
+func TestHeadObjectIfNoneMatch(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+	ts.mustPut(t, "docs", "a.txt", "hello", "Content-Type", "text/plain")
+	etag := ts.do(t, http.MethodHead, "/docs/a.txt", "").Header().Get("ETag")
+
+	for _, match := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		rec := ts.do(t, http.MethodHead, "/docs/a.txt", "", "If-None-Match", match)
+		expectStatus(t, rec, http.StatusNotModified)
+		if rec.Header().Get("ETag") != etag {
+			t.Errorf("If-None-Match %s: ETag = %q, want %q", match, rec.Header().Get("ETag"), etag)
+		}
+		for _, name := range []string{"Content-Type", "Content-Length", "Last-Modified"} {
+			if rec.Header().Get(name) != "" {
+				t.Errorf("If-None-Match %s: 304 has %s %q", match, name, rec.Header().Get(name))
+			}
+		}
+	}
+
+	for _, match := range []string{"", `"other"`} {
+		rec := ts.do(t, http.MethodHead, "/docs/a.txt", "", "If-None-Match", match)
+		expectStatus(t, rec, http.StatusOK)
+		if rec.Header().Get("ETag") != etag || rec.Header().Get("Content-Type") != "text/plain" || rec.Header().Get("Content-Length") != "5" || rec.Header().Get("Last-Modified") == "" {
+			t.Errorf("If-None-Match %q: headers %v, want the full set", match, rec.Header())
+		}
+	}
+
+	// An overwrite changes the ETag, so the old one no longer matches
+	ts.mustPut(t, "docs", "a.txt", "hello again", "Content-Type", "text/plain")
+	expectStatus(t, ts.do(t, http.MethodHead, "/docs/a.txt", "", "If-None-Match", etag), http.StatusOK)
+}
+
+// BenchmarkHeadObject serves HEAD requests for an object overwritten often
+// enough to carry a long ETag history, plain and with ?exists
+func BenchmarkHeadObject(b *testing.B) {
+	ts := newTestServer(b, "ETAG_HISTORY_LIMIT=50")
+	ts.mustCreateBucket(b, "docs")
+	for i := 0; i <= 50; i++ {
+		rec := ts.do(b, http.MethodPut, "/docs/a.txt", fmt.Sprintf("version %d", i))
+		if rec.Code != http.StatusOK {
+			b.Fatalf("PUT: status %d", rec.Code)
+		}
+	}
+
+	for _, target := range []string{"/docs/a.txt", "/docs/a.txt?exists"} {
+		b.Run(target, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if rec := ts.do(b, http.MethodHead, target, ""); rec.Code != http.StatusOK {
+					b.Fatalf("HEAD: status %d", rec.Code)
+				}
+			}
+		})
+	}
+}
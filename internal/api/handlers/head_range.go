@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+var errRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// writeHeadStatus advertises byte ranges and sends the status of a HEAD for
+// an object. Download managers probe with a Range header before fetching in
+// parts, so a range GET would honor gets the 206 or 416 the GET would send.
+// Callers set Content-Length to the full size beforehand.
+func (h *Handler) writeHeadStatus(w http.ResponseWriter, r *http.Request, metadata *model.ObjectMetadata) {
+	w.Header().Set("Accept-Ranges", "bytes")
+	h.setVaryEncoding(w, metadata.Size)
+
+	// GET ignores Range when If-Range names another version of the object
+	rangeHeader := r.Header.Get("Range")
+	if rangeHeader == "" || !ifRangeMatches(r, metadata) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	start, end, ok, err := parseByteRange(rangeHeader, metadata.Size)
+	switch {
+	case errors.Is(err, errRangeNotSatisfiable):
+		w.Header().Del("Content-Length")
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", metadata.Size))
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+	case ok:
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", end-start+1))
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, metadata.Size))
+		w.WriteHeader(http.StatusPartialContent)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// parseByteRange parses a Range header for an object of size bytes and
+// returns the inclusive bounds of its range, failing for ranges GET answers
+// with 416. ok is false when the whole object is sent instead: the range
+// starts past the end of an empty object, or there are several ranges,
+// which GET sends as a multipart body whose size isn't worth computing here.
+func parseByteRange(header string, size int64) (start, end int64, ok bool, err error) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found {
+		return 0, 0, false, errRangeNotSatisfiable
+	}
+	if strings.Contains(spec, ",") {
+		return 0, 0, false, nil
+	}
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false, errRangeNotSatisfiable
+	}
+
+	if first == "" {
+		// bytes=-N is the last N bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, false, errRangeNotSatisfiable
+		}
+		if size == 0 {
+			return 0, 0, false, nil
+		}
+		if n == 0 {
+			return 0, 0, false, errRangeNotSatisfiable
+		}
+		return max(size-n, 0), size - 1, true, nil
+	}
+
+	start, err = strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false, errRangeNotSatisfiable
+	}
+	end = size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false, errRangeNotSatisfiable
+		}
+		end = min(end, size-1)
+	}
+	if start >= size {
+		if size == 0 {
+			return 0, 0, false, nil
+		}
+		return 0, 0, false, errRangeNotSatisfiable
+	}
+	return start, end, true, nil
+}
+
+// ifRangeMatches reports whether a Range request applies to this version of
+// the object: there is no If-Range, or it holds the object's strong ETag or
+// its exact Last-Modified time.
+func ifRangeMatches(r *http.Request, metadata *model.ObjectMetadata) bool {
+	ifRange := r.Header.Get("If-Range")
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+		return ifRange == metadata.ETag
+	}
+	t, err := http.ParseTime(ifRange)
+	return err == nil && t.Equal(metadata.LastModified.Truncate(time.Second))
+}
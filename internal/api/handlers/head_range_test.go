@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseByteRange(t *testing.T) {
+	for _, tc := range []struct {
+		header     string
+		size       int64
+		start, end int64
+		ok         bool
+		invalid    bool
+	}{
+		{header: "bytes=0-3", size: 10, start: 0, end: 3, ok: true},
+		{header: "bytes=4-", size: 10, start: 4, end: 9, ok: true},
+		{header: "bytes=-3", size: 10, start: 7, end: 9, ok: true},
+		{header: "bytes=-30", size: 10, start: 0, end: 9, ok: true},
+		{header: "bytes=5-100", size: 10, start: 5, end: 9, ok: true},
+		{header: "bytes=0-1,4-5", size: 10},
+		{header: "bytes=0-", size: 0},
+		{header: "bytes=10-", size: 10, invalid: true},
+		{header: "bytes=5-4", size: 10, invalid: true},
+		{header: "bytes=-0", size: 10, invalid: true},
+		{header: "bytes=x-4", size: 10, invalid: true},
+		{header: "items=0-4", size: 10, invalid: true},
+	} {
+		start, end, ok, err := parseByteRange(tc.header, tc.size)
+		if (err != nil) != tc.invalid || ok != tc.ok || (ok && (start != tc.start || end != tc.end)) {
+			t.Errorf("parseByteRange(%q, %d) = %d, %d, %v, %v", tc.header, tc.size, start, end, ok, err)
+		}
+	}
+}
+
+func TestHeadObjectAdvertisesRanges(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+	ts.mustPut(t, "docs", "a.txt", "0123456789")
+
+	rec := ts.do(t, http.MethodHead, "/docs/a.txt", "")
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Header().Get("Accept-Ranges") != "bytes" || rec.Header().Get("Content-Length") != "10" {
+		t.Errorf("Accept-Ranges = %q, Content-Length = %q, want bytes and 10", rec.Header().Get("Accept-Ranges"), rec.Header().Get("Content-Length"))
+	}
+}
+
+// A HEAD with a Range gets the status and headers a GET with it would
+func TestHeadObjectRangeProbe(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+	ts.mustPut(t, "docs", "a.txt", "0123456789")
+	etag := ts.do(t, http.MethodHead, "/docs/a.txt", "").Header().Get("ETag")
+
+	for _, tc := range []struct {
+		headers      []string
+		status       int
+		length       string
+		contentRange string
+	}{
+		{[]string{"Range", "bytes=0-3"}, http.StatusPartialContent, "4", "bytes 0-3/10"},
+		{[]string{"Range", "bytes=-2"}, http.StatusPartialContent, "2", "bytes 8-9/10"},
+		{[]string{"Range", "bytes=20-"}, http.StatusRequestedRangeNotSatisfiable, "", "bytes */10"},
+		{[]string{"Range", "bytes=0-3", "If-Range", etag}, http.StatusPartialContent, "4", "bytes 0-3/10"},
+		{[]string{"Range", "bytes=0-3", "If-Range", `"stale"`}, http.StatusOK, "10", ""},
+	} {
+		head := ts.do(t, http.MethodHead, "/docs/a.txt", "", tc.headers...)
+		if head.Code != tc.status || head.Header().Get("Content-Length") != tc.length || head.Header().Get("Content-Range") != tc.contentRange {
+			t.Errorf("HEAD %q: %d, Content-Length %q, Content-Range %q; want %d, %q, %q",
+				tc.headers, head.Code, head.Header().Get("Content-Length"), head.Header().Get("Content-Range"), tc.status, tc.length, tc.contentRange)
+		}
+		if head.Body.Len() != 0 {
+			t.Errorf("HEAD %q sent a body", tc.headers)
+		}
+
+		get := ts.do(t, http.MethodGet, "/docs/a.txt", "", tc.headers...)
+		if get.Code != head.Code || get.Header().Get("Content-Range") != head.Header().Get("Content-Range") {
+			t.Errorf("GET %q: %d, Content-Range %q, but HEAD said %d, %q",
+				tc.headers, get.Code, get.Header().Get("Content-Range"), head.Code, head.Header().Get("Content-Range"))
+		}
+	}
+}
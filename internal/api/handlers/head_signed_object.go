@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// HeadSignedObject serves HEAD /presign/{bucket}/* for URLs signed with HEAD.
+func (h *Handler) HeadSignedObject(w http.ResponseWriter, r *http.Request) {
+	if !h.verifySignedRequest(w, r) {
+		return
+	}
+
+	bucket := chi.URLParam(r, "bucket")
+	key := chi.URLParam(r, "*")
+
+	metadata, err := h.store.HeadObject(r.Context(), bucket, key)
+	if err != nil {
+		sendObjectLookupError(w, err, bucket, key)
+		return
+	}
+
+	if h.sendObjectRedirect(w, metadata) {
+		return
+	}
+
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", metadata.Size))
+	w.Header().Set("Content-Type", metadata.ContentType)
+	w.Header().Set("ETag", metadata.ETag)
+	w.Header().Set("X-Storage-Class", storageClassOf(metadata))
+	h.setRestoreHeaders(w, metadata)
+	h.setCacheControl(w, r, bucket, metadata)
+	w.Header().Set("Last-Modified", metadata.LastModified.Format(http.TimeFormat))
+
+	h.writeHeadStatus(w, r, metadata)
+}
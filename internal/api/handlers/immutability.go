@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	gosssError "github.com/mmvergara/gosss/internal/error"
+)
+
+// allowMutation reports whether the object at bucket/key may be overwritten
+// or deleted. Objects younger than CREATE_IMMUTABILITY_SECONDS are answered
+// with 403 and a Retry-After for when the window closes. Missing objects and
+// lookup failures are let through for the operation itself to handle.
+func (h *Handler) allowMutation(w http.ResponseWriter, r *http.Request, bucket, key string) bool {
+	remaining := h.immutableFor(r.Context(), bucket, key)
+	if remaining <= 0 {
+		return true
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+	gosssError.SendGossError(w, http.StatusForbidden, "Object was created too recently to be overwritten or deleted", bucket+"/"+key)
+	return false
+}
+
+// immutableFor returns how much longer the object at bucket/key is inside
+// the immutability window, or zero if it may be changed.
+func (h *Handler) immutableFor(ctx context.Context, bucket, key string) time.Duration {
+	window := h.config.CreateImmutabilityWindow
+	if window <= 0 {
+		return 0
+	}
+
+	metadata, err := h.store.HeadObject(ctx, bucket, key)
+	if err != nil {
+		return 0
+	}
+	return max(window-time.Since(metadata.LastModified), 0)
+}
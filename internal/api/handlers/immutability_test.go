@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+// ageWindow sets the immutability window so that bucket/key, created at
+// its LastModified, has remaining time left in it
+func (ts *testServer) ageWindow(t *testing.T, bucket, key string, remaining time.Duration) {
+	t.Helper()
+	metadata, err := ts.store.HeadObject(context.Background(), bucket, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.h.config.CreateImmutabilityWindow = time.Since(metadata.LastModified) + remaining
+}
+
+func TestImmutabilityWindowBoundary(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+	ts.mustPut(t, "docs", "a.txt", "v1")
+
+	// Just inside the window
+	ts.ageWindow(t, "docs", "a.txt", 2*time.Second)
+	rec := ts.do(t, http.MethodPut, "/docs/a.txt", "v2")
+	expectStatus(t, rec, http.StatusForbidden)
+	if after, _ := strconv.Atoi(rec.Header().Get("Retry-After")); after < 1 || after > 2 {
+		t.Errorf("Retry-After = %q, want 1 or 2", rec.Header().Get("Retry-After"))
+	}
+	expectStatus(t, ts.do(t, http.MethodDelete, "/docs/a.txt", ""), http.StatusForbidden)
+
+	// Just past it
+	ts.ageWindow(t, "docs", "a.txt", -time.Millisecond)
+	ts.mustPut(t, "docs", "a.txt", "v2")
+
+	// A zero window disables the check
+	ts.h.config.CreateImmutabilityWindow = 0
+	ts.mustPut(t, "docs", "a.txt", "v3")
+}
+
+func TestImmutabilityWindowCoversEveryWrite(t *testing.T) {
+	ts := newTestServer(t, "SOFT_DELETE=true", "UPLOAD_KEY_STRATEGY=hash")
+	ts.mustCreateBucket(t, "docs")
+
+	// POST /{bucket} with a content-hash key repeats the key of an identical
+	// upload
+	rec := ts.do(t, http.MethodPost, "/docs", "same body")
+	if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Fatalf("POST: status %d: %s", rec.Code, rec.Body.String())
+	}
+	var uploaded model.ObjectMetadata
+	if err := json.Unmarshal(rec.Body.Bytes(), &uploaded); err != nil {
+		t.Fatal(err)
+	}
+	ts.ageWindow(t, "docs", uploaded.Key, time.Hour)
+	expectStatus(t, ts.do(t, http.MethodPost, "/docs", "same body"), http.StatusForbidden)
+
+	// Imports skip recent objects
+	ts.mustPut(t, "docs", "index.html", "old")
+	rec = ts.do(t, http.MethodPost, "/docs?import&format=zip", zipArchive(t, map[string]string{"index.html": "new", "other.html": "x"}))
+	expectStatus(t, rec, http.StatusOK)
+	var result model.ImportResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].Key != "index.html" || len(result.Imported) != 1 {
+		t.Errorf("import result = %+v, want index.html skipped and other.html imported", result)
+	}
+	if got := ts.do(t, http.MethodGet, "/docs/index.html", "").Body.String(); got != "old" {
+		t.Errorf("index.html = %q, want old", got)
+	}
+
+	// Trash restores replace the live object
+	ts.h.config.CreateImmutabilityWindow = 0
+	ts.mustPut(t, "docs", "notes.txt", "trashed")
+	expectStatus(t, ts.do(t, http.MethodDelete, "/docs/notes.txt", ""), http.StatusNoContent)
+	ts.mustPut(t, "docs", "notes.txt", "live")
+	ts.ageWindow(t, "docs", "notes.txt", time.Hour)
+	expectStatus(t, ts.do(t, http.MethodPost, "/docs/notes.txt?restore", ""), http.StatusForbidden)
+	if got := ts.do(t, http.MethodGet, "/docs/notes.txt", "").Body.String(); got != "live" {
+		t.Errorf("notes.txt = %q, want live", got)
+	}
+}
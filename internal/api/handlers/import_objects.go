@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/response"
+)
+
+// ImportObjects handles POST /{bucket}?import, extracting every file in a tar
+// or zip archive into the bucket using the entry path as the object key. The
+// archive format is taken from ?format=tar|zip, falling back to Content-Type.
+func (h *Handler) ImportObjects(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.requestTimeout(r))
+	defer cancel()
+
+	bucket := chi.URLParam(r, "bucket")
+
+	isValidBuckName, verr := isValidBucketName(bucket)
+	if !isValidBuckName {
+		slog.Debug("Invalid bucket name", "bucket", bucket, "reason", verr.Message)
+		sendValidationError(w, verr, bucket)
+		return
+	}
+
+	format := importFormat(r)
+	if format == "" {
+		gosssError.SendGossError(w, http.StatusBadRequest, "Archive format must be tar or zip", bucket)
+		return
+	}
+
+	if r.ContentLength > MaxImportArchiveSize {
+		gosssError.SendGossError(w, http.StatusRequestEntityTooLarge, "Archive exceeds the maximum allowed size", bucket)
+		return
+	}
+
+	if !h.ensureBucket(w, r, bucket) {
+		return
+	}
+	body := limitUploadBody(w, r, MaxImportArchiveSize)
+
+	// Entries are uploads to the bucket, so its limit applies to each
+	maxSize, err := h.maxObjectSize(ctx, bucket)
+	if err != nil {
+		slog.Error("Failed to read bucket max object size", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to check bucket", bucket)
+		return
+	}
+	maxEntrySize := min(maxSize, MaxImportEntrySize)
+
+	release, ok := acquireSlot(w, h.writeSlots, "uploads")
+	if !ok {
+		return
+	}
+	defer release()
+
+	result := model.ImportResult{
+		Imported: []string{},
+		Skipped:  []model.ImportEntry{},
+		Failed:   []model.ImportEntry{},
+	}
+
+	if format == "zip" {
+		err = h.importZip(ctx, bucket, body, maxEntrySize, &result)
+	} else {
+		err = h.importTar(ctx, bucket, body, maxEntrySize, &result)
+	}
+	if body.tooLarge() {
+		gosssError.SendGossError(w, http.StatusRequestEntityTooLarge, "Archive exceeds the maximum allowed size", bucket)
+		return
+	}
+	if err != nil {
+		slog.Debug("Failed to read archive", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusBadRequest, "Failed to read archive", bucket)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := response.Encode(w, result); err != nil {
+		slog.Error("Failed to encode import result", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Internal server error", bucket)
+		return
+	}
+}
+
+func importFormat(r *http.Request) string {
+	switch r.URL.Query().Get("format") {
+	case "tar":
+		return "tar"
+	case "zip":
+		return "zip"
+	case "":
+	default:
+		return ""
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-tar", "application/tar":
+		return "tar"
+	case "application/zip", "application/x-zip-compressed":
+		return "zip"
+	}
+	return ""
+}
+
+func (h *Handler) importTar(ctx context.Context, bucket string, body io.Reader, maxEntrySize int64, result *model.ImportResult) error {
+	tr := tar.NewReader(body)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		h.importEntry(ctx, bucket, hdr.Name, hdr.Size, tr, maxEntrySize, result)
+	}
+}
+
+func (h *Handler) importZip(ctx context.Context, bucket string, body io.Reader, maxEntrySize int64, result *model.ImportResult) error {
+	// zip needs random access to its central directory, so spool it to disk
+	spool, err := os.CreateTemp("", "gosss-import-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	size, err := io.Copy(spool, body)
+	if err != nil {
+		return err
+	}
+
+	zr, err := zip.NewReader(spool, size)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			result.Failed = append(result.Failed, model.ImportEntry{Key: f.Name, Reason: "failed to open entry"})
+			continue
+		}
+		h.importEntry(ctx, bucket, f.Name, int64(f.UncompressedSize64), rc, maxEntrySize, result)
+		rc.Close()
+	}
+	return nil
+}
+
+// importEntry stores a single archive entry, recording the outcome in result.
+// Entries over maxEntrySize bytes are skipped.
+func (h *Handler) importEntry(ctx context.Context, bucket, name string, size int64, data io.Reader, maxEntrySize int64, result *model.ImportResult) {
+	key := trimKeyWhitespace(strings.TrimPrefix(path.Clean("/"+name), "/"), h.config)
+
+	isValidObjKey, verr := isValidObjectKey(key, h.config)
+	if !isValidObjKey {
+		result.Skipped = append(result.Skipped, model.ImportEntry{Key: name, Reason: verr.Message})
+		return
+	}
+	if pattern, blocked := blockedKeyPattern(key, h.config); blocked {
+		result.Skipped = append(result.Skipped, model.ImportEntry{Key: key, Reason: fmt.Sprintf("key matches blocked pattern %s", pattern)})
+		return
+	}
+	if size > maxEntrySize {
+		result.Skipped = append(result.Skipped, model.ImportEntry{Key: key, Reason: fmt.Sprintf("entry exceeds %d bytes", maxEntrySize)})
+		return
+	}
+
+	if h.immutableFor(ctx, bucket, key) > 0 {
+		result.Skipped = append(result.Skipped, model.ImportEntry{Key: key, Reason: "object was created too recently to be overwritten"})
+		return
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	metadata, err := h.store.PutObject(ctx, bucket, key, io.LimitReader(data, maxEntrySize), size, contentType)
+	if err != nil {
+		slog.Error("Failed to import object", "bucket", bucket, "key", key, "error", err)
+		result.Failed = append(result.Failed, model.ImportEntry{Key: key, Reason: err.Error()})
+		return
+	}
+	h.objectCreated(bucket, metadata)
+	result.Imported = append(result.Imported, key)
+}
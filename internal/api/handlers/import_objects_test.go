@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+func zipArchive(t *testing.T, files map[string]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestImportZip(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "site")
+
+	archive := zipArchive(t, map[string]string{"index.html": "<h1>hi</h1>", "css/main.css": "body{}"})
+	rec := ts.do(t, http.MethodPost, "/site?import&format=zip", archive)
+	expectStatus(t, rec, http.StatusOK)
+
+	expectStatus(t, ts.do(t, http.MethodGet, "/site/css/main.css", ""), http.StatusOK)
+}
+
+func TestImportRejectsOversizedArchive(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "site")
+
+	req := httptest.NewRequest(http.MethodPost, "/site?import&format=zip", bytes.NewReader(nil))
+	req.ContentLength = MaxImportArchiveSize + 1
+	rec := httptest.NewRecorder()
+	ts.router.ServeHTTP(rec, req)
+	expectStatus(t, rec, http.StatusRequestEntityTooLarge)
+}
+
+func TestImportMissingBucket(t *testing.T) {
+	archive := zipArchive(t, map[string]string{"index.html": "<h1>hi</h1>"})
+
+	ts := newTestServer(t)
+	expectStatus(t, ts.do(t, http.MethodPost, "/site?import&format=zip", archive), http.StatusNotFound)
+	expectStatus(t, ts.do(t, http.MethodPost, "/Site?import&format=zip", archive), http.StatusBadRequest)
+
+	ts = newTestServer(t, "AUTO_CREATE_BUCKETS=true")
+	expectStatus(t, ts.do(t, http.MethodPost, "/site?import&format=zip", archive), http.StatusOK)
+	expectStatus(t, ts.do(t, http.MethodGet, "/site/index.html", ""), http.StatusOK)
+}
+
+func TestImportBucketMaxObjectSize(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "site")
+	if err := ts.store.SetBucketMaxObjectSize(context.Background(), "site", 100); err != nil {
+		t.Fatal(err)
+	}
+	archive := zipArchive(t, map[string]string{"small.txt": strings.Repeat("x", 50), "big.txt": strings.Repeat("x", 200)})
+
+	rec := ts.do(t, http.MethodPost, "/site?import&format=zip", archive)
+	expectStatus(t, rec, http.StatusOK)
+	var result model.ImportResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Imported) != 1 || result.Imported[0] != "small.txt" || len(result.Skipped) != 1 || result.Skipped[0].Key != "big.txt" {
+		t.Fatalf("result = %+v, want small.txt imported and big.txt skipped", result)
+	}
+	expectStatus(t, ts.do(t, http.MethodHead, "/site/big.txt", ""), http.StatusNotFound)
+
+	// A raised limit lets the entry through
+	if err := ts.store.SetBucketMaxObjectSize(context.Background(), "site", 1000); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, ts.do(t, http.MethodPost, "/site?import&format=zip", archive), http.StatusOK)
+	expectStatus(t, ts.do(t, http.MethodHead, "/site/big.txt", ""), http.StatusOK)
+}
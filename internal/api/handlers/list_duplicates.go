@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"cmp"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/response"
+)
+
+// ListDuplicates handles GET /{bucket}?duplicates[&prefix=...], reporting
+// every ETag that more than one object shares along with the bytes taken by
+// the extra copies. Groups are sorted by wasted bytes, largest first.
+func (h *Handler) ListDuplicates(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucket := chi.URLParam(r, "bucket")
+	prefix := r.URL.Query().Get("prefix")
+
+	exists, err := h.store.BucketExists(ctx, bucket)
+	if err != nil {
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to check bucket", bucket)
+		return
+	}
+	if !exists {
+		gosssError.SendGossError(w, http.StatusNotFound, "Bucket not found", bucket)
+		return
+	}
+
+	// Only the groups are kept, not the listing
+	byETag := make(map[string]*model.DuplicateGroup)
+	err = h.store.WalkObjects(ctx, bucket, prefix, func(obj model.ObjectMetadata) error {
+		group, ok := byETag[obj.ETag]
+		if !ok {
+			group = &model.DuplicateGroup{ETag: obj.ETag, Size: obj.Size}
+			byETag[obj.ETag] = group
+		}
+		group.Keys = append(group.Keys, obj.Key)
+		return nil
+	})
+	if err != nil {
+		slog.Error("Failed to list objects", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to list objects", bucket)
+		return
+	}
+
+	report := model.DuplicatesReport{Bucket: bucket, Groups: []model.DuplicateGroup{}}
+	for _, group := range byETag {
+		if len(group.Keys) < 2 {
+			continue
+		}
+		slices.Sort(group.Keys)
+		group.WastedBytes = group.Size * int64(len(group.Keys)-1)
+		report.TotalWastedBytes += group.WastedBytes
+		report.Groups = append(report.Groups, *group)
+	}
+	slices.SortFunc(report.Groups, func(a, b model.DuplicateGroup) int {
+		if c := cmp.Compare(b.WastedBytes, a.WastedBytes); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ETag, b.ETag)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := response.Encode(w, report); err != nil {
+		slog.Error("Failed to encode duplicates report", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Internal server error", bucket)
+		return
+	}
+}
@@ -1,48 +1,191 @@
 package handlers
 
 import (
-	"encoding/json"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	gosssError "github.com/mmvergara/gosss/internal/error"
 	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/response"
 )
 
 func (h *Handler) ListObjects(w http.ResponseWriter, r *http.Request) {
-	log.Println("ListObjects")
+	if r.URL.Query().Has("export") {
+		h.ExportObjects(w, r)
+		return
+	}
+	if r.URL.Query().Has("uploads") {
+		h.ListUploads(w, r)
+		return
+	}
+	if r.URL.Query().Has("duplicates") {
+		h.ListDuplicates(w, r)
+		return
+	}
+	if r.URL.Query().Has("stream") {
+		h.StreamObjects(w, r)
+		return
+	}
+
 	bucket := chi.URLParam(r, "bucket")
+	if key := h.defaultObject(r, bucket); key != "" {
+		h.serveObject(w, r, bucket, key)
+		return
+	}
+
 	prefix := r.URL.Query().Get("prefix")
+	// Only keys lexicographically greater than start-after are returned
+	startAfter := r.URL.Query().Get("start-after")
 
-	objects, err := h.store.ListObjects(r.Context(), bucket, prefix)
+	// Every ?tag=key:value filter must match (AND)
+	tagFilters, isValidFilter, msg := parseTagFilters(r.URL.Query()["tag"])
+	if !isValidFilter {
+		slog.Debug("Invalid tag filter", "bucket", bucket, "reason", msg)
+		gosssError.SendGossError(w, http.StatusBadRequest, msg, bucket)
+		return
+	}
+
+	// ?content-type=image/png matches exactly, ?content-type=image/ any image
+	contentType, isValidFilter, msg := parseContentTypeFilter(r.URL.Query().Get("content-type"))
+	if !isValidFilter {
+		slog.Debug("Invalid content type filter", "bucket", bucket, "reason", msg)
+		gosssError.SendGossError(w, http.StatusBadRequest, msg, bucket)
+		return
+	}
+
+	match := listingFilter(tagFilters, contentType)
+	objects, truncated, err := h.store.ListObjectsLimit(r.Context(), bucket, prefix, startAfter, h.config.MaxListKeys, match)
 	if err != nil {
-		log.Println(err)
+		slog.Warn("Failed to list objects", "bucket", bucket, "error", err)
 		gosssError.SendGossError(w, http.StatusInternalServerError, "Something went wrong or the bucket does not exist", bucket)
 		return
 	}
 
+	if truncated {
+		slog.Warn("Listing truncated", "bucket", bucket, "prefix", prefix, "maxKeys", h.config.MaxListKeys)
+	}
+
 	result := model.ListBucketResult{
-		Name:   bucket,
-		Prefix: prefix,
+		Name:        bucket,
+		Prefix:      prefix,
+		StartAfter:  startAfter,
+		IsTruncated: truncated,
 	}
 
 	for _, obj := range objects {
 		result.Contents = append(result.Contents, model.ObjectMetadata{
-			Key:          obj.Key,
-			LastModified: obj.LastModified,
-			ETag:         obj.ETag,
-			Size:         obj.Size,
+			Key:              obj.Key,
+			LastModified:     obj.LastModified,
+			ETag:             obj.ETag,
+			Size:             obj.Size,
+			StorageClass:     storageClassOf(&obj),
+			RedirectLocation: obj.RedirectLocation,
 		})
 	}
+	if truncated && len(objects) > 0 {
+		result.NextStartAfter = objects[len(objects)-1].Key
+	}
+
+	etag, err := listingETag(result)
+	if err != nil {
+		slog.Error("Failed to encode listing", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Internal server error", bucket)
+		return
+	}
+	if notModified(w, r, etag) {
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 
-	if err := json.NewEncoder(w).Encode(result); err != nil {
-		log.Println(err)
+	if err := response.Encode(w, result); err != nil {
+		slog.Error("Failed to encode listing", "bucket", bucket, "error", err)
 		gosssError.SendGossError(w, http.StatusInternalServerError, "Internal server error", bucket)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// parseTagFilters parses ?tag=key:value query values into a key/value map.
+// An object has one value per tag key, so a key may only be filtered once.
+func parseTagFilters(values []string) (map[string]string, bool, string) {
+	filters := make(map[string]string, len(values))
+	for _, v := range values {
+		k, val, ok := strings.Cut(v, ":")
+		if !ok || k == "" {
+			return nil, false, "tag filter must be in the form key:value"
+		}
+		if len(k) > MaxTagKeyLength || len(val) > MaxTagValueLength {
+			return nil, false, "tag filter key or value is too long"
+		}
+		if _, dup := filters[k]; dup {
+			return nil, false, fmt.Sprintf("tag filter key %s is given more than once", k)
+		}
+		filters[k] = val
+	}
+	return filters, true, ""
+}
+
+// parseContentTypeFilter validates a ?content-type value, either a full media
+// type such as image/png or a type followed by a slash, such as image/, to
+// match every subtype. Matching ignores case and parameters like charset.
+func parseContentTypeFilter(value string) (string, bool, string) {
+	if value == "" {
+		return "", true, ""
+	}
+	typ, subtype, ok := strings.Cut(strings.ToLower(value), "/")
+	if !ok || !isMediaToken(typ) || (subtype != "" && !isMediaToken(subtype)) {
+		return "", false, "content-type filter must be a media type like image/png or a prefix like image/"
+	}
+	return typ + "/" + subtype, true, ""
+}
+
+// isMediaToken reports whether s is a valid type or subtype (RFC 6838)
+func isMediaToken(s string) bool {
+	if s == "" || len(s) > 127 {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case strings.ContainsRune("!#$&-^_.+", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func matchesContentType(contentType, filter string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if strings.HasSuffix(filter, "/") {
+		return strings.HasPrefix(mediaType, filter)
+	}
+	return mediaType == filter
+}
+
+// listingFilter combines the listing filters into one predicate, or nil when
+// there are none so listings don't read every object's metadata.
+func listingFilter(tagFilters map[string]string, contentType string) func(model.ObjectMetadata) bool {
+	if len(tagFilters) == 0 && contentType == "" {
+		return nil
+	}
+	return func(obj model.ObjectMetadata) bool {
+		return matchesTags(obj.Tags, tagFilters) &&
+			(contentType == "" || matchesContentType(obj.ContentType, contentType))
+	}
+}
+
+func matchesTags(tags, filters map[string]string) bool {
+	for k, v := range filters {
+		if got, ok := tags[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
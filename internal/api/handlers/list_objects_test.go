@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+func TestParseTagFilters(t *testing.T) {
+	filters, ok, _ := parseTagFilters([]string{"env:prod", "team:web"})
+	if !ok || len(filters) != 2 || filters["env"] != "prod" || filters["team"] != "web" {
+		t.Fatalf("parseTagFilters = %v, %v", filters, ok)
+	}
+
+	for _, values := range [][]string{
+		{"env"},
+		{":prod"},
+		{"env:prod", "env:dev"},
+		{"env:prod", "env:prod"},
+	} {
+		if _, ok, _ := parseTagFilters(values); ok {
+			t.Errorf("parseTagFilters(%q) accepted", values)
+		}
+	}
+}
+
+func TestListObjectsByTag(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "logs")
+	ts.mustPut(t, "logs", "a.txt", "a")
+	ts.mustPut(t, "logs", "b.txt", "b")
+	ts.mustPut(t, "logs", "c.txt", "c")
+	expectStatus(t, ts.do(t, http.MethodPut, "/logs/a.txt?tagging", `{"tags":{"env":"prod","team":"web"}}`), http.StatusOK)
+	expectStatus(t, ts.do(t, http.MethodPut, "/logs/b.txt?tagging", `{"tags":{"env":"prod"}}`), http.StatusOK)
+
+	rec := ts.do(t, http.MethodGet, "/logs?tag=env:prod&tag=team:web", "")
+	expectStatus(t, rec, http.StatusOK)
+	var result model.ListBucketResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Contents) != 1 || result.Contents[0].Key != "a.txt" {
+		t.Fatalf("listing = %+v, want only a.txt", result.Contents)
+	}
+
+	expectStatus(t, ts.do(t, http.MethodGet, "/logs?tag=env:prod&tag=env:dev", ""), http.StatusBadRequest)
+}
+
+func TestListObjectsWeakETag(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+	ts.mustPut(t, "docs", "a.txt", "a")
+
+	first := ts.do(t, http.MethodGet, "/docs", "")
+	expectStatus(t, first, http.StatusOK)
+	etag := first.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("ETag = %q, want a weak validator", etag)
+	}
+	second := ts.do(t, http.MethodGet, "/docs", "")
+	if got := second.Header().Get("ETag"); got != etag {
+		t.Errorf("identical listings got ETags %q and %q", etag, got)
+	}
+
+	rec := ts.do(t, http.MethodGet, "/docs", "", "If-None-Match", etag)
+	expectStatus(t, rec, http.StatusNotModified)
+	if rec.Body.Len() != 0 {
+		t.Errorf("304 with a body: %q", rec.Body.String())
+	}
+	// The strong form of the same tag matches too
+	rec = ts.do(t, http.MethodGet, "/docs", "", "If-None-Match", `"other", `+strings.TrimPrefix(etag, "W/"))
+	expectStatus(t, rec, http.StatusNotModified)
+
+	ts.mustPut(t, "docs", "b.txt", "b")
+	rec = ts.do(t, http.MethodGet, "/docs", "", "If-None-Match", etag)
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Header().Get("ETag") == etag {
+		t.Error("ETag unchanged after the listing changed")
+	}
+}
+
+func TestParseContentTypeFilter(t *testing.T) {
+	for value, want := range map[string]string{
+		"":                         "",
+		"image/png":                "image/png",
+		"Image/PNG":                "image/png",
+		"image/":                   "image/",
+		"application/vnd.api+json": "application/vnd.api+json",
+	} {
+		got, ok, _ := parseContentTypeFilter(value)
+		if !ok || got != want {
+			t.Errorf("parseContentTypeFilter(%q) = %q, %v, want %q", value, got, ok, want)
+		}
+	}
+
+	for _, value := range []string{"image", "/png", "image/png/x", "image/png; charset=utf-8", "im age/", "*/*"} {
+		if _, ok, _ := parseContentTypeFilter(value); ok {
+			t.Errorf("parseContentTypeFilter(%q) accepted", value)
+		}
+	}
+}
+
+func TestListObjectsByContentType(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "media")
+	ts.mustPut(t, "media", "photos/a.png", "a", "Content-Type", "image/png")
+	ts.mustPut(t, "media", "photos/b.jpg", "b", "Content-Type", "image/jpeg")
+	ts.mustPut(t, "media", "photos/notes.txt", "c", "Content-Type", "text/plain; charset=utf-8")
+	ts.mustPut(t, "media", "icons/c.png", "d", "Content-Type", "IMAGE/PNG")
+	expectStatus(t, ts.do(t, http.MethodPut, "/media/photos/a.png?tagging", `{"tags":{"album":"cats"}}`), http.StatusOK)
+
+	for query, want := range map[string]string{
+		"content-type=image/png":                       "icons/c.png photos/a.png",
+		"content-type=image/":                          "icons/c.png photos/a.png photos/b.jpg",
+		"content-type=text/plain":                      "photos/notes.txt",
+		"content-type=video/":                          "",
+		"content-type=image/&prefix=photos/":           "photos/a.png photos/b.jpg",
+		"content-type=image/&tag=album:cats":           "photos/a.png",
+		"content-type=image/&start-after=photos/a.png": "photos/b.jpg",
+	} {
+		rec := ts.do(t, http.MethodGet, "/media?"+query, "")
+		expectStatus(t, rec, http.StatusOK)
+		var result model.ListBucketResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for _, obj := range result.Contents {
+			keys = append(keys, obj.Key)
+		}
+		if got := strings.Join(keys, " "); got != want {
+			t.Errorf("?%s listed %q, want %q", query, got, want)
+		}
+	}
+
+	expectStatus(t, ts.do(t, http.MethodGet, "/media?content-type=image", ""), http.StatusBadRequest)
+	expectStatus(t, ts.do(t, http.MethodGet, "/media?stream&content-type=image", ""), http.StatusBadRequest)
+}
+
+func TestListObjectsMaxListKeys(t *testing.T) {
+	ts := newTestServer(t, "MAX_LIST_KEYS=3")
+	ts.mustCreateBucket(t, "docs")
+	for _, key := range []string{"e.txt", "a.txt", "d.txt", "b.txt", "c.txt"} {
+		ts.mustPut(t, "docs", key, key)
+	}
+
+	list := func(target string) model.ListBucketResult {
+		t.Helper()
+		rec := ts.do(t, http.MethodGet, target, "")
+		expectStatus(t, rec, http.StatusOK)
+		var result model.ListBucketResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	keysOf := func(result model.ListBucketResult) string {
+		var keys []string
+		for _, obj := range result.Contents {
+			keys = append(keys, obj.Key)
+		}
+		return strings.Join(keys, ",")
+	}
+
+	first := list("/docs")
+	if keys := keysOf(first); keys != "a.txt,b.txt,c.txt" || !first.IsTruncated || first.NextStartAfter != "c.txt" {
+		t.Fatalf("first page: %s, truncated %v, next %q, want a-c, truncated, next c.txt", keys, first.IsTruncated, first.NextStartAfter)
+	}
+	rest := list("/docs?start-after=" + first.NextStartAfter)
+	if keys := keysOf(rest); keys != "d.txt,e.txt" || rest.IsTruncated || rest.NextStartAfter != "" {
+		t.Errorf("second page: %s, truncated %v, next %q, want d-e and nothing more", keys, rest.IsTruncated, rest.NextStartAfter)
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/response"
+	"github.com/mmvergara/gosss/internal/storage"
+)
+
+// ListUploads handles GET /{bucket}?uploads, listing resumable uploads that
+// are still missing bytes.
+func (h *Handler) ListUploads(w http.ResponseWriter, r *http.Request) {
+	bucket := chi.URLParam(r, "bucket")
+
+	uploads, err := h.store.ListUploads(r.Context(), bucket)
+	if errors.Is(err, storage.ErrBucketNotFound) {
+		gosssError.SendGossError(w, http.StatusNotFound, "Bucket not found", bucket)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to list uploads", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to list uploads", bucket)
+		return
+	}
+	if uploads == nil {
+		uploads = []model.UploadInfo{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := response.Encode(w, model.ListUploadsResult{Bucket: bucket, Uploads: uploads}); err != nil {
+		slog.Error("Failed to encode uploads", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Internal server error", bucket)
+		return
+	}
+}
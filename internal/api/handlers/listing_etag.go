@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// listingETag returns a weak ETag for a listing, derived from its JSON form,
+// so polling clients can tell with If-None-Match whether anything changed.
+// It is weak because the same listing may be rendered differently (?pretty,
+// TIMESTAMP_FORMAT).
+func listingETag(result any) (string, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// notModified sets etag on the response and reports whether the request's
+// If-None-Match already names it, in which case 304 has been written.
+// If-None-Match uses the weak comparison, so W/ prefixes are ignored.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/response"
+)
+
+// GetObjectHistory handles GET /{bucket}/*?history, returning the object's
+// current ETag and the ETags of the versions it replaced, newest first. Only
+// the ETags are kept, not the old bytes.
+func (h *Handler) GetObjectHistory(w http.ResponseWriter, r *http.Request) {
+	bucket := chi.URLParam(r, "bucket")
+	key := chi.URLParam(r, "*")
+
+	history, err := h.store.ObjectHistory(r.Context(), bucket, key)
+	if err != nil {
+		sendObjectLookupError(w, err, bucket, key)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := response.Encode(w, history); err != nil {
+		slog.Error("Failed to encode history", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Internal server error", bucket+"/"+key)
+		return
+	}
+}
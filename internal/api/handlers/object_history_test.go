@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+func TestGetObjectHistory(t *testing.T) {
+	ts := newTestServer(t, "ETAG_HISTORY_LIMIT=2")
+	ts.mustCreateBucket(t, "docs")
+	var etags []string
+	for _, body := range []string{"v1", "v2", "v3"} {
+		rec := ts.do(t, http.MethodPut, "/docs/a.txt", body)
+		if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+			t.Fatalf("PUT: status %d: %s", rec.Code, rec.Body.String())
+		}
+		etags = append(etags, rec.Header().Get("ETag"))
+	}
+
+	rec := ts.do(t, http.MethodGet, "/docs/a.txt?history", "")
+	expectStatus(t, rec, http.StatusOK)
+	var history model.ObjectHistory
+	if err := json.Unmarshal(rec.Body.Bytes(), &history); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body.String(), err)
+	}
+	if history.Key != "a.txt" || history.ETag != etags[2] {
+		t.Errorf("history of %s at %s, want a.txt at %s", history.Key, history.ETag, etags[2])
+	}
+	if len(history.History) != 2 || history.History[0].ETag != etags[1] || history.History[1].ETag != etags[0] {
+		t.Errorf("history = %+v, want %s then %s", history.History, etags[1], etags[0])
+	}
+
+	// The object itself is served without it
+	rec = ts.do(t, http.MethodGet, "/docs/a.txt", "")
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Body.String() != "v3" {
+		t.Errorf("GET = %q, want v3", rec.Body.String())
+	}
+
+	expectStatus(t, ts.do(t, http.MethodGet, "/docs/missing.txt?history", ""), http.StatusNotFound)
+}
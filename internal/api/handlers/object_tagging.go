@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/response"
+)
+
+// PutObjectTagging handles PUT /{bucket}/*?tagging
+func (h *Handler) PutObjectTagging(w http.ResponseWriter, r *http.Request) {
+	bucket := chi.URLParam(r, "bucket")
+	key := chi.URLParam(r, "*")
+	if !h.validObjectTarget(w, bucket, key) {
+		return
+	}
+
+	var tagging model.Tagging
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxTaggingBodySize)).Decode(&tagging); err != nil {
+		slog.Debug("Invalid tagging body", "error", err)
+		gosssError.SendGossError(w, http.StatusBadRequest, "Invalid tagging body", bucket+"/"+key)
+		return
+	}
+
+	isValidTagSet, msg := isValidTags(tagging.Tags)
+	if !isValidTagSet {
+		slog.Debug("Invalid tags", "bucket", bucket, "key", key, "reason", msg)
+		gosssError.SendGossError(w, http.StatusBadRequest, msg, bucket+"/"+key)
+		return
+	}
+
+	if err := h.store.PutObjectTagging(r.Context(), bucket, key, tagging.Tags); err != nil {
+		slog.Debug("Failed to put object tagging", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusNotFound, "Object not found", bucket+"/"+key)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetObjectTagging handles GET /{bucket}/*?tagging
+func (h *Handler) GetObjectTagging(w http.ResponseWriter, r *http.Request) {
+	bucket := chi.URLParam(r, "bucket")
+	key := chi.URLParam(r, "*")
+	if !h.validObjectTarget(w, bucket, key) {
+		return
+	}
+
+	tags, err := h.store.GetObjectTagging(r.Context(), bucket, key)
+	if err != nil {
+		slog.Debug("Failed to get object tagging", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusNotFound, "Object not found", bucket+"/"+key)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := response.Encode(w, model.Tagging{Tags: tags}); err != nil {
+		slog.Error("Failed to encode tagging", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Internal server error", bucket+"/"+key)
+		return
+	}
+}
+
+// DeleteObjectTagging handles DELETE /{bucket}/*?tagging
+func (h *Handler) DeleteObjectTagging(w http.ResponseWriter, r *http.Request) {
+	bucket := chi.URLParam(r, "bucket")
+	key := chi.URLParam(r, "*")
+	if !h.validObjectTarget(w, bucket, key) {
+		return
+	}
+
+	if err := h.store.DeleteObjectTagging(r.Context(), bucket, key); err != nil {
+		slog.Debug("Failed to delete object tagging", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusNotFound, "Object not found", bucket+"/"+key)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestObjectTaggingRejectsInvalidKeys(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "photos")
+
+	for _, method := range []string{http.MethodPut, http.MethodGet, http.MethodDelete} {
+		rec := ts.do(t, method, "/photos/-cat.jpg?tagging", `{"tags":{"a":"b"}}`)
+		expectStatus(t, rec, http.StatusBadRequest)
+	}
+}
+
+func TestObjectTagging(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "photos")
+	ts.mustPut(t, "photos", "cat.jpg", "meow")
+
+	rec := ts.do(t, http.MethodPut, "/photos/cat.jpg?tagging", `{"tags":{"kind":"cat"}}`)
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = ts.do(t, http.MethodGet, "/photos/cat.jpg?tagging", "")
+	expectStatus(t, rec, http.StatusOK)
+	if body := rec.Body.String(); !strings.Contains(body, `"kind":"cat"`) {
+		t.Fatalf("tagging = %s, want kind=cat", body)
+	}
+}
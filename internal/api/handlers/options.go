@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+)
+
+// allowMethods are the methods reported in the Allow header, in order
+var allowMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPut,
+	http.MethodPost,
+	http.MethodDelete,
+}
+
+// Options answers OPTIONS requests (including CORS preflights) with an Allow
+// header listing the methods the router has registered for the path.
+func (h *Handler) Options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Allow", strings.Join(allowedMethods(r), ", "))
+	w.WriteHeader(http.StatusOK)
+}
+
+// MethodNotAllowed is the router's 405 handler. Like Options it reports the
+// methods registered for the path in the Allow header.
+func (h *Handler) MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Allow", strings.Join(allowedMethods(r), ", "))
+	gosssError.SendGossError(w, http.StatusMethodNotAllowed, "Method "+r.Method+" is not allowed on this resource", r.URL.Path)
+}
+
+// NotFound is the router's 404 handler for paths no route matches.
+func (h *Handler) NotFound(w http.ResponseWriter, r *http.Request) {
+	gosssError.SendGossError(w, http.StatusNotFound, "No such resource", r.URL.Path)
+}
+
+// allowedMethods lists OPTIONS and the methods the router has registered for
+// the request's path.
+func allowedMethods(r *http.Request) []string {
+	allowed := []string{http.MethodOptions}
+	if routes := chi.RouteContext(r.Context()).Routes; routes != nil {
+		for _, method := range allowMethods {
+			if routes.Match(chi.NewRouteContext(), method, r.URL.Path) {
+				allowed = append(allowed, method)
+			}
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	gosssError "github.com/mmvergara/gosss/internal/error"
+)
+
+// originHeaders are copied from the origin's response to the client
+var originHeaders = []string{"Content-Type", "Content-Length", "Content-Range", "ETag", "Last-Modified", "Cache-Control"}
+
+// serveFromOrigin answers a GET for an object this server doesn't have by
+// fetching it from ORIGIN_URL. With ORIGIN_CACHE a complete object is also
+// stored while it streams to the client, so the next read is local.
+func (h *Handler) serveFromOrigin(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	// Keys this server would refuse to store aren't asked for either
+	if !h.validObjectTarget(w, bucket, key) {
+		return
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, originObjectURL(h.config.OriginURL, bucket, key), nil)
+	if err != nil {
+		slog.Error("Failed to build origin request", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Internal server error", bucket+"/"+key)
+		return
+	}
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+		req.Header.Set("Range", rangeHeader)
+	}
+
+	resp, err := h.origin.Do(req)
+	if err != nil {
+		if isClientDisconnect(r, err) {
+			return
+		}
+		slog.Warn("Failed to fetch object from origin", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusBadGateway, "Failed to fetch object from origin", bucket+"/"+key)
+		return
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent:
+	case http.StatusNotFound, http.StatusGone:
+		gosssError.SendGossError(w, http.StatusNotFound, "Object not found", bucket+"/"+key)
+		return
+	case http.StatusRequestedRangeNotSatisfiable:
+		w.Header().Set("Content-Range", resp.Header.Get("Content-Range"))
+		gosssError.SendGossError(w, http.StatusRequestedRangeNotSatisfiable, "Range not satisfiable", bucket+"/"+key)
+		return
+	default:
+		slog.Warn("Origin refused object", "bucket", bucket, "key", key, "status", resp.StatusCode)
+		gosssError.SendGossError(w, http.StatusBadGateway, "Failed to fetch object from origin", bucket+"/"+key)
+		return
+	}
+
+	for _, name := range originHeaders {
+		if v := resp.Header.Get(name); v != "" {
+			w.Header().Set(name, v)
+		}
+	}
+	h.setDownloadHeaders(w, key, resp.Header.Get("Content-Type"))
+
+	var body io.Reader = resp.Body
+	var cache *originCache
+	if h.config.OriginCache && resp.StatusCode == http.StatusOK {
+		cache = h.cacheFromOrigin(r.Context(), bucket, key, resp)
+	}
+	if cache != nil {
+		body = io.TeeReader(resp.Body, cache)
+	}
+
+	w.WriteHeader(resp.StatusCode)
+	written, err := io.Copy(w, body)
+	if cache != nil {
+		cache.finish(err)
+	}
+	if err != nil {
+		handleStreamError(w, r, err, written, bucket, key)
+	}
+}
+
+// errOriginTooLarge abandons caching an object from the origin that turns
+// out to be over the bucket's upload size limit
+var errOriginTooLarge = errors.New("object from origin exceeds the maximum allowed size")
+
+// originCache stores an object fetched from the origin as it is read. A
+// failed store never interrupts the client's download: once the store gives
+// up, the remaining bytes are simply not handed to it.
+type originCache struct {
+	pw        *io.PipeWriter
+	failed    bool
+	remaining int64 // bytes the upload size limit still allows
+	done      chan struct{}
+}
+
+// cacheFromOrigin starts storing the body of resp as bucket/key. Data
+// written to the returned originCache is stored; finish ends the upload. It
+// returns nil when the object may not be stored: its key is blocked or it is
+// over the bucket's upload size limit. Bodies of unknown length are given
+// up on once they pass the limit.
+func (h *Handler) cacheFromOrigin(ctx context.Context, bucket, key string, resp *http.Response) *originCache {
+	if _, blocked := blockedKeyPattern(key, h.config); blocked {
+		return nil
+	}
+	limit, err := h.maxObjectSize(ctx, bucket)
+	if err != nil {
+		slog.Warn("Failed to read bucket upload limit", "bucket", bucket, "error", err)
+		return nil
+	}
+	if resp.ContentLength > limit {
+		return nil
+	}
+
+	pr, pw := io.Pipe()
+	c := &originCache{pw: pw, remaining: limit, done: make(chan struct{})}
+
+	// The upload outlives neither the request nor the download
+	go func() {
+		defer close(c.done)
+		metadata, err := h.store.PutObject(ctx, bucket, key, pr, resp.ContentLength, resp.Header.Get("Content-Type"))
+		// Unblock and fail any further writes
+		pr.CloseWithError(io.ErrClosedPipe)
+		if err != nil && ctx.Err() != nil {
+			slog.Debug("Client disconnected before object from origin was cached", "bucket", bucket, "key", key)
+			return
+		}
+		if err != nil {
+			slog.Warn("Failed to cache object from origin", "bucket", bucket, "key", key, "error", err)
+			return
+		}
+		slog.Debug("Cached object from origin", "bucket", bucket, "key", key, "size", metadata.Size)
+		h.objectCreated(bucket, metadata)
+	}()
+	return c
+}
+
+func (c *originCache) Write(p []byte) (int, error) {
+	switch {
+	case c.failed:
+	case int64(len(p)) > c.remaining:
+		c.failed = true
+		c.pw.CloseWithError(errOriginTooLarge)
+	default:
+		if _, err := c.pw.Write(p); err != nil {
+			c.failed = true
+		}
+		c.remaining -= int64(len(p))
+	}
+	return len(p), nil
+}
+
+// finish ends the upload, abandoning it when the download failed, and
+// waits for the object to be stored.
+func (c *originCache) finish(err error) {
+	if err != nil {
+		c.pw.CloseWithError(err)
+	} else {
+		c.pw.Close()
+	}
+	<-c.done
+}
+
+// originObjectURL returns the URL of bucket/key at the origin
+func originObjectURL(origin, bucket, key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return origin + "/" + url.PathEscape(bucket) + "/" + strings.Join(segments, "/")
+}
+
+// originClient returns the client used to reach ORIGIN_URL, or nil when the
+// passthrough is disabled. Bodies can be large, so there is no overall
+// timeout; the client's request bounds the fetch.
+func originClient(origin string) *http.Client {
+	if origin == "" {
+		return nil
+	}
+	return &http.Client{}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeOrigin serves objects by path and counts the requests it gets. With
+// chunked set it flushes the headers first, so bodies have no
+// Content-Length.
+type fakeOrigin struct {
+	objects  map[string]string
+	chunked  bool
+	requests atomic.Int64
+}
+
+func (o *fakeOrigin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.requests.Add(1)
+	body, ok := o.objects[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	if r.Header.Get("Range") != "" {
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(body))
+		return
+	}
+	if o.chunked {
+		w.(http.Flusher).Flush()
+	}
+	w.Write([]byte(body))
+}
+
+// newOriginServer starts origin and a test server reading through to it
+func newOriginServer(t *testing.T, origin *fakeOrigin, env ...string) *testServer {
+	t.Helper()
+	upstream := httptest.NewServer(origin)
+	t.Cleanup(upstream.Close)
+	ts := newTestServer(t, append([]string{"ORIGIN_URL=" + upstream.URL}, env...)...)
+	ts.mustCreateBucket(t, "docs")
+	return ts
+}
+
+func TestOriginPassthrough(t *testing.T) {
+	origin := &fakeOrigin{objects: map[string]string{"/docs/a/b.txt": "from origin"}}
+	ts := newOriginServer(t, origin)
+
+	for i := 0; i < 2; i++ {
+		rec := ts.do(t, http.MethodGet, "/docs/a/b.txt", "")
+		expectStatus(t, rec, http.StatusOK)
+		if rec.Body.String() != "from origin" || rec.Header().Get("Content-Type") != "text/plain" {
+			t.Errorf("GET = %q as %q, want the origin's object", rec.Body.String(), rec.Header().Get("Content-Type"))
+		}
+	}
+	if origin.requests.Load() != 2 {
+		t.Errorf("origin asked %d times, want 2 without ORIGIN_CACHE", origin.requests.Load())
+	}
+	expectStatus(t, ts.do(t, http.MethodHead, "/docs/a/b.txt", ""), http.StatusNotFound)
+
+	expectStatus(t, ts.do(t, http.MethodGet, "/docs/missing.txt", ""), http.StatusNotFound)
+
+	// Local objects never reach the origin
+	ts.mustPut(t, "docs", "local.txt", "local")
+	before := origin.requests.Load()
+	if got := ts.do(t, http.MethodGet, "/docs/local.txt", "").Body.String(); got != "local" {
+		t.Errorf("GET local object = %q", got)
+	}
+	if origin.requests.Load() != before {
+		t.Error("local object fetched from origin")
+	}
+}
+
+func TestOriginFailureIsBadGateway(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "broken", http.StatusInternalServerError)
+	}))
+	defer upstream.Close()
+	ts := newTestServer(t, "ORIGIN_URL="+upstream.URL)
+	ts.mustCreateBucket(t, "docs")
+
+	expectStatus(t, ts.do(t, http.MethodGet, "/docs/a.txt", ""), http.StatusBadGateway)
+}
+
+// Keys this server would reject are never asked of the origin
+func TestOriginRejectsInvalidKeys(t *testing.T) {
+	origin := &fakeOrigin{objects: map[string]string{"/docs/aaaaaaaaaaaaaaaaaaaa": "long"}}
+	ts := newOriginServer(t, origin, "MAX_KEY_LENGTH=10")
+
+	expectStatus(t, ts.do(t, http.MethodGet, "/docs/"+strings.Repeat("a", 20), ""), http.StatusBadRequest)
+	if origin.requests.Load() != 0 {
+		t.Errorf("origin asked %d times for an invalid key", origin.requests.Load())
+	}
+}
+
+func TestOriginCache(t *testing.T) {
+	origin := &fakeOrigin{objects: map[string]string{
+		"/docs/a.txt":     "cached",
+		"/docs/range.txt": "0123456789",
+		"/docs/key.pem":   "SECRET",
+	}}
+	ts := newOriginServer(t, origin, "ORIGIN_CACHE=true", "BLOCKED_KEYS=*.pem")
+
+	expectStatus(t, ts.do(t, http.MethodGet, "/docs/a.txt", ""), http.StatusOK)
+	if got := ts.do(t, http.MethodGet, "/docs/a.txt", "").Body.String(); got != "cached" {
+		t.Errorf("second GET = %q, want cached", got)
+	}
+	if origin.requests.Load() != 1 {
+		t.Errorf("origin asked %d times, want once", origin.requests.Load())
+	}
+
+	// Partial fetches and blocked keys are served but not stored
+	rec := ts.do(t, http.MethodGet, "/docs/range.txt", "", "Range", "bytes=0-3")
+	expectStatus(t, rec, http.StatusPartialContent)
+	if rec.Body.String() != "0123" {
+		t.Errorf("range GET = %q, want 0123", rec.Body.String())
+	}
+	expectStatus(t, ts.do(t, http.MethodGet, "/docs/key.pem", ""), http.StatusOK)
+	for _, key := range []string{"range.txt", "key.pem"} {
+		if _, err := ts.store.HeadObject(context.Background(), "docs", key); err == nil {
+			t.Errorf("%s stored from origin", key)
+		}
+	}
+}
+
+// Objects over the bucket's upload limit are served but not stored, whether
+// or not the origin says how large they are up front
+func TestOriginCacheRespectsBucketLimit(t *testing.T) {
+	for _, chunked := range []bool{false, true} {
+		origin := &fakeOrigin{
+			objects: map[string]string{"/docs/big.txt": strings.Repeat("x", 200), "/docs/small.txt": "small"},
+			chunked: chunked,
+		}
+		ts := newOriginServer(t, origin, "ORIGIN_CACHE=true")
+		if err := ts.store.SetBucketMaxObjectSize(context.Background(), "docs", 100); err != nil {
+			t.Fatal(err)
+		}
+
+		rec := ts.do(t, http.MethodGet, "/docs/big.txt", "")
+		expectStatus(t, rec, http.StatusOK)
+		if rec.Body.Len() != 200 {
+			t.Errorf("chunked %v: served %d bytes, want all 200", chunked, rec.Body.Len())
+		}
+		if _, err := ts.store.HeadObject(context.Background(), "docs", "big.txt"); err == nil {
+			t.Errorf("chunked %v: object over the bucket limit stored", chunked)
+		}
+
+		expectStatus(t, ts.do(t, http.MethodGet, "/docs/small.txt", ""), http.StatusOK)
+		if _, err := ts.store.HeadObject(context.Background(), "docs", "small.txt"); err != nil {
+			t.Errorf("chunked %v: object under the limit not stored: %v", chunked, err)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+// PostBucket dispatches POST /{bucket} to the operation named in the query.
+// Without one, the body is uploaded under a server-assigned key.
+func (h *Handler) PostBucket(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	switch {
+	case query.Has("import"):
+		h.ImportObjects(w, r)
+	case query.Has("recompute"):
+		sendAdminOnly(w, r)
+	case query.Has("rename"):
+		h.RenameBucket(w, r)
+	case query.Has("metadata"):
+		h.BatchObjectMetadata(w, r)
+	default:
+		h.UploadObject(w, r)
+	}
+}
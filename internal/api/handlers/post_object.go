@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+)
+
+// PostObject dispatches POST /{bucket}/* to the operation named in the query.
+func (h *Handler) PostObject(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	switch {
+	case query.Has("restore"):
+		h.RestoreObject(w, r)
+	case query.Has("truncate"):
+		h.TruncateObject(w, r)
+	case query.Has("recompute"):
+		sendAdminOnly(w, r)
+	default:
+		bucket := chi.URLParam(r, "bucket")
+		key := chi.URLParam(r, "*")
+		gosssError.SendGossError(w, http.StatusBadRequest, "Unsupported object operation", bucket+"/"+key)
+	}
+}
@@ -1,65 +1,257 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"mime"
 	"net/http"
+	"path"
 
 	"github.com/go-chi/chi/v5"
 	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/idempotency"
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/storage"
 )
 
 func (h *Handler) PutObject(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(r.Context(), RequestTimeout)
+	if r.URL.Query().Has("tagging") {
+		h.PutObjectTagging(w, r)
+		return
+	}
+	if r.Header.Get("X-Copy-Source") != "" {
+		h.CopyObject(w, r)
+		return
+	}
+	if r.Header.Get("Content-Range") != "" {
+		h.PutObjectRange(w, r)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.requestTimeout(r))
 	defer cancel()
 
 	bucket := chi.URLParam(r, "bucket")
 	key := chi.URLParam(r, "*")
 
 	// Validate bucket name
-	isValidBuckName, msg := isValidBucketName(bucket)
+	isValidBuckName, verr := isValidBucketName(bucket)
 	if !isValidBuckName {
-		log.Printf("Invalid bucket name: %s (%s)", bucket, msg)
-		gosssError.SendGossError(w, http.StatusBadRequest, msg, bucket)
+		slog.Debug("Invalid bucket name", "bucket", bucket, "reason", verr.Message)
+		sendValidationError(w, verr, bucket)
 		return
 	}
 
-	log.Println(bucket, key)
+	slog.Debug("PutObject", "bucket", bucket, "key", key)
 
 	// Validate object key
-	isValidObjKey, msg := isValidObjectKey(key)
+	isValidObjKey, verr := isValidObjectKey(key, h.config)
 	if !isValidObjKey {
-		log.Printf("Invalid object key: %s (%s)", key, msg)
+		slog.Debug("Invalid object key", "key", key, "reason", verr.Message)
+		sendValidationError(w, verr, bucket+"/"+key)
+		return
+	}
+
+	if pattern, blocked := blockedKeyPattern(key, h.config); blocked {
+		sendBlockedKeyError(w, bucket, key, pattern)
+		return
+	}
+
+	isValidMetadata, msg := isValidUserMetadataSize(r.Header, h.config)
+	if !isValidMetadata {
+		slog.Debug("User metadata too large", "bucket", bucket, "reason", msg)
 		gosssError.SendGossError(w, http.StatusBadRequest, msg, bucket+"/"+key)
 		return
 	}
 
-	// Check file size (optional warning log)
-	if r.ContentLength > MaxFileSize {
-		log.Printf("Warning: File size is %d bytes, exceeding the maximum allowed size of %d bytes.\n", r.ContentLength, MaxFileSize)
+	storageClass, ok := parseStorageClass(r)
+	if !ok {
+		gosssError.SendGossError(w, http.StatusBadRequest, "Invalid storage class", bucket+"/"+key)
+		return
+	}
+
+	redirectLocation, ok := parseRedirectLocation(r)
+	if !ok {
+		gosssError.SendGossError(w, http.StatusBadRequest, "Redirect location must be a path starting with / or an http(s) URL", bucket+"/"+key)
+		return
+	}
+
+	cacheControl, ok := parseCacheControl(r)
+	if !ok {
+		gosssError.SendGossError(w, http.StatusBadRequest, "Invalid Cache-Control", bucket+"/"+key)
+		return
 	}
 
-	select {
-	case semaphore <- struct{}{}:
-		defer func() { <-semaphore }()
-	default:
-		gosssError.SendGossError(w, http.StatusTooManyRequests, "Too many concurrent requests", "")
+	if !h.allowMutation(w, r, bucket, key) {
+		return
+	}
+	if !h.ensureBucket(w, r, bucket) {
+		return
+	}
+
+	maxSize, err := h.maxObjectSize(ctx, bucket)
+	if err != nil {
+		slog.Error("Failed to read bucket max object size", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to check bucket", bucket+"/"+key)
 		return
 	}
 
+	// Everything up to the first read of r.Body happens before a client
+	// sending "Expect: 100-continue" transmits the body, so reject oversized
+	// uploads here rather than after they have crossed the wire. A
+	// ContentLength of -1 (chunked uploads) means the size is unknown
+	if r.ContentLength > maxSize {
+		slog.Debug("File size exceeds the maximum allowed size", "size", r.ContentLength, "max", maxSize)
+		gosssError.SendGossError(w, http.StatusRequestEntityTooLarge, "Object exceeds the maximum allowed size", bucket+"/"+key)
+		return
+	}
+	// Bodies of unknown size are cut off at the same limit while streaming
+	upload := limitUploadBody(w, r, maxSize)
+	r.Body = upload
+
+	release, ok := acquireSlot(w, h.writeSlots, "uploads")
+	if !ok {
+		return
+	}
+	defer release()
+
+	// Retries carrying a known Idempotency-Key are answered from the
+	// original result instead of storing the body again
+	idemKey := r.Header.Get("Idempotency-Key")
+	var bodyHash hash.Hash
+	if idemKey != "" && h.idempotency != nil {
+		rec, isNew := h.idempotency.Begin(idemKey, bucket, key)
+		if !isNew {
+			h.replayIdempotentPut(w, r, rec, bucket, key)
+			return
+		}
+		// Release the key unless the upload is recorded as completed below
+		defer h.idempotency.Abort(idemKey)
+
+		bodyHash = md5.New()
+		r.Body = io.NopCloser(io.TeeReader(r.Body, bodyHash))
+	}
+
+	var body io.Reader = r.Body
+	size := r.ContentLength
+	contentType := r.Header.Get("Content-Type")
+
+	// JSON-only clients send the object as base64 inside a JSON document
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/json" {
+		data, jsonContentType, err := decodeJSONObjectBody(r.Body)
+		if err != nil {
+			slog.Debug("Invalid JSON upload body", "error", err)
+			gosssError.SendGossError(w, http.StatusBadRequest, err.Error(), bucket+"/"+key)
+			return
+		}
+		body = bytes.NewReader(data)
+		size = int64(len(data))
+		contentType = jsonContentType
+	} else if size >= 0 && size <= h.config.SmallObjectThreshold {
+		// Small bodies are received in full before storage is touched, so a
+		// client that stalls or disconnects midway never holds the object
+		// lock or leaves a partial temp file behind
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r.Body, data); err != nil {
+			slog.Debug("Failed to read request body", "error", err)
+			gosssError.SendGossError(w, http.StatusBadRequest, "Failed to read request body", bucket+"/"+key)
+			return
+		}
+		body = bytes.NewReader(data)
+	}
+
+	// Without a Content-Type the key's extension decides, using the host's
+	// MIME table as extended by MIME_TYPES
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(key))
+	}
+
 	// Directly stream the data from the request body to the storage backend
-	metadata, err := h.store.PutObject(ctx, bucket, key, r.Body, r.ContentLength, r.Header.Get("Content-Type"))
+	template := model.ObjectMetadata{ContentType: contentType, StorageClass: storageClass, RedirectLocation: redirectLocation, CacheControl: cacheControl}
+	metadata, err := h.store.PutObjectWithMetadata(ctx, bucket, key, body, size, template)
+	if errors.Is(err, storage.ErrBucketNotFound) {
+		// Deleted since ensureBucket checked it
+		gosssError.SendGossError(w, http.StatusNotFound, "Bucket not found", bucket)
+		return
+	}
+	if errors.Is(err, storage.ErrTooManyObjects) {
+		gosssError.SendGossError(w, http.StatusConflict, "Bucket has reached its maximum number of objects", bucket+"/"+key)
+		return
+	}
+	if errors.Is(err, storage.ErrQuotaExceeded) {
+		gosssError.SendGossError(w, http.StatusInsufficientStorage, "Bucket quota exceeded", bucket+"/"+key)
+		return
+	}
+	if err != nil && upload.tooLarge() {
+		slog.Debug("Upload exceeded the maximum allowed size", "bucket", bucket, "key", key, "read", upload.n, "max", maxSize)
+		gosssError.SendGossError(w, http.StatusRequestEntityTooLarge, "Object exceeds the maximum allowed size", bucket+"/"+key)
+		return
+	}
 	if err != nil {
-		log.Printf("Failed to store object: %v", err)
+		slog.Error("Failed to store object", "bucket", bucket, "key", key, "error", err)
 		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to store object", bucket+"/"+key)
 		return
 	}
-	err = json.NewEncoder(w).Encode(metadata)
+	h.objectCreated(bucket, metadata)
+
+	if bodyHash != nil {
+		h.idempotency.Complete(idemKey, hex.EncodeToString(bodyHash.Sum(nil)), metadata)
+	}
+
+	writePutResponse(w, r, metadata, bucket, key)
+}
+
+// decodeJSONObjectBody reads a model.PutObjectJSONRequest and returns the
+// decoded object bytes along with the content type they should be stored as.
+func decodeJSONObjectBody(r io.Reader) ([]byte, string, error) {
+	var req model.PutObjectJSONRequest
+	if err := json.NewDecoder(io.LimitReader(r, MaxJSONUploadSize)).Decode(&req); err != nil {
+		return nil, "", fmt.Errorf("invalid JSON body")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(req.Data)
 	if err != nil {
-		log.Printf("Failed to encode metadata: %v", err)
-		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to encode metadata", bucket+"/"+key)
+		return nil, "", fmt.Errorf("invalid base64 data")
+	}
+
+	contentType := req.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return data, contentType, nil
+}
+
+// replayIdempotentPut answers a retried PutObject whose Idempotency-Key has
+// been seen before. The retry must target the same object with the same body.
+func (h *Handler) replayIdempotentPut(w http.ResponseWriter, r *http.Request, rec idempotency.Record, bucket, key string) {
+	if rec.Metadata == nil {
+		gosssError.SendGossError(w, http.StatusConflict, "A request with this Idempotency-Key is still in progress", bucket+"/"+key)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
+	if rec.Bucket != bucket || rec.Key != key {
+		gosssError.SendGossError(w, http.StatusConflict, "Idempotency-Key was already used for a different object", bucket+"/"+key)
+		return
+	}
+
+	bodyHash := md5.New()
+	if _, err := io.Copy(bodyHash, r.Body); err != nil {
+		slog.Debug("Failed to read request body", "error", err)
+		gosssError.SendGossError(w, http.StatusBadRequest, "Failed to read request body", bucket+"/"+key)
+		return
+	}
+	if hex.EncodeToString(bodyHash.Sum(nil)) != rec.BodyHash {
+		gosssError.SendGossError(w, http.StatusConflict, "Idempotency-Key was already used with a different body", bucket+"/"+key)
+		return
+	}
+
+	writePutResponse(w, r, rec.Metadata, bucket, key)
 }
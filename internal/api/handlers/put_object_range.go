@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/response"
+	"github.com/mmvergara/gosss/internal/storage"
+)
+
+// PutObjectRange handles PUT /{bucket}/* carrying a
+// Content-Range: bytes START-END/TOTAL header. Each request uploads one
+// piece of the object; pieces may be sent in any order and resent after an
+// interruption. While bytes are missing the response is 202 with the ranges
+// received so far; the request completing the object gets 200 with its
+// metadata.
+func (h *Handler) PutObjectRange(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.requestTimeout(r))
+	defer cancel()
+
+	bucket := chi.URLParam(r, "bucket")
+	key := chi.URLParam(r, "*")
+
+	// Validate bucket name
+	isValidBuckName, verr := isValidBucketName(bucket)
+	if !isValidBuckName {
+		slog.Debug("Invalid bucket name", "bucket", bucket, "reason", verr.Message)
+		sendValidationError(w, verr, bucket)
+		return
+	}
+
+	// Validate object key
+	isValidObjKey, verr := isValidObjectKey(key, h.config)
+	if !isValidObjKey {
+		slog.Debug("Invalid object key", "key", key, "reason", verr.Message)
+		sendValidationError(w, verr, bucket+"/"+key)
+		return
+	}
+
+	if pattern, blocked := blockedKeyPattern(key, h.config); blocked {
+		sendBlockedKeyError(w, bucket, key, pattern)
+		return
+	}
+
+	isValidMetadata, msg := isValidUserMetadataSize(r.Header, h.config)
+	if !isValidMetadata {
+		slog.Debug("User metadata too large", "bucket", bucket, "reason", msg)
+		gosssError.SendGossError(w, http.StatusBadRequest, msg, bucket+"/"+key)
+		return
+	}
+
+	storageClass, ok := parseStorageClass(r)
+	if !ok {
+		gosssError.SendGossError(w, http.StatusBadRequest, "Invalid storage class", bucket+"/"+key)
+		return
+	}
+
+	redirectLocation, ok := parseRedirectLocation(r)
+	if !ok {
+		gosssError.SendGossError(w, http.StatusBadRequest, "Redirect location must be a path starting with / or an http(s) URL", bucket+"/"+key)
+		return
+	}
+
+	cacheControl, ok := parseCacheControl(r)
+	if !ok {
+		gosssError.SendGossError(w, http.StatusBadRequest, "Invalid Cache-Control", bucket+"/"+key)
+		return
+	}
+
+	if !h.allowMutation(w, r, bucket, key) {
+		return
+	}
+	if !h.ensureBucket(w, r, bucket) {
+		return
+	}
+
+	maxSize, err := h.maxObjectSize(ctx, bucket)
+	if err != nil {
+		slog.Error("Failed to read bucket max object size", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to check bucket", bucket+"/"+key)
+		return
+	}
+
+	start, end, total, err := parseContentRange(r.Header.Get("Content-Range"), maxSize)
+	if err != nil {
+		slog.Debug("Invalid Content-Range", "header", r.Header.Get("Content-Range"), "error", err)
+		gosssError.SendGossError(w, http.StatusBadRequest, err.Error(), bucket+"/"+key)
+		return
+	}
+	length := end - start + 1
+	if r.ContentLength >= 0 && r.ContentLength != length {
+		gosssError.SendGossError(w, http.StatusBadRequest, "Content-Length does not match Content-Range", bucket+"/"+key)
+		return
+	}
+
+	release, ok := acquireSlot(w, h.writeSlots, "uploads")
+	if !ok {
+		return
+	}
+	defer release()
+
+	// The descriptive fields only count on the range that starts the
+	// upload; later ranges continue it as it was begun
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(key))
+	}
+	template := model.ObjectMetadata{ContentType: contentType, StorageClass: storageClass, RedirectLocation: redirectLocation, CacheControl: cacheControl}
+
+	body := io.LimitReader(r.Body, length)
+	progress, metadata, err := h.store.PutObjectRange(ctx, bucket, key, start, total, body, template)
+	switch {
+	case errors.Is(err, storage.ErrBucketNotFound):
+		sendObjectLookupError(w, err, bucket, key)
+		return
+	case errors.Is(err, storage.ErrUploadSizeMismatch):
+		gosssError.SendGossError(w, http.StatusConflict, "Total size does not match the upload in progress", bucket+"/"+key)
+		return
+	case errors.Is(err, storage.ErrTooManyObjects):
+		gosssError.SendGossError(w, http.StatusConflict, "Bucket has reached its maximum number of objects", bucket+"/"+key)
+		return
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		gosssError.SendGossError(w, http.StatusInsufficientStorage, "Bucket quota exceeded", bucket+"/"+key)
+		return
+	case err != nil:
+		slog.Error("Failed to store object range", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to store object range", bucket+"/"+key)
+		return
+	}
+
+	if metadata != nil {
+		h.objectCreated(bucket, metadata)
+		writePutResponse(w, r, metadata, bucket, key)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := response.Encode(w, progress); err != nil {
+		slog.Error("Failed to encode response", "bucket", bucket, "key", key, "error", err)
+		return
+	}
+}
+
+// parseContentRange parses "bytes START-END/TOTAL". The total must be known,
+// at most maxSize, and the range must lie within it.
+func parseContentRange(header string, maxSize int64) (start, end, total int64, err error) {
+	if _, scanErr := fmt.Sscanf(header, "bytes %d-%d/%d", &start, &end, &total); scanErr != nil {
+		return 0, 0, 0, fmt.Errorf("Content-Range must be in the form bytes START-END/TOTAL")
+	}
+	if start < 0 || end < start || end >= total {
+		return 0, 0, 0, fmt.Errorf("Content-Range is outside the object")
+	}
+	if total > maxSize {
+		return 0, 0, 0, fmt.Errorf("object size exceeds the maximum allowed size")
+	}
+	return start, end, total, nil
+}
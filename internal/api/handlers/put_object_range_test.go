@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+func TestPutObjectRange(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "media")
+
+	rec := ts.do(t, http.MethodPut, "/media/clip.bin", "world", "Content-Range", "bytes 5-9/10", "X-Storage-Class", "STANDARD_IA", "Cache-Control", "max-age=60")
+	expectStatus(t, rec, http.StatusAccepted)
+	rec = ts.do(t, http.MethodPut, "/media/clip.bin", "hello", "Content-Range", "bytes 0-4/10")
+	expectStatus(t, rec, http.StatusOK)
+
+	var metadata model.ObjectMetadata
+	if err := json.Unmarshal(rec.Body.Bytes(), &metadata); err != nil {
+		t.Fatal(err)
+	}
+	if metadata.StorageClass != "STANDARD_IA" || metadata.CacheControl != "max-age=60" {
+		t.Fatalf("metadata = %+v, want the first range's storage class and cache control", metadata)
+	}
+}
+
+func TestPutObjectRangeChecksLikePutObject(t *testing.T) {
+	ts := newTestServer(t, "MAX_USER_METADATA_SIZE=8", "CREATE_IMMUTABILITY_SECONDS=3600")
+	ts.mustCreateBucket(t, "media")
+
+	// Missing bucket without AUTO_CREATE_BUCKETS
+	rec := ts.do(t, http.MethodPut, "/nobucket/a.bin", "hello", "Content-Range", "bytes 0-4/5")
+	expectStatus(t, rec, http.StatusNotFound)
+
+	rec = ts.do(t, http.MethodPut, "/media/a.bin", "hello", "Content-Range", "bytes 0-4/5", "X-Amz-Meta-Note", strings.Repeat("x", 16))
+	expectStatus(t, rec, http.StatusBadRequest)
+
+	rec = ts.do(t, http.MethodPut, "/media/a.bin", "hello", "Content-Range", "bytes 0-4/5", "X-Storage-Class", "BOGUS")
+	expectStatus(t, rec, http.StatusBadRequest)
+
+	rec = ts.do(t, http.MethodPut, "/media/a.bin", "hello", "Content-Range", "bytes 0-4/5")
+	expectStatus(t, rec, http.StatusOK)
+
+	// Inside the immutability window
+	rec = ts.do(t, http.MethodPut, "/media/a.bin", "hello", "Content-Range", "bytes 0-4/5")
+	expectStatus(t, rec, http.StatusForbidden)
+}
+
+func TestPutObjectRangeAutoCreatesBucket(t *testing.T) {
+	ts := newTestServer(t, "AUTO_CREATE_BUCKETS=true")
+
+	rec := ts.do(t, http.MethodPut, "/fresh/a.bin", "hello", "Content-Range", "bytes 0-4/5")
+	expectStatus(t, rec, http.StatusOK)
+}
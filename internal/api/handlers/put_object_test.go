@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+func TestPutObjectSmallObjectThreshold(t *testing.T) {
+	ts := newTestServer(t, "SMALL_OBJECT_THRESHOLD=16")
+	ts.mustCreateBucket(t, "docs")
+
+	ts.mustPut(t, "docs", "small.txt", "buffered")
+	ts.mustPut(t, "docs", "large.txt", strings.Repeat("streamed", 4))
+	for key, want := range map[string]string{"small.txt": "buffered", "large.txt": strings.Repeat("streamed", 4)} {
+		rec := ts.do(t, http.MethodGet, "/docs/"+key, "")
+		expectStatus(t, rec, http.StatusOK)
+		if rec.Body.String() != want {
+			t.Errorf("%s = %q, want %q", key, rec.Body.String(), want)
+		}
+	}
+}
+
+// A small upload cut short is rejected before storage sees it, leaving
+// neither an object nor a temp file
+func TestPutObjectSmallObjectCutShort(t *testing.T) {
+	ts := newTestServer(t, "SMALL_OBJECT_THRESHOLD=16")
+	ts.mustCreateBucket(t, "docs")
+
+	req := httptest.NewRequest(http.MethodPut, "/docs/short.txt", strings.NewReader("abc"))
+	req.ContentLength = 10
+	rec := httptest.NewRecorder()
+	ts.router.ServeHTTP(rec, req)
+	expectStatus(t, rec, http.StatusBadRequest)
+
+	rec = ts.do(t, http.MethodGet, "/docs/short.txt", "")
+	expectStatus(t, rec, http.StatusNotFound)
+	err := filepath.WalkDir(filepath.Join(ts.h.config.StoragePath, "docs"), func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && filepath.Base(path) != ".bucket.metadata" {
+			t.Errorf("left behind %s", path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// BenchmarkPutObjectBuffering compares streaming small uploads to a temp
+// file with buffering them in memory first (SMALL_OBJECT_THRESHOLD).
+func BenchmarkPutObjectBuffering(b *testing.B) {
+	for _, size := range []int{1 << 10, 64 << 10} {
+		for _, threshold := range []int{0, 1 << 20} {
+			name := fmt.Sprintf("size=%dKiB/threshold=%d", size>>10, threshold)
+			b.Run(name, func(b *testing.B) {
+				ts := newTestServer(b, fmt.Sprintf("SMALL_OBJECT_THRESHOLD=%d", threshold))
+				ts.mustCreateBucket(b, "bench")
+				body := strings.Repeat("x", size)
+
+				b.SetBytes(int64(size))
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					rec := ts.do(b, http.MethodPut, fmt.Sprintf("/bench/k%d", i%64), body)
+					if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+						b.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+					}
+				}
+			})
+		}
+	}
+}
+
+// failingBody fails the test if the upload body is read
+type failingBody struct {
+	t *testing.T
+}
+
+func (b failingBody) Read(p []byte) (int, error) {
+	b.t.Error("body of an upload over quota was read")
+	return 0, io.EOF
+}
+
+func TestPutObjectOverQuotaRefusedBeforeBody(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+	if err := ts.store.SetBucketQuota(context.Background(), "docs", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/docs/big.bin", failingBody{t})
+	req.ContentLength = 11
+	rec := httptest.NewRecorder()
+	ts.router.ServeHTTP(rec, req)
+	expectStatus(t, rec, http.StatusInsufficientStorage)
+
+	ts.mustPut(t, "docs", "fits.bin", "0123456789")
+}
+
+// chunkedPut sends body as a chunked upload with no Content-Length
+func (ts *testServer) chunkedPut(t *testing.T, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPut, target, io.NopCloser(strings.NewReader(body)))
+	req.ContentLength = -1
+	req.TransferEncoding = []string{"chunked"}
+	req.Header.Del("Content-Length")
+	rec := httptest.NewRecorder()
+	ts.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPutObjectChunked(t *testing.T) {
+	ts := newTestServer(t, "SMALL_OBJECT_THRESHOLD=1024")
+	ts.mustCreateBucket(t, "docs")
+
+	body := strings.Repeat("chunk", 100)
+	rec := ts.chunkedPut(t, "/docs/a.txt", body)
+	if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	metadata, err := ts.store.HeadObject(context.Background(), "docs", "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Size != int64(len(body)) {
+		t.Errorf("size = %d, want %d", metadata.Size, len(body))
+	}
+	if got := ts.do(t, http.MethodGet, "/docs/a.txt", "").Body.String(); got != body {
+		t.Errorf("stored %d bytes, want %d", len(got), len(body))
+	}
+}
+
+func TestPutObjectChunkedOverLimit(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+	if err := ts.store.SetBucketMaxObjectSize(context.Background(), "docs", 100); err != nil {
+		t.Fatal(err)
+	}
+
+	expectStatus(t, ts.chunkedPut(t, "/docs/big.txt", strings.Repeat("x", 101)), http.StatusRequestEntityTooLarge)
+	expectStatus(t, ts.do(t, http.MethodGet, "/docs/big.txt", ""), http.StatusNotFound)
+
+	rec := ts.chunkedPut(t, "/docs/fits.txt", strings.Repeat("x", 100))
+	if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Fatalf("upload at the limit: status %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// MIME_TYPES decides the content type of uploads without one, overriding
+// the built-in table and adding extensions it lacks; an explicit header
+// still wins
+func TestPutObjectMimeTypes(t *testing.T) {
+	ts := newTestServer(t, "MIME_TYPES=.svg=image/x-test-svg,gosssmap=application/x-gosss-map")
+	if err := ts.h.config.RegisterMimeTypes(); err != nil {
+		t.Fatal(err)
+	}
+	ts.mustCreateBucket(t, "docs")
+
+	ts.mustPut(t, "docs", "logo.svg", "<svg/>")
+	ts.mustPut(t, "docs", "site.gosssmap", "{}")
+	ts.mustPut(t, "docs", "other.gosssmap", "{}", "Content-Type", "text/plain")
+	for key, want := range map[string]string{
+		"logo.svg":       "image/x-test-svg",
+		"site.gosssmap":  "application/x-gosss-map",
+		"other.gosssmap": "text/plain",
+	} {
+		rec := ts.do(t, http.MethodHead, "/docs/"+key, "")
+		expectStatus(t, rec, http.StatusOK)
+		if got := rec.Header().Get("Content-Type"); got != want {
+			t.Errorf("%s: Content-Type = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestPutObjectBlockedKeys(t *testing.T) {
+	ts := newTestServer(t, "BLOCKED_KEYS=*.pem,id_rsa")
+	ts.mustCreateBucket(t, "docs")
+
+	for _, key := range []string{"server.pem", "home/id_rsa"} {
+		rec := ts.do(t, http.MethodPut, "/docs/"+key, "secret")
+		expectStatus(t, rec, http.StatusForbidden)
+		if !strings.Contains(rec.Body.String(), "blocked pattern") {
+			t.Errorf("PUT %s: body %s doesn't name the blocked pattern", key, rec.Body.String())
+		}
+		expectStatus(t, ts.do(t, http.MethodGet, "/docs/"+key, ""), http.StatusNotFound)
+	}
+	for _, key := range []string{"server.pem.txt", "id_rsa.pub", "docs/readme.md"} {
+		ts.mustPut(t, "docs", key, "fine")
+	}
+
+	// Nor can a copy store one
+	expectStatus(t, ts.do(t, http.MethodPut, "/docs/copy.pem", "", "X-Copy-Source", "docs/id_rsa.pub"), http.StatusForbidden)
+}
+
+func TestPutObjectZeroBytes(t *testing.T) {
+	const emptyETag = `"d41d8cd98f00b204e9800998ecf8427e"`
+	for _, threshold := range []string{"0", "1024"} {
+		ts := newTestServer(t, "SMALL_OBJECT_THRESHOLD="+threshold)
+		ts.mustCreateBucket(t, "docs")
+
+		rec := ts.do(t, http.MethodPut, "/docs/marker", "")
+		expectStatus(t, rec, http.StatusOK)
+		if rec.Header().Get("ETag") != emptyETag {
+			t.Errorf("threshold %s: PUT ETag = %s, want %s", threshold, rec.Header().Get("ETag"), emptyETag)
+		}
+		rec = ts.chunkedPut(t, "/docs/chunked", "")
+		expectStatus(t, rec, http.StatusOK)
+
+		for _, key := range []string{"marker", "chunked"} {
+			rec = ts.do(t, http.MethodGet, "/docs/"+key, "")
+			expectStatus(t, rec, http.StatusOK)
+			if rec.Body.Len() != 0 || rec.Header().Get("ETag") != emptyETag || rec.Header().Get("Content-Length") != "0" {
+				t.Errorf("threshold %s: GET %s = %q, ETag %s, Content-Length %q", threshold, key, rec.Body.String(), rec.Header().Get("ETag"), rec.Header().Get("Content-Length"))
+			}
+			head := ts.do(t, http.MethodHead, "/docs/"+key, "")
+			expectStatus(t, head, http.StatusOK)
+			if head.Header().Get("Content-Length") != "0" {
+				t.Errorf("threshold %s: HEAD %s Content-Length = %q", threshold, key, head.Header().Get("Content-Length"))
+			}
+		}
+
+		rec = ts.do(t, http.MethodGet, "/docs", "")
+		expectStatus(t, rec, http.StatusOK)
+		var result model.ListBucketResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Contents) != 2 {
+			t.Fatalf("threshold %s: listing = %+v, want both empty objects", threshold, result.Contents)
+		}
+		for _, obj := range result.Contents {
+			if obj.Size != 0 || obj.ETag != emptyETag {
+				t.Errorf("threshold %s: listed %s with size %d, ETag %s", threshold, obj.Key, obj.Size, obj.ETag)
+			}
+		}
+	}
+}
+
+func TestPutObjectIdempotencyKey(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+
+	first := ts.do(t, http.MethodPut, "/docs/a.txt", "hello", "Idempotency-Key", "k1")
+	expectStatus(t, first, http.StatusOK)
+	// Overwritten without the key, so a replay that stored its body again
+	// would show
+	ts.mustPut(t, "docs", "a.txt", "changed")
+
+	replay := ts.do(t, http.MethodPut, "/docs/a.txt", "hello", "Idempotency-Key", "k1")
+	expectStatus(t, replay, http.StatusOK)
+	if got, want := replay.Header().Get("ETag"), first.Header().Get("ETag"); got != want {
+		t.Errorf("replay ETag = %q, want the original %q", got, want)
+	}
+
+	conflicts := []struct {
+		name, target, body string
+	}{
+		{"different body", "/docs/a.txt", "hello, world"},
+		{"different object", "/docs/b.txt", "hello"},
+	}
+	for _, tt := range conflicts {
+		rec := ts.do(t, http.MethodPut, tt.target, tt.body, "Idempotency-Key", "k1")
+		if rec.Code != http.StatusConflict {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, http.StatusConflict)
+		}
+	}
+
+	rec := ts.do(t, http.MethodGet, "/docs/a.txt", "")
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Body.String() != "changed" {
+		t.Errorf("object = %q after replays, want %q", rec.Body.String(), "changed")
+	}
+	rec = ts.do(t, http.MethodGet, "/docs/b.txt", "")
+	expectStatus(t, rec, http.StatusNotFound)
+}
+
+func TestPutObjectUserMetadataSize(t *testing.T) {
+	ts := newTestServer(t, "MAX_USER_METADATA_SIZE=32")
+	ts.mustCreateBucket(t, "docs")
+
+	// Names count without the X-Amz-Meta- prefix: 6+10 and 7+9 bytes
+	rec := ts.do(t, http.MethodPut, "/docs/a.txt", "hello",
+		"X-Amz-Meta-Author", strings.Repeat("a", 10), "X-Amz-Meta-Project", strings.Repeat("p", 9))
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = ts.do(t, http.MethodPut, "/docs/b.txt", "hello",
+		"X-Amz-Meta-Author", strings.Repeat("a", 10), "X-Amz-Meta-Project", strings.Repeat("p", 10))
+	expectStatus(t, rec, http.StatusBadRequest)
+	if !strings.Contains(rec.Body.String(), "cannot exceed 32 bytes") {
+		t.Errorf("error %s doesn't name the 32 byte limit", rec.Body.String())
+	}
+	expectStatus(t, ts.do(t, http.MethodHead, "/docs/b.txt", ""), http.StatusNotFound)
+}
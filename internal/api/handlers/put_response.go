@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/response"
+)
+
+// writePutResponse answers a completed upload. The stored object's ETag and
+// Last-Modified are always sent as headers, as GET and HEAD do. The body is
+// the object's metadata as JSON unless the client's Accept header rules JSON
+// out; then there is no body and Content-Type is the object's, like HEAD.
+func writePutResponse(w http.ResponseWriter, r *http.Request, metadata *model.ObjectMetadata, bucket, key string) {
+	w.Header().Set("ETag", metadata.ETag)
+	w.Header().Set("Last-Modified", metadata.LastModified.UTC().Format(http.TimeFormat))
+
+	if !acceptsJSON(r) {
+		if metadata.ContentType != "" {
+			w.Header().Set("Content-Type", metadata.ContentType)
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := response.Encode(w, metadata); err != nil {
+		slog.Error("Failed to encode metadata", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to encode metadata", bucket+"/"+key)
+	}
+}
+
+// acceptsJSON reports whether the request's Accept header allows a JSON
+// response. No header accepts anything.
+func acceptsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return true
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		switch mediaType {
+		case "*/*", "application/*", "application/json":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+func TestAcceptsJSON(t *testing.T) {
+	for accept, want := range map[string]bool{
+		"":                                   true,
+		"*/*":                                true,
+		"application/json":                   true,
+		"application/*":                      true,
+		"text/plain, application/json;q=0.5": true,
+		"text/plain":                         false,
+		"application/json;q=0, text/plain":   false,
+		"image/*":                            false,
+	} {
+		r := httptest.NewRequest(http.MethodPut, "/docs/a.txt", nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		if got := acceptsJSON(r); got != want {
+			t.Errorf("acceptsJSON(%q) = %v, want %v", accept, got, want)
+		}
+	}
+}
+
+// expectPutHeaders fails the test unless rec carries the ETag and
+// Last-Modified of the object GET serves at target
+func expectPutHeaders(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder, target string) {
+	t.Helper()
+	get := ts.do(t, http.MethodGet, target, "")
+	for _, name := range []string{"ETag", "Last-Modified"} {
+		if rec.Header().Get(name) == "" || rec.Header().Get(name) != get.Header().Get(name) {
+			t.Errorf("PUT %s: %s = %q, GET has %q", target, name, rec.Header().Get(name), get.Header().Get(name))
+		}
+	}
+}
+
+func TestPutObjectResponseHeaders(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+
+	rec := ts.do(t, http.MethodPut, "/docs/a.txt", "hello", "Content-Type", "text/plain")
+	expectStatus(t, rec, http.StatusOK)
+	expectPutHeaders(t, ts, rec, "/docs/a.txt")
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var metadata model.ObjectMetadata
+	if err := json.Unmarshal(rec.Body.Bytes(), &metadata); err != nil {
+		t.Fatalf("body %q: %v", rec.Body.String(), err)
+	}
+	if metadata.ETag != rec.Header().Get("ETag") || metadata.Size != 5 {
+		t.Errorf("body = %+v, want the stored object's metadata", metadata)
+	}
+
+	// Headers only when JSON is ruled out
+	rec = ts.do(t, http.MethodPut, "/docs/b.txt", "hello", "Content-Type", "text/plain", "Accept", "text/plain")
+	expectStatus(t, rec, http.StatusOK)
+	expectPutHeaders(t, ts, rec, "/docs/b.txt")
+	if rec.Body.Len() != 0 || rec.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("Accept: text/plain got %q as %q, want no body as text/plain", rec.Body.String(), rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestPutObjectResponseHeadersOnReplayAndRanges(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+
+	first := ts.do(t, http.MethodPut, "/docs/a.txt", "hello", "Idempotency-Key", "k1")
+	expectStatus(t, first, http.StatusOK)
+	replay := ts.do(t, http.MethodPut, "/docs/a.txt", "hello", "Idempotency-Key", "k1")
+	expectStatus(t, replay, http.StatusOK)
+	expectPutHeaders(t, ts, replay, "/docs/a.txt")
+
+	rec := ts.do(t, http.MethodPut, "/docs/clip.bin", "world", "Content-Range", "bytes 5-9/10")
+	expectStatus(t, rec, http.StatusAccepted)
+	if rec.Header().Get("ETag") != "" {
+		t.Errorf("partial upload has ETag %q", rec.Header().Get("ETag"))
+	}
+	rec = ts.do(t, http.MethodPut, "/docs/clip.bin", "hello", "Content-Range", "bytes 0-4/10")
+	expectStatus(t, rec, http.StatusOK)
+	expectPutHeaders(t, ts, rec, "/docs/clip.bin")
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/response"
+)
+
+// RecomputeObject handles POST /{bucket}/*?recompute, rebuilding the metadata
+// of an object from its data file.
+func (h *Handler) RecomputeObject(w http.ResponseWriter, r *http.Request) {
+	bucket := chi.URLParam(r, "bucket")
+	key := chi.URLParam(r, "*")
+	if !h.validObjectTarget(w, bucket, key) {
+		return
+	}
+
+	metadata, err := h.store.RecomputeObject(r.Context(), bucket, key)
+	if err != nil {
+		slog.Debug("Failed to recompute object", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusNotFound, "Object not found", bucket+"/"+key)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := response.Encode(w, metadata); err != nil {
+		slog.Error("Failed to encode metadata", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Internal server error", bucket+"/"+key)
+		return
+	}
+}
+
+// RecomputeBucket handles POST /{bucket}?recompute, rebuilding the metadata of
+// every object in the bucket.
+func (h *Handler) RecomputeBucket(w http.ResponseWriter, r *http.Request) {
+	bucket := chi.URLParam(r, "bucket")
+
+	exists, err := h.store.BucketExists(r.Context(), bucket)
+	if err != nil {
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to check bucket", bucket)
+		return
+	}
+	if !exists {
+		gosssError.SendGossError(w, http.StatusNotFound, "Bucket not found", bucket)
+		return
+	}
+
+	count, err := h.store.RecomputeBucket(r.Context(), bucket)
+	if err != nil {
+		slog.Error("Failed to recompute bucket", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to recompute bucket", bucket)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := response.Encode(w, model.RecomputeResult{Bucket: bucket, Recomputed: count}); err != nil {
+		slog.Error("Failed to encode recompute result", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Internal server error", bucket)
+		return
+	}
+}
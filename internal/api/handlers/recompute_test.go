@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRecomputeObject(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "data")
+	ts.mustPut(t, "data", "a.txt", "hello")
+
+	expectStatus(t, ts.do(t, http.MethodPost, "/admin/data/a.txt?recompute", ""), http.StatusOK)
+	expectStatus(t, ts.do(t, http.MethodPost, "/admin/data/-a.txt?recompute", ""), http.StatusBadRequest)
+	expectStatus(t, ts.do(t, http.MethodPost, "/admin/data/a/./b?recompute", ""), http.StatusBadRequest)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+// MaxRedirectLocationLength caps X-Redirect-Location (same as S3)
+const MaxRedirectLocationLength = 2048
+
+// parseRedirectLocation reads an upload's redirect target from
+// X-Redirect-Location, or X-Amz-Website-Redirect-Location as sent by S3 SDKs.
+// Targets must be a path on this server or an http(s) URL.
+func parseRedirectLocation(r *http.Request) (string, bool) {
+	location := r.Header.Get("X-Redirect-Location")
+	if location == "" {
+		location = r.Header.Get("X-Amz-Website-Redirect-Location")
+	}
+	if location == "" {
+		return "", true
+	}
+	if len(location) > MaxRedirectLocationLength {
+		return "", false
+	}
+	// Browsers drop tabs and newlines from URLs, so "/\t/evil.com" would
+	// be just as protocol-relative as "//evil.com"
+	if strings.ContainsFunc(location, func(c rune) bool { return c < 0x20 || c == 0x7f }) {
+		return "", false
+	}
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return location, true
+	}
+	// A path on this server. "//host" and "/\host" are read by browsers as
+	// a protocol-relative URL to another host
+	return location, strings.HasPrefix(location, "/") &&
+		!strings.HasPrefix(location, "//") &&
+		!strings.HasPrefix(location, "/\\")
+}
+
+// sendObjectRedirect answers a GET or HEAD of an object that carries a
+// redirect with REDIRECT_STATUS and a Location header. It reports whether
+// the object was a redirect.
+func (h *Handler) sendObjectRedirect(w http.ResponseWriter, metadata *model.ObjectMetadata) bool {
+	if metadata.RedirectLocation == "" {
+		return false
+	}
+	w.Header().Set("Location", metadata.RedirectLocation)
+	w.Header().Set("ETag", metadata.ETag)
+	w.Header().Set("Last-Modified", metadata.LastModified.Format(http.TimeFormat))
+	w.WriteHeader(h.config.RedirectStatus)
+	return true
+}
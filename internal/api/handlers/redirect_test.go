@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseRedirectLocation(t *testing.T) {
+	for location, valid := range map[string]bool{
+		"":                      true,
+		"/docs/index.html":      true,
+		"/":                     true,
+		"https://example.com/a": true,
+		"http://example.com":    true,
+		"//evil.com":            false,
+		"//evil.com/path":       false,
+		`/\evil.com`:            false,
+		`/\/evil.com`:           false,
+		"/\t/evil.com":          false,
+		"docs/index.html":       false,
+		"javascript:alert(1)":   false,
+		"ftp://example.com/a":   false,
+		"HTTPS://example.com/a": false,
+		"/" + string(make([]byte, MaxRedirectLocationLength)): false,
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/docs/a", nil)
+		req.Header["X-Redirect-Location"] = []string{location}
+		if _, ok := parseRedirectLocation(req); ok != valid {
+			t.Errorf("parseRedirectLocation(%q) ok = %v, want %v", location, ok, valid)
+		}
+	}
+}
+
+func TestPutObjectRejectsOffSiteRedirect(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+
+	for _, location := range []string{"//evil.com", `/\evil.com`} {
+		expectStatus(t, ts.do(t, http.MethodPut, "/docs/old.html", "", "X-Redirect-Location", location), http.StatusBadRequest)
+		expectStatus(t, ts.do(t, http.MethodPut, "/docs/old.html", "", "X-Amz-Website-Redirect-Location", location), http.StatusBadRequest)
+	}
+	expectStatus(t, ts.do(t, http.MethodGet, "/docs/old.html", ""), http.StatusNotFound)
+
+	ts.mustPut(t, "docs", "old.html", "", "X-Redirect-Location", "/docs/new.html")
+	rec := ts.do(t, http.MethodGet, "/docs/old.html", "")
+	if rec.Header().Get("Location") != "/docs/new.html" {
+		t.Errorf("Location = %q, want /docs/new.html", rec.Header().Get("Location"))
+	}
+}
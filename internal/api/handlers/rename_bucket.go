@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/storage"
+)
+
+// RenameBucket handles POST /{bucket}?rename=newName.
+func (h *Handler) RenameBucket(w http.ResponseWriter, r *http.Request) {
+	bucket := chi.URLParam(r, "bucket")
+	newName := bucketName(r.URL.Query().Get("rename"), h.config)
+
+	// Validate new bucket name
+	isValidBuckName, verr := isValidBucketName(newName)
+	if !isValidBuckName {
+		slog.Debug("Invalid bucket name", "bucket", newName, "reason", verr.Message)
+		verr.Field = "rename"
+		sendValidationError(w, verr, newName)
+		return
+	}
+
+	err := h.store.RenameBucket(r.Context(), bucket, newName)
+	switch {
+	case errors.Is(err, storage.ErrBucketNotFound):
+		gosssError.SendGossError(w, http.StatusNotFound, "Bucket not found", bucket)
+		return
+	case errors.Is(err, storage.ErrBucketExists):
+		gosssError.SendGossError(w, http.StatusConflict, "Bucket already exists", newName)
+		return
+	case err != nil:
+		slog.Error("Failed to rename bucket", "bucket", bucket, "newName", newName, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to rename bucket", bucket)
+		return
+	}
+
+	w.Header().Set("Location", "/"+newName)
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/storage"
+)
+
+// RestoreObject handles POST /{bucket}/*?restore. A live object of an archive
+// storage class is restored for reading; otherwise a soft-deleted object is
+// moved back out of the bucket's trash area.
+func (h *Handler) RestoreObject(w http.ResponseWriter, r *http.Request) {
+	bucket := chi.URLParam(r, "bucket")
+	key := chi.URLParam(r, "*")
+	if !h.validObjectTarget(w, bucket, key) {
+		return
+	}
+
+	if metadata, err := h.store.HeadObject(r.Context(), bucket, key); err == nil && h.isArchiveClass(metadata) {
+		h.RestoreArchivedObject(w, r)
+		return
+	}
+
+	// The restored copy replaces any live object under the key
+	if !h.allowMutation(w, r, bucket, key) {
+		return
+	}
+
+	err := h.store.RestoreObject(r.Context(), bucket, key)
+	switch {
+	case errors.Is(err, storage.ErrBucketNotFound):
+		gosssError.SendGossError(w, http.StatusNotFound, "Bucket not found", bucket)
+		return
+	case errors.Is(err, storage.ErrObjectNotFound):
+		gosssError.SendGossError(w, http.StatusNotFound, "Object not found in trash", bucket+"/"+key)
+		return
+	case err != nil:
+		slog.Error("Failed to restore object", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to restore object", bucket+"/"+key)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
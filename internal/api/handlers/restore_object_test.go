@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRestoreObjectStatus(t *testing.T) {
+	ts := newTestServer(t, "SOFT_DELETE=true")
+	ts.mustCreateBucket(t, "docs")
+
+	expectStatus(t, ts.do(t, http.MethodPost, "/docs/-bad?restore", ""), http.StatusBadRequest)
+	expectStatus(t, ts.do(t, http.MethodPost, "/docs/missing.txt?restore", ""), http.StatusNotFound)
+
+	ts.mustPut(t, "docs", "a.txt", "hello")
+	expectStatus(t, ts.do(t, http.MethodDelete, "/docs/a.txt", ""), http.StatusNoContent)
+	expectStatus(t, ts.do(t, http.MethodPost, "/docs/a.txt?restore", ""), http.StatusOK)
+	expectStatus(t, ts.do(t, http.MethodGet, "/docs/a.txt", ""), http.StatusOK)
+}
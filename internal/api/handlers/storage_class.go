@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+// DefaultStorageClass is recorded for uploads without a storage class header
+// and reported for objects stored before classes were recorded.
+const DefaultStorageClass = "STANDARD"
+
+// storageClasses are the accepted X-Storage-Class values (the S3 names).
+// They are only recorded and echoed back; every class is stored the same way.
+var storageClasses = map[string]bool{
+	"STANDARD":            true,
+	"REDUCED_REDUNDANCY":  true,
+	"STANDARD_IA":         true,
+	"ONEZONE_IA":          true,
+	"INTELLIGENT_TIERING": true,
+	"GLACIER":             true,
+	"GLACIER_IR":          true,
+	"DEEP_ARCHIVE":        true,
+}
+
+// parseStorageClass reads the storage class of an upload from X-Storage-Class,
+// or X-Amz-Storage-Class as sent by S3 SDKs.
+func parseStorageClass(r *http.Request) (string, bool) {
+	class := r.Header.Get("X-Storage-Class")
+	if class == "" {
+		class = r.Header.Get("X-Amz-Storage-Class")
+	}
+	if class == "" {
+		return DefaultStorageClass, true
+	}
+	class = strings.ToUpper(class)
+	return class, storageClasses[class]
+}
+
+// storageClassOf returns an object's storage class for responses.
+func storageClassOf(metadata *model.ObjectMetadata) string {
+	if metadata.StorageClass == "" {
+		return DefaultStorageClass
+	}
+	return metadata.StorageClass
+}
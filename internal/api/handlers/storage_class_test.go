@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+func TestStorageClassRoundTrip(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+
+	ts.mustPut(t, "docs", "default.txt", "a")
+	ts.mustPut(t, "docs", "explicit.txt", "b", "X-Storage-Class", "standard_ia")
+	ts.mustPut(t, "docs", "amz.txt", "c", "X-Amz-Storage-Class", "ONEZONE_IA")
+
+	want := map[string]string{
+		"default.txt":  "STANDARD",
+		"explicit.txt": "STANDARD_IA",
+		"amz.txt":      "ONEZONE_IA",
+	}
+	for key, class := range want {
+		for _, method := range []string{http.MethodGet, http.MethodHead} {
+			rec := ts.do(t, method, "/docs/"+key, "")
+			expectStatus(t, rec, http.StatusOK)
+			if got := rec.Header().Get("X-Storage-Class"); got != class {
+				t.Errorf("%s %s: X-Storage-Class = %q, want %q", method, key, got, class)
+			}
+		}
+	}
+
+	rec := ts.do(t, http.MethodGet, "/docs", "")
+	expectStatus(t, rec, http.StatusOK)
+	var list model.ListBucketResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Contents) != len(want) {
+		t.Fatalf("listed %d objects, want %d", len(list.Contents), len(want))
+	}
+	for _, obj := range list.Contents {
+		if obj.StorageClass != want[obj.Key] {
+			t.Errorf("listed %s with class %q, want %q", obj.Key, obj.StorageClass, want[obj.Key])
+		}
+	}
+}
+
+func TestStorageClassRejectsUnknown(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+
+	rec := ts.do(t, http.MethodPut, "/docs/a.txt", "a", "X-Storage-Class", "COLD")
+	expectStatus(t, rec, http.StatusBadRequest)
+	rec = ts.do(t, http.MethodHead, "/docs/a.txt", "")
+	expectStatus(t, rec, http.StatusNotFound)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"syscall"
+
+	gosssError "github.com/mmvergara/gosss/internal/error"
+)
+
+// isClientDisconnect reports whether a failed response write means the
+// client went away or the request timed out, rather than a server problem.
+func isClientDisconnect(r *http.Request, err error) bool {
+	return r.Context().Err() != nil ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, http.ErrHandlerTimeout)
+}
+
+// handleStreamError deals with a failed copy of an object body to the
+// client. If nothing was written yet a 500 can still be sent; otherwise the
+// status line is gone and the client just sees a truncated body, so the
+// error is only logged. Disconnects are routine and logged at debug level.
+func handleStreamError(w http.ResponseWriter, r *http.Request, err error, written int64, bucket, key string) {
+	if isClientDisconnect(r, err) {
+		slog.Debug("Client disconnected while streaming object", "bucket", bucket, "key", key, "written", written, "error", err)
+		return
+	}
+	if written > 0 {
+		slog.Error("Failed to stream object", "bucket", bucket, "key", key, "written", written, "error", err)
+		return
+	}
+
+	slog.Error("Failed to read object", "bucket", bucket, "key", key, "error", err)
+	w.Header().Del("Content-Encoding")
+	gosssError.SendGossError(w, http.StatusInternalServerError, "Internal server error", bucket+"/"+key)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/response"
+)
+
+// streamedListHead is the part of a ListBucketResult written before its
+// contents. A streamed listing is never truncated.
+type streamedListHead struct {
+	Name        string `json:"name"`
+	Prefix      string `json:"prefix"`
+	StartAfter  string `json:"startAfter,omitempty"`
+	IsTruncated bool   `json:"isTruncated"`
+}
+
+// StreamObjects handles GET /{bucket}?stream, answering with the same
+// document as a listing but writing it a page of MAX_LIST_KEYS objects at a
+// time, so it is never truncated and memory stays bounded however many
+// objects match. Each page is read with the bucket held shared and written
+// only once it is released, so a slow client can't hold up writers to the
+// bucket. Objects come in key order, as in listings, but there is no ETag.
+// The prefix, start-after, tag and content-type filters work as for
+// listings.
+func (h *Handler) StreamObjects(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucket := chi.URLParam(r, "bucket")
+	prefix := r.URL.Query().Get("prefix")
+	startAfter := r.URL.Query().Get("start-after")
+
+	tagFilters, isValidFilter, msg := parseTagFilters(r.URL.Query()["tag"])
+	if !isValidFilter {
+		slog.Debug("Invalid tag filter", "bucket", bucket, "reason", msg)
+		gosssError.SendGossError(w, http.StatusBadRequest, msg, bucket)
+		return
+	}
+	contentType, isValidFilter, msg := parseContentTypeFilter(r.URL.Query().Get("content-type"))
+	if !isValidFilter {
+		slog.Debug("Invalid content type filter", "bucket", bucket, "reason", msg)
+		gosssError.SendGossError(w, http.StatusBadRequest, msg, bucket)
+		return
+	}
+	match := listingFilter(tagFilters, contentType)
+
+	exists, err := h.store.BucketExists(ctx, bucket)
+	if err != nil {
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to check bucket", bucket)
+		return
+	}
+	if !exists {
+		gosssError.SendGossError(w, http.StatusNotFound, "Bucket not found", bucket)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	stream, err := response.StreamArray(w, streamedListHead{
+		Name:       bucket,
+		Prefix:     prefix,
+		StartAfter: startAfter,
+	}, "contents")
+	if err != nil {
+		slog.Debug("Failed to start streamed listing", "bucket", bucket, "error", err)
+		return
+	}
+
+	// The status has been sent with the first write, so failures past this
+	// point can only be logged and the document left unterminated.
+	err = h.streamPages(ctx, bucket, prefix, startAfter, match, stream)
+	if err == nil {
+		err = stream.Close()
+	}
+	if err != nil && isClientDisconnect(r, err) {
+		slog.Debug("Client disconnected during streamed listing", "bucket", bucket, "error", err)
+	} else if err != nil {
+		slog.Error("Failed to stream listing", "bucket", bucket, "error", err)
+	}
+}
+
+// streamPages writes the objects of a streamed listing to stream, one page of
+// MAX_LIST_KEYS objects at a time
+func (h *Handler) streamPages(ctx context.Context, bucket, prefix, startAfter string, match func(model.ObjectMetadata) bool, stream *response.ArrayStream) error {
+	for {
+		objects, truncated, err := h.store.ListObjectsLimit(ctx, bucket, prefix, startAfter, h.config.MaxListKeys, match)
+		if err != nil {
+			return err
+		}
+		for _, obj := range objects {
+			if err := stream.Write(model.ObjectMetadata{
+				Key:              obj.Key,
+				LastModified:     obj.LastModified,
+				ETag:             obj.ETag,
+				Size:             obj.Size,
+				StorageClass:     storageClassOf(&obj),
+				RedirectLocation: obj.RedirectLocation,
+			}); err != nil {
+				return err
+			}
+		}
+		if !truncated || len(objects) == 0 {
+			return nil
+		}
+		startAfter = objects[len(objects)-1].Key
+	}
+}
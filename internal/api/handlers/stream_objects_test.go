@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/storage"
+)
+
+// pageRecorder records the size of every listing page read through it
+type pageRecorder struct {
+	storage.Storage
+	pages []int
+}
+
+func (p *pageRecorder) ListObjectsLimit(ctx context.Context, bucket, prefix, startAfter string, maxKeys int, match func(model.ObjectMetadata) bool) ([]model.ObjectMetadata, bool, error) {
+	objects, truncated, err := p.Storage.ListObjectsLimit(ctx, bucket, prefix, startAfter, maxKeys, match)
+	p.pages = append(p.pages, len(objects))
+	return objects, truncated, err
+}
+
+// A streamed listing is read MAX_LIST_KEYS objects at a time, so memory stays
+// bounded, yet returns every object in key order
+func TestStreamObjectsPages(t *testing.T) {
+	ts := newTestServer(t, "MAX_LIST_KEYS=2")
+	ts.mustCreateBucket(t, "docs")
+	keys := []string{"a.b", "a/x", "b", "c/d/e", "f"}
+	for _, key := range keys {
+		ts.mustPut(t, "docs", key, "x")
+	}
+	recorder := &pageRecorder{Storage: ts.store}
+	ts.h.store = recorder
+
+	rec := ts.do(t, http.MethodGet, "/docs?stream", "")
+	expectStatus(t, rec, http.StatusOK)
+	var listing model.ListBucketResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &listing); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body.String(), err)
+	}
+	var got []string
+	for _, obj := range listing.Contents {
+		got = append(got, obj.Key)
+	}
+	if !slices.Equal(got, keys) || listing.IsTruncated {
+		t.Errorf("streamed %v (truncated %v), want all of %v", got, listing.IsTruncated, keys)
+	}
+	if !slices.Equal(recorder.pages, []int{2, 2, 1}) {
+		t.Errorf("read pages of %v objects, want 2, 2, 1", recorder.pages)
+	}
+
+	recorder.pages = nil
+	rec = ts.do(t, http.MethodGet, "/docs?stream&start-after=a/x", "")
+	expectStatus(t, rec, http.StatusOK)
+	if err := json.Unmarshal(rec.Body.Bytes(), &listing); err != nil {
+		t.Fatal(err)
+	}
+	if len(listing.Contents) != 3 || listing.Contents[0].Key != "b" {
+		t.Errorf("streamed %+v after a/x, want b, c/d/e and f", listing.Contents)
+	}
+}
+
+// blockedWriter runs block the first time an object is written
+type blockedWriter struct {
+	*httptest.ResponseRecorder
+	block func()
+}
+
+func (b *blockedWriter) Write(p []byte) (int, error) {
+	if b.block != nil && bytes.Contains(p, []byte(`"key"`)) {
+		b.block()
+		b.block = nil
+	}
+	return b.ResponseRecorder.Write(p)
+}
+
+// Writing to a slow client doesn't hold the bucket, so bucket writers
+// aren't stuck behind it
+func TestStreamObjectsReleasesBucketWhileWriting(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+	ts.mustPut(t, "docs", "a.txt", "x")
+	ts.mustPut(t, "docs", "b.txt", "x")
+
+	w := &blockedWriter{ResponseRecorder: httptest.NewRecorder()}
+	writes := 0
+	w.block = func() {
+		writes++
+		done := make(chan error, 1)
+		go func() { done <- ts.store.SetBucketQuota(context.Background(), "docs", 1<<20) }()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("SetBucketQuota: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("bucket writer blocked by a streamed listing")
+		}
+	}
+	ts.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs?stream", nil))
+	expectStatus(t, w.ResponseRecorder, http.StatusOK)
+	if writes != 1 {
+		t.Errorf("blocked %d object writes, want 1", writes)
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+)
+
+// brokenPipeWriter is a ResponseWriter whose connection goes away after the
+// headers: every body write fails with EPIPE.
+type brokenPipeWriter struct {
+	header   http.Header
+	statuses []int
+}
+
+func (w *brokenPipeWriter) Header() http.Header { return w.header }
+
+func (w *brokenPipeWriter) WriteHeader(code int) { w.statuses = append(w.statuses, code) }
+
+func (w *brokenPipeWriter) Write(p []byte) (int, error) {
+	if len(w.statuses) == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	return 0, &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)}
+}
+
+// captureErrorLogs collects log records at error level for the rest of the
+// test
+func captureErrorLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError})))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func TestGetObjectClientDisconnectMidStream(t *testing.T) {
+	ts := newTestServer(t, "GZIP_RESPONSES=true", "GZIP_MIN_SIZE=0")
+	ts.mustCreateBucket(t, "media")
+	body := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(body)
+	ts.mustPut(t, "media", "clip.bin", string(body))
+
+	logs := captureErrorLogs(t)
+	req := httptest.NewRequest(http.MethodGet, "/media/clip.bin", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := &brokenPipeWriter{header: http.Header{}}
+	ts.router.ServeHTTP(w, req)
+
+	if len(w.statuses) != 1 || w.statuses[0] != http.StatusOK {
+		t.Errorf("statuses written = %v, want just 200", w.statuses)
+	}
+	if logs.Len() > 0 {
+		t.Errorf("disconnect logged as an error: %s", logs.String())
+	}
+}
+
+func TestHandleStreamError(t *testing.T) {
+	disconnects := []error{
+		&net.OpError{Op: "write", Err: os.NewSyscallError("write", syscall.EPIPE)},
+		&net.OpError{Op: "write", Err: os.NewSyscallError("write", syscall.ECONNRESET)},
+		fmt.Errorf("copy: %w", context.Canceled),
+		http.ErrHandlerTimeout,
+	}
+	for _, err := range disconnects {
+		for _, written := range []int64{0, 512} {
+			logs := captureErrorLogs(t)
+			rec := httptest.NewRecorder()
+			handleStreamError(rec, httptest.NewRequest(http.MethodGet, "/b/k", nil), err, written, "b", "k")
+			if rec.Body.Len() > 0 || logs.Len() > 0 {
+				t.Errorf("%v after %d bytes: wrote %q, logged %q", err, written, rec.Body.String(), logs.String())
+			}
+		}
+	}
+
+	// A read failure before anything was sent still gets a 500
+	rec := httptest.NewRecorder()
+	captureErrorLogs(t)
+	handleStreamError(rec, httptest.NewRequest(http.MethodGet, "/b/k", nil), errors.New("read failed"), 0, "b", "k")
+	expectStatus(t, rec, http.StatusInternalServerError)
+}
+
+func TestHandleStreamErrorCancelledRequest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/b/k", nil).WithContext(ctx)
+
+	logs := captureErrorLogs(t)
+	rec := httptest.NewRecorder()
+	handleStreamError(rec, req, errors.New("i/o error"), 0, "b", "k")
+	if rec.Body.Len() > 0 || logs.Len() > 0 {
+		t.Errorf("cancelled request: wrote %q, logged %q", rec.Body.String(), logs.String())
+	}
+}
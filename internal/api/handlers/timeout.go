@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// requestTimeout returns the deadline to use for r: RequestTimeout, unless
+// the client asked for another one with X-Timeout-Seconds. Requested values
+// are clamped to the configured maximum; invalid ones are ignored.
+func (h *Handler) requestTimeout(r *http.Request) time.Duration {
+	value := r.Header.Get("X-Timeout-Seconds")
+	if value == "" {
+		return RequestTimeout
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds <= 0 {
+		return RequestTimeout
+	}
+	if maxTimeout := h.config.MaxRequestTimeout; seconds > int64(maxTimeout/time.Second) {
+		return maxTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	ts := newTestServer(t, "MAX_REQUEST_TIMEOUT=2m")
+
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", RequestTimeout},
+		{"90", 90 * time.Second},
+		{"120", 2 * time.Minute},
+		{"121", 2 * time.Minute},
+		{"9223372036854775807", 2 * time.Minute},
+		{"0", RequestTimeout},
+		{"-5", RequestTimeout},
+		{"1.5", RequestTimeout},
+		{"ten", RequestTimeout},
+		{"99999999999999999999", RequestTimeout},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/docs/a.txt", nil)
+		if tt.header != "" {
+			r.Header.Set("X-Timeout-Seconds", tt.header)
+		}
+		if got := ts.h.requestTimeout(r); got != tt.want {
+			t.Errorf("X-Timeout-Seconds %q: timeout %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
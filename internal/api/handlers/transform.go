@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/transform"
+)
+
+// errTooManyTransforms is returned by a transform refused for want of a
+// free transform slot
+var errTooManyTransforms = errors.New("too many concurrent transforms")
+
+// newTransformer returns the transformer for IMAGE_TRANSFORMS, or nil when
+// transforms are disabled.
+func newTransformer(enabled bool, maxDimension int) transform.Transformer {
+	if !enabled {
+		return nil
+	}
+	return transform.ImageResizer{MaxDimension: maxDimension}
+}
+
+// transformRequested reports whether a GET asks for a variant of an object
+// the transformer handles. Objects it doesn't handle ignore the parameters.
+func (h *Handler) transformRequested(key string, metadata *model.ObjectMetadata) bool {
+	return h.transformer != nil && h.transformer.Accepts(servedContentType(key, metadata.ContentType))
+}
+
+// serveVariant answers a GET for a transformed object, e.g. ?w=200&h=200,
+// from the variant cache or by transforming obj. Variants carry their own
+// ETag so they are never confused with the stored bytes or each other, and
+// a client revalidating one isn't made to wait for a transform.
+func (h *Handler) serveVariant(w http.ResponseWriter, r *http.Request, bucket, key string, obj io.Reader, metadata *model.ObjectMetadata) {
+	resource := bucket + "/" + key
+
+	opts, requested, err := transform.ParseOptions(r.URL.Query(), h.config.ImageTransformMaxDimension)
+	if err != nil {
+		gosssError.SendGossError(w, http.StatusBadRequest, err.Error(), resource)
+		return
+	}
+	if !requested {
+		h.writeObject(w, r, bucket, key, obj, metadata)
+		return
+	}
+	if metadata.Size > MaxTransformSourceSize {
+		gosssError.SendGossError(w, http.StatusUnprocessableEntity, "Object is too large to transform", resource)
+		return
+	}
+
+	etag := variantETag(metadata.ETag, opts)
+	w.Header().Set("Last-Modified", metadata.LastModified.Format(http.TimeFormat))
+	if notModified(w, r, etag) {
+		return
+	}
+
+	cacheKey := bucket + "\x00" + key + "\x00" + etag
+	variant, cached := h.variants.Get(cacheKey)
+	if !cached {
+		// Concurrent requests for the same variant share one transform,
+		// reading the first caller's object
+		v, err, _ := h.variantFlight.Do(cacheKey, func() (any, error) {
+			// Decoding and resizing hold whole images in memory and keep a
+			// CPU busy, so only one transform runs per CPU
+			select {
+			case h.transformSlots <- struct{}{}:
+				defer func() { <-h.transformSlots }()
+			default:
+				return nil, errTooManyTransforms
+			}
+			data, contentType, err := h.transformer.Transform(obj, opts)
+			if err != nil {
+				return nil, err
+			}
+			variant := transform.Variant{Data: data, ContentType: contentType}
+			h.variants.Add(cacheKey, variant)
+			return variant, nil
+		})
+		if errors.Is(err, errTooManyTransforms) {
+			gosssError.SendGossError(w, http.StatusTooManyRequests, "Too many concurrent transforms", resource)
+			return
+		}
+		if errors.Is(err, transform.ErrUnsupported) {
+			gosssError.SendGossError(w, http.StatusUnprocessableEntity, "Object cannot be transformed", resource)
+			return
+		}
+		if err != nil {
+			slog.Error("Failed to transform object", "bucket", bucket, "key", key, "options", opts.String(), "error", err)
+			gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to transform object", resource)
+			return
+		}
+		variant = v.(transform.Variant)
+	}
+
+	w.Header().Set("Content-Type", variant.ContentType)
+	w.Header().Set("X-Storage-Class", storageClassOf(metadata))
+	h.setCacheControl(w, r, bucket, metadata)
+	h.setDownloadHeaders(w, key, variant.ContentType)
+	w.Header().Set("Accept-Ranges", "bytes")
+
+	http.ServeContent(w, r, path.Base(key), metadata.LastModified, bytes.NewReader(variant.Data))
+}
+
+// variantETag derives the ETag of a variant from the stored one, e.g. "abc"
+// becomes "abc-w200-h200-cover".
+func variantETag(etag string, opts transform.Options) string {
+	if strings.HasSuffix(etag, `"`) {
+		return strings.TrimSuffix(etag, `"`) + "-" + opts.String() + `"`
+	}
+	return etag + "-" + opts.String()
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"testing"
+)
+
+func TestTransformSlots(t *testing.T) {
+	ts := newTestServer(t, "IMAGE_TRANSFORMS=true", "IMAGE_TRANSFORM_CACHE_BYTES=0")
+	ts.mustCreateBucket(t, "images")
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 2000))); err != nil {
+		t.Fatal(err)
+	}
+	ts.mustPut(t, "images", "tall.png", buf.String(), "Content-Type", "image/png")
+
+	rec := ts.do(t, http.MethodGet, "/images/tall.png?w=2048", "")
+	expectStatus(t, rec, http.StatusOK)
+	cfg, err := png.DecodeConfig(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 1 || cfg.Height != 2048 {
+		t.Errorf("w=2048 on a 1x2000 image: %dx%d, want 1x2048", cfg.Width, cfg.Height)
+	}
+
+	// With every slot taken, transforms are refused rather than queued
+	for i := 0; i < cap(ts.h.transformSlots); i++ {
+		ts.h.transformSlots <- struct{}{}
+	}
+	expectStatus(t, ts.do(t, http.MethodGet, "/images/tall.png?w=100", ""), http.StatusTooManyRequests)
+	expectStatus(t, ts.do(t, http.MethodGet, "/images/tall.png", ""), http.StatusOK)
+	<-ts.h.transformSlots
+	expectStatus(t, ts.do(t, http.MethodGet, "/images/tall.png?w=100", ""), http.StatusOK)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/response"
+	"github.com/mmvergara/gosss/internal/storage"
+)
+
+// TruncateObject handles POST /{bucket}/*?truncate=N, keeping the first N
+// bytes of an object. N may only exceed the object's size when extend is
+// also given, in which case the object is padded with zero bytes.
+func (h *Handler) TruncateObject(w http.ResponseWriter, r *http.Request) {
+	bucket := chi.URLParam(r, "bucket")
+	key := chi.URLParam(r, "*")
+
+	if !h.validObjectTarget(w, bucket, key) {
+		return
+	}
+	if pattern, blocked := blockedKeyPattern(key, h.config); blocked {
+		sendBlockedKeyError(w, bucket, key, pattern)
+		return
+	}
+
+	size, err := strconv.ParseInt(r.URL.Query().Get("truncate"), 10, 64)
+	if err != nil || size < 0 {
+		gosssError.SendGossError(w, http.StatusBadRequest, "truncate must be a size between 0 and the maximum object size", bucket+"/"+key)
+		return
+	}
+	maxSize, err := h.maxObjectSize(r.Context(), bucket)
+	if err != nil {
+		slog.Error("Failed to read bucket max object size", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to check bucket", bucket+"/"+key)
+		return
+	}
+	if size > maxSize {
+		gosssError.SendGossError(w, http.StatusRequestEntityTooLarge, "Object exceeds the maximum allowed size", bucket+"/"+key)
+		return
+	}
+
+	metadata, err := h.store.HeadObject(r.Context(), bucket, key)
+	if err != nil {
+		sendObjectLookupError(w, err, bucket, key)
+		return
+	}
+	if h.isArchived(metadata) {
+		sendArchivedError(w, bucket, key)
+		return
+	}
+	if !h.allowMutation(w, r, bucket, key) {
+		return
+	}
+
+	metadata, err = h.store.TruncateObject(r.Context(), bucket, key, size, r.URL.Query().Has("extend"))
+	switch {
+	case errors.Is(err, storage.ErrBucketNotFound) || errors.Is(err, storage.ErrObjectNotFound):
+		sendObjectLookupError(w, err, bucket, key)
+		return
+	case errors.Is(err, storage.ErrTruncateExtends):
+		gosssError.SendGossError(w, http.StatusBadRequest, "truncate is larger than the object; add extend to pad it with zero bytes", bucket+"/"+key)
+		return
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		gosssError.SendGossError(w, http.StatusInsufficientStorage, "Bucket quota exceeded", bucket+"/"+key)
+		return
+	case err != nil:
+		slog.Error("Failed to truncate object", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to truncate object", bucket+"/"+key)
+		return
+	}
+	h.objectCreated(bucket, metadata)
+
+	if err := response.Encode(w, metadata); err != nil {
+		slog.Error("Failed to encode metadata", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to encode metadata", bucket+"/"+key)
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTruncateObject(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+	ts.mustPut(t, "docs", "a.txt", "hello world")
+
+	expectStatus(t, ts.do(t, http.MethodPost, "/docs/a.txt?truncate=5", ""), http.StatusOK)
+	if got := ts.do(t, http.MethodGet, "/docs/a.txt", "").Body.String(); got != "hello" {
+		t.Errorf("after truncate = %q, want hello", got)
+	}
+
+	expectStatus(t, ts.do(t, http.MethodPost, "/docs/a.txt?truncate=8", ""), http.StatusBadRequest)
+	expectStatus(t, ts.do(t, http.MethodPost, "/docs/a.txt?truncate=8&extend", ""), http.StatusOK)
+	if got := ts.do(t, http.MethodGet, "/docs/a.txt", "").Body.String(); got != "hello\x00\x00\x00" {
+		t.Errorf("after extend = %q, want zero padding", got)
+	}
+
+	for _, target := range []string{"/docs/a.txt?truncate=-1", "/docs/a.txt?truncate=x"} {
+		expectStatus(t, ts.do(t, http.MethodPost, target, ""), http.StatusBadRequest)
+	}
+	expectStatus(t, ts.do(t, http.MethodPost, "/docs/missing.txt?truncate=0", ""), http.StatusNotFound)
+}
+
+func TestTruncateObjectValidatesKey(t *testing.T) {
+	ts := newTestServer(t, "BLOCKED_KEYS=*.pem")
+	ts.mustCreateBucket(t, "docs")
+
+	// A file outside the bucket that a traversal key would reach
+	outside := filepath.Join(ts.h.config.StoragePath, "outside.txt")
+	if err := os.WriteFile(outside, []byte("untouched"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a/../../outside.txt", "../outside.txt", "a/./b"} {
+		expectStatus(t, ts.do(t, http.MethodPost, "/docs/"+key+"?truncate=0", ""), http.StatusBadRequest)
+	}
+	if data, _ := os.ReadFile(outside); string(data) != "untouched" {
+		t.Errorf("file outside the bucket = %q after traversal truncate", data)
+	}
+
+	expectStatus(t, ts.do(t, http.MethodPost, "/docs/key.pem?truncate=0", ""), http.StatusForbidden)
+}
+
+func TestTruncateObjectBucketLimit(t *testing.T) {
+	ts := newTestServer(t)
+	ts.mustCreateBucket(t, "docs")
+	ts.mustPut(t, "docs", "a.txt", "hello")
+	if err := ts.store.SetBucketMaxObjectSize(context.Background(), "docs", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	expectStatus(t, ts.do(t, http.MethodPost, "/docs/a.txt?truncate=11&extend", ""), http.StatusRequestEntityTooLarge)
+	expectStatus(t, ts.do(t, http.MethodPost, "/docs/a.txt?truncate=10&extend", ""), http.StatusOK)
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+// uploadBody wraps a request body that is streamed into storage. It cuts the
+// body off after limit bytes and remembers why a read failed, since storage
+// only reports that the write failed. This is what enforces the size limit
+// for uploads of unknown size (Transfer-Encoding: chunked, ContentLength -1),
+// which can't be rejected before the body is read.
+type uploadBody struct {
+	io.ReadCloser
+	n   int64
+	err error
+}
+
+// limitUploadBody caps r.Body at limit bytes. Like http.MaxBytesReader, a
+// body over the limit makes the server close the connection afterwards.
+func limitUploadBody(w http.ResponseWriter, r *http.Request, limit int64) *uploadBody {
+	return &uploadBody{ReadCloser: http.MaxBytesReader(w, r.Body, limit)}
+}
+
+func (b *uploadBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if err != nil && err != io.EOF && b.err == nil {
+		b.err = err
+	}
+	return n, err
+}
+
+// tooLarge reports whether reading stopped because the body exceeded the limit
+func (b *uploadBody) tooLarge() bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(b.err, &maxBytesErr)
+}
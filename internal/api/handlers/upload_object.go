@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+
+	"github.com/go-chi/chi/v5"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/response"
+	"github.com/mmvergara/gosss/internal/storage"
+)
+
+// Key strategies for server-assigned upload keys
+const (
+	UploadKeyUUID = "uuid"
+	UploadKeyHash = "hash"
+)
+
+// preferredExtensions picks the usual extension where mime.ExtensionsByType
+// would return several (it sorts them alphabetically, so image/jpeg gives
+// ".jfif")
+var preferredExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"text/plain":      ".txt",
+	"text/html":       ".html",
+	"application/pdf": ".pdf",
+	"video/mp4":       ".mp4",
+	"audio/mpeg":      ".mp3",
+}
+
+// UploadObject handles a plain POST /{bucket}: the body is stored under a key
+// chosen by the server, so untrusted clients never pick object keys. The key
+// is UPLOAD_KEY_PREFIX followed by a random UUID or the SHA-256 of the body
+// (UPLOAD_KEY_STRATEGY), optionally with an extension matching the content
+// type. The response is 201 with the object's metadata.
+func (h *Handler) UploadObject(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.requestTimeout(r))
+	defer cancel()
+
+	bucket := chi.URLParam(r, "bucket")
+
+	// Validate bucket name
+	isValidBuckName, verr := isValidBucketName(bucket)
+	if !isValidBuckName {
+		slog.Debug("Invalid bucket name", "bucket", bucket, "reason", verr.Message)
+		sendValidationError(w, verr, bucket)
+		return
+	}
+
+	isValidMetadata, msg := isValidUserMetadataSize(r.Header, h.config)
+	if !isValidMetadata {
+		slog.Debug("User metadata too large", "bucket", bucket, "reason", msg)
+		gosssError.SendGossError(w, http.StatusBadRequest, msg, bucket)
+		return
+	}
+
+	storageClass, ok := parseStorageClass(r)
+	if !ok {
+		gosssError.SendGossError(w, http.StatusBadRequest, "Invalid storage class", bucket)
+		return
+	}
+
+	if !h.ensureBucket(w, r, bucket) {
+		return
+	}
+
+	maxSize, err := h.maxObjectSize(ctx, bucket)
+	if err != nil {
+		slog.Error("Failed to read bucket max object size", "bucket", bucket, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to check bucket", bucket)
+		return
+	}
+
+	// As in PutObject, known sizes are checked up front and unknown ones
+	// (chunked uploads) while streaming
+	if r.ContentLength > maxSize {
+		gosssError.SendGossError(w, http.StatusRequestEntityTooLarge, "Object exceeds the maximum allowed size", bucket)
+		return
+	}
+	upload := limitUploadBody(w, r, maxSize)
+
+	release, ok := acquireSlot(w, h.writeSlots, "uploads")
+	if !ok {
+		return
+	}
+	defer release()
+
+	// Sniff the content type if the client didn't send one
+	body := bufio.NewReader(upload)
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		head, _ := body.Peek(512)
+		contentType = http.DetectContentType(head)
+	}
+
+	var (
+		name string
+		data io.Reader = body
+	)
+	switch h.config.UploadKeyStrategy {
+	case UploadKeyHash:
+		// The key depends on the whole body, so spool it first
+		spool, sum, err := spoolAndHash(body)
+		if err != nil && upload.tooLarge() {
+			gosssError.SendGossError(w, http.StatusRequestEntityTooLarge, "Object exceeds the maximum allowed size", bucket)
+			return
+		}
+		if err != nil {
+			slog.Error("Failed to spool upload", "bucket", bucket, "error", err)
+			gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to store object", bucket)
+			return
+		}
+		defer os.Remove(spool.Name())
+		defer spool.Close()
+		name, data = sum, spool
+	default:
+		id, err := newUUID()
+		if err != nil {
+			slog.Error("Failed to generate key", "error", err)
+			gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to store object", bucket)
+			return
+		}
+		name = id
+	}
+
+	key := h.config.UploadKeyPrefix + name
+	if h.config.UploadKeyExtension {
+		key += extensionFor(contentType)
+	}
+
+	isValidObjKey, verr := isValidObjectKey(key, h.config)
+	if !isValidObjKey {
+		slog.Error("Generated an invalid object key", "key", key, "reason", verr.Message)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to store object", bucket)
+		return
+	}
+
+	if pattern, blocked := blockedKeyPattern(key, h.config); blocked {
+		sendBlockedKeyError(w, bucket, key, pattern)
+		return
+	}
+
+	// Content-hash keys repeat for identical uploads
+	if !h.allowMutation(w, r, bucket, key) {
+		return
+	}
+
+	template := model.ObjectMetadata{ContentType: contentType, StorageClass: storageClass}
+	metadata, err := h.store.PutObjectWithMetadata(ctx, bucket, key, data, r.ContentLength, template)
+	if errors.Is(err, storage.ErrBucketNotFound) {
+		// Deleted since ensureBucket checked it
+		gosssError.SendGossError(w, http.StatusNotFound, "Bucket not found", bucket)
+		return
+	}
+	if errors.Is(err, storage.ErrTooManyObjects) {
+		gosssError.SendGossError(w, http.StatusConflict, "Bucket has reached its maximum number of objects", bucket)
+		return
+	}
+	if errors.Is(err, storage.ErrQuotaExceeded) {
+		gosssError.SendGossError(w, http.StatusInsufficientStorage, "Bucket quota exceeded", bucket)
+		return
+	}
+	if err != nil && upload.tooLarge() {
+		gosssError.SendGossError(w, http.StatusRequestEntityTooLarge, "Object exceeds the maximum allowed size", bucket)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to store object", "bucket", bucket, "key", key, "error", err)
+		gosssError.SendGossError(w, http.StatusInternalServerError, "Failed to store object", bucket)
+		return
+	}
+	h.objectCreated(bucket, metadata)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/"+bucket+"/"+key)
+	w.WriteHeader(http.StatusCreated)
+	if err := response.Encode(w, metadata); err != nil {
+		slog.Error("Failed to encode metadata", "bucket", bucket, "key", key, "error", err)
+		return
+	}
+}
+
+// spoolAndHash copies r to a temporary file and returns it rewound, along
+// with the hex SHA-256 of its content.
+func spoolAndHash(r io.Reader) (*os.File, string, error) {
+	spool, err := os.CreateTemp("", "gosss-upload-*")
+	if err != nil {
+		return nil, "", err
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(spool, hash), r); err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		return nil, "", err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		return nil, "", err
+	}
+	return spool, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// extensionFor returns the file extension for a content type, or "" if
+// there is no well-known one.
+func extensionFor(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	if ext, ok := preferredExtensions[mediaType]; ok {
+		return ext
+	}
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
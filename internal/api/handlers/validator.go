@@ -2,42 +2,100 @@ package handlers
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net"
+	"net/http"
+	"path"
 	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mmvergara/gosss/internal/config"
+)
+
+// Object key character policies (KEY_CHARACTER_POLICY)
+var (
+	// strictKeyPattern is the original safe set: ASCII letters, digits and
+	// the punctuation between '!' and '_'
+	strictKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9!-_.*'()/]+$`)
+	// relaxedKeyPattern adds spaces, the remaining printable ASCII
+	// punctuation and letters, marks and digits from any script
+	relaxedKeyPattern = regexp.MustCompile("^[\\p{L}\\p{M}\\p{N} !-_`{|}~]+$")
 )
 
-func isValidBucketName(name string) (bool, string) {
+// ValidateBucketName checks a bucket name outside of request handling, e.g.
+// DEFAULT_BUCKET at startup.
+func ValidateBucketName(name string) error {
+	if ok, verr := isValidBucketName(name); !ok {
+		return errors.New(verr.Message)
+	}
+	return nil
+}
+
+// bucketName applies BUCKET_NAME_CASE to a bucket name that arrives outside
+// the URL, which the router already handles.
+func bucketName(name string, cfg *config.Config) string {
+	if cfg.BucketNameCase == "lowercase" {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// validationError describes why a bucket name or object key was rejected:
+// Message for people, and the Field and Rule violated for clients, which
+// are sent as the error response's details.
+type validationError struct {
+	Message string
+	Field   string
+	Rule    string
+}
+
+func invalidBucket(rule, message string) validationError {
+	return validationError{Message: message, Field: "bucket", Rule: rule}
+}
+
+func invalidKey(rule, message string) validationError {
+	return validationError{Message: message, Field: "key", Rule: rule}
+}
+
+func isValidBucketName(name string) (bool, validationError) {
 	// Check length constraint: between 3 and 63 characters
 	if len(name) < 3 || len(name) > 63 {
-		return false, "Bucket name must be between 3 and 63 characters"
+		return false, invalidBucket("bucket_name_length", "Bucket name must be between 3 and 63 characters")
 	}
 
 	// Check for invalid characters: Only lowercase letters, numbers, hyphens, and periods are allowed
 	matched, _ := regexp.MatchString("^[a-z0-9.-]+$", name)
 	if !matched {
-		return false, "Bucket name can only contain lowercase letters, numbers, hyphens, and periods"
+		return false, invalidBucket("bucket_name_characters", "Bucket name can only contain lowercase letters, numbers, hyphens, and periods")
 	}
 
 	// Bucket name must start and end with a letter or number
 	if !regexp.MustCompile("^[a-z0-9]").MatchString(name) || !regexp.MustCompile("[a-z0-9]$").MatchString(name) {
-		return false, "Bucket name must start and end with a letter or number"
+		return false, invalidBucket("bucket_name_edges", "Bucket name must start and end with a letter or number")
 	}
 
 	// Periods (.) cannot be adjacent to each other
 	if strings.Contains(name, "..") {
-		return false, "Periods (.) cannot be adjacent to each other"
+		return false, invalidBucket("bucket_name_adjacent_periods", "Periods (.) cannot be adjacent to each other")
 	}
 
 	// Hyphens (-) cannot be adjacent to each other or at the beginning or end
 	if strings.Contains(name, "--") || strings.HasPrefix(name, "-") || strings.HasSuffix(name, "-") {
-		return false, "Hyphens (-) cannot be adjacent to each other or at the beginning or end"
+		return false, invalidBucket("bucket_name_hyphens", "Hyphens (-) cannot be adjacent to each other or at the beginning or end")
+	}
+
+	// The admin API lives under /admin
+	if name == AdminBucketName {
+		return false, invalidBucket("bucket_name_reserved", "Bucket name admin is reserved")
 	}
 
 	// Check if it's a valid IP address (IPv4 or IPv6)
 	if net.ParseIP(name) != nil {
-		return false, "Bucket name cannot be an IP address"
+		return false, invalidBucket("bucket_name_ip_address", "Bucket name cannot be an IP address")
 	}
 
 	// Check if it's a DNS-compliant name
@@ -46,22 +104,45 @@ func isValidBucketName(name string) (bool, string) {
 	re := regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])*(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])*)*$`)
 	isDNSCompliant := re.MatchString(name)
 	if !isDNSCompliant {
-		return false, "Bucket name must be a valid DNS-compliant name, containing only letters, numbers, hyphens, and periods. It cannot start or end with a hyphen or period."
+		return false, invalidBucket("bucket_name_dns", "Bucket name must be a valid DNS-compliant name, containing only letters, numbers, hyphens, and periods. It cannot start or end with a hyphen or period.")
 	}
 
-	return true, ""
+	return true, validationError{}
 }
 
-func isValidObjectKey(key string) (bool, string) {
+func isValidObjectKey(key string, cfg *config.Config) (bool, validationError) {
 
 	// Check if key is empty
 	if len(key) == 0 {
-		return false, "key cannot be empty"
+		return false, invalidKey("key_empty", "key cannot be empty")
 	}
 
 	// Check maximum length (1024 bytes for most regions)
-	if len(key) > 1024 {
-		return false, "key length cannot exceed 1024 bytes"
+	if len(key) > cfg.MaxKeyLength {
+		return false, invalidKey("key_length", fmt.Sprintf("key length cannot exceed %d bytes", cfg.MaxKeyLength))
+	}
+
+	// Every "/" becomes a directory on disk, so bound the nesting depth and
+	// the length of each path component (most filesystems cap names at 255)
+	segments := strings.Split(key, "/")
+	if len(segments) > cfg.MaxKeySegments {
+		return false, invalidKey("key_segments", fmt.Sprintf("key cannot have more than %d segments", cfg.MaxKeySegments))
+	}
+	for _, segment := range segments {
+		if len(segment) > MaxKeySegmentLength {
+			return false, invalidKey("key_segment_length", fmt.Sprintf("key segments cannot exceed %d bytes", MaxKeySegmentLength))
+		}
+		// "." and ".." would resolve outside the key's own path on disk
+		if segment == "." || segment == ".." {
+			return false, invalidKey("key_dot_segment", "key cannot contain . or .. segments")
+		}
+	}
+
+	// Leading or trailing spaces and tabs make keys that are easy to mistype.
+	// In trim mode they were already stripped from the request, so any left
+	// here came from a request body
+	if cfg.KeyWhitespace != "allow" && hasEdgeWhitespace(key) {
+		return false, invalidKey("key_whitespace", "key cannot start or end with whitespace")
 	}
 
 	// Check for invalid characters
@@ -73,7 +154,7 @@ func isValidObjectKey(key string) (bool, string) {
 
 	for _, char := range invalidChars {
 		if bytes.Contains([]byte(key), []byte{char}) {
-			return false, "key contains invalid control characters"
+			return false, invalidKey("key_control_characters", "key contains invalid control characters")
 		}
 	}
 
@@ -87,7 +168,7 @@ func isValidObjectKey(key string) (bool, string) {
 
 	for _, prefix := range invalidPrefixes {
 		if strings.HasPrefix(key, prefix) {
-			return false, fmt.Sprintf("key cannot start with %s", prefix)
+			return false, invalidKey("key_prefix", fmt.Sprintf("key cannot start with %s", prefix))
 		}
 	}
 
@@ -99,20 +180,130 @@ func isValidObjectKey(key string) (bool, string) {
 
 	for _, seq := range invalidSequences {
 		if strings.Contains(key, seq) {
-			return false, fmt.Sprintf("key cannot contain %s", seq)
+			return false, invalidKey("key_sequence", fmt.Sprintf("key cannot contain %s", seq))
 		}
 	}
 
 	// Check if key ends with forward slash (directory style)
 	if strings.HasSuffix(key, "/") {
-		return false, "key cannot end with forward slash"
+		return false, invalidKey("key_trailing_slash", "key cannot end with forward slash")
+	}
+
+	if !keyCharactersAllowed(key, cfg.KeyCharacterPolicy) {
+		return false, invalidKey("key_characters", "key contains invalid characters")
+	}
+
+	return true, validationError{}
+}
+
+// validObjectTarget checks the bucket name and object key a request names,
+// sending the validation error if either is rejected.
+func (h *Handler) validObjectTarget(w http.ResponseWriter, bucket, key string) bool {
+	if ok, verr := isValidBucketName(bucket); !ok {
+		slog.Debug("Invalid bucket name", "bucket", bucket, "reason", verr.Message)
+		sendValidationError(w, verr, bucket)
+		return false
 	}
+	if ok, verr := isValidObjectKey(key, h.config); !ok {
+		slog.Debug("Invalid object key", "key", key, "reason", verr.Message)
+		sendValidationError(w, verr, bucket+"/"+key)
+		return false
+	}
+	return true
+}
+
+// hasEdgeWhitespace reports whether key starts or ends with a space or tab.
+func hasEdgeWhitespace(key string) bool {
+	return strings.Trim(key, " \t") != key
+}
+
+// trimKeyWhitespace strips leading and trailing spaces and tabs from keys
+// that arrive outside the URL when KEY_WHITESPACE is trim.
+func trimKeyWhitespace(key string, cfg *config.Config) string {
+	if cfg.KeyWhitespace == "trim" {
+		return strings.Trim(key, " \t")
+	}
+	return key
+}
+
+// blockedKeyPattern returns the BLOCKED_KEYS pattern key matches, if any.
+// Patterns containing a "/" are matched against the whole key, the others
+// against its last segment, so ".env" also blocks "app/.env".
+func blockedKeyPattern(key string, cfg *config.Config) (string, bool) {
+	name := path.Base(key)
+	for _, pattern := range cfg.BlockedKeys {
+		subject := name
+		if strings.Contains(pattern, "/") {
+			subject = key
+		}
+		if matched, _ := path.Match(pattern, subject); matched {
+			return pattern, true
+		}
+	}
+	return "", false
+}
 
-	// Additional safe character validation using regex
-	safePattern := regexp.MustCompile(`^[a-zA-Z0-9!-_.*'()/]+$`)
-	if !safePattern.MatchString(key) {
-		return false, "key contains invalid characters"
+// keyCharactersAllowed applies the configured character policy. permissive
+// accepts any valid UTF-8 without control characters.
+func keyCharactersAllowed(key, policy string) bool {
+	switch policy {
+	case "relaxed":
+		return relaxedKeyPattern.MatchString(key)
+	case "permissive":
+		if !utf8.ValidString(key) {
+			return false
+		}
+		for _, r := range key {
+			if unicode.IsControl(r) {
+				return false
+			}
+		}
+		return true
+	default:
+		return strictKeyPattern.MatchString(key)
 	}
+}
 
+func isValidTags(tags map[string]string) (bool, string) {
+	if len(tags) > MaxTagsPerObject {
+		return false, fmt.Sprintf("an object can have at most %d tags", MaxTagsPerObject)
+	}
+
+	for k, v := range tags {
+		if len(k) == 0 {
+			return false, "tag key cannot be empty"
+		}
+		if len(k) > MaxTagKeyLength {
+			return false, fmt.Sprintf("tag key cannot exceed %d characters", MaxTagKeyLength)
+		}
+		if len(v) > MaxTagValueLength {
+			return false, fmt.Sprintf("tag value cannot exceed %d characters", MaxTagValueLength)
+		}
+	}
+
+	return true, ""
+}
+
+// userMetadataPrefix marks request headers carrying user-defined metadata
+const userMetadataPrefix = "X-Amz-Meta-"
+
+// isValidUserMetadataSize checks the combined size of the X-Amz-Meta-*
+// headers against cfg.MaxUserMetadataSize. Like S3, each header counts its
+// name without the prefix plus all of its values.
+func isValidUserMetadataSize(header http.Header, cfg *config.Config) (bool, string) {
+	size := 0
+	for name, values := range header {
+		if !strings.HasPrefix(name, userMetadataPrefix) {
+			continue
+		}
+		size += len(name) - len(userMetadataPrefix)
+		for _, v := range values {
+			size += len(v)
+		}
+	}
+
+	if size > cfg.MaxUserMetadataSize {
+		return false, fmt.Sprintf("User metadata headers cannot exceed %d bytes in total", cfg.MaxUserMetadataSize)
+	}
 	return true, ""
 }
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mmvergara/gosss/internal/config"
+)
+
+func TestObjectKeyLimits(t *testing.T) {
+	cfg := &config.Config{MaxKeyLength: 16, MaxKeySegments: 3, KeyCharacterPolicy: "strict", KeyWhitespace: "allow"}
+
+	tests := []struct {
+		key  string
+		want bool
+		rule string
+	}{
+		{strings.Repeat("a", 16), true, ""},
+		{strings.Repeat("a", 17), false, "key_length"},
+		{"a/b/c", true, ""},
+		{"a/b/c/d", false, "key_segments"},
+	}
+	for _, tt := range tests {
+		ok, verr := isValidObjectKey(tt.key, cfg)
+		if ok != tt.want || verr.Rule != tt.rule {
+			t.Errorf("isValidObjectKey(%q) = %v, %q, want %v, %q", tt.key, ok, verr.Rule, tt.want, tt.rule)
+		}
+	}
+
+	segment := strings.Repeat("s", MaxKeySegmentLength)
+	cfg.MaxKeyLength = 1024
+	if ok, _ := isValidObjectKey(segment, cfg); !ok {
+		t.Errorf("segment of %d bytes rejected", MaxKeySegmentLength)
+	}
+	if ok, verr := isValidObjectKey(segment+"s", cfg); ok || verr.Rule != "key_segment_length" {
+		t.Errorf("segment of %d bytes = %v, %q", MaxKeySegmentLength+1, ok, verr.Rule)
+	}
+}
+
+func TestKeyCharacterPolicies(t *testing.T) {
+	tests := []struct {
+		key                         string
+		strict, relaxed, permissive bool
+	}{
+		{"photos/cat.jpg", true, true, true},
+		{"a(1)!*'.txt", true, true, true},
+		{"my file.txt", false, true, true},
+		{"a+b&c@d.txt", true, true, true},
+		{"notes~{draft}.md", false, true, true},
+		{"café/résumé.pdf", false, true, true},
+		{"日本語.txt", false, true, true},
+		{"emoji-\U0001F600.png", false, false, true},
+		{"tab\there", false, false, false},
+		{"bell\x07", false, false, false},
+		{"invalid-\xff.txt", false, false, false},
+		{"a/../b", false, false, false},
+	}
+	for _, policy := range []string{"strict", "relaxed", "permissive"} {
+		cfg := &config.Config{MaxKeyLength: 1024, MaxKeySegments: 128, KeyCharacterPolicy: policy, KeyWhitespace: "allow"}
+		for _, tt := range tests {
+			want := map[string]bool{"strict": tt.strict, "relaxed": tt.relaxed, "permissive": tt.permissive}[policy]
+			if ok, verr := isValidObjectKey(tt.key, cfg); ok != want {
+				t.Errorf("%s: isValidObjectKey(%q) = %v (%s), want %v", policy, tt.key, ok, verr.Rule, want)
+			}
+		}
+	}
+}
+
+func TestKeyWhitespace(t *testing.T) {
+	tests := []struct {
+		key                 string
+		allow, reject, trim bool
+	}{
+		{"a.txt", true, true, true},
+		{"my file.txt", true, true, true},
+		{" a.txt", true, false, false},
+		{"a.txt ", true, false, false},
+		{"dir/ a.txt", true, true, true},
+	}
+	for _, mode := range []string{"allow", "reject", "trim"} {
+		cfg := &config.Config{MaxKeyLength: 1024, MaxKeySegments: 128, KeyCharacterPolicy: "relaxed", KeyWhitespace: mode}
+		for _, tt := range tests {
+			want := map[string]bool{"allow": tt.allow, "reject": tt.reject, "trim": tt.trim}[mode]
+			if ok, _ := isValidObjectKey(tt.key, cfg); ok != want {
+				t.Errorf("%s: isValidObjectKey(%q) = %v, want %v", mode, tt.key, ok, want)
+			}
+		}
+	}
+
+	// Only trim mode rewrites keys, and only their edges
+	for mode, want := range map[string]string{"allow": " \ta b\t ", "reject": " \ta b\t ", "trim": "a b"} {
+		if got := trimKeyWhitespace(" \ta b\t ", &config.Config{KeyWhitespace: mode}); got != want {
+			t.Errorf("%s: trimKeyWhitespace = %q, want %q", mode, got, want)
+		}
+	}
+}
+
+func TestBlockedKeyPattern(t *testing.T) {
+	cfg := &config.Config{BlockedKeys: []string{".env", "*.pem", "id_rsa", "secrets/*"}}
+
+	for key, want := range map[string]string{
+		".env":              ".env",
+		"app/.env":          ".env",
+		"certs/server.pem":  "*.pem",
+		"home/me/id_rsa":    "id_rsa",
+		"secrets/token":     "secrets/*",
+		"app/.env.example":  "",
+		"id_rsa.pub":        "",
+		"server.pem.txt":    "",
+		"app/secrets/token": "",
+		"secrets/a/token":   "",
+	} {
+		pattern, blocked := blockedKeyPattern(key, cfg)
+		if blocked != (want != "") || pattern != want {
+			t.Errorf("blockedKeyPattern(%q) = %q, %v, want %q", key, pattern, blocked, want)
+		}
+	}
+}
+
+func TestValidationRules(t *testing.T) {
+	buckets := []struct {
+		name, rule string
+	}{
+		{"ab", "bucket_name_length"},
+		{"Photos", "bucket_name_characters"},
+		{".photos", "bucket_name_edges"},
+		{"my..photos", "bucket_name_adjacent_periods"},
+		{"my--photos", "bucket_name_hyphens"},
+		{"admin", "bucket_name_reserved"},
+		{"192.168.1.1", "bucket_name_ip_address"},
+		{"my.-photos", "bucket_name_dns"},
+	}
+	for _, tt := range buckets {
+		ok, verr := isValidBucketName(tt.name)
+		if ok || verr.Field != "bucket" || verr.Rule != tt.rule || verr.Message == "" {
+			t.Errorf("isValidBucketName(%q) = %v, %+v, want rule %s", tt.name, ok, verr, tt.rule)
+		}
+	}
+
+	cfg := &config.Config{MaxKeyLength: 64, MaxKeySegments: 3, KeyCharacterPolicy: "strict", KeyWhitespace: "reject"}
+	keys := []struct {
+		key, rule string
+	}{
+		{"", "key_empty"},
+		{strings.Repeat("a", 65), "key_length"},
+		{"a/b/c/d", "key_segments"},
+		{"a/./b", "key_dot_segment"},
+		{" a.txt", "key_whitespace"},
+		{"a\nb", "key_control_characters"},
+		{"_a.txt", "key_prefix"},
+		{"a//b", "key_sequence"},
+		{"a/", "key_trailing_slash"},
+		{"a~b", "key_characters"},
+	}
+	for _, tt := range keys {
+		ok, verr := isValidObjectKey(tt.key, cfg)
+		if ok || verr.Field != "key" || verr.Rule != tt.rule || verr.Message == "" {
+			t.Errorf("isValidObjectKey(%q) = %v, %+v, want rule %s", tt.key, ok, verr, tt.rule)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/mmvergara/gosss/internal/config"
+)
+
+// NewPprofHandler serves the net/http/pprof endpoints under /debug/pprof/.
+// It has no authentication and is only mounted on the loopback listener
+// started with ENABLE_PPROF, never on the public router.
+func NewPprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// ListenPprof opens the pprof listener on cfg.PprofAddr when ENABLE_PPROF is
+// set, for NewPprofHandler to be served on. Without it nothing is opened and
+// the listener is nil.
+func ListenPprof(cfg *config.Config) (net.Listener, error) {
+	if !cfg.EnablePprof {
+		return nil, nil
+	}
+	return net.Listen("tcp", cfg.PprofAddr)
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mmvergara/gosss/internal/config"
+)
+
+func TestPprofNotOnRouter(t *testing.T) {
+	router, _ := newTestRouter(t, "ENABLE_PPROF=true")
+	for _, headers := range [][]string{
+		nil,
+		{"Authorization", testAuthorization},
+		{"Authorization", "Bearer " + testAdminKey},
+	} {
+		for _, target := range []string{"/debug/pprof/", "/debug/pprof/cmdline", "/admin/debug/pprof/"} {
+			rec := serve(router, http.MethodGet, target, "", headers...)
+			if rec.Code == http.StatusOK || strings.Contains(rec.Body.String(), "Types of profiles") {
+				t.Errorf("GET %s with %q: status %d, body %q", target, headers, rec.Code, rec.Body.String())
+			}
+		}
+	}
+	rec := serve(router, http.MethodGet, "/debug/pprof/", "", "Authorization", testAuthorization)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /debug/pprof/: status %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+// freeAddr returns a loopback address nothing listens on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+func TestListenPprof(t *testing.T) {
+	addr := freeAddr(t)
+	ln, err := ListenPprof(&config.Config{PprofAddr: addr})
+	if err != nil || ln != nil {
+		t.Fatalf("ListenPprof without ENABLE_PPROF = %v, %v, want no listener", ln, err)
+	}
+	// Nothing took the address
+	free, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("%s taken without ENABLE_PPROF: %v", addr, err)
+	}
+	free.Close()
+
+	ln, err = ListenPprof(&config.Config{EnablePprof: true, PprofAddr: addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go http.Serve(ln, NewPprofHandler())
+
+	resp, err := http.Get("http://" + addr + "/debug/pprof/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "Types of profiles") {
+		t.Errorf("pprof listener: status %d, body %q", resp.StatusCode, body)
+	}
+}
@@ -1,37 +1,115 @@
 package api
 
 import (
+	"expvar"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/mmvergara/gosss/internal/api/handlers"
+	"github.com/mmvergara/gosss/internal/audit"
 	"github.com/mmvergara/gosss/internal/config"
 	"github.com/mmvergara/gosss/internal/middleware"
+	"github.com/mmvergara/gosss/internal/response"
 	"github.com/mmvergara/gosss/internal/storage"
+	"github.com/mmvergara/gosss/internal/tracing"
 )
 
-func NewRouter(store storage.Storage, cfg *config.Config) *chi.Mux {
+func NewRouter(store storage.Storage, cfg *config.Config, auditLog *audit.Logger) *chi.Mux {
 	h := handlers.NewHandler(store, cfg)
+	response.SetTimestampFormat(cfg.TimestampFormat)
+	response.SetEnvelope(cfg.EnvelopeResponses)
+
 	r := chi.NewRouter()
-	r.Use(middleware.CorsMiddleware)
+	r.Use(middleware.CreateRequestIDMiddleware(cfg))
+	r.Use(middleware.CreateResponseHeadersMiddleware(cfg))
+	// Ahead of everything that may answer with an error, so that those
+	// bodies are formatted like the handlers'
+	r.Use(middleware.ResponseFormat)
+	if cfg.Tracing {
+		r.Use(tracing.Middleware)
+	}
+	r.Use(middleware.CreateCorsMiddleware(cfg))
 	r.Use(middleware.LoggerMiddleware)
+	r.Use(middleware.CreateIPConcurrencyMiddleware(cfg))
+
+	// Keep router-level errors in the same JSON format as the handlers'
+	r.MethodNotAllowed(h.MethodNotAllowed)
+	r.NotFound(h.NotFound)
 
 	r.Group(func(r chi.Router) {
+		if cfg.NormalizeKeys {
+			r.Use(middleware.NormalizeKeys)
+		}
+		if cfg.KeyWhitespace == "trim" {
+			r.Use(middleware.TrimKeyWhitespace)
+		}
+		if cfg.BucketNameCase == "lowercase" {
+			r.Use(middleware.LowercaseBuckets)
+		}
+
 		r.Get("/presign/{bucket}/*", h.GetSignedObject)
+		r.Head("/presign/{bucket}/*", h.HeadSignedObject)
+		r.Delete("/presign/{bucket}/*", h.DeleteSignedObject)
+
+		// Method discovery doesn't require authentication. CORS preflights
+		// are answered by the CORS middleware before reaching these
+		r.Options("/presign/{bucket}/*", h.Options)
+		r.Options("/{bucket}", h.Options)
+		r.Options("/{bucket}/*", h.Options)
 	})
 
 	r.Group(func(r chi.Router) {
 		r.Use(middleware.CreateAuthMiddleware(cfg))
+		r.Use(middleware.CreateAuditMiddleware(auditLog))
+		if cfg.NormalizeKeys {
+			r.Use(middleware.NormalizeKeys)
+		}
+		if cfg.KeyWhitespace == "trim" {
+			r.Use(middleware.TrimKeyWhitespace)
+		}
+		if cfg.BucketNameCase == "lowercase" {
+			r.Use(middleware.LowercaseBuckets)
+		}
 
 		// Bucket operations
 		r.Put("/{bucket}", h.CreateBucket)
+		r.Post("/{bucket}", h.PostBucket)
 		r.Delete("/{bucket}", h.DeleteBucket)
 		r.Head("/{bucket}", h.HeadBucket)
 
 		// Object operations
 		r.Get("/{bucket}/*", h.GetObject)
 		r.Put("/{bucket}/*", h.PutObject)
+		r.Post("/{bucket}/*", h.PostObject)
 		r.Delete("/{bucket}/*", h.DeleteObject)
 		r.Get("/{bucket}", h.ListObjects)
 		r.Head("/{bucket}/*", h.HeadObject)
 	})
+
+	// Administrative operations take the admin API key instead of the data
+	// credentials, so data access can be granted without them
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(middleware.CreateAdminAuthMiddleware(cfg))
+		r.Use(middleware.CreateAuditMiddleware(auditLog))
+		if cfg.NormalizeKeys {
+			r.Use(middleware.NormalizeKeys)
+		}
+		if cfg.KeyWhitespace == "trim" {
+			r.Use(middleware.TrimKeyWhitespace)
+		}
+		if cfg.BucketNameCase == "lowercase" {
+			r.Use(middleware.LowercaseBuckets)
+		}
+
+		// Served under /admin so it doesn't shadow key vars in a bucket named
+		// debug
+		if cfg.StorageMetrics {
+			r.Get("/debug/vars", expvar.Handler().ServeHTTP)
+		}
+
+		r.Put("/{bucket}", h.AdminPutBucket)
+		r.Post("/{bucket}", h.AdminPostBucket)
+		r.Delete("/{bucket}", h.AdminDeleteBucket)
+		r.Post("/{bucket}/*", h.AdminPostObject)
+	})
 	return r
 }
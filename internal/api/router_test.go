@@ -0,0 +1,359 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mmvergara/gosss/internal/config"
+	"github.com/mmvergara/gosss/internal/storage"
+)
+
+const (
+	testAuthorization = "test-access-key=test-secret-key"
+	testAdminKey      = "test-admin-key"
+)
+
+// newTestRouter builds the full router over a LocalStorage in a temporary
+// directory. env holds extra NAME=value settings applied before the
+// configuration is loaded.
+func newTestRouter(t *testing.T, env ...string) (*chi.Mux, *storage.LocalStorage) {
+	t.Helper()
+	t.Setenv("ACCESS_KEY_ID", "test-access-key")
+	t.Setenv("SECRET_ACCESS_KEY", "test-secret-key")
+	t.Setenv("ADMIN_API_KEY", testAdminKey)
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		t.Setenv(name, value)
+	}
+	cfg, err := config.New()
+	if err != nil {
+		t.Fatalf("config.New: %v", err)
+	}
+	cfg.StoragePath = t.TempDir()
+	store := storage.New(cfg.StoragePath, storage.Options{})
+	return NewRouter(store, cfg, nil), store
+}
+
+// serve sends a request through router with headers given as alternating
+// names and values.
+func serve(router http.Handler, method, target, body string, headers ...string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestStorageMetricsUnderAdmin(t *testing.T) {
+	router, store := newTestRouter(t, "STORAGE_METRICS=true")
+	ctx := context.Background()
+	if err := store.CreateBucket(ctx, "debug"); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serve(router, http.MethodPut, "/debug/vars", "object", "Authorization", testAuthorization)
+	if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Fatalf("PUT /debug/vars: status %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = serve(router, http.MethodGet, "/debug/vars", "", "Authorization", testAuthorization)
+	if rec.Code != http.StatusOK || rec.Body.String() != "object" {
+		t.Errorf("GET /debug/vars = %d %q, want the object", rec.Code, rec.Body.String())
+	}
+
+	rec = serve(router, http.MethodGet, "/admin/debug/vars", "", "Authorization", "Bearer "+testAdminKey)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"memstats"`) {
+		t.Errorf("GET /admin/debug/vars = %d, want the expvars", rec.Code)
+	}
+	rec = serve(router, http.MethodGet, "/admin/debug/vars", "", "Authorization", testAuthorization)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET /admin/debug/vars with data credentials = %d, want 401", rec.Code)
+	}
+}
+
+// The admin key only opens the admin routes, not the data ones
+func TestAdminKeyRejectedOnDataRoutes(t *testing.T) {
+	router, store := newTestRouter(t)
+	ctx := context.Background()
+	if err := store.CreateBucket(ctx, "photos"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.PutObject(ctx, "photos", "a.jpg", strings.NewReader("original"), 8, "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, auth := range []string{"Bearer " + testAdminKey, testAdminKey, testAdminKey + "=" + testAdminKey} {
+		for _, tc := range []struct{ method, target string }{
+			{http.MethodGet, "/photos"},
+			{http.MethodPut, "/albums"},
+			{http.MethodGet, "/photos/a.jpg"},
+			{http.MethodPut, "/photos/a.jpg"},
+		} {
+			rec := serve(router, tc.method, tc.target, "replaced", "Authorization", auth)
+			if rec.Code != http.StatusUnauthorized && rec.Code != http.StatusForbidden {
+				t.Errorf("%s %s with Authorization %q: status %d, want 401 or 403", tc.method, tc.target, auth, rec.Code)
+			}
+		}
+	}
+
+	if exists, err := store.BucketExists(ctx, "albums"); err != nil || exists {
+		t.Errorf("bucket created with the admin key: %v, %v", exists, err)
+	}
+	rec := serve(router, http.MethodGet, "/photos/a.jpg", "", "Authorization", testAuthorization)
+	if rec.Code != http.StatusOK || rec.Body.String() != "original" {
+		t.Errorf("object = %d %q, want it untouched", rec.Code, rec.Body.String())
+	}
+}
+
+// Requests the router can't route get the same JSON error body as the
+// handlers' errors
+func TestRouterErrors(t *testing.T) {
+	router, _ := newTestRouter(t)
+	for _, tc := range []struct {
+		method, target string
+		status         int
+		allow          string
+	}{
+		{http.MethodGet, "/", http.StatusNotFound, ""},
+		{http.MethodPatch, "/photos/cat.jpg", http.StatusMethodNotAllowed, "OPTIONS, GET, HEAD, PUT, POST, DELETE"},
+		{http.MethodPatch, "/photos", http.StatusMethodNotAllowed, "OPTIONS, GET, HEAD, PUT, POST, DELETE"},
+	} {
+		rec := serve(router, tc.method, tc.target, "", "Authorization", testAuthorization)
+		if rec.Code != tc.status {
+			t.Errorf("%s %s: status %d, want %d", tc.method, tc.target, rec.Code, tc.status)
+			continue
+		}
+		if got := rec.Header().Get("Content-Type"); got != "application/json" {
+			t.Errorf("%s %s: Content-Type = %q, want application/json", tc.method, tc.target, got)
+		}
+		if got := rec.Header().Get("Allow"); got != tc.allow {
+			t.Errorf("%s %s: Allow = %q, want %q", tc.method, tc.target, got, tc.allow)
+		}
+		var body struct {
+			Code     string `json:"code"`
+			Message  string `json:"message"`
+			Resource string `json:"resource"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Errorf("%s %s: body %q is not JSON: %v", tc.method, tc.target, rec.Body.String(), err)
+			continue
+		}
+		if body.Code != strconv.Itoa(tc.status) || body.Message == "" || body.Resource != tc.target {
+			t.Errorf("%s %s: body = %+v", tc.method, tc.target, body)
+		}
+	}
+}
+
+func TestResponseHeadersOnEveryResponse(t *testing.T) {
+	router, store := newTestRouter(t, "RESPONSE_HEADER_X_CONTENT_TYPE_OPTIONS=nosniff", "RESPONSE_HEADER_X_FRAME_OPTIONS=DENY")
+	if err := store.CreateBucket(context.Background(), "docs"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		method, target string
+		headers        []string
+		status         int
+	}{
+		{http.MethodPut, "/docs/a.txt", []string{"Authorization", testAuthorization}, http.StatusOK},
+		{http.MethodGet, "/docs/a.txt", []string{"Authorization", testAuthorization}, http.StatusOK},
+		{http.MethodGet, "/docs/missing.txt", []string{"Authorization", testAuthorization}, http.StatusNotFound},
+		{http.MethodGet, "/docs/a.txt", nil, http.StatusUnauthorized},
+		{http.MethodPatch, "/docs/a.txt", []string{"Authorization", testAuthorization}, http.StatusMethodNotAllowed},
+	} {
+		rec := serve(router, tc.method, tc.target, "body", tc.headers...)
+		if rec.Code != tc.status {
+			t.Errorf("%s %s: status %d, want %d", tc.method, tc.target, rec.Code, tc.status)
+		}
+		if rec.Header().Get("X-Content-Type-Options") != "nosniff" || rec.Header().Get("X-Frame-Options") != "DENY" {
+			t.Errorf("%s %s (%d): configured headers missing: %v", tc.method, tc.target, rec.Code, rec.Header())
+		}
+	}
+}
+
+func TestKeyWhitespaceModes(t *testing.T) {
+	router, store := newTestRouter(t, "KEY_CHARACTER_POLICY=relaxed", "KEY_WHITESPACE=trim")
+	if err := store.CreateBucket(context.Background(), "docs"); err != nil {
+		t.Fatal(err)
+	}
+	rec := serve(router, http.MethodPut, "/docs/%20%20photo.jpg%20", "image", "Authorization", testAuthorization)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("trim: PUT with edge spaces: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := store.HeadObject(context.Background(), "docs", "photo.jpg"); err != nil {
+		t.Errorf("trim: object not stored under the trimmed key: %v", err)
+	}
+	rec = serve(router, http.MethodGet, "/docs/photo.jpg%20", "", "Authorization", testAuthorization)
+	if rec.Code != http.StatusOK || rec.Body.String() != "image" {
+		t.Errorf("trim: GET with a trailing space = %d %q, want the object", rec.Code, rec.Body.String())
+	}
+
+	router, store = newTestRouter(t, "KEY_CHARACTER_POLICY=relaxed", "KEY_WHITESPACE=reject")
+	if err := store.CreateBucket(context.Background(), "docs"); err != nil {
+		t.Fatal(err)
+	}
+	rec = serve(router, http.MethodPut, "/docs/%20photo.jpg", "image", "Authorization", testAuthorization)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("reject: PUT with a leading space: status %d, want 400", rec.Code)
+	}
+	rec = serve(router, http.MethodPut, "/docs/my%20photo.jpg", "image", "Authorization", testAuthorization)
+	if rec.Code != http.StatusOK {
+		t.Errorf("reject: PUT with an inner space: status %d, want 200", rec.Code)
+	}
+}
+
+func TestBucketNameCaseModes(t *testing.T) {
+	router, _ := newTestRouter(t)
+	rec := serve(router, http.MethodPut, "/Photos", "", "Authorization", testAuthorization)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("strict: PUT /Photos: status %d, want 400", rec.Code)
+	}
+
+	router, store := newTestRouter(t, "BUCKET_NAME_CASE=lowercase")
+	rec = serve(router, http.MethodPut, "/Photos", "", "Authorization", testAuthorization)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("lowercase: PUT /Photos: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if exists, err := store.BucketExists(context.Background(), "photos"); err != nil || !exists {
+		t.Fatalf("lowercase: bucket not stored as photos: %v", err)
+	}
+	rec = serve(router, http.MethodPut, "/PHOTOS/cat.jpg", "meow", "Authorization", testAuthorization)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("lowercase: PUT /PHOTOS/cat.jpg: status %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = serve(router, http.MethodGet, "/photos/cat.jpg", "", "Authorization", testAuthorization)
+	if rec.Code != http.StatusOK || rec.Body.String() != "meow" {
+		t.Errorf("lowercase: GET /photos/cat.jpg = %d %q, want the object", rec.Code, rec.Body.String())
+	}
+	rec = serve(router, http.MethodPut, "/photos/copy.jpg", "", "Authorization", testAuthorization, "X-Copy-Source", "/Photos/cat.jpg")
+	if rec.Code != http.StatusOK {
+		t.Errorf("lowercase: copy from /Photos/cat.jpg: status %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// envelopeOf decodes an enveloped body, failing the test if it isn't one
+func envelopeOf(t *testing.T, rec *httptest.ResponseRecorder) (ok bool, data, errBody json.RawMessage) {
+	t.Helper()
+	var env struct {
+		OK    *bool           `json:"ok"`
+		Data  json.RawMessage `json:"data"`
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil || env.OK == nil || env.Data == nil || env.Error == nil {
+		t.Fatalf("status %d: body %q is not an envelope (%v)", rec.Code, rec.Body.String(), err)
+	}
+	return *env.OK, env.Data, env.Error
+}
+
+func TestEnvelopeOnEveryResponse(t *testing.T) {
+	router, store := newTestRouter(t)
+	if err := store.CreateBucket(context.Background(), "photos"); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serve(router, http.MethodGet, "/photos?envelope=true", "", "Authorization", testAuthorization)
+	if ok, data, errBody := envelopeOf(t, rec); !ok || string(errBody) != "null" || !strings.Contains(string(data), `"photos"`) {
+		t.Errorf("listing: ok %v, data %s, error %s", ok, data, errBody)
+	}
+
+	for _, tc := range []struct {
+		name, method, target string
+		headers              []string
+		status               int
+		code                 string
+	}{
+		{"handler error", http.MethodGet, "/photos/missing.jpg?envelope=true", []string{"Authorization", testAuthorization}, http.StatusNotFound, "NoSuchKey"},
+		{"missing credentials", http.MethodGet, "/photos?envelope=true", nil, http.StatusUnauthorized, "401"},
+		{"unknown route", http.MethodGet, "/?envelope=true", []string{"Authorization", testAuthorization}, http.StatusNotFound, "404"},
+		{"method not allowed", http.MethodPatch, "/photos?envelope=true", []string{"Authorization", testAuthorization}, http.StatusMethodNotAllowed, "405"},
+		{"admin without key", http.MethodPut, "/admin/photos?quota=1&envelope=true", nil, http.StatusUnauthorized, "401"},
+	} {
+		rec := serve(router, tc.method, tc.target, "", tc.headers...)
+		if rec.Code != tc.status {
+			t.Errorf("%s: status %d, want %d", tc.name, rec.Code, tc.status)
+			continue
+		}
+		ok, data, errBody := envelopeOf(t, rec)
+		if ok || string(data) != "null" || !strings.Contains(string(errBody), `"code":"`+tc.code+`"`) {
+			t.Errorf("%s: ok %v, data %s, error %s", tc.name, ok, data, errBody)
+		}
+	}
+
+	// Without the query nothing is wrapped
+	rec = serve(router, http.MethodGet, "/photos", "")
+	if strings.Contains(rec.Body.String(), `"ok"`) {
+		t.Errorf("unwrapped 401 has an envelope: %s", rec.Body.String())
+	}
+	rec = serve(router, http.MethodGet, "/photos?envelope=maybe", "", "Authorization", testAuthorization)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "envelope") {
+		t.Errorf("?envelope=maybe: status %d, body %s, want 400", rec.Code, rec.Body.String())
+	}
+}
+
+func TestEnvelopeDefaultOn(t *testing.T) {
+	router, store := newTestRouter(t, "ENVELOPE_RESPONSES=true", "MAX_CONCURRENT_PER_IP=1")
+	if err := store.CreateBucket(context.Background(), "photos"); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serve(router, http.MethodGet, "/photos", "")
+	expectEnvelopeError(t, rec, http.StatusUnauthorized)
+	rec = serve(router, http.MethodGet, "/photos?envelope=false", "")
+	if rec.Code != http.StatusUnauthorized || strings.Contains(rec.Body.String(), `"ok"`) {
+		t.Errorf("?envelope=false on a 401: status %d, body %s, want a raw error", rec.Code, rec.Body.String())
+	}
+
+	// Hold the client's only slot with an upload whose body never ends
+	body, hold := io.Pipe()
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req := httptest.NewRequest(http.MethodPut, "/photos/slow.jpg", &signalReader{r: body, started: started})
+		req.Header.Set("Authorization", testAuthorization)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	<-started
+	expectEnvelopeError(t, serve(router, http.MethodGet, "/photos/a.jpg", "", "Authorization", testAuthorization), http.StatusTooManyRequests)
+	hold.Close()
+	<-done
+}
+
+// expectEnvelopeError fails the test unless rec is an enveloped error with
+// the wanted status
+func expectEnvelopeError(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status %d, want %d: %s", rec.Code, status, rec.Body.String())
+	}
+	if ok, _, errBody := envelopeOf(t, rec); ok || string(errBody) == "null" {
+		t.Errorf("status %d: ok %v, error %s", status, ok, errBody)
+	}
+}
+
+// signalReader closes started on its first read
+type signalReader struct {
+	r       io.Reader
+	started chan struct{}
+	once    bool
+}
+
+func (s *signalReader) Read(p []byte) (int, error) {
+	if !s.once {
+		s.once = true
+		close(s.started)
+	}
+	return s.r.Read(p)
+}
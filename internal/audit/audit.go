@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+const queueSize = 1024
+
+// Entry is a single line of the audit log.
+type Entry struct {
+	Time        time.Time `json:"time"`
+	AccessKeyID string    `json:"accessKeyId"`
+	Method      string    `json:"method"`
+	Bucket      string    `json:"bucket"`
+	Key         string    `json:"key,omitempty"`
+	Status      int       `json:"status"`
+	RemoteAddr  string    `json:"remoteAddr"`
+}
+
+// Logger appends audit entries as JSON lines to a file. Writes happen on a
+// background goroutine so request handling never waits on disk I/O. When the
+// file grows past maxSize bytes it is rotated to <path>.<timestamp>.
+type Logger struct {
+	path    string
+	maxSize int64
+	file    *os.File
+	size    int64
+	entries chan Entry
+}
+
+// New opens (or creates) the audit log at path. A nil Logger is returned when
+// path is empty; calling Record on it is a no-op.
+func New(path string, maxSize int64) (*Logger, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	l := &Logger{
+		path:    path,
+		maxSize: maxSize,
+		entries: make(chan Entry, queueSize),
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	go l.run()
+	return l, nil
+}
+
+// Record queues an entry for writing. If the queue is full the entry is
+// dropped (and logged) rather than blocking the request.
+func (l *Logger) Record(e Entry) {
+	if l == nil {
+		return
+	}
+
+	select {
+	case l.entries <- e:
+	default:
+		slog.Warn("Audit log queue full, dropping entry", "method", e.Method, "bucket", e.Bucket, "key", e.Key)
+	}
+}
+
+func (l *Logger) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+func (l *Logger) run() {
+	for e := range l.entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			slog.Error("Failed to encode audit entry", "error", err)
+			continue
+		}
+		line = append(line, '\n')
+
+		if l.maxSize > 0 && l.size+int64(len(line)) > l.maxSize {
+			if err := l.rotate(); err != nil {
+				slog.Error("Failed to rotate audit log", "error", err)
+			}
+		}
+
+		n, err := l.file.Write(line)
+		l.size += int64(n)
+		if err != nil {
+			slog.Error("Failed to write audit entry", "error", err)
+		}
+	}
+}
+
+func (l *Logger) rotate() error {
+	l.file.Close()
+	rotated := l.path + "." + time.Now().UTC().Format("20060102T150405")
+	if err := os.Rename(l.path, rotated); err != nil {
+		// Keep appending to the current file rather than losing entries
+		slog.Error("Failed to rename audit log", "error", err)
+	}
+	return l.open()
+}
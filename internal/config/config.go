@@ -3,7 +3,17 @@ package config
 import (
 	"fmt"
 	"log"
+	"log/slog"
+	"mime"
+	"net"
+	"net/netip"
+	"net/textproto"
+	"net/url"
 	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	_ "github.com/joho/godotenv/autoload"
 )
@@ -13,6 +23,242 @@ type Config struct {
 	StoragePath string
 	AccessKeyID string
 	SecretKey   string
+
+	// HTTP server timeouts. ReadTimeout and WriteTimeout cover the whole
+	// request body and response respectively, so they must be generous
+	// enough for large uploads and downloads.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// MaxHeaderBytes caps the size of a request's header section, request
+	// line included
+	MaxHeaderBytes int
+	// MaxUserMetadataSize caps the combined size of a PutObject's
+	// X-Amz-Meta-* headers, names (without the prefix) plus values
+	MaxUserMetadataSize int
+
+	// AdminAPIKey authenticates the /admin routes (quota, cleanup,
+	// recompute). It is separate from the data-plane credentials; empty
+	// disables the admin API.
+	AdminAPIKey string
+
+	// EnablePprof serves the net/http/pprof endpoints on PprofAddr, a
+	// separate listener that must be bound to a loopback address since it
+	// has no authentication
+	EnablePprof bool
+	PprofAddr   string
+
+	// MaxRequestTimeout caps the per-request deadline clients may ask for
+	// with the X-Timeout-Seconds header.
+	MaxRequestTimeout time.Duration
+
+	// PresignClockSkew is how far past its expiration a presigned URL is still
+	// accepted, to absorb clock differences between client and server.
+	PresignClockSkew time.Duration
+	// MaxPresignTTL bounds how far in the future a presigned URL may expire.
+	// Zero disables the limit.
+	MaxPresignTTL time.Duration
+	// PresignAlgorithm is the HMAC hash (SHA256 or SHA512) of presigned URLs
+	// that don't name one in ?algorithm
+	PresignAlgorithm string
+
+	// BucketNameCase is "strict", rejecting bucket names with upper-case
+	// letters, or "lowercase", lower-casing them on input
+	BucketNameCase string
+
+	// DefaultBucket is created at startup if it doesn't exist. Empty
+	// disables it.
+	DefaultBucket string
+	// AutoCreateBuckets creates the bucket of an upload that targets a
+	// bucket which doesn't exist yet
+	AutoCreateBuckets bool
+
+	// SoftDelete moves deleted objects into a per-bucket trash area instead of
+	// removing them. Trashed objects are purged after TrashRetention.
+	SoftDelete     bool
+	TrashRetention time.Duration
+
+	// WebhookURL receives object events for every bucket without its own
+	// entry in BucketWebhooks. Empty disables notifications.
+	WebhookURL     string
+	BucketWebhooks map[string]string
+
+	// OriginURL is fetched from, as ORIGIN_URL/{bucket}/{key}, for GETs of
+	// objects this server doesn't have. Empty disables the passthrough. With
+	// OriginCache the fetched objects are stored locally as well.
+	OriginURL   string
+	OriginCache bool
+
+	// AuditLogPath is the file every mutating request is appended to. Empty
+	// disables auditing. The file is rotated once it exceeds AuditLogMaxSize.
+	AuditLogPath    string
+	AuditLogMaxSize int64
+
+	// TempFileMaxAge is how old a temp file must be before DELETE
+	// /{bucket}?cleanup considers it abandoned.
+	TempFileMaxAge time.Duration
+
+	// UploadExpiry aborts resumable uploads that have received no data for
+	// this long. Zero keeps them until they complete.
+	UploadExpiry time.Duration
+
+	// IdempotencyTTL is how long PutObject remembers an Idempotency-Key and
+	// its result. Zero disables Idempotency-Key handling.
+	IdempotencyTTL time.Duration
+
+	// CreateImmutabilityWindow is how long after it was last written an
+	// object can't be overwritten or deleted. Zero disables the window.
+	CreateImmutabilityWindow time.Duration
+
+	// MaxObjectsPerBucket caps how many objects a bucket may hold. Zero means
+	// unlimited.
+	MaxObjectsPerBucket int64
+
+	// Object key limits. Each "/" in a key is a directory on disk, so deep
+	// keys mean deep trees that slow down walks.
+	MaxKeyLength   int
+	MaxKeySegments int
+	// KeyCharacterPolicy selects which characters object keys may contain:
+	// "strict", "relaxed" or "permissive"
+	KeyCharacterPolicy string
+	// KeyWhitespace controls leading and trailing spaces and tabs in object
+	// keys: "allow", "reject" or "trim"
+	KeyWhitespace string
+	// BlockedKeys are glob patterns of keys that may never be stored, e.g.
+	// ".env" or "*.pem". Patterns without a "/" match the key's last segment
+	BlockedKeys []string
+
+	// MimeTypes maps file extensions (".webp") to the content type assumed
+	// for objects uploaded without a Content-Type. Entries are added to, and
+	// take precedence over, the host's MIME table.
+	MimeTypes map[string]string
+
+	// SmallObjectThreshold is the largest upload, in bytes, that PutObject
+	// receives into memory before handing it to storage. Zero streams every
+	// upload.
+	SmallObjectThreshold int64
+
+	// MaxListKeys caps the number of objects a single listing returns
+	MaxListKeys int
+
+	// MaxBatchMetadataKeys caps the keys one POST /{bucket}?metadata request
+	// may ask for
+	MaxBatchMetadataKeys int
+
+	// NormalizeKeys canonicalizes object keys to Unicode NFC before they are
+	// used, so NFC and NFD spellings of a name are the same object. Only
+	// meaningful with a key character policy that admits non-ASCII keys.
+	NormalizeKeys bool
+
+	// Server-assigned keys for POST /{bucket} uploads: UploadKeyPrefix
+	// followed by a random UUID or the body's SHA-256 (UploadKeyStrategy),
+	// plus an extension for the content type if UploadKeyExtension is set.
+	UploadKeyStrategy  string
+	UploadKeyPrefix    string
+	UploadKeyExtension bool
+
+	// DirMode and FileMode are the permissions of created bucket/object
+	// directories and of object and metadata files.
+	DirMode  os.FileMode
+	FileMode os.FileMode
+
+	// DurableWrites fsyncs written files and their directories before a write
+	// is acknowledged. Safer across power loss, but slower.
+	DurableWrites bool
+
+	// ContentAddressed stores identical object content only once per bucket
+	ContentAddressed bool
+
+	// ETagHistoryLimit is how many earlier ETags are remembered per object
+	// across overwrites. Zero disables the history.
+	ETagHistoryLimit int
+
+	// ShardKeys stores objects in directories derived from a hash of their
+	// key, so no single directory grows too large.
+	ShardKeys bool
+
+	// StorageMetrics times every storage operation and publishes the totals
+	// at /admin/debug/vars.
+	StorageMetrics bool
+
+	// Objects up to CacheMaxObjectSize bytes are kept in memory, up to
+	// CacheMaxBytes in total, for CacheTTL. A zero CacheMaxObjectSize
+	// disables the cache.
+	CacheMaxObjectSize int64
+	CacheMaxBytes      int64
+	CacheTTL           time.Duration
+
+	// Concurrent reads of the same object up to CoalesceMaxObjectSize
+	// bytes share one disk read, whether or not the cache is enabled. Zero
+	// turns coalescing off for objects the cache doesn't hold.
+	CoalesceMaxObjectSize int64
+
+	// GzipResponses compresses object downloads for clients that accept
+	// gzip. Bodies smaller than GzipMinSize are always sent uncompressed.
+	GzipResponses bool
+	GzipMinSize   int64
+
+	// ForceAttachment serves objects a browser would render as active
+	// content (HTML, SVG, XML) with Content-Disposition: attachment.
+	ForceAttachment bool
+
+	// ImageTransforms resizes images on GET when w or h is given, up to
+	// ImageTransformMaxDimension pixels a side. Variants are cached in
+	// memory up to ImageTransformCacheBytes; zero disables the cache.
+	ImageTransforms            bool
+	ImageTransformMaxDimension int
+	ImageTransformCacheBytes   int64
+
+	// MaxConcurrentWrites and MaxConcurrentReads cap the uploads and
+	// downloads in flight, each with its own pool. Zero means unlimited.
+	MaxConcurrentWrites int
+	MaxConcurrentReads  int
+	// MaxConcurrentPerIP caps the requests a single client IP may have in
+	// flight. Zero disables the limit.
+	MaxConcurrentPerIP int
+	// TrustedProxies are the load balancers/reverse proxies whose
+	// X-Forwarded-For header is believed when resolving the client IP.
+	TrustedProxies []netip.Prefix
+	// TrustRequestID keeps a well-formed X-Request-ID sent by the client or
+	// gateway instead of generating a new one. Only enable it behind a
+	// gateway that sets the header itself.
+	TrustRequestID bool
+
+	// Tracing is set when an OTLP endpoint is configured through
+	// OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT. The
+	// OpenTelemetry SDK reads the OTEL_* variables itself.
+	Tracing bool
+
+	// CorsMaxAge is how long browsers may cache a CORS preflight response.
+	// Zero leaves Access-Control-Max-Age unset.
+	CorsMaxAge time.Duration
+
+	// ResponseHeaders are added to every response, from the
+	// RESPONSE_HEADER_<NAME> variables. StripResponseHeaders are removed
+	// from every response, whoever set them.
+	ResponseHeaders      map[string]string
+	StripResponseHeaders []string
+
+	// RedirectStatus is the status GET and HEAD answer with for objects
+	// uploaded with X-Redirect-Location: 301, 302, 307 or 308
+	RedirectStatus int
+
+	// ArchiveClasses are the storage classes whose objects must be restored
+	// with POST ?restore before they can be read
+	ArchiveClasses []string
+
+	// TimestampFormat is how timestamps are written in JSON response bodies:
+	// "rfc3339" or "unix-millis"
+	TimestampFormat string
+
+	// EnvelopeResponses wraps JSON bodies in {"ok", "data", "error"} unless
+	// a request asks otherwise with ?envelope=false
+	EnvelopeResponses bool
+
+	// LogLevel controls which log lines are emitted (debug, info, warn, error)
+	LogLevel slog.Level
 }
 
 func New() (*Config, error) {
@@ -28,16 +274,476 @@ func New() (*Config, error) {
 
 	storagePath := "data"
 
+	readHeaderTimeout, err := getEnvDuration("READ_HEADER_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	readTimeout, err := getEnvDuration("READ_TIMEOUT", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	writeTimeout, err := getEnvDuration("WRITE_TIMEOUT", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	idleTimeout, err := getEnvDuration("IDLE_TIMEOUT", 2*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	maxRequestTimeout, err := getEnvDuration("MAX_REQUEST_TIMEOUT", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	maxHeaderBytes, err := getEnvInt("MAX_HEADER_BYTES", 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	maxUserMetadataSize, err := getEnvInt("MAX_USER_METADATA_SIZE", 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	enablePprof, err := getEnvBool("ENABLE_PPROF", false)
+	if err != nil {
+		return nil, err
+	}
+	pprofAddr := getEnvDefault("PPROF_ADDR", "localhost:6060")
+	if enablePprof && !isLoopbackAddr(pprofAddr) {
+		return nil, fmt.Errorf("PPROF_ADDR must be a loopback address such as localhost:6060")
+	}
+
+	originURL := strings.TrimSuffix(os.Getenv("ORIGIN_URL"), "/")
+	if originURL != "" && !isHTTPURL(originURL) {
+		return nil, fmt.Errorf("ORIGIN_URL must be an http(s) URL")
+	}
+	originCache, err := getEnvBool("ORIGIN_CACHE", false)
+	if err != nil {
+		return nil, err
+	}
+
 	log.Println("Access Key ID:", accessKeyID)
 	log.Println("Secret Key:", secretKey)
 	log.Println("Storage Path:", storagePath)
 	log.Println("Port:", os.Getenv("PORT"))
 
+	presignClockSkew, err := getEnvDuration("PRESIGN_CLOCK_SKEW", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	maxPresignTTL, err := getEnvDuration("MAX_PRESIGN_TTL", 7*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	presignAlgorithm := strings.ToUpper(getEnvDefault("PRESIGN_ALGORITHM", "SHA256"))
+	if presignAlgorithm != "SHA256" && presignAlgorithm != "SHA512" {
+		return nil, fmt.Errorf("PRESIGN_ALGORITHM must be SHA256 or SHA512")
+	}
+
+	autoCreateBuckets, err := getEnvBool("AUTO_CREATE_BUCKETS", false)
+	if err != nil {
+		return nil, err
+	}
+
+	softDelete, err := getEnvBool("SOFT_DELETE", false)
+	if err != nil {
+		return nil, err
+	}
+	trashRetention, err := getEnvDuration("TRASH_RETENTION", 7*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketNameCase := strings.ToLower(getEnvDefault("BUCKET_NAME_CASE", "strict"))
+	if bucketNameCase != "strict" && bucketNameCase != "lowercase" {
+		return nil, fmt.Errorf("BUCKET_NAME_CASE must be strict or lowercase")
+	}
+
+	defaultBucket := os.Getenv("DEFAULT_BUCKET")
+	bucketWebhooks, err := parseBucketMap("BUCKET_WEBHOOKS")
+	if err != nil {
+		return nil, err
+	}
+	// Buckets configured by name must match the lowercased request names
+	if bucketNameCase == "lowercase" {
+		defaultBucket = strings.ToLower(defaultBucket)
+		lowered := make(map[string]string, len(bucketWebhooks))
+		for bucket, url := range bucketWebhooks {
+			lowered[strings.ToLower(bucket)] = url
+		}
+		bucketWebhooks = lowered
+	}
+
+	auditLogMaxSize, err := getEnvInt("AUDIT_LOG_MAX_SIZE", 100*1024*1024)
+	if err != nil {
+		return nil, err
+	}
+
+	tempFileMaxAge, err := getEnvDuration("TEMP_FILE_MAX_AGE", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	uploadExpiry, err := getEnvDuration("UPLOAD_EXPIRY", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	idempotencyTTL, err := getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	createImmutabilitySeconds, err := getEnvInt("CREATE_IMMUTABILITY_SECONDS", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	maxObjectsPerBucket, err := getEnvInt("MAX_OBJECTS_PER_BUCKET", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	maxKeyLength, err := getEnvInt("MAX_KEY_LENGTH", 1024)
+	if err != nil {
+		return nil, err
+	}
+	if maxKeyLength == 0 {
+		return nil, fmt.Errorf("MAX_KEY_LENGTH must be at least 1")
+	}
+	maxKeySegments, err := getEnvInt("MAX_KEY_SEGMENTS", 64)
+	if err != nil {
+		return nil, err
+	}
+	if maxKeySegments == 0 {
+		return nil, fmt.Errorf("MAX_KEY_SEGMENTS must be at least 1")
+	}
+	keyCharacterPolicy := strings.ToLower(getEnvDefault("KEY_CHARACTER_POLICY", "strict"))
+	if keyCharacterPolicy != "strict" && keyCharacterPolicy != "relaxed" && keyCharacterPolicy != "permissive" {
+		return nil, fmt.Errorf("KEY_CHARACTER_POLICY must be strict, relaxed or permissive")
+	}
+	keyWhitespace := strings.ToLower(getEnvDefault("KEY_WHITESPACE", "allow"))
+	if keyWhitespace != "allow" && keyWhitespace != "reject" && keyWhitespace != "trim" {
+		return nil, fmt.Errorf("KEY_WHITESPACE must be allow, reject or trim")
+	}
+	blockedKeys, err := parseKeyPatterns("BLOCKED_KEYS")
+	if err != nil {
+		return nil, err
+	}
+
+	mimeTypes, err := parseMimeTypes("MIME_TYPES")
+	if err != nil {
+		return nil, err
+	}
+
+	smallObjectThreshold, err := getEnvInt("SMALL_OBJECT_THRESHOLD", 0)
+	if err != nil {
+		return nil, err
+	}
+	// Each in-flight small upload holds this much memory
+	if smallObjectThreshold > 64<<20 {
+		return nil, fmt.Errorf("SMALL_OBJECT_THRESHOLD cannot exceed 67108864 (64MB)")
+	}
+
+	maxListKeys, err := getEnvInt("MAX_LIST_KEYS", 10000)
+	if err != nil {
+		return nil, err
+	}
+	if maxListKeys == 0 {
+		return nil, fmt.Errorf("MAX_LIST_KEYS must be at least 1")
+	}
+
+	maxBatchMetadataKeys, err := getEnvInt("MAX_BATCH_METADATA_KEYS", 1000)
+	if err != nil {
+		return nil, err
+	}
+	if maxBatchMetadataKeys == 0 {
+		return nil, fmt.Errorf("MAX_BATCH_METADATA_KEYS must be at least 1")
+	}
+
+	normalizeKeys, err := getEnvBool("NORMALIZE_KEYS", false)
+	if err != nil {
+		return nil, err
+	}
+	// The strict policy only admits ASCII, which NFC leaves unchanged
+	if normalizeKeys && keyCharacterPolicy == "strict" {
+		return nil, fmt.Errorf("NORMALIZE_KEYS requires KEY_CHARACTER_POLICY relaxed or permissive; strict only allows ASCII keys")
+	}
+
+	uploadKeyStrategy := strings.ToLower(getEnvDefault("UPLOAD_KEY_STRATEGY", "uuid"))
+	if uploadKeyStrategy != "uuid" && uploadKeyStrategy != "hash" {
+		return nil, fmt.Errorf("UPLOAD_KEY_STRATEGY must be uuid or hash")
+	}
+	uploadKeyExtension, err := getEnvBool("UPLOAD_KEY_EXTENSION", false)
+	if err != nil {
+		return nil, err
+	}
+
+	dirMode, err := getEnvFileMode("DIR_MODE", 0755)
+	if err != nil {
+		return nil, err
+	}
+	fileMode, err := getEnvFileMode("FILE_MODE", 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	durableWrites, err := getEnvBool("DURABLE_WRITES", false)
+	if err != nil {
+		return nil, err
+	}
+	contentAddressed, err := getEnvBool("CONTENT_ADDRESSED", false)
+	if err != nil {
+		return nil, err
+	}
+
+	etagHistoryLimit, err := getEnvInt("ETAG_HISTORY_LIMIT", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	shardKeys, err := getEnvBool("SHARD_KEYS", false)
+	if err != nil {
+		return nil, err
+	}
+
+	storageMetrics, err := getEnvBool("STORAGE_METRICS", false)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheMaxObjectSize, err := getEnvInt("CACHE_MAX_OBJECT_SIZE", 0)
+	if err != nil {
+		return nil, err
+	}
+	cacheMaxBytes, err := getEnvInt("CACHE_MAX_BYTES", 64<<20)
+	if err != nil {
+		return nil, err
+	}
+	cacheTTL, err := getEnvDuration("CACHE_TTL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	coalesceMaxObjectSize, err := getEnvInt("COALESCE_MAX_OBJECT_SIZE", 1<<20)
+	if err != nil {
+		return nil, err
+	}
+
+	gzipResponses, err := getEnvBool("GZIP_RESPONSES", false)
+	if err != nil {
+		return nil, err
+	}
+	gzipMinSize, err := getEnvInt("GZIP_MIN_SIZE", 1024)
+	if err != nil {
+		return nil, err
+	}
+
+	forceAttachment, err := getEnvBool("FORCE_ATTACHMENT", false)
+	if err != nil {
+		return nil, err
+	}
+
+	imageTransforms, err := getEnvBool("IMAGE_TRANSFORMS", false)
+	if err != nil {
+		return nil, err
+	}
+	imageTransformMaxDimension, err := getEnvInt("IMAGE_TRANSFORM_MAX_DIMENSION", 2048)
+	if err != nil {
+		return nil, err
+	}
+	if imageTransformMaxDimension == 0 {
+		return nil, fmt.Errorf("invalid IMAGE_TRANSFORM_MAX_DIMENSION: must be at least 1")
+	}
+	imageTransformCacheBytes, err := getEnvInt("IMAGE_TRANSFORM_CACHE_BYTES", 64*1024*1024)
+	if err != nil {
+		return nil, err
+	}
+
+	maxConcurrentWrites, err := getEnvInt("MAX_CONCURRENT_WRITES", 100)
+	if err != nil {
+		return nil, err
+	}
+	maxConcurrentReads, err := getEnvInt("MAX_CONCURRENT_READS", 0)
+	if err != nil {
+		return nil, err
+	}
+	maxConcurrentPerIP, err := getEnvInt("MAX_CONCURRENT_PER_IP", 0)
+	if err != nil {
+		return nil, err
+	}
+	trustedProxies, err := parsePrefixList("TRUSTED_PROXIES")
+	if err != nil {
+		return nil, err
+	}
+
+	trustRequestID, err := getEnvBool("TRUST_REQUEST_ID", false)
+	if err != nil {
+		return nil, err
+	}
+
+	responseHeaders, err := parseResponseHeaders("RESPONSE_HEADER_")
+	if err != nil {
+		return nil, err
+	}
+	stripResponseHeaders, err := parseHeaderList("STRIP_RESPONSE_HEADERS")
+	if err != nil {
+		return nil, err
+	}
+
+	tracing := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+	if disabled, _ := strconv.ParseBool(os.Getenv("OTEL_SDK_DISABLED")); disabled {
+		tracing = false
+	}
+
+	corsMaxAge, err := getEnvDuration("CORS_MAX_AGE", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	redirectStatus, err := getEnvInt("REDIRECT_STATUS", 301)
+	if err != nil {
+		return nil, err
+	}
+	switch redirectStatus {
+	case 301, 302, 307, 308:
+	default:
+		return nil, fmt.Errorf("REDIRECT_STATUS must be 301, 302, 307 or 308")
+	}
+
+	archiveClasses := getEnvList("ARCHIVE_CLASSES", []string{"GLACIER", "DEEP_ARCHIVE"})
+
+	timestampFormat := strings.ToLower(getEnvDefault("TIMESTAMP_FORMAT", "rfc3339"))
+	if timestampFormat != "rfc3339" && timestampFormat != "unix-millis" {
+		return nil, fmt.Errorf("TIMESTAMP_FORMAT must be rfc3339 or unix-millis")
+	}
+
+	envelopeResponses, err := getEnvBool("ENVELOPE_RESPONSES", false)
+	if err != nil {
+		return nil, err
+	}
+
+	logLevel, err := parseLogLevel(getEnvDefault("LOG_LEVEL", "info"))
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
 		StoragePath: storagePath,
 		PORT:        getEnvDefault("PORT", "8191"),
 		AccessKeyID: accessKeyID,
 		SecretKey:   secretKey,
+
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+
+		MaxHeaderBytes:      int(maxHeaderBytes),
+		MaxUserMetadataSize: int(maxUserMetadataSize),
+
+		AdminAPIKey: os.Getenv("ADMIN_API_KEY"),
+
+		EnablePprof: enablePprof,
+		PprofAddr:   pprofAddr,
+
+		MaxRequestTimeout: maxRequestTimeout,
+
+		PresignClockSkew: presignClockSkew,
+		MaxPresignTTL:    maxPresignTTL,
+		PresignAlgorithm: presignAlgorithm,
+
+		BucketNameCase: bucketNameCase,
+
+		DefaultBucket:     defaultBucket,
+		AutoCreateBuckets: autoCreateBuckets,
+
+		SoftDelete:     softDelete,
+		TrashRetention: trashRetention,
+
+		WebhookURL:     os.Getenv("WEBHOOK_URL"),
+		BucketWebhooks: bucketWebhooks,
+
+		OriginURL:   originURL,
+		OriginCache: originCache,
+
+		AuditLogPath:    os.Getenv("AUDIT_LOG_PATH"),
+		AuditLogMaxSize: auditLogMaxSize,
+
+		TempFileMaxAge: tempFileMaxAge,
+		UploadExpiry:   uploadExpiry,
+
+		IdempotencyTTL: idempotencyTTL,
+
+		CreateImmutabilityWindow: time.Duration(createImmutabilitySeconds) * time.Second,
+
+		MaxObjectsPerBucket: maxObjectsPerBucket,
+
+		MaxKeyLength:   int(maxKeyLength),
+		MaxKeySegments: int(maxKeySegments),
+
+		KeyCharacterPolicy: keyCharacterPolicy,
+		KeyWhitespace:      keyWhitespace,
+		BlockedKeys:        blockedKeys,
+
+		MimeTypes: mimeTypes,
+
+		SmallObjectThreshold: smallObjectThreshold,
+
+		MaxListKeys:          int(maxListKeys),
+		MaxBatchMetadataKeys: int(maxBatchMetadataKeys),
+
+		NormalizeKeys: normalizeKeys,
+
+		UploadKeyStrategy:  uploadKeyStrategy,
+		UploadKeyPrefix:    os.Getenv("UPLOAD_KEY_PREFIX"),
+		UploadKeyExtension: uploadKeyExtension,
+
+		DirMode:  dirMode,
+		FileMode: fileMode,
+
+		DurableWrites:    durableWrites,
+		ContentAddressed: contentAddressed,
+		StorageMetrics:   storageMetrics,
+
+		ETagHistoryLimit: int(etagHistoryLimit),
+		ShardKeys:        shardKeys,
+
+		CacheMaxObjectSize: cacheMaxObjectSize,
+		CacheMaxBytes:      cacheMaxBytes,
+		CacheTTL:           cacheTTL,
+
+		CoalesceMaxObjectSize: coalesceMaxObjectSize,
+
+		GzipResponses: gzipResponses,
+		GzipMinSize:   gzipMinSize,
+
+		ForceAttachment: forceAttachment,
+
+		ImageTransforms:            imageTransforms,
+		ImageTransformMaxDimension: int(imageTransformMaxDimension),
+		ImageTransformCacheBytes:   imageTransformCacheBytes,
+
+		MaxConcurrentWrites: int(maxConcurrentWrites),
+		MaxConcurrentReads:  int(maxConcurrentReads),
+		MaxConcurrentPerIP:  int(maxConcurrentPerIP),
+		TrustedProxies:      trustedProxies,
+		TrustRequestID:      trustRequestID,
+		Tracing:             tracing,
+
+		CorsMaxAge: corsMaxAge,
+
+		ResponseHeaders:      responseHeaders,
+		StripResponseHeaders: stripResponseHeaders,
+
+		RedirectStatus: int(redirectStatus),
+
+		ArchiveClasses: archiveClasses,
+
+		TimestampFormat: timestampFormat,
+
+		EnvelopeResponses: envelopeResponses,
+
+		LogLevel: logLevel,
 	}, nil
 }
 
@@ -47,3 +753,255 @@ func getEnvDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvDuration parses a Go duration string (e.g. "30s", "5m") from the
+// environment, falling back to defaultValue when unset.
+func getEnvDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s must be a valid non-negative duration (e.g. 30s, 5m)", key)
+	}
+	return d, nil
+}
+
+// getEnvInt parses a non-negative integer from the environment, falling back
+// to defaultValue when unset.
+func getEnvInt(key string, defaultValue int64) (int64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", key)
+	}
+	return n, nil
+}
+
+// getEnvBool parses a boolean ("true", "false", "1", "0", ...) from the
+// environment, falling back to defaultValue when unset.
+func getEnvBool(key string, defaultValue bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean (true or false)", key)
+	}
+	return b, nil
+}
+
+// getEnvFileMode parses an octal permission string (e.g. "0755") from the
+// environment, falling back to defaultValue when unset.
+func getEnvFileMode(key string, defaultValue os.FileMode) (os.FileMode, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || n == 0 || n > 0777 {
+		return 0, fmt.Errorf("%s must be an octal permission between 0001 and 0777 (e.g. 0755)", key)
+	}
+	return os.FileMode(n), nil
+}
+
+// parseBucketMap parses a comma separated list of bucket=value pairs
+func parseBucketMap(key string) (map[string]string, error) {
+	result := map[string]string{}
+	value := os.Getenv(key)
+	if value == "" {
+		return result, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		bucket, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || bucket == "" || v == "" {
+			return nil, fmt.Errorf("%s must be a comma separated list of bucket=value pairs", key)
+		}
+		result[bucket] = v
+	}
+	return result, nil
+}
+
+// parseMimeTypes parses a comma separated list of .ext=type/subtype pairs.
+// Extensions are lower-cased and get a leading dot if it was left out.
+func parseMimeTypes(key string) (map[string]string, error) {
+	result := map[string]string{}
+	value := os.Getenv(key)
+	if value == "" {
+		return result, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		ext, contentType, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if !ok || strings.Trim(ext, ".") == "" {
+			return nil, fmt.Errorf("%s must be a comma separated list of .ext=type/subtype pairs", key)
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		contentType = strings.TrimSpace(contentType)
+		if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || !strings.Contains(mediaType, "/") {
+			return nil, fmt.Errorf("%s has an invalid content type for %s: %q", key, ext, contentType)
+		}
+		result[ext] = contentType
+	}
+	return result, nil
+}
+
+// RegisterMimeTypes adds MimeTypes to the process-wide MIME table consulted
+// for uploads without a Content-Type.
+func (c *Config) RegisterMimeTypes() error {
+	for ext, contentType := range c.MimeTypes {
+		if err := mime.AddExtensionType(ext, contentType); err != nil {
+			return fmt.Errorf("failed to register MIME type for %s: %w", ext, err)
+		}
+	}
+	return nil
+}
+
+// parseKeyPatterns parses a comma separated list of path.Match glob
+// patterns, rejecting malformed ones so they can't silently match nothing.
+func parseKeyPatterns(key string) ([]string, error) {
+	var result []string
+	for _, pattern := range strings.Split(os.Getenv(key), ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s has an invalid pattern %q: %w", key, pattern, err)
+		}
+		result = append(result, pattern)
+	}
+	return result, nil
+}
+
+// getEnvList parses a comma separated list of upper-cased names. An unset
+// variable gives defaultValue; an empty one gives an empty list.
+func getEnvList(key string, defaultValue []string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue
+	}
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToUpper(strings.TrimSpace(item)); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// parsePrefixList parses a comma separated list of IP addresses and CIDR
+// ranges (e.g. "10.0.0.0/8,192.168.1.5")
+func parsePrefixList(key string) ([]netip.Prefix, error) {
+	var result []netip.Prefix
+	value := os.Getenv(key)
+	if value == "" {
+		return result, nil
+	}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if prefix, err := netip.ParsePrefix(item); err == nil {
+			result = append(result, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a comma separated list of IP addresses or CIDR ranges", key)
+		}
+		result = append(result, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return result, nil
+}
+
+// parseResponseHeaders collects the variables named prefix + NAME into
+// header name/value pairs. NAME is the header name with "-" written as "_",
+// so RESPONSE_HEADER_X_FRAME_OPTIONS=DENY sends X-Frame-Options: DENY. Empty
+// variables are ignored.
+func parseResponseHeaders(prefix string) (map[string]string, error) {
+	result := map[string]string{}
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		suffix, ok := strings.CutPrefix(name, prefix)
+		if !ok || value == "" {
+			continue
+		}
+		header := textproto.CanonicalMIMEHeaderKey(strings.ReplaceAll(suffix, "_", "-"))
+		if !isHeaderName(header) || strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("%s must name a header and hold a single-line value", name)
+		}
+		result[header] = value
+	}
+	return result, nil
+}
+
+// parseHeaderList parses a comma separated list of header names into their
+// canonical form.
+func parseHeaderList(key string) ([]string, error) {
+	var result []string
+	value := os.Getenv(key)
+	if value == "" {
+		return result, nil
+	}
+	for _, item := range strings.Split(value, ",") {
+		header := textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(item))
+		if !isHeaderName(header) {
+			return nil, fmt.Errorf("%s must be a comma separated list of header names", key)
+		}
+		result = append(result, header)
+	}
+	return result, nil
+}
+
+// isHeaderName reports whether s is a valid HTTP header name
+func isHeaderName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c > '~' || c <= ' ' || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", c) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseLogLevel(value string) (slog.Level, error) {
+	switch strings.ToLower(value) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("LOG_LEVEL must be one of debug, info, warn, error")
+}
+
+// isLoopbackAddr reports whether a host:port listen address only accepts
+// local connections. An empty host listens on every interface.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip, err := netip.ParseAddr(host)
+	return err == nil && ip.IsLoopback()
+}
+
+// isHTTPURL reports whether s is an absolute http or https URL
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// loadConfig runs New with the required credentials and env, given as
+// NAME=value pairs
+func loadConfig(t *testing.T, env ...string) (*Config, error) {
+	t.Helper()
+	t.Setenv("ACCESS_KEY_ID", "test-access-key")
+	t.Setenv("SECRET_ACCESS_KEY", "test-secret-key")
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		t.Setenv(name, value)
+	}
+	return New()
+}
+
+func TestKeyLimitsMustBePositive(t *testing.T) {
+	for _, env := range []string{"MAX_KEY_LENGTH=0", "MAX_KEY_SEGMENTS=0"} {
+		if _, err := loadConfig(t, env); err == nil {
+			t.Errorf("%s accepted", env)
+		}
+	}
+
+	cfg, err := loadConfig(t, "MAX_KEY_LENGTH=1", "MAX_KEY_SEGMENTS=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxKeyLength != 1 || cfg.MaxKeySegments != 1 {
+		t.Fatalf("limits = %d, %d, want 1, 1", cfg.MaxKeyLength, cfg.MaxKeySegments)
+	}
+}
+
+func TestDefaultModes(t *testing.T) {
+	cfg, err := loadConfig(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DirMode != 0755 || cfg.FileMode != 0600 {
+		t.Fatalf("modes = %o, %o, want 755, 600", cfg.DirMode, cfg.FileMode)
+	}
+}
+
+func TestNormalizeKeysNeedsNonASCIIPolicy(t *testing.T) {
+	if _, err := loadConfig(t, "NORMALIZE_KEYS=true"); err == nil {
+		t.Error("NORMALIZE_KEYS accepted with the strict key policy")
+	}
+	for _, policy := range []string{"relaxed", "permissive"} {
+		if _, err := loadConfig(t, "NORMALIZE_KEYS=true", "KEY_CHARACTER_POLICY="+policy); err != nil {
+			t.Errorf("NORMALIZE_KEYS with %s: %v", policy, err)
+		}
+	}
+}
+
+func TestKeyCharacterPolicy(t *testing.T) {
+	cfg, err := loadConfig(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.KeyCharacterPolicy != "strict" {
+		t.Errorf("default policy = %q, want strict", cfg.KeyCharacterPolicy)
+	}
+
+	cfg, err = loadConfig(t, "KEY_CHARACTER_POLICY=Relaxed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.KeyCharacterPolicy != "relaxed" {
+		t.Errorf("policy = %q, want relaxed", cfg.KeyCharacterPolicy)
+	}
+
+	if _, err := loadConfig(t, "KEY_CHARACTER_POLICY=anything"); err == nil {
+		t.Error("unknown policy accepted")
+	}
+}
+
+func TestMimeTypes(t *testing.T) {
+	cfg, err := loadConfig(t, "MIME_TYPES= .WEBP=image/webp , avif=image/avif,.ts=text/typescript; charset=utf-8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{".webp": "image/webp", ".avif": "image/avif", ".ts": "text/typescript; charset=utf-8"}
+	if len(cfg.MimeTypes) != len(want) {
+		t.Errorf("MimeTypes = %v, want %v", cfg.MimeTypes, want)
+	}
+	for ext, contentType := range want {
+		if cfg.MimeTypes[ext] != contentType {
+			t.Errorf("MimeTypes[%s] = %q, want %q", ext, cfg.MimeTypes[ext], contentType)
+		}
+	}
+
+	for _, value := range []string{".webp", "=image/webp", ".=image/webp", ".webp=", ".webp=webp", ".webp=image/webp;;"} {
+		if _, err := loadConfig(t, "MIME_TYPES="+value); err == nil {
+			t.Errorf("MIME_TYPES=%s accepted", value)
+		}
+	}
+}
+
+func TestRequestIDUntrustedByDefault(t *testing.T) {
+	cfg, err := loadConfig(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TrustRequestID {
+		t.Error("TRUST_REQUEST_ID defaults to true")
+	}
+}
+
+func TestBlockedKeys(t *testing.T) {
+	cfg, err := loadConfig(t, "BLOCKED_KEYS= .env, *.pem ,,id_rsa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(cfg.BlockedKeys, "|") != ".env|*.pem|id_rsa" {
+		t.Errorf("BlockedKeys = %q", cfg.BlockedKeys)
+	}
+
+	if _, err := loadConfig(t, "BLOCKED_KEYS=*.pem,[a-"); err == nil {
+		t.Error("malformed pattern accepted")
+	}
+}
+
+func TestBucketNameCase(t *testing.T) {
+	cfg, err := loadConfig(t, "DEFAULT_BUCKET=Photos", "BUCKET_WEBHOOKS=Logs=http://a/hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BucketNameCase != "strict" || cfg.DefaultBucket != "Photos" || cfg.BucketWebhooks["Logs"] == "" {
+		t.Errorf("strict: case = %q, default bucket = %q, webhooks = %v", cfg.BucketNameCase, cfg.DefaultBucket, cfg.BucketWebhooks)
+	}
+
+	cfg, err = loadConfig(t, "BUCKET_NAME_CASE=lowercase", "DEFAULT_BUCKET=Photos", "BUCKET_WEBHOOKS=Logs=http://a/hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DefaultBucket != "photos" || cfg.BucketWebhooks["logs"] != "http://a/hook" {
+		t.Errorf("lowercase: default bucket = %q, webhooks = %v, want lower-cased names", cfg.DefaultBucket, cfg.BucketWebhooks)
+	}
+
+	if _, err := loadConfig(t, "BUCKET_NAME_CASE=upper"); err == nil {
+		t.Error("BUCKET_NAME_CASE=upper accepted")
+	}
+}
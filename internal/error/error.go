@@ -1,18 +1,29 @@
 package gosssError
 
 import (
-	"encoding/json"
 	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
+
+	"github.com/mmvergara/gosss/internal/response"
 )
 
 type ErrorResponse struct {
-	Code      string `json:"code"`
-	Message   string `json:"message"`
-	Resource  string `json:"resource"`
-	TimeStamp string `json:"timestamp"`
+	Code      string        `json:"code"`
+	Message   string        `json:"message"`
+	Resource  string        `json:"resource"`
+	TimeStamp time.Time     `json:"timestamp"`
+	Details   *ErrorDetails `json:"details,omitempty"`
+}
+
+// ErrorDetails names the input a request was rejected for and the rule it
+// broke, e.g. field "bucket" and rule "bucket_name_length", so clients can
+// act on validation failures without parsing the message.
+type ErrorDetails struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
 }
 
 type ErrorLogger struct {
@@ -24,18 +35,37 @@ func NewErrorLogger(l *log.Logger) *ErrorLogger {
 }
 
 func SendGossError(w http.ResponseWriter, code uint, message, resource string) {
+	SendGossErrorDetails(w, code, message, resource, nil)
+}
+
+// SendGossErrorDetails is SendGossError with structured details, which are
+// left out of the response when nil.
+func SendGossErrorDetails(w http.ResponseWriter, code uint, message, resource string, details *ErrorDetails) {
+	sendGossError(w, code, strconv.Itoa(int(code)), message, resource, details)
+}
+
+// SendGossErrorCode is SendGossError with a named error code, e.g.
+// NoSuchKey, in place of the status. HEAD responses have no body, so the
+// code is also sent in the X-Error-Code header.
+func SendGossErrorCode(w http.ResponseWriter, status uint, code, message, resource string) {
+	w.Header().Set("X-Error-Code", code)
+	sendGossError(w, status, code, message, resource, nil)
+}
+
+func sendGossError(w http.ResponseWriter, status uint, code, message, resource string, details *ErrorDetails) {
 	errorResponse := ErrorResponse{
-		Code:      strconv.Itoa(int(code)),
+		Code:      code,
 		Message:   message,
 		Resource:  resource,
-		TimeStamp: time.Now().UTC().Format(time.RFC3339),
+		TimeStamp: time.Now().UTC(),
+		Details:   details,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(int(code))
-	log.Printf("Sending error response: %+v", errorResponse)
-	if err := json.NewEncoder(w).Encode(errorResponse); err != nil {
-		log.Printf("Failed to generate error response: %v", err)
+	w.WriteHeader(int(status))
+	slog.Debug("Sending error response", "code", errorResponse.Code, "message", errorResponse.Message, "resource", errorResponse.Resource)
+	if err := response.EncodeError(w, errorResponse); err != nil {
+		slog.Error("Failed to generate error response", "error", err)
 		http.Error(w, "Failed to generate error response", http.StatusInternalServerError)
 		return
 	}
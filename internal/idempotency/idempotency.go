@@ -0,0 +1,101 @@
+package idempotency
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+// Record is what the store remembers about a request made under an
+// Idempotency-Key.
+type Record struct {
+	Bucket   string
+	Key      string
+	BodyHash string // MD5 of the raw request body
+	Metadata *model.ObjectMetadata
+
+	done    bool
+	expires time.Time
+}
+
+// Store is an in-memory idempotency key store. Completed records are kept for
+// ttl so retries within that window can be answered from the original result.
+type Store struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	records map[string]*Record
+}
+
+// New creates a Store and starts a janitor that drops expired records. A nil
+// Store is returned when ttl is zero, which disables idempotency handling.
+func New(ttl time.Duration) *Store {
+	if ttl == 0 {
+		return nil
+	}
+
+	s := &Store{
+		ttl:     ttl,
+		records: make(map[string]*Record),
+	}
+	go s.janitor()
+	return s
+}
+
+// Begin claims idemKey for a new request. If the key is already known the
+// existing record is returned with ok set to false; the record has no
+// Metadata while the original request is still in flight.
+func (s *Store) Begin(idemKey, bucket, key string) (rec Record, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, found := s.records[idemKey]; found && time.Now().Before(existing.expires) {
+		return *existing, false
+	}
+
+	s.records[idemKey] = &Record{
+		Bucket: bucket,
+		Key:    key,
+		// In-flight records expire too, in case the request never completes
+		expires: time.Now().Add(s.ttl),
+	}
+	return Record{}, true
+}
+
+// Complete stores the outcome of a request begun with Begin.
+func (s *Store) Complete(idemKey, bodyHash string, metadata *model.ObjectMetadata) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec, found := s.records[idemKey]; found {
+		rec.BodyHash = bodyHash
+		rec.Metadata = metadata
+		rec.done = true
+		rec.expires = time.Now().Add(s.ttl)
+	}
+}
+
+// Abort releases idemKey after a failed request so it can be retried.
+func (s *Store) Abort(idemKey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec, found := s.records[idemKey]; found && !rec.done {
+		delete(s.records, idemKey)
+	}
+}
+
+func (s *Store) janitor() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		s.mu.Lock()
+		for k, rec := range s.records {
+			if now.After(rec.expires) {
+				delete(s.records, k)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/mmvergara/gosss/internal/config"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+)
+
+// AdminPrincipal is what AccessKeyID reports for requests authenticated with
+// the admin API key, e.g. in the audit log.
+const AdminPrincipal = "admin"
+
+// CreateAdminAuthMiddleware guards the /admin routes with ADMIN_API_KEY,
+// sent as "Authorization: Bearer <key>". It is separate from the data-plane
+// credentials: the access key and secret are not accepted here, and the
+// admin key is not accepted by CreateAuthMiddleware. Without ADMIN_API_KEY
+// every admin request is refused.
+func CreateAdminAuthMiddleware(config *config.Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if config.AdminAPIKey == "" {
+				gosssError.SendGossError(w, http.StatusForbidden, "Admin API is disabled, set ADMIN_API_KEY to enable it", "")
+				return
+			}
+
+			key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok {
+				slog.Warn("Admin API key required", "path", r.URL.Path)
+				gosssError.SendGossError(w, http.StatusUnauthorized, "Admin API key required", "")
+				return
+			}
+			if subtle.ConstantTimeCompare([]byte(key), []byte(config.AdminAPIKey)) != 1 {
+				slog.Warn("Invalid admin API key", "path", r.URL.Path)
+				gosssError.SendGossError(w, http.StatusUnauthorized, "Invalid admin API key", "")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), accessKeyIDKey{}, AdminPrincipal)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mmvergara/gosss/internal/audit"
+)
+
+// CreateAuditMiddleware records every mutating request (PUT, POST, DELETE)
+// along with the authenticated principal and the response status. It must be
+// installed after the auth middleware.
+func CreateAuditMiddleware(auditLog *audit.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if auditLog == nil || !isMutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			lrw := &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(lrw, r)
+
+			auditLog.Record(audit.Entry{
+				Time:        time.Now().UTC(),
+				AccessKeyID: AccessKeyID(r.Context()),
+				Method:      r.Method,
+				Bucket:      chi.URLParam(r, "bucket"),
+				Key:         chi.URLParam(r, "*"),
+				Status:      lrw.statusCode,
+				RemoteAddr:  r.RemoteAddr,
+			})
+		})
+	}
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPut, http.MethodPost, http.MethodDelete:
+		return true
+	}
+	return false
+}
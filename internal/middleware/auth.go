@@ -1,7 +1,8 @@
 package middleware
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
 	"strings"
 
@@ -9,6 +10,15 @@ import (
 	gosssError "github.com/mmvergara/gosss/internal/error"
 )
 
+type accessKeyIDKey struct{}
+
+// AccessKeyID returns the access key ID of the authenticated principal, or ""
+// if the request was not authenticated.
+func AccessKeyID(ctx context.Context) string {
+	id, _ := ctx.Value(accessKeyIDKey{}).(string)
+	return id
+}
+
 // createAuthMiddleware takes a config and returns a middleware function.
 func CreateAuthMiddleware(config *config.Config) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -22,7 +32,7 @@ func CreateAuthMiddleware(config *config.Config) func(http.Handler) http.Handler
 
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				log.Println("Authorization header required")
+				slog.Warn("Authorization header required", "path", r.URL.Path)
 				gosssError.SendGossError(w, http.StatusUnauthorized, "Authorization header required", "")
 				return
 			}
@@ -30,26 +40,27 @@ func CreateAuthMiddleware(config *config.Config) func(http.Handler) http.Handler
 			// Extract credentials from authorization header
 			parts := strings.Split(authHeader, "=")
 			if len(parts) < 2 {
-				log.Println("Invalid authorization header format")
+				slog.Warn("Invalid authorization header format", "path", r.URL.Path)
 				gosssError.SendGossError(w, http.StatusUnauthorized, "Invalid authorization header format", "")
 				return
 			}
 
 			accessKeyID := parts[0]
 			if accessKeyID != config.AccessKeyID {
-				log.Println("Invalid access key ID")
+				slog.Warn("Invalid access key ID", "path", r.URL.Path)
 				gosssError.SendGossError(w, http.StatusUnauthorized, "Invalid access key ID", "")
 				return
 			}
 
 			secretAccessKey := parts[1]
 			if secretAccessKey != config.SecretKey {
-				log.Println("Invalid secret access key")
+				slog.Warn("Invalid secret access key", "path", r.URL.Path)
 				gosssError.SendGossError(w, http.StatusUnauthorized, "Invalid secret access key", "")
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			ctx := context.WithValue(r.Context(), accessKeyIDKey{}, accessKeyID)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// LowercaseBuckets rewrites the bucket route parameter to lower case
+// (BUCKET_NAME_CASE=lowercase), so "Photos" and "photos" name the same
+// bucket instead of the former being rejected.
+func LowercaseBuckets(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			for i, k := range rctx.URLParams.Keys {
+				if k == "bucket" {
+					rctx.URLParams.Values[i] = strings.ToLower(rctx.URLParams.Values[i])
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/mmvergara/gosss/internal/config"
+	gosssError "github.com/mmvergara/gosss/internal/error"
+)
+
+// CreateIPConcurrencyMiddleware caps the number of requests a single client
+// IP may have in flight at once, so one client can't take every upload slot.
+// Requests beyond the limit are rejected with 429. A limit of zero disables
+// the middleware.
+func CreateIPConcurrencyMiddleware(cfg *config.Config) func(http.Handler) http.Handler {
+	var (
+		mu     sync.Mutex
+		active = make(map[string]int)
+	)
+
+	return func(next http.Handler) http.Handler {
+		if cfg.MaxConcurrentPerIP == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := ClientIP(r, cfg.TrustedProxies)
+
+			mu.Lock()
+			if active[ip] >= cfg.MaxConcurrentPerIP {
+				mu.Unlock()
+				slog.Warn("Too many concurrent requests from client", "ip", ip)
+				gosssError.SendGossError(w, http.StatusTooManyRequests, "Too many concurrent requests from this client", "")
+				return
+			}
+			active[ip]++
+			mu.Unlock()
+
+			defer func() {
+				mu.Lock()
+				if active[ip]--; active[ip] == 0 {
+					delete(active, ip)
+				}
+				mu.Unlock()
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/mmvergara/gosss/internal/config"
+)
+
+func TestIPConcurrencyLimit(t *testing.T) {
+	const limit = 3
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := CreateIPConcurrencyMiddleware(&config.Config{MaxConcurrentPerIP: limit})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hold" {
+			started <- struct{}{}
+			<-release
+		}
+	}))
+	serveFrom := func(ip, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	var held sync.WaitGroup
+	for i := 0; i < limit; i++ {
+		held.Add(1)
+		go func() {
+			defer held.Done()
+			if rec := serveFrom("192.0.2.1", "/hold"); rec.Code != http.StatusOK {
+				t.Errorf("held request: status %d", rec.Code)
+			}
+		}()
+		<-started
+	}
+
+	if rec := serveFrom("192.0.2.1", "/"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("request %d from a busy client: status %d, want %d", limit+1, rec.Code, http.StatusTooManyRequests)
+	}
+	if rec := serveFrom("192.0.2.2", "/"); rec.Code != http.StatusOK {
+		t.Errorf("request from another client: status %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	// Finished requests free their slots
+	close(release)
+	held.Wait()
+	if rec := serveFrom("192.0.2.1", "/"); rec.Code != http.StatusOK {
+		t.Errorf("request after the held ones finished: status %d, want %d", rec.Code, http.StatusOK)
+	}
+}
@@ -1,20 +1,99 @@
 package middleware
 
-import "net/http"
-
-func CorsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		// If it's a preflight request, return 200 status code
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mmvergara/gosss/internal/config"
+)
+
+// corsAllowedHeaders are the request headers the API reads. User metadata
+// headers (X-Amz-Meta-*) can't be listed ahead of time, so those a
+// preflight asks for are added to the list.
+var corsAllowedHeaders = strings.Join([]string{
+	"Authorization",
+	"Content-Type",
+	"Content-Range",
+	"Range",
+	"Cache-Control",
+	"If-Match",
+	"If-None-Match",
+	"If-Modified-Since",
+	"If-Unmodified-Since",
+	"If-Range",
+	"X-Storage-Class",
+	"X-Amz-Storage-Class",
+	"X-Redirect-Location",
+	"X-Amz-Website-Redirect-Location",
+	"X-Copy-Source",
+	"X-Metadata-Directive",
+	"Idempotency-Key",
+	"X-Timeout-Seconds",
+	"X-Request-ID",
+}, ", ")
+
+// corsExposedHeaders are the response headers scripts may read besides the
+// CORS-safelisted ones (Content-Type, Last-Modified, ...)
+var corsExposedHeaders = strings.Join([]string{
+	"ETag",
+	"Content-Range",
+	"Content-Disposition",
+	"Content-Encoding",
+	"Accept-Ranges",
+	"Location",
+	"Retry-After",
+	"X-Request-ID",
+	"X-Storage-Class",
+	"X-Restore-Status",
+	"X-Restore-Expiry-Date",
+	"X-Bucket-Object-Count",
+	"X-Bucket-Size-Bytes",
+	"X-Bucket-Quota-Bytes",
+	"X-Bucket-Max-Object-Size",
+	"X-Bucket-Created-At",
+	"X-Archived-Objects-Skipped",
+}, ", ")
+
+// CreateCorsMiddleware adds the CORS headers to every response. Preflight
+// requests (OPTIONS with Access-Control-Request-Method) are answered here
+// with 204 and never reach authentication or the handlers; plain OPTIONS
+// requests still go to the OPTIONS routes for method discovery.
+// Access-Control-Max-Age lets browsers cache a preflight for CORS_MAX_AGE.
+func CreateCorsMiddleware(cfg *config.Config) func(http.Handler) http.Handler {
+	maxAge := strconv.Itoa(int(cfg.CorsMaxAge.Seconds()))
 
-		// Continue with the next handler
-		next.ServeHTTP(w, r)
-	})
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", allowedHeaders(r))
+			w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				// The allowed headers depend on the ones asked for
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+				if cfg.CorsMaxAge > 0 {
+					w.Header().Set("Access-Control-Max-Age", maxAge)
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// allowedHeaders returns corsAllowedHeaders plus the user metadata headers
+// a preflight requests.
+func allowedHeaders(r *http.Request) string {
+	allowed := corsAllowedHeaders
+	for _, name := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		name = strings.TrimSpace(name)
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-meta-") && lower != "x-amz-meta-" {
+			allowed += ", " + name
+		}
+	}
+	return allowed
 }
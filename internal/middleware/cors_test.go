@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mmvergara/gosss/internal/config"
+)
+
+func headerList(h http.Header, name string) map[string]bool {
+	list := map[string]bool{}
+	for _, v := range strings.Split(h.Get(name), ",") {
+		list[strings.ToLower(strings.TrimSpace(v))] = true
+	}
+	return list
+}
+
+func TestCorsPreflightAllowsAPIHeaders(t *testing.T) {
+	handler := CreateCorsMiddleware(&config.Config{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("preflight reached the handler")
+	}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/bucket/key", nil)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+	req.Header.Set("Access-Control-Request-Headers", "content-type,x-amz-meta-author, X-Amz-Meta-Project,x-unknown")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204", rec.Code)
+	}
+	allowed := headerList(rec.Header(), "Access-Control-Allow-Headers")
+	for _, name := range []string{
+		"Authorization", "Content-Type", "X-Storage-Class", "X-Redirect-Location", "Content-Range",
+		"X-Copy-Source", "X-Metadata-Directive", "Idempotency-Key", "X-Timeout-Seconds", "X-Request-ID",
+		"x-amz-meta-author", "X-Amz-Meta-Project",
+	} {
+		if !allowed[strings.ToLower(name)] {
+			t.Errorf("Access-Control-Allow-Headers lacks %s: %s", name, rec.Header().Get("Access-Control-Allow-Headers"))
+		}
+	}
+	if allowed["x-unknown"] {
+		t.Error("unrelated requested header allowed")
+	}
+}
+
+func TestCorsExposesResponseHeaders(t *testing.T) {
+	handler := CreateCorsMiddleware(&config.Config{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bucket/key", nil))
+
+	exposed := headerList(rec.Header(), "Access-Control-Expose-Headers")
+	for _, name := range []string{"ETag", "X-Request-ID", "X-Bucket-Object-Count", "X-Bucket-Size-Bytes", "X-Bucket-Quota-Bytes", "X-Bucket-Max-Object-Size", "X-Bucket-Created-At"} {
+		if !exposed[strings.ToLower(name)] {
+			t.Errorf("Access-Control-Expose-Headers lacks %s", name)
+		}
+	}
+}
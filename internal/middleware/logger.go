@@ -1,7 +1,7 @@
 package middleware
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 )
@@ -19,7 +19,7 @@ func LoggerMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(lrw, r)
 
 		// Log the method, URL, status code, and response time
-		log.Printf("%s %s %d %s", r.Method, r.URL.Path, lrw.statusCode, time.Since(start))
+		slog.Info("request", "method", r.Method, "path", r.URL.Path, "status", lrw.statusCode, "duration", time.Since(start), "requestId", RequestID(r.Context()))
 	})
 }
 
@@ -33,3 +33,9 @@ func (lrw *loggingResponseWriter) WriteHeader(statusCode int) {
 	lrw.statusCode = statusCode
 	lrw.ResponseWriter.WriteHeader(statusCode)
 }
+
+// Unwrap lets http.ResponseController and response.Encode reach the
+// underlying writer
+func (lrw *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return lrw.ResponseWriter
+}
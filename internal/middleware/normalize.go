@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeKeys rewrites the object key route parameter, along with the
+// prefix and start-after listing parameters, to Unicode NFC. Keys that only
+// differ in normalization form (e.g. NFD names from macOS clients) then refer
+// to the same object.
+func NormalizeKeys(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			for i, k := range rctx.URLParams.Keys {
+				if k == "*" {
+					rctx.URLParams.Values[i] = norm.NFC.String(rctx.URLParams.Values[i])
+				}
+			}
+		}
+
+		query := r.URL.Query()
+		normalized := false
+		for _, param := range []string{"prefix", "start-after"} {
+			if v := query.Get(param); v != "" && !norm.NFC.IsNormalString(v) {
+				query.Set(param, norm.NFC.String(v))
+				normalized = true
+			}
+		}
+		if normalized {
+			r.URL.RawQuery = query.Encode()
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestNormalizeKeys(t *testing.T) {
+	const nfc, nfd = "caf\u00e9.txt", "cafe\u0301.txt"
+
+	var gotKey, gotPrefix string
+	r := chi.NewRouter()
+	r.With(NormalizeKeys).Get("/{bucket}/*", func(w http.ResponseWriter, r *http.Request) {
+		gotKey = chi.URLParam(r, "*")
+		gotPrefix = r.URL.Query().Get("prefix")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/docs/"+url.PathEscape(nfd)+"?prefix="+url.QueryEscape(nfd), nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotKey != nfc {
+		t.Errorf("key = %q, want %q", gotKey, nfc)
+	}
+	if gotPrefix != nfc {
+		t.Errorf("prefix = %q, want %q", gotPrefix, nfc)
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/config"
+)
+
+// MaxRequestIDLength bounds the X-Request-ID values taken from clients
+const MaxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// CreateRequestIDMiddleware gives every request an ID, stored in its context
+// for RequestID and echoed in the X-Request-ID response header. With
+// TRUST_REQUEST_ID, which is off by default since clients could otherwise
+// pick the IDs in the logs, the ID a gateway sent in X-Request-ID is kept so
+// gosss logs line up with upstream traces; a new one is generated when the
+// header is absent or malformed.
+func CreateRequestIDMiddleware(cfg *config.Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get("X-Request-ID")
+			if !cfg.TrustRequestID || !isValidRequestID(id) {
+				if id != "" && cfg.TrustRequestID {
+					slog.Debug("Ignoring malformed X-Request-ID", "length", len(id))
+				}
+				id = newRequestID()
+			}
+
+			w.Header().Set("X-Request-ID", id)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+		})
+	}
+}
+
+// RequestID returns the ID CreateRequestIDMiddleware gave the request, or ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// isValidRequestID accepts IDs of up to MaxRequestIDLength letters, digits
+// and the punctuation of common trace ID formats, so nothing a client sends
+// can break log lines or response headers.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > MaxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':' || c == '=' || c == '+' || c == '/' || c == '@':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// randRead fills request IDs; tests replace it to simulate failures
+var randRead = rand.Read
+
+// requestIDSeq numbers the request IDs generated without crypto/rand
+var requestIDSeq atomic.Uint64
+
+func newRequestID() string {
+	var b [16]byte
+	if _, err := randRead(b[:]); err != nil {
+		// An ID only has to be unique, so a failing entropy source is no
+		// reason to fail the request
+		slog.Warn("Failed to generate random request ID", "error", err)
+		binary.BigEndian.PutUint64(b[:8], uint64(time.Now().UnixNano()))
+		binary.BigEndian.PutUint64(b[8:], requestIDSeq.Add(1))
+	}
+	return hex.EncodeToString(b[:])
+}
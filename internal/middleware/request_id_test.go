@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mmvergara/gosss/internal/config"
+)
+
+// requestIDOf serves a request through the middleware and returns the ID
+// the handler saw, failing if the response header doesn't match it
+func requestIDOf(t *testing.T, cfg *config.Config, sent string) string {
+	t.Helper()
+	var seen string
+	handler := CreateRequestIDMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/bucket/key", nil)
+	if sent != "" {
+		req.Header.Set("X-Request-ID", sent)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := rec.Header().Get("X-Request-ID"); got != seen {
+		t.Errorf("X-Request-ID = %q, but the handler saw %q", got, seen)
+	}
+	return seen
+}
+
+func TestRequestIDTrust(t *testing.T) {
+	if id := requestIDOf(t, &config.Config{}, "gateway-123"); id == "gateway-123" || len(id) != 32 {
+		t.Errorf("untrusted X-Request-ID kept as %q", id)
+	}
+
+	trusted := &config.Config{TrustRequestID: true}
+	if id := requestIDOf(t, trusted, "gateway-123"); id != "gateway-123" {
+		t.Errorf("trusted X-Request-ID replaced by %q", id)
+	}
+	if id := requestIDOf(t, trusted, "bad id\r\n"); id == "bad id\r\n" || len(id) != 32 {
+		t.Errorf("malformed X-Request-ID kept as %q", id)
+	}
+}
+
+// Request IDs don't depend on crypto/rand working
+func TestRequestIDWithoutRandomness(t *testing.T) {
+	randRead = func([]byte) (int, error) { return 0, errors.New("no entropy") }
+	t.Cleanup(func() { randRead = rand.Read })
+
+	first := requestIDOf(t, &config.Config{}, "")
+	second := requestIDOf(t, &config.Config{}, "")
+	if !isValidRequestID(first) || !isValidRequestID(second) || first == second {
+		t.Errorf("fallback IDs %q and %q, want two distinct valid IDs", first, second)
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	gosssError "github.com/mmvergara/gosss/internal/error"
+	"github.com/mmvergara/gosss/internal/response"
+)
+
+// ResponseFormat applies ?pretty and ?envelope to every JSON body, including
+// the errors of the middlewares after it and of unknown routes. A request
+// with an ?envelope that isn't a boolean gets 400.
+func ResponseFormat(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fw, err := response.Format(w, r)
+		if err != nil {
+			gosssError.SendGossError(w, http.StatusBadRequest, err.Error(), r.URL.Path)
+			return
+		}
+		next.ServeHTTP(fw, r)
+	})
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/mmvergara/gosss/internal/config"
+)
+
+// CreateResponseHeadersMiddleware applies RESPONSE_HEADER_* and
+// STRIP_RESPONSE_HEADERS to every response, errors and preflights included,
+// so it must be installed before anything that answers requests itself.
+// Configured headers are set before the request is handled, so a handler
+// setting the same header, e.g. an object's Cache-Control, takes precedence.
+// Stripped headers are removed as the response is written, after everyone
+// has had their say.
+func CreateResponseHeadersMiddleware(cfg *config.Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(cfg.ResponseHeaders) == 0 && len(cfg.StripResponseHeaders) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for name, value := range cfg.ResponseHeaders {
+				w.Header().Set(name, value)
+			}
+			if len(cfg.StripResponseHeaders) > 0 {
+				w = &stripHeadersWriter{ResponseWriter: w, strip: cfg.StripResponseHeaders}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// stripHeadersWriter removes headers just before they are sent
+type stripHeadersWriter struct {
+	http.ResponseWriter
+	strip       []string
+	wroteHeader bool
+}
+
+func (sw *stripHeadersWriter) WriteHeader(status int) {
+	if !sw.wroteHeader {
+		sw.wroteHeader = true
+		for _, name := range sw.strip {
+			sw.Header().Del(name)
+		}
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *stripHeadersWriter) Write(p []byte) (int, error) {
+	if !sw.wroteHeader {
+		sw.WriteHeader(http.StatusOK)
+	}
+	return sw.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (sw *stripHeadersWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
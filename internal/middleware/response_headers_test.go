@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mmvergara/gosss/internal/config"
+)
+
+func TestResponseHeaders(t *testing.T) {
+	cfg := &config.Config{
+		ResponseHeaders:      map[string]string{"X-Content-Type-Options": "nosniff", "Cache-Control": "no-store"},
+		StripResponseHeaders: []string{"Server", "X-Powered-By"},
+	}
+	handler := CreateResponseHeadersMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "gosss")
+		w.Header().Set("X-Powered-By", "go")
+		switch r.URL.Path {
+		case "/own":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/error":
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	for path, cacheControl := range map[string]string{"/ok": "no-store", "/own": "max-age=60", "/error": "no-store"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+			t.Errorf("%s: X-Content-Type-Options = %q, want nosniff", path, got)
+		}
+		if got := rec.Header().Get("Cache-Control"); got != cacheControl {
+			t.Errorf("%s: Cache-Control = %q, want %q", path, got, cacheControl)
+		}
+		for _, name := range cfg.StripResponseHeaders {
+			if rec.Header().Get(name) != "" {
+				t.Errorf("%s: %s not stripped", path, name)
+			}
+		}
+	}
+}
+
+func TestResponseHeadersUnconfigured(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := CreateResponseHeadersMiddleware(&config.Config{})(next)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if len(rec.Header()) != 0 {
+		t.Errorf("headers %v added without configuration", rec.Header())
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TrimKeyWhitespace strips leading and trailing spaces and tabs from the
+// object key route parameter (KEY_WHITESPACE=trim), so "  photo.jpg " and
+// "photo.jpg" refer to the same object.
+func TrimKeyWhitespace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			for i, k := range rctx.URLParams.Keys {
+				if k == "*" {
+					rctx.URLParams.Values[i] = strings.Trim(rctx.URLParams.Values[i], " \t")
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -5,9 +5,15 @@ import (
 )
 
 type ListBucketResult struct {
-	Name     string           `json:"name"`
-	Prefix   string           `json:"prefix"`
-	Contents []ObjectMetadata `json:"contents"`
+	Name       string `json:"name"`
+	Prefix     string `json:"prefix"`
+	StartAfter string `json:"startAfter,omitempty"`
+	// IsTruncated is set when more keys matched than MAX_LIST_KEYS allows
+	IsTruncated bool `json:"isTruncated"`
+	// NextStartAfter, set with IsTruncated, is the start-after that
+	// continues the listing: the last key returned
+	NextStartAfter string           `json:"nextStartAfter,omitempty"`
+	Contents       []ObjectMetadata `json:"contents"`
 }
 
 type ObjectMetadata struct {
@@ -16,4 +22,170 @@ type ObjectMetadata struct {
 	LastModified time.Time `json:"lastModified"`
 	ETag         string    `json:"etag"`
 	ContentType  string    `json:"contentType"`
+	// StorageClass is the class the object was uploaded with, e.g. STANDARD
+	StorageClass string `json:"storageClass,omitempty"`
+	// RestoredUntil is set on archived objects that have been restored and
+	// stay readable until then
+	RestoredUntil *time.Time `json:"restoredUntil,omitempty"`
+	// RedirectLocation makes GET and HEAD answer with a redirect to it
+	// instead of the object's content
+	RedirectLocation string `json:"redirectLocation,omitempty"`
+	// CacheControl is the Cache-Control the object was uploaded with
+	CacheControl string `json:"cacheControl,omitempty"`
+
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// SHA256 is the hex SHA-256 of the content, recorded when the store is
+	// content-addressed
+	SHA256 string `json:"sha256,omitempty"`
+
+	// DeletedAt is set on objects sitting in a bucket's trash area
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+
+	// History lists the versions this object replaced, newest first. It is
+	// kept in the metadata file and only returned by ?history.
+	History []ETagHistoryEntry `json:"history,omitempty"`
+}
+
+// ETagHistoryEntry records an earlier version of an overwritten object.
+type ETagHistoryEntry struct {
+	ETag         string    `json:"etag"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+	ReplacedAt   time.Time `json:"replacedAt"`
+}
+
+// ObjectHistory is returned by GET /{bucket}/{key}?history.
+type ObjectHistory struct {
+	Bucket       string             `json:"bucket"`
+	Key          string             `json:"key"`
+	ETag         string             `json:"etag"`
+	LastModified time.Time          `json:"lastModified"`
+	History      []ETagHistoryEntry `json:"history"`
+}
+
+// Tagging is the request and response body of the ?tagging endpoints.
+type Tagging struct {
+	Tags map[string]string `json:"tags"`
+}
+
+// PutObjectJSONRequest is the body accepted by PutObject when the request is
+// sent as application/json, for clients that can only speak JSON.
+type PutObjectJSONRequest struct {
+	ContentType string `json:"contentType"`
+	Data        string `json:"data"` // base64 (standard encoding)
+}
+
+// BatchMetadataRequest is the body of POST /{bucket}?metadata.
+type BatchMetadataRequest struct {
+	Keys []string `json:"keys"`
+}
+
+// BatchMetadataEntry is the answer for one key of a batch metadata request:
+// either the object's metadata or an error such as "NotFound".
+type BatchMetadataEntry struct {
+	Metadata *ObjectMetadata `json:"metadata,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// BatchMetadataResult is returned by POST /{bucket}?metadata, keyed by the
+// object keys as they were requested.
+type BatchMetadataResult struct {
+	Bucket  string                        `json:"bucket"`
+	Objects map[string]BatchMetadataEntry `json:"objects"`
+}
+
+// ImportResult summarises a bulk archive import.
+type ImportResult struct {
+	Imported []string      `json:"imported"`
+	Skipped  []ImportEntry `json:"skipped"`
+	Failed   []ImportEntry `json:"failed"`
+}
+
+// ImportEntry is an archive entry that was not imported, and why.
+type ImportEntry struct {
+	Key    string `json:"key"`
+	Reason string `json:"reason"`
+}
+
+// ObjectEvent is the JSON body POSTed to webhooks when an object changes.
+type ObjectEvent struct {
+	EventType string    `json:"eventType"`
+	Bucket    string    `json:"bucket"`
+	Key       string    `json:"key"`
+	Size      int64     `json:"size"`
+	ETag      string    `json:"etag"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// RecomputeResult is returned by the bucket-wide ?recompute operation.
+type RecomputeResult struct {
+	Bucket     string `json:"bucket"`
+	Recomputed int    `json:"recomputed"`
+}
+
+// ByteRange is an inclusive range of byte offsets.
+type ByteRange struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// UploadProgress reports which bytes of a resumable (Content-Range) upload
+// have been received so far.
+type UploadProgress struct {
+	Key      string      `json:"key"`
+	Size     int64       `json:"size"`
+	Received []ByteRange `json:"received"`
+}
+
+// UploadInfo describes a resumable upload that is still missing bytes.
+type UploadInfo struct {
+	Key          string      `json:"key"`
+	Size         int64       `json:"size"`
+	Received     []ByteRange `json:"received"`
+	Initiated    time.Time   `json:"initiated"`
+	LastModified time.Time   `json:"lastModified"`
+}
+
+// ListUploadsResult is returned by GET /{bucket}?uploads.
+type ListUploadsResult struct {
+	Bucket  string       `json:"bucket"`
+	Uploads []UploadInfo `json:"uploads"`
+}
+
+// BucketStats summarises a bucket's contents, see HEAD /{bucket}.
+type BucketStats struct {
+	Bucket      string
+	ObjectCount int64
+	SizeBytes   int64
+	// Quota is the bucket's size cap in bytes; zero is unlimited
+	Quota int64
+	// MaxObjectSize is the bucket's upload size limit in bytes; zero means
+	// the server-wide limit applies
+	MaxObjectSize int64
+	// CreatedAt is zero when the creation time wasn't recorded
+	CreatedAt time.Time
+}
+
+// DuplicateGroup is a set of objects sharing one ETag, i.e. the same content.
+type DuplicateGroup struct {
+	ETag string   `json:"etag"`
+	Size int64    `json:"size"`
+	Keys []string `json:"keys"`
+	// WastedBytes is the space taken by every copy but the first
+	WastedBytes int64 `json:"wastedBytes"`
+}
+
+// DuplicatesReport is returned by GET /{bucket}?duplicates, largest waste
+// first.
+type DuplicatesReport struct {
+	Bucket           string           `json:"bucket"`
+	Groups           []DuplicateGroup `json:"groups"`
+	TotalWastedBytes int64            `json:"totalWastedBytes"`
+}
+
+// CleanupResult is returned by the bucket ?cleanup operation.
+type CleanupResult struct {
+	Bucket  string `json:"bucket"`
+	Removed int    `json:"removed"`
 }
@@ -0,0 +1,67 @@
+// Package nonce remembers the nonces of one-time presigned URLs that have
+// been used, so each such URL is honored only once.
+package nonce
+
+import (
+	"sync"
+	"time"
+)
+
+// Store is an in-memory set of used nonces. A nonce only needs to be kept
+// until the URL carrying it expires; after that the URL is refused anyway.
+type Store struct {
+	mu   sync.Mutex
+	used map[string]time.Time // nonce -> when it can be forgotten
+	now  func() time.Time
+}
+
+// janitorInterval is how often expired nonces are forgotten
+const janitorInterval = time.Minute
+
+// New creates a Store and starts a janitor that forgets nonces whose URLs
+// have expired.
+func New() *Store {
+	s := newStore(time.Now)
+	go s.janitor(janitorInterval)
+	return s
+}
+
+// newStore creates a Store reading the time from now, without a janitor
+func newStore(now func() time.Time) *Store {
+	return &Store{used: make(map[string]time.Time), now: now}
+}
+
+// Use marks nonce as used until forgetAt, typically the expiration of its
+// URL. It reports false if the nonce was already used.
+func (s *Store) Use(nonce string, forgetAt time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if until, found := s.used[nonce]; found && s.now().Before(until) {
+		return false
+	}
+	s.used[nonce] = forgetAt
+	return true
+}
+
+func (s *Store) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.sweep()
+	}
+}
+
+// sweep forgets the nonces whose URLs have expired
+func (s *Store) sweep() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for nonce, until := range s.used {
+		if now.After(until) {
+			delete(s.used, nonce)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package nonce
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a time source tests move forward by hand
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestStore() (*Store, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	return newStore(clock.Now), clock
+}
+
+func (s *Store) size() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.used)
+}
+
+func TestUseOnce(t *testing.T) {
+	s, clock := newTestStore()
+	expires := clock.Now().Add(time.Minute)
+
+	if !s.Use("a", expires) {
+		t.Fatal("first use of a refused")
+	}
+	if s.Use("a", expires) {
+		t.Error("second use of a allowed")
+	}
+	if !s.Use("b", expires) {
+		t.Error("first use of b refused")
+	}
+
+	// Until the URL has expired, however much time passes before that
+	clock.Advance(59 * time.Second)
+	if s.Use("a", expires) {
+		t.Error("use of a allowed before its URL expired")
+	}
+}
+
+func TestUseConcurrently(t *testing.T) {
+	s, clock := newTestStore()
+	expires := clock.Now().Add(time.Minute)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.Use("a", expires) {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if accepted != 1 {
+		t.Errorf("nonce accepted %d times, want once", accepted)
+	}
+}
+
+func TestSweepForgetsExpired(t *testing.T) {
+	s, clock := newTestStore()
+	s.Use("short", clock.Now().Add(time.Minute))
+	s.Use("long", clock.Now().Add(time.Hour))
+
+	s.sweep()
+	if s.size() != 2 {
+		t.Fatalf("sweep before expiry left %d nonces, want 2", s.size())
+	}
+
+	clock.Advance(2 * time.Minute)
+	s.sweep()
+	if s.size() != 1 {
+		t.Fatalf("sweep left %d nonces, want only the unexpired one", s.size())
+	}
+	if s.Use("long", clock.Now().Add(time.Hour)) {
+		t.Error("unexpired nonce forgotten by sweep")
+	}
+}
+
+func TestJanitorSweeps(t *testing.T) {
+	s, clock := newTestStore()
+	s.Use("a", clock.Now().Add(time.Minute))
+	clock.Advance(2 * time.Minute)
+
+	go s.janitor(time.Millisecond)
+	deadline := time.Now().Add(5 * time.Second)
+	for s.size() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("janitor never forgot the expired nonce")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+const (
+	EventObjectCreated = "ObjectCreated:Put"
+	EventObjectRemoved = "ObjectRemoved:Delete"
+
+	workers      = 4
+	queueSize    = 1000
+	maxAttempts  = 4
+	firstBackoff = 500 * time.Millisecond
+)
+
+// Notifier delivers object events to webhook URLs asynchronously. Events are
+// queued and POSTed by a fixed pool of workers, so a slow or failing webhook
+// never blocks the request that produced the event.
+type Notifier struct {
+	defaultURL string
+	bucketURLs map[string]string
+	client     *http.Client
+	queue      chan delivery
+	sleep      func(time.Duration)
+}
+
+type delivery struct {
+	url   string
+	event model.ObjectEvent
+}
+
+// New starts a Notifier. Events for buckets listed in bucketURLs go to that
+// URL; all others go to defaultURL. With no URLs configured it is a no-op.
+func New(defaultURL string, bucketURLs map[string]string) *Notifier {
+	return newNotifier(defaultURL, bucketURLs, time.Sleep)
+}
+
+// newNotifier is New with sleep waiting out the backoff between attempts
+func newNotifier(defaultURL string, bucketURLs map[string]string, sleep func(time.Duration)) *Notifier {
+	n := &Notifier{
+		defaultURL: defaultURL,
+		bucketURLs: bucketURLs,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		queue:      make(chan delivery, queueSize),
+		sleep:      sleep,
+	}
+	if defaultURL == "" && len(bucketURLs) == 0 {
+		return n
+	}
+	for i := 0; i < workers; i++ {
+		go n.worker()
+	}
+	return n
+}
+
+// Publish queues an event for delivery. If the queue is full the event is
+// dropped rather than blocking the caller.
+func (n *Notifier) Publish(event model.ObjectEvent) {
+	url, ok := n.bucketURLs[event.Bucket]
+	if !ok {
+		url = n.defaultURL
+	}
+	if url == "" {
+		return
+	}
+
+	select {
+	case n.queue <- delivery{url: url, event: event}:
+	default:
+		slog.Warn("Webhook queue full, dropping event", "event", event.EventType, "bucket", event.Bucket, "key", event.Key)
+	}
+}
+
+func (n *Notifier) worker() {
+	for d := range n.queue {
+		backoff := firstBackoff
+		for attempt := 1; ; attempt++ {
+			err := n.send(d)
+			if err == nil {
+				break
+			}
+			if attempt == maxAttempts {
+				slog.Error("Failed to deliver webhook", "url", d.url, "event", d.event.EventType, "bucket", d.event.Bucket, "key", d.event.Key, "error", err)
+				break
+			}
+			slog.Debug("Retrying webhook", "url", d.url, "attempt", attempt, "error", err)
+			n.sleep(backoff)
+			backoff *= 2
+		}
+	}
+}
+
+func (n *Notifier) send(d delivery) error {
+	body, err := json.Marshal(d.event)
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(d.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+// receiver is a webhook endpoint recording the events POSTed to it. The
+// first failures requests are answered with 500.
+type receiver struct {
+	mu       sync.Mutex
+	events   []model.ObjectEvent
+	attempts int
+	failures int
+	got      chan struct{}
+}
+
+func newReceiver(t *testing.T, failures int) (*receiver, *httptest.Server) {
+	rcv := &receiver{failures: failures, got: make(chan struct{}, 10)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rcv.mu.Lock()
+		defer func() {
+			rcv.mu.Unlock()
+			rcv.got <- struct{}{}
+		}()
+		rcv.attempts++
+		if rcv.attempts <= rcv.failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var event model.ObjectEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("bad webhook request: %v, Content-Type %q", err, r.Header.Get("Content-Type"))
+		}
+		rcv.events = append(rcv.events, event)
+	}))
+	t.Cleanup(srv.Close)
+	return rcv, srv
+}
+
+// wait blocks until the receiver has answered n more requests
+func (rcv *receiver) wait(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-rcv.got:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("webhook request %d of %d never arrived", i+1, n)
+		}
+	}
+}
+
+func TestPublishDeliversEvent(t *testing.T) {
+	rcv, srv := newReceiver(t, 0)
+	other, otherSrv := newReceiver(t, 0)
+	n := newNotifier(srv.URL, map[string]string{"logs": otherSrv.URL}, func(time.Duration) {})
+
+	event := model.ObjectEvent{EventType: EventObjectCreated, Bucket: "photos", Key: "cat.jpg", Size: 4, ETag: `"abc"`, Timestamp: time.Unix(1700000000, 0).UTC()}
+	n.Publish(event)
+	n.Publish(model.ObjectEvent{EventType: EventObjectRemoved, Bucket: "logs", Key: "a.log"})
+	rcv.wait(t, 1)
+	other.wait(t, 1)
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	if len(rcv.events) != 1 || rcv.events[0] != event {
+		t.Errorf("default receiver got %+v, want %+v", rcv.events, event)
+	}
+	other.mu.Lock()
+	defer other.mu.Unlock()
+	if len(other.events) != 1 || other.events[0].Key != "a.log" {
+		t.Errorf("bucket receiver got %+v, want the logs event", other.events)
+	}
+}
+
+func TestPublishDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+	n := newNotifier(srv.URL, nil, func(time.Duration) {})
+
+	// Every worker is stuck on the receiver and the queue overflows, yet
+	// Publish returns at once
+	start := time.Now()
+	for i := 0; i < workers+queueSize+10; i++ {
+		n.Publish(model.ObjectEvent{EventType: EventObjectCreated, Bucket: "photos", Key: "cat.jpg"})
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Publish blocked for %v", elapsed)
+	}
+}
+
+func TestPublishRetriesWithBackoff(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		sleeps []time.Duration
+	)
+	sleep := func(d time.Duration) {
+		mu.Lock()
+		sleeps = append(sleeps, d)
+		mu.Unlock()
+	}
+
+	// Succeeds on the last attempt
+	rcv, srv := newReceiver(t, maxAttempts-1)
+	n := newNotifier(srv.URL, nil, sleep)
+	n.Publish(model.ObjectEvent{EventType: EventObjectCreated, Bucket: "photos", Key: "cat.jpg"})
+	rcv.wait(t, maxAttempts)
+	rcv.mu.Lock()
+	if len(rcv.events) != 1 {
+		t.Errorf("delivered %d events after %d failures, want 1", len(rcv.events), maxAttempts-1)
+	}
+	rcv.mu.Unlock()
+
+	mu.Lock()
+	want := []time.Duration{firstBackoff, 2 * firstBackoff, 4 * firstBackoff}
+	if len(sleeps) != len(want) {
+		t.Fatalf("backoffs = %v, want %v", sleeps, want)
+	}
+	for i := range want {
+		if sleeps[i] != want[i] {
+			t.Errorf("backoffs = %v, want %v", sleeps, want)
+			break
+		}
+	}
+	mu.Unlock()
+
+	// Gives up after maxAttempts
+	rcv, srv = newReceiver(t, maxAttempts+10)
+	n = newNotifier(srv.URL, nil, sleep)
+	n.Publish(model.ObjectEvent{EventType: EventObjectCreated, Bucket: "photos", Key: "cat.jpg"})
+	rcv.wait(t, maxAttempts)
+	select {
+	case <-rcv.got:
+		t.Error("webhook retried past maxAttempts")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
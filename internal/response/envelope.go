@@ -0,0 +1,33 @@
+package response
+
+// envelopeDefault is whether responses are enveloped when the request
+// doesn't say
+var envelopeDefault = false
+
+// SetEnvelope makes every JSON body, success or error, wrapped in an
+// envelope unless the request has ?envelope=false:
+//
+//	{"ok": true, "data": {...}, "error": null}
+//	{"ok": false, "data": null, "error": {"code": "404", ...}}
+//
+// S3-style clients expect the raw bodies, so it is off by default; requests
+// can still opt in with ?envelope=true. It must be called before the server
+// starts handling requests.
+func SetEnvelope(enabled bool) {
+	envelopeDefault = enabled
+}
+
+// envelope is the body of enveloped responses. Both data and error are
+// always present, one of them null, so clients can rely on the shape.
+type envelope struct {
+	OK    bool `json:"ok"`
+	Data  any  `json:"data"`
+	Error any  `json:"error"`
+}
+
+func wrap(v any, ok bool) envelope {
+	if ok {
+		return envelope{OK: true, Data: v}
+	}
+	return envelope{OK: false, Error: v}
+}
@@ -0,0 +1,89 @@
+// Package response writes JSON response bodies, compact by default or
+// indented for requests made with ?pretty=true, and optionally wrapped in an
+// envelope (see SetEnvelope). Timestamps are RFC 3339 in UTC unless
+// SetTimestampFormat selects Unix milliseconds.
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// formatWriter marks a response whose JSON body is formatted differently
+// from the default: indented, or with the envelope turned on or off
+type formatWriter struct {
+	http.ResponseWriter
+	pretty   bool
+	envelope bool
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (fw formatWriter) Unwrap() http.ResponseWriter {
+	return fw.ResponseWriter
+}
+
+// Format returns w marked so that JSON written with Encode is indented when
+// the request has ?pretty=true, and enveloped or not as ?envelope=true|false
+// asks. It fails when ?envelope isn't a boolean. Writers wrapping the result
+// must implement Unwrap so that Encode can still find the marks.
+func Format(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, error) {
+	query := r.URL.Query()
+	pretty, _ := strconv.ParseBool(query.Get("pretty"))
+	envelope := envelopeDefault
+	if query.Has("envelope") {
+		var err error
+		if envelope, err = strconv.ParseBool(query.Get("envelope")); err != nil {
+			return nil, errors.New("envelope must be true or false")
+		}
+	}
+	if pretty || envelope != envelopeDefault {
+		w = formatWriter{ResponseWriter: w, pretty: pretty, envelope: envelope}
+	}
+	return w, nil
+}
+
+// formatOf returns how JSON written to w is formatted, looking through the
+// writers that wrap the one Format marked.
+func formatOf(w http.ResponseWriter) (pretty, envelope bool) {
+	for {
+		if fw, ok := w.(formatWriter); ok {
+			return fw.pretty, fw.envelope
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return false, envelopeDefault
+		}
+		w = u.Unwrap()
+	}
+}
+
+// Encode writes v to w as JSON followed by a newline, indented if the
+// request asked for it, with timestamps in the configured format and, in
+// envelope mode, as the envelope's data.
+func Encode(w http.ResponseWriter, v any) error {
+	return encode(w, v, true)
+}
+
+// EncodeError is Encode for error bodies, which in envelope mode become the
+// envelope's error.
+func EncodeError(w http.ResponseWriter, v any) error {
+	return encode(w, v, false)
+}
+
+func encode(w http.ResponseWriter, v any, ok bool) error {
+	pretty, enveloped := formatOf(w)
+	if timestampFormat == TimestampUnixMillis {
+		v = withUnixMillis(v)
+	}
+	if enveloped {
+		v = wrap(v, ok)
+	}
+
+	enc := json.NewEncoder(w)
+	if pretty {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(v)
+}
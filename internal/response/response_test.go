@@ -0,0 +1,47 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// wrapper stands for a middleware's writer wrapping the formatted one
+type wrapper struct {
+	http.ResponseWriter
+}
+
+func (w wrapper) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestFormat(t *testing.T) {
+	for _, tc := range []struct {
+		target           string
+		pretty, envelope bool
+	}{
+		{"/b", false, false},
+		{"/b?pretty=true", true, false},
+		{"/b?envelope=true", false, true},
+		{"/b?envelope=1&pretty=1", true, true},
+		{"/b?envelope=false", false, false},
+	} {
+		w, err := Format(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tc.target, nil))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.target, err)
+		}
+		// Found through the writers of later middlewares
+		pretty, envelope := formatOf(wrapper{wrapper{w}})
+		if pretty != tc.pretty || envelope != tc.envelope {
+			t.Errorf("%s: pretty %v, envelope %v, want %v, %v", tc.target, pretty, envelope, tc.pretty, tc.envelope)
+		}
+	}
+
+	for _, value := range []string{"garbage", "yes", ""} {
+		_, err := Format(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/b?envelope="+value, nil))
+		if err == nil || !strings.Contains(err.Error(), "envelope") {
+			t.Errorf("?envelope=%s: err = %v, want an envelope error", value, err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+// ArrayStream writes a JSON object whose last member is an array, one
+// element at a time, so arrays too large to hold in memory can still be
+// sent. Like Encode it honours ?pretty, the envelope and the timestamp
+// format.
+type ArrayStream struct {
+	w        http.ResponseWriter
+	pretty   bool
+	envelope bool
+	// indent is the pretty indentation of the object, nested one level when
+	// it is the envelope's data
+	indent string
+	n      int
+}
+
+// StreamArray writes head, which must encode to a JSON object, leaving it
+// open with a member named field holding an array. Elements are added with
+// Write and the object is finished with Close. Head must not have a member
+// named field itself.
+func StreamArray(w http.ResponseWriter, head any, field string) (*ArrayStream, error) {
+	s := &ArrayStream{w: w}
+	s.pretty, s.envelope = formatOf(w)
+
+	var data []byte
+	if s.envelope {
+		// The object is the envelope's data, so it is opened inside it; the
+		// rest of the envelope follows in Close
+		if s.pretty {
+			s.indent = "  "
+			data = []byte("{\n  \"ok\": true,\n  \"data\": ")
+		} else {
+			data = []byte(`{"ok":true,"data":`)
+		}
+	}
+
+	headData, err := s.marshal(head, s.indent)
+	if err != nil {
+		return nil, err
+	}
+	name, err := json.Marshal(field)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reopen the object by dropping its closing brace
+	headData = bytes.TrimRight(bytes.TrimSuffix(headData, []byte("}")), " \n")
+	if !bytes.HasSuffix(headData, []byte("{")) {
+		headData = append(headData, ',')
+	}
+	data = append(data, headData...)
+	if s.pretty {
+		data = append(data, "\n"+s.indent+"  "...)
+		data = append(data, name...)
+		data = append(data, ": ["...)
+	} else {
+		data = append(data, name...)
+		data = append(data, ":["...)
+	}
+
+	_, err = w.Write(data)
+	return s, err
+}
+
+// Write appends v to the array.
+func (s *ArrayStream) Write(v any) error {
+	data, err := s.marshal(v, s.indent+"    ")
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if s.n > 0 {
+		buf.WriteByte(',')
+	}
+	if s.pretty {
+		buf.WriteString("\n" + s.indent + "    ")
+	}
+	buf.Write(data)
+	s.n++
+
+	_, err = s.w.Write(buf.Bytes())
+	return err
+}
+
+// Close ends the array and the object, and the envelope around them.
+func (s *ArrayStream) Close() error {
+	end := "]}"
+	if s.pretty {
+		end = "]\n" + s.indent + "}"
+		if s.n > 0 {
+			end = "\n" + s.indent + "  " + end
+		}
+	}
+	if s.envelope {
+		if s.pretty {
+			end += ",\n  \"error\": null\n}"
+		} else {
+			end += `,"error":null}`
+		}
+	}
+	_, err := s.w.Write([]byte(end + "\n"))
+	return err
+}
+
+// marshal encodes v as Encode would, with pretty output indented by prefix.
+func (s *ArrayStream) marshal(v any, prefix string) ([]byte, error) {
+	if timestampFormat == TimestampUnixMillis {
+		v = withUnixMillis(v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if s.pretty {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, data, prefix, "  "); err != nil {
+			return nil, err
+		}
+		data = buf.Bytes()
+	}
+	return data, nil
+}
@@ -0,0 +1,212 @@
+package response
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Timestamp formats for JSON response bodies. HTTP headers such as
+// Last-Modified always use http.TimeFormat.
+const (
+	// TimestampRFC3339 emits RFC 3339 strings in UTC, e.g.
+	// "2024-05-01T12:00:00.123456789Z" (the default)
+	TimestampRFC3339 = "rfc3339"
+	// TimestampUnixMillis emits milliseconds since the Unix epoch
+	TimestampUnixMillis = "unix-millis"
+)
+
+var timestampFormat = TimestampRFC3339
+
+// SetTimestampFormat selects how timestamps are written in JSON responses.
+// It must be called before the server starts handling requests.
+func SetTimestampFormat(format string) {
+	timestampFormat = format
+}
+
+// millisTime is a time.Time that marshals as milliseconds since the Unix
+// epoch.
+type millisTime time.Time
+
+func (t millisTime) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, time.Time(t).UnixMilli(), 10), nil
+}
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	millisTimeType = reflect.TypeFor[millisTime]()
+	marshalerType  = reflect.TypeFor[json.Marshaler]()
+	textType       = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// mirror describes the type a value is converted to for Unix millisecond
+// output: the same shape with every time.Time replaced by millisTime.
+type mirror struct {
+	t reflect.Type
+	// walk is whether values of the type hold a time.Time or an interface
+	// that might, and so have to be converted
+	walk bool
+	// elem is the mirror of the element type of pointers, slices, arrays
+	// and maps
+	elem *mirror
+	// fields are the fields of a struct mirror, in order
+	fields []mirrorField
+}
+
+// mirrorField is a field of a struct mirror
+type mirrorField struct {
+	// index is the index of the field in the original struct
+	index int
+	m     *mirror
+}
+
+// mirrors caches the mirror of each type seen
+var mirrors sync.Map
+
+// withUnixMillis returns v with every time.Time in it, however deeply
+// nested, marshalling as Unix milliseconds. The JSON of everything else,
+// including member names and order, is unchanged. Values holding no
+// timestamps are returned as they are.
+func withUnixMillis(v any) any {
+	if v == nil {
+		return nil
+	}
+	src := reflect.ValueOf(v)
+	m := mirrorOf(src.Type(), nil)
+	if !m.walk {
+		return v
+	}
+	return toMillis(src, m).Interface()
+}
+
+// mirrorOf returns the mirror of t. visiting holds the types being built
+// further up; where a type refers back to one of them it is left as it is
+// rather than recursed into forever.
+func mirrorOf(t reflect.Type, visiting map[reflect.Type]bool) *mirror {
+	if m, ok := mirrors.Load(t); ok {
+		return m.(*mirror)
+	}
+	if visiting[t] {
+		return &mirror{t: t}
+	}
+	if visiting == nil {
+		visiting = make(map[reflect.Type]bool)
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	m := &mirror{t: t}
+	switch {
+	case t == timeType:
+		m.t, m.walk = millisTimeType, true
+	case t.Kind() == reflect.Interface:
+		m.walk = true
+	case t.Kind() == reflect.Pointer:
+		if m.elem = mirrorOf(t.Elem(), visiting); m.elem.walk {
+			m.t, m.walk = reflect.PointerTo(m.elem.t), true
+		}
+	case t.Implements(marshalerType) || t.Implements(textType) ||
+		reflect.PointerTo(t).Implements(marshalerType) || reflect.PointerTo(t).Implements(textType):
+		// Types that marshal themselves are opaque
+	case t.Kind() == reflect.Slice:
+		if m.elem = mirrorOf(t.Elem(), visiting); m.elem.walk {
+			m.t, m.walk = reflect.SliceOf(m.elem.t), true
+		}
+	case t.Kind() == reflect.Array:
+		if m.elem = mirrorOf(t.Elem(), visiting); m.elem.walk {
+			m.t, m.walk = reflect.ArrayOf(t.Len(), m.elem.t), true
+		}
+	case t.Kind() == reflect.Map:
+		if m.elem = mirrorOf(t.Elem(), visiting); m.elem.walk {
+			m.t, m.walk = reflect.MapOf(t.Key(), m.elem.t), true
+		}
+	case t.Kind() == reflect.Struct:
+		var fields []reflect.StructField
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				// encoding/json skips unexported fields too
+				continue
+			}
+			fm := mirrorOf(f.Type, visiting)
+			if fm.walk {
+				f.Type, m.walk = fm.t, true
+			}
+			f.Offset, f.Index = 0, nil
+			fields = append(fields, f)
+			m.fields = append(m.fields, mirrorField{index: i, m: fm})
+		}
+		if m.walk {
+			m.t = reflect.StructOf(fields)
+		}
+	}
+	mirrors.Store(t, m)
+	return m
+}
+
+// toMillis converts src to the type of its mirror m.
+func toMillis(src reflect.Value, m *mirror) reflect.Value {
+	if !m.walk {
+		return src
+	}
+
+	switch src.Kind() {
+	case reflect.Interface:
+		if src.IsNil() {
+			return src
+		}
+		elem := src.Elem()
+		converted := toMillis(elem, mirrorOf(elem.Type(), nil))
+		if !converted.Type().AssignableTo(src.Type()) {
+			return src
+		}
+		dst := reflect.New(src.Type()).Elem()
+		dst.Set(converted)
+		return dst
+	case reflect.Pointer:
+		if src.IsNil() {
+			return reflect.Zero(m.t)
+		}
+		dst := reflect.New(m.t.Elem())
+		dst.Elem().Set(toMillis(src.Elem(), m.elem))
+		return dst
+	case reflect.Slice:
+		if src.IsNil() {
+			return reflect.Zero(m.t)
+		}
+		dst := reflect.MakeSlice(m.t, src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			dst.Index(i).Set(toMillis(src.Index(i), m.elem))
+		}
+		return dst
+	case reflect.Array:
+		dst := reflect.New(m.t).Elem()
+		for i := 0; i < src.Len(); i++ {
+			dst.Index(i).Set(toMillis(src.Index(i), m.elem))
+		}
+		return dst
+	case reflect.Map:
+		if src.IsNil() {
+			return reflect.Zero(m.t)
+		}
+		dst := reflect.MakeMapWithSize(m.t, src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			dst.SetMapIndex(iter.Key(), toMillis(iter.Value(), m.elem))
+		}
+		return dst
+	case reflect.Struct:
+		if src.Type() == timeType {
+			return reflect.ValueOf(millisTime(src.Interface().(time.Time)))
+		}
+		dst := reflect.New(m.t).Elem()
+		for i, f := range m.fields {
+			dst.Field(i).Set(toMillis(src.Field(f.index), f.m))
+		}
+		return dst
+	}
+	return src
+}
@@ -0,0 +1,108 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type timedItem struct {
+	Key          string     `json:"key"`
+	LastModified time.Time  `json:"lastModified"`
+	DeletedAt    *time.Time `json:"deletedAt,omitempty"`
+	Note         string     `json:"note"`
+}
+
+type timedList struct {
+	Items  []timedItem          `json:"items"`
+	ByKey  map[string]timedItem `json:"byKey"`
+	Extra  any                  `json:"extra"`
+	hidden time.Time
+}
+
+func withFormat(t *testing.T, format string) {
+	t.Helper()
+	SetTimestampFormat(format)
+	t.Cleanup(func() { SetTimestampFormat(TimestampRFC3339) })
+}
+
+func TestEncodeUnixMillis(t *testing.T) {
+	withFormat(t, TimestampUnixMillis)
+	at := time.UnixMilli(1714564800123).UTC()
+	v := timedList{
+		Items: []timedItem{{Key: "a", LastModified: at, DeletedAt: &at}},
+		ByKey: map[string]timedItem{"b": {Key: "b", LastModified: at}},
+		// A string that looks like a timestamp member is left alone
+		Extra: timedItem{Key: "c", LastModified: at, Note: `"lastModified":"2024-05-01T12:00:00Z"`},
+	}
+
+	rec := httptest.NewRecorder()
+	if err := Encode(rec, v); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"items":[{"key":"a","lastModified":1714564800123,"deletedAt":1714564800123,"note":""}],` +
+		`"byKey":{"b":{"key":"b","lastModified":1714564800123,"note":""}},` +
+		`"extra":{"key":"c","lastModified":1714564800123,"note":"\"lastModified\":\"2024-05-01T12:00:00Z\""}}` + "\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("body =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestEncodeUnixMillisEnvelopedAndPretty(t *testing.T) {
+	withFormat(t, TimestampUnixMillis)
+	at := time.UnixMilli(1714564800123).UTC()
+
+	rec := httptest.NewRecorder()
+	w := formatWriter{ResponseWriter: rec, pretty: true, envelope: true}
+	if err := Encode(w, timedItem{Key: "a", LastModified: at}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(rec.Body.String(), "\n    \"lastModified\": 1714564800123,\n") {
+		t.Errorf("body = %s", rec.Body.String())
+	}
+
+	var body struct {
+		OK   bool      `json:"ok"`
+		Data timedItem `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err == nil {
+		t.Error("unix-millis timestamp unmarshalled as a time.Time")
+	}
+}
+
+func TestEncodeRFC3339Unchanged(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 123, time.UTC)
+	rec := httptest.NewRecorder()
+	if err := Encode(rec, timedItem{Key: "a", LastModified: at}); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"key":"a","lastModified":"2024-05-01T12:00:00.000000123Z","note":""}` + "\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+}
+
+func TestStreamUnixMillis(t *testing.T) {
+	withFormat(t, TimestampUnixMillis)
+	at := time.UnixMilli(1714564800123).UTC()
+
+	rec := httptest.NewRecorder()
+	s, err := StreamArray(rec, struct {
+		Initiated time.Time `json:"initiated"`
+	}{at}, "items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(timedItem{Key: "a", LastModified: at}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"initiated":1714564800123,"items":[{"key":"a","lastModified":1714564800123,"note":""}]}` + "\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,45 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"time"
+)
+
+// Do calls fn up to attempts times, sleeping backoff before the second
+// attempt and doubling it after every failure. Only errors for which
+// IsRetryable reports true are retried; any other error, or ctx being done,
+// ends the loop early. The last error from fn is returned.
+func Do(ctx context.Context, attempts int, backoff time.Duration, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= attempts || !IsRetryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// IsRetryable reports whether err is a transient failure worth retrying, such
+// as EAGAIN or EBUSY from a network filesystem. Not-found, permission and
+// other permanent errors are not retryable.
+func IsRetryable(err error) bool {
+	for _, errno := range []syscall.Errno{syscall.EAGAIN, syscall.EBUSY, syscall.EINTR} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+
+	var temporary interface{ Temporary() bool }
+	return errors.As(err, &temporary) && temporary.Temporary()
+}
@@ -0,0 +1,78 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDoRetriesTransientErrors(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), 3, time.Millisecond, func() error {
+		calls++
+		if calls < 3 {
+			return syscall.EAGAIN
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("Do = %v after %d calls, want nil after 3", err, calls)
+	}
+}
+
+func TestDoStopsAtMaxAttempts(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), 4, time.Millisecond, func() error {
+		calls++
+		return fmt.Errorf("rename: %w", syscall.EBUSY)
+	})
+	if !errors.Is(err, syscall.EBUSY) || calls != 4 {
+		t.Fatalf("Do = %v after %d calls, want EBUSY after 4", err, calls)
+	}
+}
+
+func TestDoDoesNotRetryPermanentErrors(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), 5, time.Millisecond, func() error {
+		calls++
+		return os.ErrNotExist
+	})
+	if !errors.Is(err, os.ErrNotExist) || calls != 1 {
+		t.Fatalf("Do = %v after %d calls, want ErrNotExist after 1", err, calls)
+	}
+}
+
+func TestDoBacksOffExponentially(t *testing.T) {
+	var times []time.Time
+	backoff := 20 * time.Millisecond
+	Do(context.Background(), 3, backoff, func() error {
+		times = append(times, time.Now())
+		return syscall.EAGAIN
+	})
+	if len(times) != 3 {
+		t.Fatalf("%d calls, want 3", len(times))
+	}
+	if first := times[1].Sub(times[0]); first < backoff {
+		t.Errorf("first backoff %v, want at least %v", first, backoff)
+	}
+	if second := times[2].Sub(times[1]); second < 2*backoff {
+		t.Errorf("second backoff %v, want at least %v", second, 2*backoff)
+	}
+}
+
+func TestDoStopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := Do(ctx, 10, time.Hour, func() error {
+		calls++
+		cancel()
+		return syscall.EAGAIN
+	})
+	if !errors.Is(err, syscall.EAGAIN) || calls != 1 {
+		t.Fatalf("Do = %v after %d calls, want EAGAIN after 1", err, calls)
+	}
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+// RestoreArchivedObject makes an object of an archive storage class readable
+// until the given time. Archived data is kept as-is on local disk, so
+// restoring only records the window in the object's metadata.
+func (ls *LocalStorage) RestoreArchivedObject(ctx context.Context, bucket, key string, until time.Time) (*model.ObjectMetadata, error) {
+	unlock := ls.lockObject(bucket, key)
+	defer unlock()
+
+	metadataPath := ls.objectPath(bucket, key) + ".metadata"
+
+	metadata, err := ls.readMetadata(metadataPath)
+	if err != nil {
+		slog.Debug("Failed to read metadata", "path", metadataPath, "error", err)
+		if isNotExist(err) {
+			return nil, ls.notFoundError(bucket)
+		}
+		return nil, fmt.Errorf("failed to read metadata")
+	}
+
+	until = until.UTC()
+	metadata.RestoredUntil = &until
+	if err := ls.writeMetadata(metadataPath, metadata); err != nil {
+		slog.Error("Failed to write metadata", "error", err)
+		return nil, fmt.Errorf("failed to write metadata")
+	}
+	return metadata, nil
+}
@@ -3,69 +3,240 @@ package storage
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/retry"
 )
 
 type LocalStorage struct {
 	basePath string
-	mu       sync.RWMutex
+	opts     Options
+	fs       FileSystem
+
+	// Per-bucket and per-object locks, see lock.go
+	buckets *keyedLocker
+	objects *keyedLocker
+
+	// counts caches the number of objects per bucket, see objectCount
+	countsMu sync.Mutex
+	counts   map[string]int64
+
+	// usage and quotas cache each bucket's total object size and its quota,
+	// see quota.go. usageLocks serializes quota checks per bucket, so a
+	// bucket's size is walked once without holding up other buckets
+	usageMu    sync.Mutex
+	usage      map[string]int64
+	quotas     map[string]int64
+	usageLocks *keyedLocker
+
+	// meta caches each bucket's metadata file, see bucket_metadata.go
+	metaMu sync.Mutex
+	meta   map[string]bucketMetadata
+
+	// blobs serializes linking to and releasing each content-addressed blob,
+	// keyed by bucket and content hash
+	blobs *keyedLocker
+}
+
+// Options tunes the behaviour of LocalStorage. The zero value is a plain
+// hard-deleting store.
+type Options struct {
+	// SoftDelete moves deleted objects into the bucket's trash area so they
+	// can be restored until TrashRetention elapses.
+	SoftDelete     bool
+	TrashRetention time.Duration
+
+	// MaxObjectsPerBucket caps the number of objects in a bucket. Zero means
+	// unlimited.
+	MaxObjectsPerBucket int64
+
+	// DirMode and FileMode are the permissions given to created directories
+	// and to object and metadata files. Zero values use DefaultDirMode and
+	// DefaultFileMode.
+	DirMode  os.FileMode
+	FileMode os.FileMode
+
+	// DurableWrites fsyncs object and metadata files before they are renamed
+	// into place, and their directory afterwards, so acknowledged writes
+	// survive a power loss.
+	DurableWrites bool
+
+	// ContentAddressed stores each distinct content once per bucket; objects
+	// with identical bytes are hard links to the same blob (see cas.go).
+	ContentAddressed bool
+
+	// ETagHistoryLimit is how many earlier ETags an object's metadata keeps
+	// when it is overwritten. Zero records no history.
+	ETagHistoryLimit int
+
+	// ShardKeys stores objects below directories derived from a hash of
+	// their key instead of directly at their key's path (see shard.go).
+	// Stores written with one layout must be migrated to use the other.
+	ShardKeys bool
+
+	// FS is the filesystem objects are stored on. Nil uses OSFileSystem.
+	FS FileSystem
 }
 
-func New(basePath string) *LocalStorage {
+// The defaults are the permissions gosss used before they were configurable
+const (
+	DefaultDirMode  os.FileMode = 0755
+	DefaultFileMode os.FileMode = 0600
+)
+
+func New(basePath string, opts Options) *LocalStorage {
+	if opts.DirMode == 0 {
+		opts.DirMode = DefaultDirMode
+	}
+	if opts.FileMode == 0 {
+		opts.FileMode = DefaultFileMode
+	}
+	if opts.FS == nil {
+		opts.FS = OSFileSystem{}
+	}
 	return &LocalStorage{
 		basePath: basePath,
+		opts:     opts,
+		fs:       opts.FS,
+		buckets:  newKeyedLocker(),
+		objects:  newKeyedLocker(),
+		blobs:    newKeyedLocker(),
+		counts:   make(map[string]int64),
+		usage:    make(map[string]int64),
+		quotas:   make(map[string]int64),
+		meta:     make(map[string]bucketMetadata),
+
+		usageLocks: newKeyedLocker(),
 	}
 }
 
 func (ls *LocalStorage) CreateBucket(ctx context.Context, name string) error {
-	ls.mu.Lock()
-	defer ls.mu.Unlock()
+	unlock := ls.lockBucket(name)
+	defer unlock()
+
+	ls.resetCount(name)
+	ls.resetUsage(name)
 
 	bucketPath := filepath.Join(ls.basePath, name)
-	if err := os.MkdirAll(bucketPath, 0755); err != nil {
-		log.Printf("Failed to create bucket: %v", err)
+	if err := ls.mkdirAll(bucketPath); err != nil {
+		slog.Error("Failed to create bucket", "error", err)
 		return fmt.Errorf("failed to create bucket")
 	}
+
+	// Keep the original creation time if the bucket already existed
+	metaPath := filepath.Join(bucketPath, bucketMetadataFile)
+	if _, err := ls.fs.Stat(metaPath); isNotExist(err) {
+		if err := ls.writeJSON(metaPath, &bucketMetadata{CreatedAt: time.Now().UTC()}); err != nil {
+			slog.Error("Failed to write bucket metadata", "error", err)
+			return fmt.Errorf("failed to create bucket")
+		}
+	}
 	return nil
 }
 
 func (ls *LocalStorage) DeleteBucket(ctx context.Context, name string) error {
-	ls.mu.Lock()
-	defer ls.mu.Unlock()
+	unlock := ls.lockBucket(name)
+	defer unlock()
 
-	bucketPath := filepath.Join(ls.basePath, name)
-	// Check if bucket is empty
-	entries, err := os.ReadDir(bucketPath)
+	found, err := ls.hasObject(name)
 	if err != nil {
-		log.Printf("Failed to read bucket: %v", err)
+		slog.Error("Failed to read bucket", "error", err)
 		return fmt.Errorf("failed to read bucket")
 	}
-	if len(entries) > 0 {
-		log.Printf("Bucket not empty: %s", name)
+	if found {
+		slog.Debug("Bucket not empty", "bucket", name)
 		return fmt.Errorf("bucket not empty")
 	}
 
-	if err := os.Remove(bucketPath); err != nil {
-		log.Printf("Failed to delete bucket: %v", err)
+	// Whatever is left isn't an object: trashed objects, unfinished uploads,
+	// blobs, temp files, metadata and directories emptied by deletes. Only
+	// those are removed, so anything unexpected makes the final Remove fail
+	// rather than being wiped. The bucket lock keeps anything new from being
+	// written meanwhile
+	bucketPath := filepath.Join(ls.basePath, name)
+	err = retry.Do(ctx, retryAttempts, retryBackoff, func() error {
+		for _, dir := range []string{trashDir, uploadsDir, blobsDir, tempDir} {
+			if err := ls.fs.RemoveAll(filepath.Join(bucketPath, dir)); err != nil {
+				return err
+			}
+		}
+		if err := ls.removeLeftovers(bucketPath); err != nil {
+			return err
+		}
+		return ls.fs.Remove(bucketPath)
+	})
+	if err != nil {
+		slog.Error("Failed to delete bucket", "error", err)
 		return fmt.Errorf("failed to delete bucket")
 	}
+	ls.resetCount(name)
+	ls.resetUsage(name)
+	return nil
+}
+
+// removeLeftovers removes the metadata files under dir and the directories
+// they leave empty, keeping dir itself. Callers must hold the bucket lock and
+// have checked that no objects are left.
+func (ls *LocalStorage) removeLeftovers(dir string) error {
+	entries, err := ls.fs.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			if err := ls.removeLeftovers(path); err != nil {
+				return err
+			}
+			if err := ls.fs.Remove(path); err != nil {
+				return err
+			}
+			continue
+		}
+		if ls.isMetadataFile(path) {
+			if err := ls.fs.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
+// ListBuckets returns the names of all buckets in lexical order
+func (ls *LocalStorage) ListBuckets(ctx context.Context) ([]string, error) {
+	entries, err := ls.fs.ReadDir(ls.basePath)
+	if isNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		slog.Error("Failed to read storage directory", "error", err)
+		return nil, fmt.Errorf("failed to read storage directory")
+	}
+
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
 func (ls *LocalStorage) BucketExists(ctx context.Context, name string) (bool, error) {
-	ls.mu.RLock()
-	defer ls.mu.RUnlock()
+	unlock := ls.rLockBucket(name)
+	defer unlock()
 
 	bucketPath := filepath.Join(ls.basePath, name)
-	_, err := os.Stat(bucketPath)
+	_, err := ls.fs.Stat(bucketPath)
 	if os.IsNotExist(err) {
 		return false, nil
 	}
 	if err != nil {
-		log.Printf("Failed to check bucket: %v", err)
+		slog.Error("Failed to check bucket", "error", err)
 		return false, fmt.Errorf("failed to check bucket")
 	}
 	return true, nil
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+)
+
+// loadBucketMetadata returns a bucket's metadata. It is read from disk once
+// and then served from memory, so the settings consulted on every request
+// (default Cache-Control, max object size) cost no I/O.
+func (ls *LocalStorage) loadBucketMetadata(bucket string) (bucketMetadata, error) {
+	ls.metaMu.Lock()
+	meta, ok := ls.meta[bucket]
+	ls.metaMu.Unlock()
+	if ok {
+		return meta, nil
+	}
+
+	unlock := ls.rLockBucket(bucket)
+	defer unlock()
+	return ls.readBucketMetadata(bucket)
+}
+
+// updateBucketMetadata applies update to a bucket's metadata and writes it
+// back, keeping the in-memory copy in step.
+func (ls *LocalStorage) updateBucketMetadata(bucket string, update func(*bucketMetadata)) error {
+	unlock := ls.lockBucket(bucket)
+	defer unlock()
+
+	meta, err := ls.readBucketMetadata(bucket)
+	if err != nil {
+		return err
+	}
+	update(&meta)
+	if err := ls.writeJSON(filepath.Join(ls.basePath, bucket, bucketMetadataFile), &meta); err != nil {
+		slog.Error("Failed to write bucket metadata", "bucket", bucket, "error", err)
+		ls.forgetBucketMetadata(bucket)
+		return fmt.Errorf("failed to write bucket metadata")
+	}
+
+	ls.metaMu.Lock()
+	ls.meta[bucket] = meta
+	ls.metaMu.Unlock()
+	return nil
+}
+
+// readBucketMetadata returns a bucket's metadata from memory, or reads and
+// remembers it. A bucket without a metadata file has zero metadata. Callers
+// must hold the bucket lock.
+func (ls *LocalStorage) readBucketMetadata(bucket string) (bucketMetadata, error) {
+	ls.metaMu.Lock()
+	meta, ok := ls.meta[bucket]
+	ls.metaMu.Unlock()
+	if ok {
+		return meta, nil
+	}
+
+	bucketPath := filepath.Join(ls.basePath, bucket)
+	if _, err := ls.fs.Stat(bucketPath); err != nil {
+		if isNotExist(err) {
+			return meta, ErrBucketNotFound
+		}
+		return meta, fmt.Errorf("failed to check bucket")
+	}
+	if err := ls.readJSON(filepath.Join(bucketPath, bucketMetadataFile), &meta); err != nil && !isNotExist(err) {
+		slog.Error("Failed to read bucket metadata", "bucket", bucket, "error", err)
+		return bucketMetadata{}, fmt.Errorf("failed to read bucket metadata")
+	}
+
+	ls.metaMu.Lock()
+	ls.meta[bucket] = meta
+	ls.metaMu.Unlock()
+	return meta, nil
+}
+
+// forgetBucketMetadata drops the in-memory copy of a bucket's metadata so it
+// is read from disk on next use.
+func (ls *LocalStorage) forgetBucketMetadata(bucket string) {
+	ls.metaMu.Lock()
+	defer ls.metaMu.Unlock()
+
+	delete(ls.meta, bucket)
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// metaReadFS counts the times a bucket metadata file is opened
+type metaReadFS struct {
+	OSFileSystem
+	reads atomic.Int64
+}
+
+func (f *metaReadFS) Open(name string) (File, error) {
+	if filepath.Base(name) == bucketMetadataFile {
+		f.reads.Add(1)
+	}
+	return f.OSFileSystem.Open(name)
+}
+
+func TestBucketMetadataServedFromMemory(t *testing.T) {
+	fs := &metaReadFS{}
+	ls := newTestStorage(t, Options{FS: fs})
+	ctx := context.Background()
+
+	if err := ls.SetBucketDefaultCacheControl(ctx, "test", "max-age=60"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.SetBucketMaxObjectSize(ctx, "test", 1024); err != nil {
+		t.Fatal(err)
+	}
+	before := fs.reads.Load()
+	for i := 0; i < 10; i++ {
+		value, err := ls.BucketDefaultCacheControl(ctx, "test")
+		if err != nil || value != "max-age=60" {
+			t.Fatalf("BucketDefaultCacheControl = %q, %v", value, err)
+		}
+		size, err := ls.BucketMaxObjectSize(ctx, "test")
+		if err != nil || size != 1024 {
+			t.Fatalf("BucketMaxObjectSize = %d, %v", size, err)
+		}
+	}
+	if n := fs.reads.Load() - before; n != 0 {
+		t.Errorf("metadata file read %d times after it was set", n)
+	}
+
+	// Both settings reached the disk
+	fresh := New(ls.basePath, Options{})
+	if value, _ := fresh.BucketDefaultCacheControl(ctx, "test"); value != "max-age=60" {
+		t.Errorf("reopened store has default Cache-Control %q", value)
+	}
+	if size, _ := fresh.BucketMaxObjectSize(ctx, "test"); size != 1024 {
+		t.Errorf("reopened store has max object size %d", size)
+	}
+}
+
+func TestBucketMetadataFollowsBucket(t *testing.T) {
+	ls := newTestStorage(t, Options{})
+	ctx := context.Background()
+
+	if err := ls.SetBucketDefaultCacheControl(ctx, "test", "no-cache"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.RenameBucket(ctx, "test", "renamed"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ls.BucketDefaultCacheControl(ctx, "test"); !errors.Is(err, ErrBucketNotFound) {
+		t.Errorf("old name after rename: %v, want ErrBucketNotFound", err)
+	}
+	if value, _ := ls.BucketDefaultCacheControl(ctx, "renamed"); value != "no-cache" {
+		t.Errorf("renamed bucket has default Cache-Control %q", value)
+	}
+
+	// A bucket recreated under a deleted one's name starts clean
+	if err := ls.DeleteBucket(ctx, "renamed"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ls.BucketDefaultCacheControl(ctx, "renamed"); !errors.Is(err, ErrBucketNotFound) {
+		t.Errorf("deleted bucket: %v, want ErrBucketNotFound", err)
+	}
+	if err := ls.CreateBucket(ctx, "renamed"); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := ls.BucketDefaultCacheControl(ctx, "renamed"); err != nil || value != "" {
+		t.Errorf("recreated bucket has default Cache-Control %q, %v", value, err)
+	}
+}
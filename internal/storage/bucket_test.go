@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// dirReadFS counts the directory entries read through open directories
+type dirReadFS struct {
+	OSFileSystem
+	entries atomic.Int64
+}
+
+func (f *dirReadFS) Open(name string) (File, error) {
+	file, err := f.OSFileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &dirReadFile{File: file, entries: &f.entries}, nil
+}
+
+type dirReadFile struct {
+	File
+	entries *atomic.Int64
+}
+
+func (f *dirReadFile) ReadDir(n int) ([]os.DirEntry, error) {
+	entries, err := f.File.ReadDir(n)
+	f.entries.Add(int64(len(entries)))
+	return entries, err
+}
+
+func TestHasObjectLargeBucket(t *testing.T) {
+	fs := &dirReadFS{}
+	ls := newTestStorage(t, Options{FS: fs})
+	bucketPath := filepath.Join(ls.basePath, "test")
+
+	// Written directly, as PutObject would take a while for this many
+	for i := 0; i < 10000; i++ {
+		dir := filepath.Join(bucketPath, fmt.Sprintf("dir%02d", i%50))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("object%04d", i))
+		if err := os.WriteFile(path, []byte("x"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path+".metadata", []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found, err := ls.hasObject("test")
+	if err != nil || !found {
+		t.Fatalf("hasObject = %v, %v, want true", found, err)
+	}
+	// The bucket's own metadata and an object's sidecar may come before the
+	// first object, in a directory that may be the second one read
+	if n := fs.entries.Load(); n > 10 {
+		t.Errorf("hasObject read %d directory entries, want a handful", n)
+	}
+
+	if err := ls.DeleteBucket(context.Background(), "test"); err == nil {
+		t.Fatal("DeleteBucket removed a bucket of 10000 objects")
+	}
+	if _, err := os.Stat(filepath.Join(bucketPath, "dir00", "object0000")); err != nil {
+		t.Errorf("object gone after a refused delete: %v", err)
+	}
+}
+
+func TestDeleteBucketKeepsMetadataNamedKeys(t *testing.T) {
+	ls := newTestStorage(t, Options{})
+	mustPut(t, ls, "test", "notes.metadata", "not metadata")
+
+	found, err := ls.hasObject("test")
+	if err != nil || !found {
+		t.Fatalf("hasObject = %v, %v, want true", found, err)
+	}
+	if err := ls.DeleteBucket(context.Background(), "test"); err == nil {
+		t.Fatal("DeleteBucket removed a bucket holding notes.metadata")
+	}
+	if got := readObject(t, ls, "test", "notes.metadata"); got != "not metadata" {
+		t.Errorf("object = %q after a refused delete", got)
+	}
+}
+
+func TestDeleteBucketRemovesLeftovers(t *testing.T) {
+	ctx := context.Background()
+	ls := newTestStorage(t, Options{SoftDelete: true, ShardKeys: true})
+	if err := ls.SetBucketMaxObjectSize(ctx, "test", 1024); err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, ls, "test", "a/b/c.txt", "c")
+	mustPut(t, ls, "test", "d.txt", "d")
+	for _, key := range []string{"a/b/c.txt", "d.txt"} {
+		if err := ls.DeleteObject(ctx, "test", key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bucketPath := filepath.Join(ls.basePath, "test")
+	if err := os.MkdirAll(filepath.Join(bucketPath, tempDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bucketPath, tempDir, "partial"), []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ls.DeleteBucket(ctx, "test"); err != nil {
+		t.Fatalf("DeleteBucket: %v", err)
+	}
+	if _, err := os.Stat(bucketPath); !os.IsNotExist(err) {
+		t.Errorf("bucket directory still there: %v", err)
+	}
+}
@@ -0,0 +1,383 @@
+package storage
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"io"
+	"maps"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/model"
+	"golang.org/x/sync/singleflight"
+)
+
+// CacheOptions configures CachingStorage.
+type CacheOptions struct {
+	// MaxObjectSize is the largest object that is cached, in bytes. Zero
+	// disables the cache.
+	MaxObjectSize int64
+	// MaxBytes bounds the total size of cached object data. Least recently
+	// used entries are evicted first.
+	MaxBytes int64
+	// TTL is how long an entry is served before it is read from disk again
+	TTL time.Duration
+	// CoalesceMaxObjectSize is the largest object whose concurrent reads
+	// share one disk read when it isn't cached. Objects up to MaxObjectSize
+	// are always shared.
+	CoalesceMaxObjectSize int64
+}
+
+// CachingStorage wraps a Storage and keeps small objects and their metadata
+// in memory. Every write that goes through it invalidates the affected key
+// (or the whole bucket), so reads never see data older than the last write
+// made through the same CachingStorage. Concurrent misses for the same
+// small object share a single disk read, whether or not it is then cached.
+type CachingStorage struct {
+	Storage
+
+	opts   CacheOptions
+	flight singleflight.Group
+
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	lru     *list.List
+	size    int64
+	// reads holds the disk read in progress for each key, which a write to
+	// the key or its bucket marks stale and detaches, so the read neither
+	// fills the cache nor is joined by later callers
+	reads  map[cacheKey]*diskRead
+	nextID uint64
+}
+
+type cacheKey struct {
+	bucket, key string
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	data    []byte
+	meta    model.ObjectMetadata
+	expires time.Time
+}
+
+// diskRead is a read of an object from the wrapped Storage
+type diskRead struct {
+	id    uint64
+	stale bool
+}
+
+// cachedObject is the body returned for cache hits. It is seekable so Range
+// and conditional requests work as they do for files.
+type cachedObject struct {
+	*bytes.Reader
+}
+
+func (cachedObject) Close() error { return nil }
+
+// NewCaching returns a Storage that serves small objects from memory.
+func NewCaching(next Storage, opts CacheOptions) *CachingStorage {
+	return &CachingStorage{
+		Storage: next,
+		opts:    opts,
+		entries: make(map[cacheKey]*list.Element),
+		lru:     list.New(),
+		reads:   make(map[cacheKey]*diskRead),
+	}
+}
+
+// bufferLimit is the largest object that is read into memory to be shared
+func (c *CachingStorage) bufferLimit() int64 {
+	return max(c.opts.MaxObjectSize, c.opts.CoalesceMaxObjectSize)
+}
+
+func (c *CachingStorage) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, *model.ObjectMetadata, error) {
+	k := cacheKey{bucket, key}
+	if data, meta, ok := c.lookup(k); ok {
+		return cachedObject{bytes.NewReader(data)}, meta, nil
+	}
+	if c.bufferLimit() <= 0 {
+		return c.Storage.GetObject(ctx, bucket, key)
+	}
+
+	read := c.startRead(k)
+	// Callers only join a read that no write has overtaken, so one that
+	// arrives after a write never receives data read before it
+	flightKey := strconv.FormatUint(read.id, 10)
+
+	// Objects too large to buffer are streamed to the caller that opened
+	// them; the others then open the file themselves
+	var stream io.ReadCloser
+	var streamMeta *model.ObjectMetadata
+
+	v, err, _ := c.flight.Do(flightKey, func() (any, error) {
+		defer c.endRead(k, read)
+
+		// The read is shared, so it must not fail because the first caller
+		// went away
+		obj, meta, err := c.Storage.GetObject(context.WithoutCancel(ctx), bucket, key)
+		if err != nil {
+			return nil, err
+		}
+		if meta.Size > c.bufferLimit() {
+			stream, streamMeta = obj, meta
+			return nil, nil
+		}
+		defer obj.Close()
+
+		data, err := io.ReadAll(io.LimitReader(obj, c.bufferLimit()+1))
+		if err != nil {
+			return nil, err
+		}
+		// The file changed size under us; serve what was read but don't keep it
+		if int64(len(data)) == meta.Size {
+			c.add(k, data, *meta, read)
+		}
+		return &cacheEntry{key: k, data: data, meta: *meta}, nil
+	})
+	if stream != nil {
+		return stream, streamMeta, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if v == nil {
+		return c.Storage.GetObject(ctx, bucket, key)
+	}
+
+	entry := v.(*cacheEntry)
+	meta := entry.meta
+	meta.Tags = maps.Clone(entry.meta.Tags)
+	return cachedObject{bytes.NewReader(entry.data)}, &meta, nil
+}
+
+// startRead returns the disk read of k to join, starting one if there is
+// none.
+func (c *CachingStorage) startRead(k cacheKey) *diskRead {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	read, ok := c.reads[k]
+	if !ok {
+		c.nextID++
+		read = &diskRead{id: c.nextID}
+		c.reads[k] = read
+	}
+	return read
+}
+
+// endRead forgets a finished read, unless a write already detached it.
+func (c *CachingStorage) endRead(k cacheKey, read *diskRead) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.reads[k] == read {
+		delete(c.reads, k)
+	}
+}
+
+// HeadObject answers from the cache when the object is already cached, but
+// doesn't populate it.
+func (c *CachingStorage) HeadObject(ctx context.Context, bucket, key string) (*model.ObjectMetadata, error) {
+	if _, meta, ok := c.lookup(cacheKey{bucket, key}); ok {
+		return meta, nil
+	}
+	return c.Storage.HeadObject(ctx, bucket, key)
+}
+
+func (c *CachingStorage) PutObject(ctx context.Context, bucket, key string, data io.Reader, size int64, contentType string) (*model.ObjectMetadata, error) {
+	defer c.invalidate(bucket, key)
+	return c.Storage.PutObject(ctx, bucket, key, data, size, contentType)
+}
+
+func (c *CachingStorage) PutObjectWithMetadata(ctx context.Context, bucket, key string, data io.Reader, size int64, template model.ObjectMetadata) (*model.ObjectMetadata, error) {
+	defer c.invalidate(bucket, key)
+	return c.Storage.PutObjectWithMetadata(ctx, bucket, key, data, size, template)
+}
+
+func (c *CachingStorage) DeleteObject(ctx context.Context, bucket, key string) error {
+	defer c.invalidate(bucket, key)
+	return c.Storage.DeleteObject(ctx, bucket, key)
+}
+
+func (c *CachingStorage) PutObjectRange(ctx context.Context, bucket, key string, start, total int64, data io.Reader, template model.ObjectMetadata) (*model.UploadProgress, *model.ObjectMetadata, error) {
+	defer c.invalidate(bucket, key)
+	return c.Storage.PutObjectRange(ctx, bucket, key, start, total, data, template)
+}
+
+func (c *CachingStorage) CopyObject(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string, override *model.ObjectMetadata) (*model.ObjectMetadata, error) {
+	defer c.invalidate(dstBucket, dstKey)
+	return c.Storage.CopyObject(ctx, srcBucket, srcKey, dstBucket, dstKey, override)
+}
+
+func (c *CachingStorage) RestoreObject(ctx context.Context, bucket, key string) error {
+	defer c.invalidate(bucket, key)
+	return c.Storage.RestoreObject(ctx, bucket, key)
+}
+
+func (c *CachingStorage) RestoreArchivedObject(ctx context.Context, bucket, key string, until time.Time) (*model.ObjectMetadata, error) {
+	defer c.invalidate(bucket, key)
+	return c.Storage.RestoreArchivedObject(ctx, bucket, key, until)
+}
+
+func (c *CachingStorage) TruncateObject(ctx context.Context, bucket, key string, size int64, extend bool) (*model.ObjectMetadata, error) {
+	defer c.invalidate(bucket, key)
+	return c.Storage.TruncateObject(ctx, bucket, key, size, extend)
+}
+
+func (c *CachingStorage) RecomputeObject(ctx context.Context, bucket, key string) (*model.ObjectMetadata, error) {
+	defer c.invalidate(bucket, key)
+	return c.Storage.RecomputeObject(ctx, bucket, key)
+}
+
+func (c *CachingStorage) PutObjectTagging(ctx context.Context, bucket, key string, tags map[string]string) error {
+	defer c.invalidate(bucket, key)
+	return c.Storage.PutObjectTagging(ctx, bucket, key, tags)
+}
+
+func (c *CachingStorage) DeleteObjectTagging(ctx context.Context, bucket, key string) error {
+	defer c.invalidate(bucket, key)
+	return c.Storage.DeleteObjectTagging(ctx, bucket, key)
+}
+
+func (c *CachingStorage) CreateBucket(ctx context.Context, name string) error {
+	defer c.invalidateBucket(name)
+	return c.Storage.CreateBucket(ctx, name)
+}
+
+func (c *CachingStorage) DeleteBucket(ctx context.Context, name string) error {
+	defer c.invalidateBucket(name)
+	return c.Storage.DeleteBucket(ctx, name)
+}
+
+func (c *CachingStorage) RenameBucket(ctx context.Context, oldName, newName string) error {
+	defer c.invalidateBucket(newName)
+	defer c.invalidateBucket(oldName)
+	return c.Storage.RenameBucket(ctx, oldName, newName)
+}
+
+func (c *CachingStorage) RecomputeBucket(ctx context.Context, bucket string) (int, error) {
+	defer c.invalidateBucket(bucket)
+	return c.Storage.RecomputeBucket(ctx, bucket)
+}
+
+func (c *CachingStorage) CleanupTempFiles(ctx context.Context, bucket string, olderThan time.Duration) (int, error) {
+	defer c.invalidateBucket(bucket)
+	return c.Storage.CleanupTempFiles(ctx, bucket, olderThan)
+}
+
+// Bucket settings don't change object data, but are invalidated all the
+// same so that no write leaves a cached read behind
+
+func (c *CachingStorage) SetBucketQuota(ctx context.Context, bucket string, quota int64) error {
+	defer c.invalidateBucket(bucket)
+	return c.Storage.SetBucketQuota(ctx, bucket, quota)
+}
+
+func (c *CachingStorage) SetBucketDefaultObject(ctx context.Context, bucket, key string) error {
+	defer c.invalidateBucket(bucket)
+	return c.Storage.SetBucketDefaultObject(ctx, bucket, key)
+}
+
+func (c *CachingStorage) SetBucketDefaultCacheControl(ctx context.Context, bucket, value string) error {
+	defer c.invalidateBucket(bucket)
+	return c.Storage.SetBucketDefaultCacheControl(ctx, bucket, value)
+}
+
+func (c *CachingStorage) SetBucketMaxObjectSize(ctx context.Context, bucket string, size int64) error {
+	defer c.invalidateBucket(bucket)
+	return c.Storage.SetBucketMaxObjectSize(ctx, bucket, size)
+}
+
+// lookup returns a cached object, moving it to the front of the LRU.
+// Expired entries are dropped.
+func (c *CachingStorage) lookup(k cacheKey) ([]byte, *model.ObjectMetadata, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[k]
+	if !ok {
+		return nil, nil, false
+	}
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(el)
+		return nil, nil, false
+	}
+	c.lru.MoveToFront(el)
+
+	meta := entry.meta
+	meta.Tags = maps.Clone(entry.meta.Tags)
+	return entry.data, &meta, true
+}
+
+// add caches an object read by read, evicting the least recently used
+// entries until it fits. Objects over MaxObjectSize are only shared, and a
+// read overtaken by a write is not kept.
+func (c *CachingStorage) add(k cacheKey, data []byte, meta model.ObjectMetadata, read *diskRead) {
+	if int64(len(data)) > c.opts.MaxObjectSize || int64(len(data)) > c.opts.MaxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if read.stale || c.reads[k] != read {
+		return
+	}
+	if el, ok := c.entries[k]; ok {
+		c.remove(el)
+	}
+	for c.size+int64(len(data)) > c.opts.MaxBytes {
+		c.remove(c.lru.Back())
+	}
+
+	meta.Tags = maps.Clone(meta.Tags)
+	c.entries[k] = c.lru.PushFront(&cacheEntry{
+		key:     k,
+		data:    data,
+		meta:    meta,
+		expires: time.Now().Add(c.opts.TTL),
+	})
+	c.size += int64(len(data))
+}
+
+func (c *CachingStorage) invalidate(bucket, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	k := cacheKey{bucket, key}
+	if read, ok := c.reads[k]; ok {
+		read.stale = true
+		delete(c.reads, k)
+	}
+	if el, ok := c.entries[k]; ok {
+		c.remove(el)
+	}
+}
+
+func (c *CachingStorage) invalidateBucket(bucket string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, read := range c.reads {
+		if k.bucket == bucket {
+			read.stale = true
+			delete(c.reads, k)
+		}
+	}
+	for k, el := range c.entries {
+		if k.bucket == bucket {
+			c.remove(el)
+		}
+	}
+}
+
+// remove drops an entry. Callers must hold c.mu.
+func (c *CachingStorage) remove(el *list.Element) {
+	entry := c.lru.Remove(el).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.data))
+}
@@ -0,0 +1,21 @@
+package storage
+
+import "context"
+
+// SetBucketDefaultCacheControl records the Cache-Control sent with objects of
+// the bucket that weren't uploaded with their own. An empty value removes it.
+func (ls *LocalStorage) SetBucketDefaultCacheControl(ctx context.Context, bucket, value string) error {
+	return ls.updateBucketMetadata(bucket, func(meta *bucketMetadata) {
+		meta.DefaultCacheControl = value
+	})
+}
+
+// BucketDefaultCacheControl returns the bucket's default Cache-Control, or ""
+// when none is set.
+func (ls *LocalStorage) BucketDefaultCacheControl(ctx context.Context, bucket string) (string, error) {
+	meta, err := ls.loadBucketMetadata(bucket)
+	if err != nil {
+		return "", err
+	}
+	return meta.DefaultCacheControl, nil
+}
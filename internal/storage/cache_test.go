@@ -0,0 +1,303 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+// countingStorage counts the GetObject calls that reach the wrapped Storage.
+// When gate is set, each call waits for it to be closed after opening the
+// object, announcing itself on opened first.
+type countingStorage struct {
+	Storage
+	gets   atomic.Int32
+	opened chan struct{}
+	gate   chan struct{}
+}
+
+func (s *countingStorage) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, *model.ObjectMetadata, error) {
+	s.gets.Add(1)
+	obj, meta, err := s.Storage.GetObject(ctx, bucket, key)
+	if s.gate != nil {
+		s.opened <- struct{}{}
+		<-s.gate
+	}
+	return obj, meta, err
+}
+
+func newTestCache(t testing.TB, opts CacheOptions) (*CachingStorage, *countingStorage) {
+	t.Helper()
+	ls := New(t.TempDir(), Options{})
+	if err := ls.CreateBucket(context.Background(), "test"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	disk := &countingStorage{Storage: ls}
+	return NewCaching(disk, opts), disk
+}
+
+var testCacheOptions = CacheOptions{MaxObjectSize: 1024, MaxBytes: 1 << 20, TTL: time.Minute}
+
+func TestCacheServesRepeatedReadsFromMemory(t *testing.T) {
+	cache, disk := newTestCache(t, testCacheOptions)
+	mustPut(t, cache, "test", "k", "hello")
+
+	for i := 0; i < 3; i++ {
+		if got := readObject(t, cache, "test", "k"); got != "hello" {
+			t.Fatalf("read %d = %q, want hello", i, got)
+		}
+	}
+	if n := disk.gets.Load(); n != 1 {
+		t.Errorf("disk reads = %d, want 1", n)
+	}
+}
+
+func TestCacheInvalidatedByWrites(t *testing.T) {
+	ctx := context.Background()
+	writes := map[string]func(c *CachingStorage) error{
+		"PutObject": func(c *CachingStorage) error {
+			_, err := c.PutObject(ctx, "test", "k", strings.NewReader("new"), 3, "text/plain")
+			return err
+		},
+		"PutObjectWithMetadata": func(c *CachingStorage) error {
+			_, err := c.PutObjectWithMetadata(ctx, "test", "k", strings.NewReader("new"), 3, model.ObjectMetadata{})
+			return err
+		},
+		"DeleteObject": func(c *CachingStorage) error {
+			return c.DeleteObject(ctx, "test", "k")
+		},
+		"CopyObject": func(c *CachingStorage) error {
+			mustPut(t, c, "test", "src", "new")
+			_, err := c.CopyObject(ctx, "test", "src", "test", "k", nil)
+			return err
+		},
+		"TruncateObject": func(c *CachingStorage) error {
+			_, err := c.TruncateObject(ctx, "test", "k", 1, false)
+			return err
+		},
+		"RecomputeObject": func(c *CachingStorage) error {
+			_, err := c.RecomputeObject(ctx, "test", "k")
+			return err
+		},
+		"PutObjectTagging": func(c *CachingStorage) error {
+			return c.PutObjectTagging(ctx, "test", "k", map[string]string{"a": "b"})
+		},
+		"DeleteObjectTagging": func(c *CachingStorage) error {
+			return c.DeleteObjectTagging(ctx, "test", "k")
+		},
+		"RecomputeBucket": func(c *CachingStorage) error {
+			_, err := c.RecomputeBucket(ctx, "test")
+			return err
+		},
+		"CleanupTempFiles": func(c *CachingStorage) error {
+			_, err := c.CleanupTempFiles(ctx, "test", 0)
+			return err
+		},
+		"RenameBucket": func(c *CachingStorage) error {
+			return c.RenameBucket(ctx, "test", "renamed")
+		},
+		"SetBucketQuota": func(c *CachingStorage) error {
+			return c.SetBucketQuota(ctx, "test", 1<<20)
+		},
+		"SetBucketDefaultObject": func(c *CachingStorage) error {
+			return c.SetBucketDefaultObject(ctx, "test", "k")
+		},
+		"SetBucketDefaultCacheControl": func(c *CachingStorage) error {
+			return c.SetBucketDefaultCacheControl(ctx, "test", "no-cache")
+		},
+		"SetBucketMaxObjectSize": func(c *CachingStorage) error {
+			return c.SetBucketMaxObjectSize(ctx, "test", 1<<20)
+		},
+	}
+
+	for name, write := range writes {
+		t.Run(name, func(t *testing.T) {
+			cache, disk := newTestCache(t, testCacheOptions)
+			mustPut(t, cache, "test", "k", "old")
+			readObject(t, cache, "test", "k")
+
+			if err := write(cache); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			before := disk.gets.Load()
+			if obj, _, err := cache.GetObject(ctx, "test", "k"); err == nil {
+				obj.Close()
+			}
+			if disk.gets.Load() == before {
+				t.Errorf("read after %s was served from the cache", name)
+			}
+		})
+	}
+}
+
+// A read that a write to the same key overtakes must not fill the cache, but
+// writes to other keys don't stop it
+func TestCacheReadOvertakenByWrite(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		key        string
+		wantCached bool
+	}{
+		{"same key", "k", false},
+		{"other key", "other", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cache, disk := newTestCache(t, testCacheOptions)
+			mustPut(t, cache, "test", "k", "old")
+			disk.opened, disk.gate = make(chan struct{}), make(chan struct{})
+
+			done := make(chan string)
+			go func() {
+				obj, _, err := cache.GetObject(context.Background(), "test", "k")
+				if err != nil {
+					done <- err.Error()
+					return
+				}
+				defer obj.Close()
+				data, _ := io.ReadAll(obj)
+				done <- string(data)
+			}()
+			<-disk.opened
+			mustPut(t, cache, "test", tc.key, "new")
+			close(disk.gate)
+			if got := <-done; got != "old" {
+				t.Fatalf("overtaken read = %q, want old", got)
+			}
+
+			disk.gate = nil
+			before := disk.gets.Load()
+			want := "old"
+			if tc.key == "k" {
+				want = "new"
+			}
+			if got := readObject(t, cache, "test", "k"); got != want {
+				t.Errorf("read after write = %q, want %q", got, want)
+			}
+			if cached := disk.gets.Load() == before; cached != tc.wantCached {
+				t.Errorf("served from cache = %v, want %v", cached, tc.wantCached)
+			}
+		})
+	}
+}
+
+func TestCacheCoalescesConcurrentReads(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts CacheOptions
+	}{
+		{"cache enabled", testCacheOptions},
+		{"cache disabled", CacheOptions{CoalesceMaxObjectSize: 1024}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cache, disk := newTestCache(t, tc.opts)
+			mustPut(t, cache, "test", "k", "hello")
+			disk.opened, disk.gate = make(chan struct{}, 10), make(chan struct{})
+
+			const readers = 10
+			var wg sync.WaitGroup
+			results := make([]string, readers)
+			read := func(i int) {
+				defer wg.Done()
+				obj, _, err := cache.GetObject(context.Background(), "test", "k")
+				if err != nil {
+					results[i] = err.Error()
+					return
+				}
+				defer obj.Close()
+				data, _ := io.ReadAll(obj)
+				results[i] = string(data)
+			}
+			wg.Add(readers)
+			go read(0)
+			<-disk.opened
+			for i := 1; i < readers; i++ {
+				go read(i)
+			}
+			// Give the other readers time to join the read in progress
+			time.Sleep(50 * time.Millisecond)
+			close(disk.gate)
+			wg.Wait()
+
+			for i, got := range results {
+				if got != "hello" {
+					t.Errorf("reader %d got %q, want hello", i, got)
+				}
+			}
+			if n := disk.gets.Load(); n != 1 {
+				t.Errorf("disk reads = %d, want 1", n)
+			}
+		})
+	}
+}
+
+func TestCacheDisabledDoesNotKeepObjects(t *testing.T) {
+	cache, disk := newTestCache(t, CacheOptions{CoalesceMaxObjectSize: 1024})
+	mustPut(t, cache, "test", "k", "hello")
+	readObject(t, cache, "test", "k")
+	readObject(t, cache, "test", "k")
+	if n := disk.gets.Load(); n != 2 {
+		t.Errorf("disk reads = %d, want 2", n)
+	}
+}
+
+func TestCacheStreamsLargeObjects(t *testing.T) {
+	cache, disk := newTestCache(t, CacheOptions{MaxObjectSize: 4, MaxBytes: 1 << 20, TTL: time.Minute})
+	mustPut(t, cache, "test", "k", "larger than four bytes")
+
+	obj, _, err := cache.GetObject(context.Background(), "test", "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obj.Close()
+	if _, ok := obj.(cachedObject); ok {
+		t.Error("large object was buffered")
+	}
+	if got := readObject(t, cache, "test", "k"); got != "larger than four bytes" {
+		t.Errorf("read = %q", got)
+	}
+	if n := disk.gets.Load(); n != 2 {
+		t.Errorf("disk reads = %d, want 2", n)
+	}
+}
+
+func BenchmarkCachingGetObject(b *testing.B) {
+	for _, tc := range []struct {
+		name string
+		opts CacheOptions
+	}{
+		{"disk", CacheOptions{}},
+		{"cached", CacheOptions{MaxObjectSize: 64 << 10, MaxBytes: 64 << 20, TTL: time.Hour}},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			cache, _ := newTestCache(b, tc.opts)
+			body := strings.Repeat("x", 16<<10)
+			for i := 0; i < 16; i++ {
+				if _, err := cache.PutObject(context.Background(), "test", fmt.Sprintf("k%d", i), strings.NewReader(body), int64(len(body)), "text/plain"); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.SetBytes(int64(len(body)))
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					obj, _, err := cache.GetObject(context.Background(), "test", fmt.Sprintf("k%d", i%16))
+					if err != nil {
+						b.Error(err)
+						return
+					}
+					io.Copy(io.Discard, obj)
+					obj.Close()
+					i++
+				}
+			})
+		})
+	}
+}
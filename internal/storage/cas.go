@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+)
+
+// blobsDir is the per-bucket directory holding content-addressed data when
+// Options.ContentAddressed is set. Each object's data file is a hard link to
+// the blob named after its SHA-256, so identical uploads share their bytes
+// and a blob is freed once no object links to it.
+const blobsDir = ".blobs"
+
+// blobRefsExt names the file next to a blob holding its reference count, on
+// platforms where hard link counts can't be read (see hardLinkCounts).
+const blobRefsExt = ".refs"
+
+func (ls *LocalStorage) blobPath(bucket, sum string) string {
+	return filepath.Join(ls.basePath, bucket, blobsDir, sum[:2], sum)
+}
+
+// linkBlob makes path, a freshly written data file with the given SHA-256, a
+// link to the bucket's blob for that content. If the blob already exists the
+// new copy is replaced by a link to it; otherwise the file becomes the blob.
+func (ls *LocalStorage) linkBlob(bucket, sum, path string) error {
+	unlock := ls.blobs.Lock(bucket + "/" + sum)
+	defer unlock()
+
+	blob := ls.blobPath(bucket, sum)
+	if err := ls.mkdirAll(filepath.Dir(blob)); err != nil {
+		return err
+	}
+
+	err := ls.fs.Link(path, blob)
+	if err == nil {
+		return ls.addBlobRef(blob, true)
+	}
+	if !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+
+	// Identical content is already stored, link to it instead. The link is
+	// made next to path first so path is never missing if this fails.
+	linkPath := path + ".link"
+	if err := ls.fs.Link(blob, linkPath); err != nil {
+		return err
+	}
+	if err := ls.fs.Rename(linkPath, path); err != nil {
+		return err
+	}
+	return ls.addBlobRef(blob, false)
+}
+
+// releaseBlob removes a blob once it is only referenced by the blob store
+// itself, i.e. the last object linking to it is gone.
+func (ls *LocalStorage) releaseBlob(bucket, sum string) error {
+	if sum == "" {
+		return nil
+	}
+
+	unlock := ls.blobs.Lock(bucket + "/" + sum)
+	defer unlock()
+
+	blob := ls.blobPath(bucket, sum)
+	info, err := ls.fs.Stat(blob)
+	if err != nil {
+		if isNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if hardLinkCounts {
+		if linkCount(info) > 1 {
+			return nil
+		}
+		return ls.fs.Remove(blob)
+	}
+
+	refs, err := ls.blobRefs(blob)
+	if isNotExist(err) {
+		// Not counted, so it can't be known to be unreferenced
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if refs > 1 {
+		return ls.writeJSON(blob+blobRefsExt, refs-1)
+	}
+	if err := ls.fs.Remove(blob); err != nil {
+		return err
+	}
+	return ls.fs.Remove(blob + blobRefsExt)
+}
+
+// addBlobRef counts a new object linking to blob where link counts can't be
+// read. Blobs stored before they were counted stay uncounted, and are kept.
+func (ls *LocalStorage) addBlobRef(blob string, created bool) error {
+	if hardLinkCounts {
+		return nil
+	}
+	if created {
+		return ls.writeJSON(blob+blobRefsExt, 1)
+	}
+	refs, err := ls.blobRefs(blob)
+	if isNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return ls.writeJSON(blob+blobRefsExt, refs+1)
+}
+
+// blobRefs reads the reference count kept next to blob
+func (ls *LocalStorage) blobRefs(blob string) (int64, error) {
+	var refs int64
+	err := ls.readJSON(blob+blobRefsExt, &refs)
+	return refs, err
+}
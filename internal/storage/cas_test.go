@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+)
+
+// withRefCountFiles runs a test with the reference count files used where
+// hard link counts aren't available
+func withRefCountFiles(t *testing.T) {
+	t.Helper()
+	saved := hardLinkCounts
+	hardLinkCounts = false
+	t.Cleanup(func() { hardLinkCounts = saved })
+}
+
+func blobExists(t *testing.T, ls *LocalStorage, content string) bool {
+	t.Helper()
+	sum := sha256.Sum256([]byte(content))
+	_, err := os.Stat(ls.blobPath("test", hex.EncodeToString(sum[:])))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return err == nil
+}
+
+func testBlobRefCounting(t *testing.T) {
+	ctx := context.Background()
+	ls := newTestStorage(t, Options{ContentAddressed: true})
+
+	mustPut(t, ls, "test", "a.txt", "same bytes")
+	mustPut(t, ls, "test", "b.txt", "same bytes")
+
+	a, _ := os.Stat(ls.objectPath("test", "a.txt"))
+	b, _ := os.Stat(ls.objectPath("test", "b.txt"))
+	if !os.SameFile(a, b) {
+		t.Fatal("identical objects don't share their data")
+	}
+
+	// Overwriting with the same content keeps the reference count right
+	mustPut(t, ls, "test", "a.txt", "same bytes")
+
+	if err := ls.DeleteObject(ctx, "test", "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if !blobExists(t, ls, "same bytes") {
+		t.Fatal("blob freed while b.txt still uses it")
+	}
+	if got := readObject(t, ls, "test", "b.txt"); got != "same bytes" {
+		t.Fatalf("b.txt = %q", got)
+	}
+
+	if err := ls.DeleteObject(ctx, "test", "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if blobExists(t, ls, "same bytes") {
+		t.Fatal("blob kept after its last object was deleted")
+	}
+
+	// Replacing content frees the old blob
+	mustPut(t, ls, "test", "c.txt", "old")
+	mustPut(t, ls, "test", "c.txt", "new")
+	if blobExists(t, ls, "old") || !blobExists(t, ls, "new") {
+		t.Fatal("overwrite didn't move c.txt to the new blob")
+	}
+}
+
+func TestBlobRefCounting(t *testing.T) {
+	t.Run("link counts", testBlobRefCounting)
+	t.Run("count files", func(t *testing.T) {
+		withRefCountFiles(t)
+		testBlobRefCounting(t)
+	})
+}
+
+func testConcurrentBlobUse(t *testing.T) {
+	ctx := context.Background()
+	ls := newTestStorage(t, Options{ContentAddressed: true})
+
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := fmt.Sprintf("obj-%d", i)
+			// Two contents, so writers of different blobs run side by side
+			mustPut(t, ls, "test", key, fmt.Sprintf("content %d", i%2))
+			if i%4 == 0 {
+				if err := ls.DeleteObject(ctx, "test", key); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := range 16 {
+		if i%4 == 0 {
+			continue
+		}
+		if got, want := readObject(t, ls, "test", fmt.Sprintf("obj-%d", i)), fmt.Sprintf("content %d", i%2); got != want {
+			t.Fatalf("obj-%d = %q, want %q", i, got, want)
+		}
+	}
+
+	// Deleting the rest frees both blobs
+	for i := range 16 {
+		if i%4 != 0 {
+			if err := ls.DeleteObject(ctx, "test", fmt.Sprintf("obj-%d", i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if blobExists(t, ls, "content 0") || blobExists(t, ls, "content 1") {
+		t.Fatal("blobs kept after every object was deleted")
+	}
+}
+
+func TestConcurrentBlobUse(t *testing.T) {
+	t.Run("link counts", testConcurrentBlobUse)
+	t.Run("count files", func(t *testing.T) {
+		withRefCountFiles(t)
+		testConcurrentBlobUse(t)
+	})
+}
+
+func testRestoreOverLiveObject(t *testing.T) {
+	ctx := context.Background()
+	ls := newTestStorage(t, Options{ContentAddressed: true, SoftDelete: true})
+
+	mustPut(t, ls, "test", "c.txt", "old")
+	if err := ls.DeleteObject(ctx, "test", "c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, ls, "test", "c.txt", "new")
+	if err := ls.RestoreObject(ctx, "test", "c.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readObject(t, ls, "test", "c.txt"); got != "old" {
+		t.Fatalf("c.txt = %q after restore, want %q", got, "old")
+	}
+	if blobExists(t, ls, "new") || !blobExists(t, ls, "old") {
+		t.Fatal("restore didn't move c.txt back to the old blob")
+	}
+}
+
+func TestRestoreOverLiveObject(t *testing.T) {
+	t.Run("link counts", testRestoreOverLiveObject)
+	t.Run("count files", func(t *testing.T) {
+		withRefCountFiles(t)
+		testRestoreOverLiveObject(t)
+	})
+}
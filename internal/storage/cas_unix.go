@@ -0,0 +1,20 @@
+//go:build unix
+
+package storage
+
+import (
+	"os"
+	"syscall"
+)
+
+// hardLinkCounts reports whether linkCount works here, so blobs are freed
+// once their link count drops to one rather than by a separate count.
+var hardLinkCounts = true
+
+// linkCount returns the number of hard links to a file
+func linkCount(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Nlink)
+	}
+	return 2
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// tempDir is the per-bucket directory every write stages its temporary files
+// in before renaming them into place. Object keys cannot start with ".", so
+// temp files never share a name with an object.
+const tempDir = ".tmp"
+
+// tempDirOf returns the temp area of the bucket path lies in
+func (ls *LocalStorage) tempDirOf(path string) string {
+	rel, err := filepath.Rel(ls.basePath, path)
+	if err != nil {
+		return filepath.Join(filepath.Dir(path), tempDir)
+	}
+	bucket, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	return filepath.Join(ls.basePath, bucket, tempDir)
+}
+
+// CleanupTempFiles removes temporary files (data, metadata and link files)
+// left in a bucket's temp area by interrupted writes, and returns how many
+// were removed. Only files last modified more than olderThan ago are
+// touched, so the temp files of uploads still in progress survive.
+func (ls *LocalStorage) CleanupTempFiles(ctx context.Context, bucket string, olderThan time.Duration) (int, error) {
+	unlock := ls.rLockBucket(bucket)
+	defer unlock()
+
+	tempPath := filepath.Join(ls.basePath, bucket, tempDir)
+	cutoff := time.Now().Add(-olderThan)
+	removed := 0
+
+	err := ls.fs.Walk(tempPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if isNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || info.ModTime().After(cutoff) {
+			return nil
+		}
+
+		if err := ls.fs.Remove(path); err != nil && !isNotExist(err) {
+			return err
+		}
+		removed++
+		return nil
+	})
+	if err != nil {
+		slog.Error("Failed to clean up temp files", "bucket", bucket, "error", err)
+		return removed, fmt.Errorf("failed to clean up temp files")
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTempFilesStayOutOfObjectNames(t *testing.T) {
+	ctx := context.Background()
+	ls := newTestStorage(t, Options{})
+
+	// Keys that look like the temp files of earlier versions are objects
+	mustPut(t, ls, "test", "tmp-report.csv", "a,b")
+	mustPut(t, ls, "test", "dir/tmp-metadata-1", "x")
+
+	stale := filepath.Join(ls.basePath, "test", tempDir, "tmp-123")
+	fresh := filepath.Join(ls.basePath, "test", tempDir, "tmp-456")
+	for _, path := range []string{stale, fresh} {
+		if err := os.WriteFile(path, []byte("partial"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := ls.CleanupTempFiles(ctx, "test", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Fatalf("removed %d temp files, want 1", removed)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatal("stale temp file kept")
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Fatal("recent temp file removed")
+	}
+
+	objects, err := ls.ListObjects(ctx, "test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 || objects[0].Key != "dir/tmp-metadata-1" || objects[1].Key != "tmp-report.csv" {
+		t.Fatalf("objects = %+v, want both tmp- keys", objects)
+	}
+	if got := readObject(t, ls, "test", "tmp-report.csv"); got != "a,b" {
+		t.Fatalf("tmp-report.csv = %q", got)
+	}
+
+	stats, err := ls.BucketStats(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.ObjectCount != 2 {
+		t.Fatalf("ObjectCount = %d, want 2", stats.ObjectCount)
+	}
+}
+
+func TestWritesLeaveNoTempFiles(t *testing.T) {
+	ls := newTestStorage(t, Options{ContentAddressed: true})
+
+	mustPut(t, ls, "test", "a/b.txt", "hello")
+	mustPut(t, ls, "test", "c.txt", "hello")
+
+	entries, err := os.ReadDir(filepath.Join(ls.basePath, "test", tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("temp area holds %d files after successful writes", len(entries))
+	}
+}
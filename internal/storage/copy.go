@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+// CopyObject copies an object's bytes to another key (possibly in another
+// bucket). With a nil override the source's content type, storage class and
+// tags are kept; otherwise they are replaced by the override's. Size and ETag always match
+// the source since the bytes are identical.
+func (ls *LocalStorage) CopyObject(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string, override *model.ObjectMetadata) (*model.ObjectMetadata, error) {
+	// The source stays readable after its lock is released (and even if it
+	// is replaced meanwhile) because we hold an open file descriptor.
+	src, srcMetadata, err := ls.GetObject(ctx, srcBucket, srcKey)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	template := model.ObjectMetadata{
+		ContentType:      srcMetadata.ContentType,
+		StorageClass:     srcMetadata.StorageClass,
+		Tags:             srcMetadata.Tags,
+		RedirectLocation: srcMetadata.RedirectLocation,
+		CacheControl:     srcMetadata.CacheControl,
+	}
+	if override != nil {
+		template.ContentType = override.ContentType
+		template.StorageClass = override.StorageClass
+		template.Tags = override.Tags
+		template.RedirectLocation = override.RedirectLocation
+		template.CacheControl = override.CacheControl
+	}
+
+	return ls.putObject(ctx, dstBucket, dstKey, src, srcMetadata.Size, template)
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// reserveObjectSlot accounts for a new key being added to a bucket, failing
+// with ErrTooManyObjects when MaxObjectsPerBucket is already reached. The
+// count is computed with a single walk the first time a capped bucket is
+// written to and then kept up to date by the mutating operations. Callers
+// must hold the object lock and release the slot with adjustCount(-1) if the
+// write fails.
+func (ls *LocalStorage) reserveObjectSlot(bucket string) error {
+	ls.countsMu.Lock()
+	defer ls.countsMu.Unlock()
+
+	if ls.opts.MaxObjectsPerBucket == 0 {
+		if _, ok := ls.counts[bucket]; ok {
+			ls.counts[bucket]++
+		}
+		return nil
+	}
+
+	n, ok := ls.counts[bucket]
+	if !ok {
+		var err error
+		if n, err = ls.walkObjectCount(bucket); err != nil {
+			return err
+		}
+	}
+	if n >= ls.opts.MaxObjectsPerBucket {
+		ls.counts[bucket] = n
+		return ErrTooManyObjects
+	}
+	ls.counts[bucket] = n + 1
+	return nil
+}
+
+// adjustCount applies delta to a bucket's cached object count, if it has been
+// computed.
+func (ls *LocalStorage) adjustCount(bucket string, delta int64) {
+	ls.countsMu.Lock()
+	defer ls.countsMu.Unlock()
+
+	if _, ok := ls.counts[bucket]; ok {
+		ls.counts[bucket] += delta
+	}
+}
+
+// resetCount forgets a bucket's cached object count so it is recomputed on
+// next use.
+func (ls *LocalStorage) resetCount(bucket string) {
+	ls.countsMu.Lock()
+	defer ls.countsMu.Unlock()
+
+	delete(ls.counts, bucket)
+}
+
+func (ls *LocalStorage) walkObjectCount(bucket string) (int64, error) {
+	bucketPath := filepath.Join(ls.basePath, bucket)
+	var n int64
+	err := ls.fs.Walk(bucketPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if isNotExist(err) && path != bucketPath {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			if isInternalDir(bucketPath, path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ls.isMetadataFile(path) {
+			return nil
+		}
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// hasObject reports whether a bucket holds at least one object. Directories
+// are read an entry at a time and the search stops at the first object, so a
+// bucket that isn't empty is answered after a few reads however many objects
+// it holds. Only directories left empty by deletes are read in full. Callers
+// must hold the bucket lock.
+func (ls *LocalStorage) hasObject(bucket string) (bool, error) {
+	bucketPath := filepath.Join(ls.basePath, bucket)
+	return ls.dirHasObject(bucketPath, bucketPath)
+}
+
+func (ls *LocalStorage) dirHasObject(bucketPath, dir string) (bool, error) {
+	f, err := ls.fs.Open(dir)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	for {
+		entries, err := f.ReadDir(1)
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		entry := entries[0]
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			if isInternalDir(bucketPath, path) {
+				continue
+			}
+			if found, err := ls.dirHasObject(bucketPath, path); found || err != nil {
+				return found, err
+			}
+			continue
+		}
+		if ls.isMetadataFile(path) {
+			continue
+		}
+		return true, nil
+	}
+}
+
+// isMetadataFile reports whether path, a file in a bucket, holds metadata
+// (an object's or the bucket's own) rather than an object's data. Keys may
+// end in .metadata themselves, so such a file is only metadata when it has
+// no metadata of its own.
+func (ls *LocalStorage) isMetadataFile(path string) bool {
+	if !strings.HasSuffix(path, ".metadata") {
+		return false
+	}
+	_, err := ls.fs.Stat(path + ".metadata")
+	return isNotExist(err)
+}
+
+// objectExists reports whether an object's data file is present. Callers must
+// hold the object lock.
+func (ls *LocalStorage) objectExists(bucket, key string) bool {
+	_, err := ls.fs.Stat(ls.objectPath(bucket, key))
+	return err == nil
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestObjectCountCap(t *testing.T) {
+	ls := newTestStorage(t, Options{MaxObjectsPerBucket: 2})
+
+	mustPut(t, ls, "test", "a", "a")
+	mustPut(t, ls, "test", "b", "b")
+	if err := putSized(ls, "c", "c"); !errors.Is(err, ErrTooManyObjects) {
+		t.Fatalf("PUT of a new key at the cap: %v, want ErrTooManyObjects", err)
+	}
+	if _, err := ls.HeadObject(context.Background(), "test", "c"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("rejected object stored: %v", err)
+	}
+	// Overwrites don't add an object
+	if err := putSized(ls, "a", "new a"); err != nil {
+		t.Fatalf("overwrite at the cap: %v", err)
+	}
+	if got := readObject(t, ls, "test", "a"); got != "new a" {
+		t.Errorf("object = %q after overwrite, want %q", got, "new a")
+	}
+
+	// A delete frees a slot
+	if err := ls.DeleteObject(context.Background(), "test", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := putSized(ls, "c", "c"); err != nil {
+		t.Errorf("PUT after a delete: %v", err)
+	}
+}
+
+func TestObjectCountWalkCountsMetadataNamedKeys(t *testing.T) {
+	ls := newTestStorage(t, Options{})
+	mustPut(t, ls, "test", "notes.metadata", "not metadata")
+	mustPut(t, ls, "test", "a", "a")
+
+	// A fresh instance counts the bucket with a walk on first write
+	capped := New(ls.basePath, Options{MaxObjectsPerBucket: 2})
+	if err := putSized(capped, "b", "b"); !errors.Is(err, ErrTooManyObjects) {
+		t.Fatalf("PUT over a cap filled by notes.metadata: %v, want ErrTooManyObjects", err)
+	}
+	if err := putSized(capped, "notes.metadata", "overwritten"); err != nil {
+		t.Errorf("overwrite of notes.metadata at the cap: %v", err)
+	}
+
+	stats, err := capped.BucketStats(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.ObjectCount != 2 {
+		t.Errorf("ObjectCount = %d, want 2", stats.ObjectCount)
+	}
+	if _, err := os.Stat(filepath.Join(ls.basePath, "test", "b")); !os.IsNotExist(err) {
+		t.Errorf("rejected object stored: %v", err)
+	}
+}
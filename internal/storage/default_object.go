@@ -0,0 +1,21 @@
+package storage
+
+import "context"
+
+// SetBucketDefaultObject records the key served for GET /{bucket} instead of
+// a listing. An empty key restores the listing.
+func (ls *LocalStorage) SetBucketDefaultObject(ctx context.Context, bucket, key string) error {
+	return ls.updateBucketMetadata(bucket, func(meta *bucketMetadata) {
+		meta.DefaultObject = key
+	})
+}
+
+// BucketDefaultObject returns the bucket's default object key, or "" when
+// none is set.
+func (ls *LocalStorage) BucketDefaultObject(ctx context.Context, bucket string) (string, error) {
+	meta, err := ls.loadBucketMetadata(bucket)
+	if err != nil {
+		return "", err
+	}
+	return meta.DefaultObject, nil
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"syscall"
+)
+
+var (
+	// ErrTooManyObjects is returned by PutObject when storing a new key would
+	// exceed Options.MaxObjectsPerBucket.
+	ErrTooManyObjects = errors.New("bucket has reached its maximum number of objects")
+
+	// ErrQuotaExceeded is returned when a write would take a bucket past the
+	// quota set with SetBucketQuota.
+	ErrQuotaExceeded = errors.New("bucket quota exceeded")
+
+	// ErrBucketNotFound and ErrObjectNotFound distinguish a missing bucket
+	// from a missing key in an existing bucket.
+	ErrBucketNotFound = errors.New("bucket not found")
+	ErrObjectNotFound = errors.New("object not found")
+
+	// ErrBucketExists is returned by RenameBucket when the target name is
+	// already taken.
+	ErrBucketExists = errors.New("bucket already exists")
+
+	// ErrUploadSizeMismatch is returned by PutObjectRange when a range names a
+	// different total size than the upload it continues.
+	ErrUploadSizeMismatch = errors.New("total size does not match the staged upload")
+
+	// ErrTruncateExtends is returned by TruncateObject when the size asked
+	// for is larger than the object and extending wasn't allowed.
+	ErrTruncateExtends = errors.New("size is larger than the object")
+
+	// ErrLayoutMismatch is returned by CheckLayout when the storage
+	// directory was written with the other setting of Options.ShardKeys.
+	ErrLayoutMismatch = errors.New("storage directory uses a different SHARD_KEYS layout")
+)
+
+// isNotExist reports whether err means a path does not exist. ENOTDIR covers
+// keys nested under another object, e.g. "a/b" when "a" is a file.
+func isNotExist(err error) bool {
+	return errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR)
+}
+
+// notFoundError picks ErrBucketNotFound or ErrObjectNotFound for an object
+// lookup that failed because a path did not exist.
+func (ls *LocalStorage) notFoundError(bucket string) error {
+	if _, err := ls.fs.Stat(filepath.Join(ls.basePath, bucket)); isNotExist(err) {
+		return ErrBucketNotFound
+	}
+	return ErrObjectNotFound
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"expvar"
+	"time"
+)
+
+// ExpvarRecorder publishes per-operation call counts, error counts and total
+// latency under the "storage" expvar, served at /admin/debug/vars.
+type ExpvarRecorder struct {
+	calls  *expvar.Map
+	errors *expvar.Map
+	micros *expvar.Map
+}
+
+// NewExpvarRecorder registers the "storage" expvar. It panics if called more
+// than once, like expvar.NewMap.
+func NewExpvarRecorder() *ExpvarRecorder {
+	root := expvar.NewMap("storage")
+	rec := &ExpvarRecorder{
+		calls:  new(expvar.Map).Init(),
+		errors: new(expvar.Map).Init(),
+		micros: new(expvar.Map).Init(),
+	}
+	root.Set("calls", rec.calls)
+	root.Set("errors", rec.errors)
+	root.Set("latency_us", rec.micros)
+	return rec
+}
+
+func (r *ExpvarRecorder) Observe(op string, duration time.Duration, err error) {
+	r.calls.Add(op, 1)
+	r.micros.Add(op, duration.Microseconds())
+	if err != nil {
+		r.errors.Add(op, 1)
+	}
+}
@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/retry"
+)
+
+// Transient filesystem errors (see retry.IsRetryable) are retried this many
+// times, starting with retryBackoff between attempts.
+const (
+	retryAttempts = 3
+	retryBackoff  = 50 * time.Millisecond
+)
+
+// FileSystem is the subset of filesystem operations LocalStorage needs. It
+// lets failure paths (disk full, permission denied, failed renames) be
+// exercised without a real broken disk. OSFileSystem is the default.
+type FileSystem interface {
+	CreateTemp(dir, pattern string) (File, error)
+	Open(name string) (File, error)
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	Rename(oldpath, newpath string) error
+	Link(oldname, newname string) error
+	Remove(name string) error
+	RemoveAll(path string) error
+	MkdirAll(path string, perm os.FileMode) error
+	Stat(name string) (os.FileInfo, error)
+	ReadDir(name string) ([]os.DirEntry, error)
+	Walk(root string, fn filepath.WalkFunc) error
+}
+
+// File is an open file returned by a FileSystem. *os.File implements it.
+type File interface {
+	io.Reader
+	io.Writer
+	io.Seeker
+	io.Closer
+	Name() string
+	Stat() (os.FileInfo, error)
+	Sync() error
+	Chmod(mode os.FileMode) error
+	// ReadDir reads up to n entries of an open directory
+	ReadDir(n int) ([]os.DirEntry, error)
+}
+
+// OSFileSystem implements FileSystem with the os package.
+type OSFileSystem struct{}
+
+func (OSFileSystem) CreateTemp(dir, pattern string) (File, error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (OSFileSystem) Open(name string) (File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (OSFileSystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (OSFileSystem) Rename(oldpath, newpath string) error         { return os.Rename(oldpath, newpath) }
+func (OSFileSystem) Link(oldname, newname string) error           { return os.Link(oldname, newname) }
+func (OSFileSystem) Remove(name string) error                     { return os.Remove(name) }
+func (OSFileSystem) RemoveAll(path string) error                  { return os.RemoveAll(path) }
+func (OSFileSystem) MkdirAll(path string, perm os.FileMode) error { return os.MkdirAll(path, perm) }
+func (OSFileSystem) Stat(name string) (os.FileInfo, error)        { return os.Stat(name) }
+func (OSFileSystem) ReadDir(name string) ([]os.DirEntry, error)   { return os.ReadDir(name) }
+
+func (OSFileSystem) Walk(root string, fn filepath.WalkFunc) error {
+	return filepath.Walk(root, fn)
+}
+
+// mkdirAll creates dir and any missing parents with the configured DirMode.
+// Like os.MkdirAll, the process umask still applies.
+func (ls *LocalStorage) mkdirAll(dir string) error {
+	return ls.fs.MkdirAll(dir, ls.opts.DirMode)
+}
+
+// createTemp creates a temporary file in the temp area of the bucket target
+// is in, to be renamed to target once written, and gives it the configured
+// FileMode, since os.CreateTemp always uses 0600. The mode is set explicitly,
+// so it is not reduced by the umask.
+func (ls *LocalStorage) createTemp(target, pattern string) (File, error) {
+	dir := ls.tempDirOf(target)
+	f, err := ls.fs.CreateTemp(dir, pattern)
+	if isNotExist(err) {
+		// Buckets created before the temp area existed get it on first use
+		if err := ls.mkdirAll(dir); err != nil {
+			return nil, err
+		}
+		f, err = ls.fs.CreateTemp(dir, pattern)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(ls.opts.FileMode); err != nil {
+		f.Close()
+		ls.fs.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// syncFile flushes f to stable storage when DurableWrites is enabled.
+func (ls *LocalStorage) syncFile(f File) error {
+	if !ls.opts.DurableWrites {
+		return nil
+	}
+	return f.Sync()
+}
+
+// syncDir flushes dir's entries, making preceding renames into it durable,
+// when DurableWrites is enabled.
+func (ls *LocalStorage) syncDir(dir string) error {
+	if !ls.opts.DurableWrites {
+		return nil
+	}
+	d, err := ls.fs.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// rename moves oldpath to newpath, retrying transient failures such as
+// EBUSY on network filesystems.
+func (ls *LocalStorage) rename(ctx context.Context, oldpath, newpath string) error {
+	return retry.Do(ctx, retryAttempts, retryBackoff, func() error {
+		return ls.fs.Rename(oldpath, newpath)
+	})
+}
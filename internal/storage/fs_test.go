@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+// faultFS is an OSFileSystem that counts Sync calls and fails chosen
+// operations, to exercise durability and failure paths.
+type faultFS struct {
+	OSFileSystem
+
+	mu        sync.Mutex
+	fileSyncs int
+	dirSyncs  int
+
+	// writeErr is returned by writes to files created with CreateTemp
+	writeErr error
+	// renameErr is returned by renames whose destination ends in renameSuffix
+	renameErr    error
+	renameSuffix string
+}
+
+func (f *faultFS) CreateTemp(dir, pattern string) (File, error) {
+	file, err := f.OSFileSystem.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &faultFile{File: file, fs: f, writeErr: f.writeErr}, nil
+}
+
+func (f *faultFS) Open(name string) (File, error) {
+	file, err := f.OSFileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &faultFile{File: file, fs: f}, nil
+}
+
+func (f *faultFS) Rename(oldpath, newpath string) error {
+	if f.renameErr != nil && strings.HasSuffix(newpath, f.renameSuffix) {
+		return f.renameErr
+	}
+	return f.OSFileSystem.Rename(oldpath, newpath)
+}
+
+func (f *faultFS) syncs() (files, dirs int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.fileSyncs, f.dirSyncs
+}
+
+type faultFile struct {
+	File
+	fs       *faultFS
+	writeErr error
+}
+
+func (f *faultFile) Write(p []byte) (int, error) {
+	if f.writeErr != nil {
+		return 0, f.writeErr
+	}
+	return f.File.Write(p)
+}
+
+func (f *faultFile) Sync() error {
+	info, err := f.File.Stat()
+	if err != nil {
+		return err
+	}
+	f.fs.mu.Lock()
+	if info.IsDir() {
+		f.fs.dirSyncs++
+	} else {
+		f.fs.fileSyncs++
+	}
+	f.fs.mu.Unlock()
+	return f.File.Sync()
+}
+
+func TestDurableWritesSync(t *testing.T) {
+	fs := &faultFS{}
+	ls := newTestStorage(t, Options{DurableWrites: true, FS: fs})
+	fs.fileSyncs, fs.dirSyncs = 0, 0
+
+	mustPut(t, ls, "test", "a/b.txt", "hello")
+
+	// The data and metadata files, then the directory they were renamed into
+	files, dirs := fs.syncs()
+	if files < 2 || dirs < 1 {
+		t.Fatalf("syncs = %d files, %d dirs; want at least 2 files and 1 dir", files, dirs)
+	}
+}
+
+func TestWritesNotSyncedByDefault(t *testing.T) {
+	fs := &faultFS{}
+	ls := newTestStorage(t, Options{FS: fs})
+
+	mustPut(t, ls, "test", "a/b.txt", "hello")
+
+	if files, dirs := fs.syncs(); files != 0 || dirs != 0 {
+		t.Fatalf("syncs = %d files, %d dirs; want none", files, dirs)
+	}
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"hash"
+	"io"
+	"runtime"
+	"sync"
+)
+
+const (
+	// asyncHashDepth is how many written chunks may wait to be hashed
+	// before Write blocks
+	asyncHashDepth = 8
+	// asyncHashChunkSize matches io.Copy's buffer; larger writes get a
+	// chunk of their own that isn't pooled
+	asyncHashChunkSize = 32 * 1024
+)
+
+// overlapHashing is whether uploads are hashed on their own goroutines. It
+// takes a spare CPU: on a single one the overlap can't happen, and copying
+// the chunks only adds work.
+var overlapHashing = runtime.GOMAXPROCS(0) > 1
+
+var hashChunkPool = sync.Pool{
+	New: func() any {
+		chunk := make([]byte, 0, asyncHashChunkSize)
+		return &chunk
+	},
+}
+
+// uploadHash is a hash fed while an upload is written to disk. Close must be
+// called once it is no longer needed.
+type uploadHash interface {
+	io.Writer
+	Sum(b []byte) []byte
+	Close()
+}
+
+// newUploadHash returns h hashing on its own goroutine with overlapHashing,
+// and inline otherwise.
+func newUploadHash(h hash.Hash) uploadHash {
+	if overlapHashing {
+		return newAsyncHash(h)
+	}
+	return inlineHash{h}
+}
+
+// inlineHash hashes on the writing goroutine.
+type inlineHash struct {
+	hash.Hash
+}
+
+func (inlineHash) Close() {}
+
+// asyncHash computes a hash on its own goroutine, so hashing an upload
+// overlaps with writing it to disk instead of taking turns with it on the
+// copying goroutine. Writes are copied, since io.Copy reuses its buffer, and
+// queued; Sum waits for the queue to drain.
+type asyncHash struct {
+	hash   hash.Hash
+	chunks chan *[]byte
+	done   chan struct{}
+	close  sync.Once
+}
+
+func newAsyncHash(h hash.Hash) *asyncHash {
+	a := &asyncHash{
+		hash:   h,
+		chunks: make(chan *[]byte, asyncHashDepth),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(a.done)
+		for chunk := range a.chunks {
+			a.hash.Write(*chunk)
+			if cap(*chunk) == asyncHashChunkSize {
+				*chunk = (*chunk)[:0]
+				hashChunkPool.Put(chunk)
+			}
+		}
+	}()
+	return a
+}
+
+// Write queues a copy of p for hashing. Like hash.Hash, it never fails.
+func (a *asyncHash) Write(p []byte) (int, error) {
+	var chunk *[]byte
+	if len(p) <= asyncHashChunkSize {
+		chunk = hashChunkPool.Get().(*[]byte)
+		*chunk = append(*chunk, p...)
+	} else {
+		owned := append([]byte(nil), p...)
+		chunk = &owned
+	}
+	a.chunks <- chunk
+	return len(p), nil
+}
+
+// Sum waits for every written byte to be hashed and appends the digest to
+// b. Nothing may be written afterwards.
+func (a *asyncHash) Sum(b []byte) []byte {
+	a.Close()
+	<-a.done
+	return a.hash.Sum(b)
+}
+
+// Close stops the hashing goroutine once it has drained the queue. It is
+// safe to call after Sum, and must be called when a write is abandoned.
+func (a *asyncHash) Close() {
+	a.close.Do(func() { close(a.chunks) })
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/md5"
+	"testing"
+)
+
+func TestAsyncHashMatchesInline(t *testing.T) {
+	data := make([]byte, 3*asyncHashChunkSize+17)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	want := md5.Sum(data)
+
+	// Writes smaller than, equal to and larger than a pooled chunk
+	for _, size := range []int{1, 1000, asyncHashChunkSize, 2*asyncHashChunkSize + 5} {
+		h := newAsyncHash(md5.New())
+		for rest := data; len(rest) > 0; {
+			n := min(size, len(rest))
+			// io.Copy reuses its buffer, so the chunk is overwritten
+			// once written
+			buf := append([]byte(nil), rest[:n]...)
+			if _, err := h.Write(buf); err != nil {
+				t.Fatal(err)
+			}
+			clear(buf)
+			rest = rest[n:]
+		}
+		if got := h.Sum(nil); !bytes.Equal(got, want[:]) {
+			t.Errorf("%d byte writes: sum %x, want %x", size, got, want)
+		}
+		h.Close()
+	}
+
+	// Abandoned writes stop the goroutine without a Sum
+	h := newAsyncHash(md5.New())
+	h.Write(data)
+	h.Close()
+	<-h.done
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+// ObjectHistory returns the current ETag of an object along with the ETags
+// it replaced, newest first. History is only recorded while
+// Options.ETagHistoryLimit is set.
+func (ls *LocalStorage) ObjectHistory(ctx context.Context, bucket, key string) (*model.ObjectHistory, error) {
+	unlock := ls.rLockObject(bucket, key)
+	defer unlock()
+
+	metadataPath := ls.objectPath(bucket, key) + ".metadata"
+
+	metadata, err := ls.readMetadata(metadataPath)
+	if err != nil {
+		slog.Debug("Failed to read metadata", "path", metadataPath, "error", err)
+		if isNotExist(err) {
+			return nil, ls.notFoundError(bucket)
+		}
+		return nil, fmt.Errorf("failed to read metadata")
+	}
+
+	history := metadata.History
+	if history == nil {
+		history = []model.ETagHistoryEntry{}
+	}
+	return &model.ObjectHistory{
+		Bucket:       bucket,
+		Key:          metadata.Key,
+		ETag:         metadata.ETag,
+		LastModified: metadata.LastModified,
+		History:      history,
+	}, nil
+}
+
+// nextHistory returns the history of an object replacing previous at
+// replacedAt: previous itself followed by its own history, cut to
+// Options.ETagHistoryLimit entries.
+func (ls *LocalStorage) nextHistory(previous *model.ObjectMetadata, replacedAt time.Time) []model.ETagHistoryEntry {
+	limit := ls.opts.ETagHistoryLimit
+	if limit <= 0 || previous == nil {
+		return nil
+	}
+
+	history := make([]model.ETagHistoryEntry, 0, min(len(previous.History)+1, limit))
+	history = append(history, model.ETagHistoryEntry{
+		ETag:         previous.ETag,
+		Size:         previous.Size,
+		LastModified: previous.LastModified,
+		ReplacedAt:   replacedAt,
+	})
+	for _, entry := range previous.History {
+		if len(history) == limit {
+			break
+		}
+		history = append(history, entry)
+	}
+	return history
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"testing"
+)
+
+func etagOf(body string) string {
+	sum := md5.Sum([]byte(body))
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func TestObjectHistory(t *testing.T) {
+	ls := newTestStorage(t, Options{ETagHistoryLimit: 2})
+	ctx := context.Background()
+
+	mustPut(t, ls, "test", "a.txt", "v1")
+	history, err := ls.ObjectHistory(ctx, "test", "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history.ETag != etagOf("v1") || history.History == nil || len(history.History) != 0 {
+		t.Fatalf("new object history = %+v, want the current ETag and no entries", history)
+	}
+
+	// Newest first, capped at the limit
+	for _, body := range []string{"v2", "v3", "v4"} {
+		mustPut(t, ls, "test", "a.txt", body)
+	}
+	history, err = ls.ObjectHistory(ctx, "test", "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history.ETag != etagOf("v4") {
+		t.Errorf("ETag = %s, want that of v4", history.ETag)
+	}
+	want := []string{etagOf("v3"), etagOf("v2")}
+	if len(history.History) != len(want) {
+		t.Fatalf("history has %d entries, want %d", len(history.History), len(want))
+	}
+	for i, entry := range history.History {
+		if entry.ETag != want[i] || entry.Size != 2 {
+			t.Errorf("entry %d = %+v, want ETag %s and size 2", i, entry, want[i])
+		}
+		if entry.ReplacedAt.Before(entry.LastModified) {
+			t.Errorf("entry %d replaced at %v, before it was written at %v", i, entry.ReplacedAt, entry.LastModified)
+		}
+	}
+	if !history.History[0].ReplacedAt.Equal(history.LastModified) {
+		t.Errorf("newest entry replaced at %v, want the current version's %v", history.History[0].ReplacedAt, history.LastModified)
+	}
+
+	// The history isn't part of the object's regular metadata
+	metadata, err := ls.HeadObject(ctx, "test", "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.History != nil {
+		t.Errorf("HeadObject returned history %+v", metadata.History)
+	}
+}
+
+func TestObjectHistoryDisabled(t *testing.T) {
+	ls := newTestStorage(t, Options{})
+	mustPut(t, ls, "test", "a.txt", "v1")
+	mustPut(t, ls, "test", "a.txt", "v2")
+
+	history, err := ls.ObjectHistory(context.Background(), "test", "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.History) != 0 {
+		t.Errorf("history = %+v, want none without a limit", history.History)
+	}
+	if _, err := ls.ObjectHistory(context.Background(), "test", "missing.txt"); err == nil {
+		t.Error("history of a missing object succeeded")
+	}
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+// ListObjectsLimit returns, in key order, at most maxKeys objects whose key
+// starts with prefix and sorts after startAfter, and whether more matched.
+// Only maxKeys+1 keys are held while walking the bucket, so memory stays
+// bounded however large the bucket is. A non-nil match further restricts the
+// objects during the walk, so truncation and start-after stay consistent;
+// it costs a metadata read per key under prefix.
+func (ls *LocalStorage) ListObjectsLimit(ctx context.Context, bucket, prefix, startAfter string, maxKeys int, match func(model.ObjectMetadata) bool) ([]model.ObjectMetadata, bool, error) {
+	unlock := ls.rLockBucket(bucket)
+	defer unlock()
+
+	bucketPath := filepath.Join(ls.basePath, bucket)
+
+	// Max-heap of the smallest keys seen so far
+	keys := &keyHeap{}
+	err := ls.fs.Walk(bucketPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip internal areas, directories and metadata
+		if info.IsDir() {
+			if isInternalDir(bucketPath, path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".metadata") {
+			return nil
+		}
+
+		key, ok := ls.keyForPath(bucketPath, path)
+		if !ok || !strings.HasPrefix(key, prefix) || key <= startAfter {
+			return nil
+		}
+		if match != nil {
+			metadata, err := ls.readMetadata(path + ".metadata")
+			if err != nil {
+				slog.Warn("Failed to read metadata", "key", key, "error", err)
+				return nil
+			}
+			if !match(*metadata) {
+				return nil
+			}
+		}
+
+		if keys.Len() <= maxKeys {
+			heap.Push(keys, key)
+		} else if key < (*keys)[0] {
+			(*keys)[0] = key
+			heap.Fix(keys, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Failed to list objects", "error", err)
+		return nil, false, fmt.Errorf("failed to list objects")
+	}
+
+	sorted := []string(*keys)
+	sort.Strings(sorted)
+	truncated := len(sorted) > maxKeys
+	if truncated {
+		sorted = sorted[:maxKeys]
+	}
+
+	objects := make([]model.ObjectMetadata, 0, len(sorted))
+	for _, key := range sorted {
+		metadata, err := ls.readMetadata(ls.objectPath(bucket, key) + ".metadata")
+		if err != nil {
+			// Log error but continue processing other files
+			slog.Warn("Failed to read metadata", "key", key, "error", err)
+			continue
+		}
+		objects = append(objects, model.ObjectMetadata{
+			Key:              metadata.Key,
+			Size:             metadata.Size,
+			LastModified:     metadata.LastModified,
+			ETag:             metadata.ETag,
+			ContentType:      metadata.ContentType,
+			StorageClass:     metadata.StorageClass,
+			RestoredUntil:    metadata.RestoredUntil,
+			RedirectLocation: metadata.RedirectLocation,
+			Tags:             metadata.Tags,
+		})
+	}
+
+	return objects, truncated, nil
+}
+
+// WalkObjects calls fn with the metadata of every object whose key starts
+// with prefix, in walk order: keys are sorted within each directory, but a
+// directory's objects come before sibling keys that sort ahead of its
+// "/". Nothing is collected, so memory use doesn't grow with the bucket.
+// The walk stops at the first error fn returns, and WalkObjects returns that
+// error as is. The bucket is held shared while fn runs, so fn must not call
+// back into the store: taking the bucket again can deadlock behind a waiting
+// bucket writer.
+func (ls *LocalStorage) WalkObjects(ctx context.Context, bucket, prefix string, fn func(model.ObjectMetadata) error) error {
+	unlock := ls.rLockBucket(bucket)
+	defer unlock()
+
+	bucketPath := filepath.Join(ls.basePath, bucket)
+
+	var fnErr error
+	err := ls.fs.Walk(bucketPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Removed by an object operation since its directory was read
+			if isNotExist(err) && path != bucketPath {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Skip internal areas, directories and metadata
+		if info.IsDir() {
+			if isInternalDir(bucketPath, path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".metadata") {
+			return nil
+		}
+
+		key, ok := ls.keyForPath(bucketPath, path)
+		if !ok || !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		metadata, err := ls.readMetadata(path + ".metadata")
+		if err != nil {
+			// Log error but continue processing other files
+			slog.Warn("Failed to read metadata", "key", key, "error", err)
+			return nil
+		}
+
+		fnErr = fn(model.ObjectMetadata{
+			Key:              metadata.Key,
+			Size:             metadata.Size,
+			LastModified:     metadata.LastModified,
+			ETag:             metadata.ETag,
+			ContentType:      metadata.ContentType,
+			StorageClass:     metadata.StorageClass,
+			RestoredUntil:    metadata.RestoredUntil,
+			RedirectLocation: metadata.RedirectLocation,
+			Tags:             metadata.Tags,
+		})
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		slog.Error("Failed to walk objects", "bucket", bucket, "error", err)
+		return fmt.Errorf("failed to list objects")
+	}
+	return nil
+}
+
+// keyHeap is a max-heap of keys
+type keyHeap []string
+
+func (h keyHeap) Len() int           { return len(h) }
+func (h keyHeap) Less(i, j int) bool { return h[i] > h[j] }
+func (h keyHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *keyHeap) Push(x any)        { *h = append(*h, x.(string)) }
+
+func (h *keyHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
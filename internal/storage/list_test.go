@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+func TestWalkObjectsStopsAtError(t *testing.T) {
+	ls := newTestStorage(t, Options{})
+	for _, key := range []string{"a", "b", "c", "d"} {
+		mustPut(t, ls, "test", key, "x")
+	}
+
+	errStop := errors.New("stop")
+	var seen []string
+	err := ls.WalkObjects(context.Background(), "test", "", func(obj model.ObjectMetadata) error {
+		seen = append(seen, obj.Key)
+		if obj.Key == "b" {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("WalkObjects returned %v, want fn's error as is", err)
+	}
+	if !slices.Equal(seen, []string{"a", "b"}) {
+		t.Errorf("walked %v, want a and b only", seen)
+	}
+}
+
+func TestWalkObjectsStopsWhenCanceled(t *testing.T) {
+	ls := newTestStorage(t, Options{})
+	for _, key := range []string{"a", "b", "c"} {
+		mustPut(t, ls, "test", key, "x")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var seen int
+	err := ls.WalkObjects(ctx, "test", "", func(model.ObjectMetadata) error {
+		seen++
+		cancel()
+		return nil
+	})
+	if err == nil || seen != 1 {
+		t.Errorf("walk canceled after the first object saw %d and returned %v, want 1 and an error", seen, err)
+	}
+}
+
+func TestListObjectsUsesWalk(t *testing.T) {
+	ls := newTestStorage(t, Options{})
+	for _, key := range []string{"docs/b", "docs/a", "img/c"} {
+		mustPut(t, ls, "test", key, "x")
+	}
+
+	objects, err := ls.ListObjects(context.Background(), "test", "docs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, obj := range objects {
+		keys = append(keys, obj.Key)
+	}
+	if !slices.Equal(keys, []string{"docs/a", "docs/b"}) {
+		t.Errorf("ListObjects = %v, want docs/a and docs/b", keys)
+	}
+
+	// A missing bucket fails rather than listing nothing
+	if _, err := ls.ListObjects(context.Background(), "missing", ""); err == nil {
+		t.Error("listing a missing bucket succeeded")
+	}
+}
@@ -0,0 +1,103 @@
+package storage
+
+import "sync"
+
+// keyedLocker hands out a RWMutex per key, creating it on first use and
+// dropping it again once nobody holds or waits for it, so the map only ever
+// contains keys with in-flight operations.
+type keyedLocker struct {
+	mu    sync.Mutex
+	locks map[string]*refLock
+}
+
+type refLock struct {
+	sync.RWMutex
+	refs int
+}
+
+func newKeyedLocker() *keyedLocker {
+	return &keyedLocker{locks: make(map[string]*refLock)}
+}
+
+func (k *keyedLocker) acquire(key string) *refLock {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	l, ok := k.locks[key]
+	if !ok {
+		l = &refLock{}
+		k.locks[key] = l
+	}
+	l.refs++
+	return l
+}
+
+func (k *keyedLocker) release(key string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	l := k.locks[key]
+	l.refs--
+	if l.refs == 0 {
+		delete(k.locks, key)
+	}
+}
+
+// Lock takes key exclusively and returns the matching unlock function.
+func (k *keyedLocker) Lock(key string) func() {
+	l := k.acquire(key)
+	l.Lock()
+	return func() {
+		l.Unlock()
+		k.release(key)
+	}
+}
+
+// RLock takes key shared and returns the matching unlock function.
+func (k *keyedLocker) RLock(key string) func() {
+	l := k.acquire(key)
+	l.RLock()
+	return func() {
+		l.RUnlock()
+		k.release(key)
+	}
+}
+
+// Locking in LocalStorage is two-level: object operations hold their bucket
+// shared and their key exclusively (writes) or shared (reads), while
+// bucket-wide operations hold the bucket exclusively. Buckets are always
+// locked before keys, so the ordering cannot deadlock.
+
+// lockBucket takes a bucket exclusively, waiting out all object operations.
+func (ls *LocalStorage) lockBucket(bucket string) func() {
+	return ls.buckets.Lock(bucket)
+}
+
+// rLockBucket takes a bucket shared, e.g. for listings.
+func (ls *LocalStorage) rLockBucket(bucket string) func() {
+	return ls.buckets.RLock(bucket)
+}
+
+// lockObject serializes writers of a single object.
+func (ls *LocalStorage) lockObject(bucket, key string) func() {
+	unlockBucket := ls.buckets.RLock(bucket)
+	unlockKey := ls.objects.Lock(bucket + "/" + key)
+	return func() {
+		unlockKey()
+		unlockBucket()
+	}
+}
+
+// rLockObject lets readers of a single object proceed together while keeping
+// writers of that object out. Readers that need the data and metadata to
+// match must read both under one rLockObject: a file opened under the lock
+// keeps the bytes its metadata describes even if the object is replaced
+// afterwards.
+func (ls *LocalStorage) rLockObject(bucket, key string) func() {
+	unlockBucket := ls.buckets.RLock(bucket)
+	unlockKey := ls.objects.RLock(bucket + "/" + key)
+	return func() {
+		unlockKey()
+		unlockBucket()
+	}
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Run with -race: object operations on many keys of one bucket interleave
+// with bucket-wide operations, which must neither corrupt objects nor leave
+// locks or counts behind.
+func TestLockStress(t *testing.T) {
+	ctx := context.Background()
+	ls := newTestStorage(t, Options{MaxObjectsPerBucket: 1000})
+	const (
+		writers = 8
+		rounds  = 20
+	)
+
+	var writing, bucketOps sync.WaitGroup
+	errs := make(chan error, writers+4)
+	for g := 0; g < writers; g++ {
+		writing.Add(1)
+		go func(g int) {
+			defer writing.Done()
+			for i := 0; i < rounds; i++ {
+				key := fmt.Sprintf("w%d/k%d", g, i%5)
+				body := fmt.Sprintf("writer %d round %d", g, i)
+				if _, err := ls.PutObject(ctx, "test", key, strings.NewReader(body), int64(len(body)), "text/plain"); err != nil {
+					errs <- fmt.Errorf("PutObject(%s): %w", key, err)
+					return
+				}
+				rc, _, err := ls.GetObject(ctx, "test", key)
+				if err != nil {
+					errs <- fmt.Errorf("GetObject(%s): %w", key, err)
+					return
+				}
+				got, err := io.ReadAll(rc)
+				rc.Close()
+				if err != nil || string(got) != body {
+					errs <- fmt.Errorf("GetObject(%s) = %q, %v, want %q", key, got, err, body)
+					return
+				}
+				if _, err := ls.CopyObject(ctx, "test", key, "test", key+".copy", nil); err != nil {
+					errs <- fmt.Errorf("CopyObject(%s): %w", key, err)
+					return
+				}
+				if i%2 == 0 {
+					if err := ls.DeleteObject(ctx, "test", key+".copy"); err != nil {
+						errs <- fmt.Errorf("DeleteObject(%s.copy): %w", key, err)
+						return
+					}
+				}
+			}
+		}(g)
+	}
+
+	done := make(chan struct{})
+	bucketOp := func(name string, op func() error) {
+		bucketOps.Add(1)
+		go func() {
+			defer bucketOps.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if err := op(); err != nil {
+					errs <- fmt.Errorf("%s: %w", name, err)
+					return
+				}
+			}
+		}()
+	}
+	bucketOp("ListObjects", func() error {
+		_, err := ls.ListObjects(ctx, "test", "")
+		return err
+	})
+	bucketOp("BucketStats", func() error {
+		_, err := ls.BucketStats(ctx, "test")
+		return err
+	})
+	bucketOp("SetBucketQuota", func() error {
+		return ls.SetBucketQuota(ctx, "test", 1<<20)
+	})
+	bucketOp("RecomputeBucket", func() error {
+		_, err := ls.RecomputeBucket(ctx, "test")
+		return err
+	})
+
+	writing.Wait()
+	close(done)
+	bucketOps.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// Each writer leaves its 5 keys and the copies of k0, k2 and k4, last
+	// written in odd rounds
+	objects, err := ls.ListObjects(ctx, "test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := ls.BucketStats(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := writers * 8; len(objects) != want || stats.ObjectCount != int64(want) {
+		t.Errorf("%d objects listed and %d counted, want %d", len(objects), stats.ObjectCount, want)
+	}
+	ls.countsMu.Lock()
+	if n, ok := ls.counts["test"]; ok && n != int64(len(objects)) {
+		t.Errorf("cached object count = %d, want %d", n, len(objects))
+	}
+	ls.countsMu.Unlock()
+
+	for name, locker := range map[string]*keyedLocker{"bucket": ls.buckets, "object": ls.objects} {
+		locker.mu.Lock()
+		if n := len(locker.locks); n != 0 {
+			t.Errorf("%d %s locks left behind", n, name)
+		}
+		locker.mu.Unlock()
+	}
+}
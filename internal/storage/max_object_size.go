@@ -0,0 +1,22 @@
+package storage
+
+import "context"
+
+// SetBucketMaxObjectSize records the largest object, in bytes, that may be
+// uploaded to a bucket, in place of the server-wide limit. Zero removes it.
+// Objects already larger are kept.
+func (ls *LocalStorage) SetBucketMaxObjectSize(ctx context.Context, bucket string, size int64) error {
+	return ls.updateBucketMetadata(bucket, func(meta *bucketMetadata) {
+		meta.MaxObjectSize = size
+	})
+}
+
+// BucketMaxObjectSize returns the bucket's maximum object size, or 0 when
+// the server-wide limit applies.
+func (ls *LocalStorage) BucketMaxObjectSize(ctx context.Context, bucket string) (int64, error) {
+	meta, err := ls.loadBucketMetadata(bucket)
+	if err != nil {
+		return 0, err
+	}
+	return meta.MaxObjectSize, nil
+}
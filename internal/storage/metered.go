@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+// Recorder receives the latency of a single storage operation. op is the
+// Storage method name, e.g. "ListObjects".
+type Recorder interface {
+	Observe(op string, duration time.Duration, err error)
+}
+
+// MeteredStorage wraps a Storage and reports the latency of every call to a
+// Recorder. Reads are timed up to the point the object is opened; streaming
+// the body is not included.
+type MeteredStorage struct {
+	next     Storage
+	recorder Recorder
+}
+
+// NewMetered returns a Storage that forwards to next and reports timings to
+// recorder.
+func NewMetered(next Storage, recorder Recorder) *MeteredStorage {
+	return &MeteredStorage{next: next, recorder: recorder}
+}
+
+// observe reports the time elapsed since start. It is deferred with a pointer
+// to the named error result so the final error is recorded.
+func (m *MeteredStorage) observe(op string, start time.Time, err *error) {
+	m.recorder.Observe(op, time.Since(start), *err)
+}
+
+func (m *MeteredStorage) CreateBucket(ctx context.Context, name string) (err error) {
+	defer m.observe("CreateBucket", time.Now(), &err)
+	return m.next.CreateBucket(ctx, name)
+}
+
+func (m *MeteredStorage) DeleteBucket(ctx context.Context, name string) (err error) {
+	defer m.observe("DeleteBucket", time.Now(), &err)
+	return m.next.DeleteBucket(ctx, name)
+}
+
+func (m *MeteredStorage) BucketExists(ctx context.Context, name string) (exists bool, err error) {
+	defer m.observe("BucketExists", time.Now(), &err)
+	return m.next.BucketExists(ctx, name)
+}
+
+func (m *MeteredStorage) ListBuckets(ctx context.Context) (buckets []string, err error) {
+	defer m.observe("ListBuckets", time.Now(), &err)
+	return m.next.ListBuckets(ctx)
+}
+
+func (m *MeteredStorage) RenameBucket(ctx context.Context, oldName, newName string) (err error) {
+	defer m.observe("RenameBucket", time.Now(), &err)
+	return m.next.RenameBucket(ctx, oldName, newName)
+}
+
+func (m *MeteredStorage) BucketStats(ctx context.Context, bucket string) (stats *model.BucketStats, err error) {
+	defer m.observe("BucketStats", time.Now(), &err)
+	return m.next.BucketStats(ctx, bucket)
+}
+
+func (m *MeteredStorage) SetBucketQuota(ctx context.Context, bucket string, quota int64) (err error) {
+	defer m.observe("SetBucketQuota", time.Now(), &err)
+	return m.next.SetBucketQuota(ctx, bucket, quota)
+}
+
+func (m *MeteredStorage) SetBucketDefaultObject(ctx context.Context, bucket, key string) (err error) {
+	defer m.observe("SetBucketDefaultObject", time.Now(), &err)
+	return m.next.SetBucketDefaultObject(ctx, bucket, key)
+}
+
+func (m *MeteredStorage) BucketDefaultObject(ctx context.Context, bucket string) (key string, err error) {
+	defer m.observe("BucketDefaultObject", time.Now(), &err)
+	return m.next.BucketDefaultObject(ctx, bucket)
+}
+
+func (m *MeteredStorage) SetBucketDefaultCacheControl(ctx context.Context, bucket, value string) (err error) {
+	defer m.observe("SetBucketDefaultCacheControl", time.Now(), &err)
+	return m.next.SetBucketDefaultCacheControl(ctx, bucket, value)
+}
+
+func (m *MeteredStorage) BucketDefaultCacheControl(ctx context.Context, bucket string) (value string, err error) {
+	defer m.observe("BucketDefaultCacheControl", time.Now(), &err)
+	return m.next.BucketDefaultCacheControl(ctx, bucket)
+}
+
+func (m *MeteredStorage) SetBucketMaxObjectSize(ctx context.Context, bucket string, size int64) (err error) {
+	defer m.observe("SetBucketMaxObjectSize", time.Now(), &err)
+	return m.next.SetBucketMaxObjectSize(ctx, bucket, size)
+}
+
+func (m *MeteredStorage) BucketMaxObjectSize(ctx context.Context, bucket string) (size int64, err error) {
+	defer m.observe("BucketMaxObjectSize", time.Now(), &err)
+	return m.next.BucketMaxObjectSize(ctx, bucket)
+}
+
+func (m *MeteredStorage) PutObject(ctx context.Context, bucket, key string, data io.Reader, size int64, contentType string) (meta *model.ObjectMetadata, err error) {
+	defer m.observe("PutObject", time.Now(), &err)
+	return m.next.PutObject(ctx, bucket, key, data, size, contentType)
+}
+
+func (m *MeteredStorage) PutObjectWithMetadata(ctx context.Context, bucket, key string, data io.Reader, size int64, template model.ObjectMetadata) (meta *model.ObjectMetadata, err error) {
+	defer m.observe("PutObjectWithMetadata", time.Now(), &err)
+	return m.next.PutObjectWithMetadata(ctx, bucket, key, data, size, template)
+}
+
+func (m *MeteredStorage) GetObject(ctx context.Context, bucket, key string) (body io.ReadCloser, meta *model.ObjectMetadata, err error) {
+	defer m.observe("GetObject", time.Now(), &err)
+	return m.next.GetObject(ctx, bucket, key)
+}
+
+func (m *MeteredStorage) DeleteObject(ctx context.Context, bucket, key string) (err error) {
+	defer m.observe("DeleteObject", time.Now(), &err)
+	return m.next.DeleteObject(ctx, bucket, key)
+}
+
+func (m *MeteredStorage) ListObjects(ctx context.Context, bucket, prefix string) (objects []model.ObjectMetadata, err error) {
+	defer m.observe("ListObjects", time.Now(), &err)
+	return m.next.ListObjects(ctx, bucket, prefix)
+}
+
+// WalkObjects is timed including the callbacks, so duplicate reports count
+// the time spent grouping objects.
+func (m *MeteredStorage) WalkObjects(ctx context.Context, bucket, prefix string, fn func(model.ObjectMetadata) error) (err error) {
+	defer m.observe("WalkObjects", time.Now(), &err)
+	return m.next.WalkObjects(ctx, bucket, prefix, fn)
+}
+
+func (m *MeteredStorage) ListObjectsLimit(ctx context.Context, bucket, prefix, startAfter string, maxKeys int, match func(model.ObjectMetadata) bool) (objects []model.ObjectMetadata, truncated bool, err error) {
+	defer m.observe("ListObjectsLimit", time.Now(), &err)
+	return m.next.ListObjectsLimit(ctx, bucket, prefix, startAfter, maxKeys, match)
+}
+
+func (m *MeteredStorage) HasObject(ctx context.Context, bucket string) (has bool, err error) {
+	defer m.observe("HasObject", time.Now(), &err)
+	return m.next.HasObject(ctx, bucket)
+}
+
+func (m *MeteredStorage) HeadObject(ctx context.Context, bucket, key string) (meta *model.ObjectMetadata, err error) {
+	defer m.observe("HeadObject", time.Now(), &err)
+	return m.next.HeadObject(ctx, bucket, key)
+}
+
+func (m *MeteredStorage) StatObject(ctx context.Context, bucket, key string) (size int64, err error) {
+	defer m.observe("StatObject", time.Now(), &err)
+	return m.next.StatObject(ctx, bucket, key)
+}
+
+func (m *MeteredStorage) PutObjectRange(ctx context.Context, bucket, key string, start, total int64, data io.Reader, template model.ObjectMetadata) (progress *model.UploadProgress, meta *model.ObjectMetadata, err error) {
+	defer m.observe("PutObjectRange", time.Now(), &err)
+	return m.next.PutObjectRange(ctx, bucket, key, start, total, data, template)
+}
+
+func (m *MeteredStorage) CopyObject(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string, override *model.ObjectMetadata) (meta *model.ObjectMetadata, err error) {
+	defer m.observe("CopyObject", time.Now(), &err)
+	return m.next.CopyObject(ctx, srcBucket, srcKey, dstBucket, dstKey, override)
+}
+
+func (m *MeteredStorage) RestoreObject(ctx context.Context, bucket, key string) (err error) {
+	defer m.observe("RestoreObject", time.Now(), &err)
+	return m.next.RestoreObject(ctx, bucket, key)
+}
+
+func (m *MeteredStorage) RestoreArchivedObject(ctx context.Context, bucket, key string, until time.Time) (meta *model.ObjectMetadata, err error) {
+	defer m.observe("RestoreArchivedObject", time.Now(), &err)
+	return m.next.RestoreArchivedObject(ctx, bucket, key, until)
+}
+
+func (m *MeteredStorage) ObjectHistory(ctx context.Context, bucket, key string) (history *model.ObjectHistory, err error) {
+	defer m.observe("ObjectHistory", time.Now(), &err)
+	return m.next.ObjectHistory(ctx, bucket, key)
+}
+
+func (m *MeteredStorage) TruncateObject(ctx context.Context, bucket, key string, size int64, extend bool) (meta *model.ObjectMetadata, err error) {
+	defer m.observe("TruncateObject", time.Now(), &err)
+	return m.next.TruncateObject(ctx, bucket, key, size, extend)
+}
+
+func (m *MeteredStorage) RecomputeObject(ctx context.Context, bucket, key string) (meta *model.ObjectMetadata, err error) {
+	defer m.observe("RecomputeObject", time.Now(), &err)
+	return m.next.RecomputeObject(ctx, bucket, key)
+}
+
+func (m *MeteredStorage) RecomputeBucket(ctx context.Context, bucket string) (count int, err error) {
+	defer m.observe("RecomputeBucket", time.Now(), &err)
+	return m.next.RecomputeBucket(ctx, bucket)
+}
+
+func (m *MeteredStorage) CleanupTempFiles(ctx context.Context, bucket string, olderThan time.Duration) (removed int, err error) {
+	defer m.observe("CleanupTempFiles", time.Now(), &err)
+	return m.next.CleanupTempFiles(ctx, bucket, olderThan)
+}
+
+func (m *MeteredStorage) ListUploads(ctx context.Context, bucket string) (uploads []model.UploadInfo, err error) {
+	defer m.observe("ListUploads", time.Now(), &err)
+	return m.next.ListUploads(ctx, bucket)
+}
+
+func (m *MeteredStorage) PutObjectTagging(ctx context.Context, bucket, key string, tags map[string]string) (err error) {
+	defer m.observe("PutObjectTagging", time.Now(), &err)
+	return m.next.PutObjectTagging(ctx, bucket, key, tags)
+}
+
+func (m *MeteredStorage) GetObjectTagging(ctx context.Context, bucket, key string) (tags map[string]string, err error) {
+	defer m.observe("GetObjectTagging", time.Now(), &err)
+	return m.next.GetObjectTagging(ctx, bucket, key)
+}
+
+func (m *MeteredStorage) DeleteObjectTagging(ctx context.Context, bucket, key string) (err error) {
+	defer m.observe("DeleteObjectTagging", time.Now(), &err)
+	return m.next.DeleteObjectTagging(ctx, bucket, key)
+}
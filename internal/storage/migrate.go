@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// CopyProgress is reported by CopyAll after each object.
+type CopyProgress struct {
+	Bucket  string
+	Key     string
+	Skipped bool // already present at the destination with the same ETag
+}
+
+// CopyAll copies every bucket and object from src to dst, preserving object
+// metadata and each bucket's settings and creation time. Objects that
+// already exist at dst with a matching ETag are skipped, so an interrupted
+// copy can simply be run again. progress, if not nil, is called after every
+// object.
+func CopyAll(ctx context.Context, src, dst *LocalStorage, progress func(CopyProgress)) error {
+	buckets, err := src.ListBuckets(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, bucket := range buckets {
+		if err := dst.CreateBucket(ctx, bucket); err != nil {
+			return fmt.Errorf("bucket %s: %w", bucket, err)
+		}
+
+		objects, err := src.ListObjects(ctx, bucket, "")
+		if err != nil {
+			return fmt.Errorf("bucket %s: %w", bucket, err)
+		}
+
+		for _, obj := range objects {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			skipped, err := copyObjectTo(ctx, src, dst, bucket, obj.Key)
+			if err != nil {
+				return fmt.Errorf("object %s/%s: %w", bucket, obj.Key, err)
+			}
+			if progress != nil {
+				progress(CopyProgress{Bucket: bucket, Key: obj.Key, Skipped: skipped})
+			}
+		}
+
+		// Only once the objects are in, so a quota can't get in their way
+		if err := copyBucketMetadata(src, dst, bucket); err != nil {
+			return fmt.Errorf("bucket %s: %w", bucket, err)
+		}
+	}
+	return nil
+}
+
+// copyObjectTo copies a single object, reporting whether it was skipped
+// because dst already has it.
+func copyObjectTo(ctx context.Context, src, dst Storage, bucket, key string) (bool, error) {
+	data, metadata, err := src.GetObject(ctx, bucket, key)
+	if err != nil {
+		return false, err
+	}
+	defer data.Close()
+
+	existing, err := dst.HeadObject(ctx, bucket, key)
+	if err != nil && !errors.Is(err, ErrObjectNotFound) {
+		return false, err
+	}
+	if existing != nil && existing.ETag == metadata.ETag {
+		return true, nil
+	}
+
+	// Everything the store doesn't compute itself carries over
+	template := *metadata
+	if _, err := dst.PutObjectWithMetadata(ctx, bucket, key, data, metadata.Size, template); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// copyBucketMetadata gives bucket in dst the settings and creation time it
+// has in src
+func copyBucketMetadata(src, dst *LocalStorage, bucket string) error {
+	var meta bucketMetadata
+	unlockSrc := src.rLockBucket(bucket)
+	err := src.readJSON(filepath.Join(src.basePath, bucket, bucketMetadataFile), &meta)
+	unlockSrc()
+	if isNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read bucket metadata: %w", err)
+	}
+
+	unlock := dst.lockBucket(bucket)
+	defer unlock()
+	if err := dst.writeJSON(filepath.Join(dst.basePath, bucket, bucketMetadataFile), &meta); err != nil {
+		return fmt.Errorf("failed to write bucket metadata: %w", err)
+	}
+	// The quota is cached
+	dst.resetUsage(bucket)
+	return nil
+}
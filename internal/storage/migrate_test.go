@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+func TestCopyAllKeepsMetadataAndSettings(t *testing.T) {
+	ctx := context.Background()
+	src := newTestStorage(t, Options{})
+	template := model.ObjectMetadata{
+		ContentType:      "text/html",
+		StorageClass:     "GLACIER",
+		RedirectLocation: "/new.html",
+		CacheControl:     "max-age=60",
+		Tags:             map[string]string{"env": "prod"},
+	}
+	if _, err := src.PutObjectWithMetadata(ctx, "test", "old.html", strings.NewReader("moved"), 5, template); err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, src, "test", "index.html", "home")
+	for _, err := range []error{
+		src.SetBucketQuota(ctx, "test", 1<<20),
+		src.SetBucketMaxObjectSize(ctx, "test", 1<<10),
+		src.SetBucketDefaultObject(ctx, "test", "index.html"),
+		src.SetBucketDefaultCacheControl(ctx, "test", "no-cache"),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dst := New(t.TempDir(), Options{ShardKeys: true})
+	if err := CopyAll(ctx, src, dst, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	copied, err := dst.HeadObject(ctx, "test", "old.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied.ContentType != template.ContentType || copied.StorageClass != template.StorageClass ||
+		copied.RedirectLocation != template.RedirectLocation || copied.CacheControl != template.CacheControl ||
+		copied.Tags["env"] != "prod" {
+		t.Errorf("copied metadata = %+v, want that of %+v", copied, template)
+	}
+	if got := readObject(t, dst, "test", "old.html"); got != "moved" {
+		t.Errorf("copied %q, want moved", got)
+	}
+
+	srcStats, err := src.BucketStats(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstStats, err := dst.BucketStats(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dstStats.Quota != srcStats.Quota || dstStats.MaxObjectSize != srcStats.MaxObjectSize || !dstStats.CreatedAt.Equal(srcStats.CreatedAt) {
+		t.Errorf("copied bucket stats = %+v, want the settings of %+v", dstStats, srcStats)
+	}
+	if object, _ := dst.BucketDefaultObject(ctx, "test"); object != "index.html" {
+		t.Errorf("default object = %q, want index.html", object)
+	}
+	if cacheControl, _ := dst.BucketDefaultCacheControl(ctx, "test"); cacheControl != "no-cache" {
+		t.Errorf("default Cache-Control = %q, want no-cache", cacheControl)
+	}
+
+	// The copied quota is enforced
+	if _, err := dst.PutObject(ctx, "test", "big", strings.NewReader(""), 2<<20, ""); err != ErrQuotaExceeded {
+		t.Errorf("upload over the copied quota: %v, want ErrQuotaExceeded", err)
+	}
+
+	// A second run has nothing left to copy
+	skipped := 0
+	if err := CopyAll(ctx, src, dst, func(p CopyProgress) {
+		if p.Skipped {
+			skipped++
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if skipped != 2 {
+		t.Errorf("second run skipped %d objects, want 2", skipped)
+	}
+}
@@ -3,260 +3,495 @@ package storage
 import (
 	"context"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
-	"os"
+	"log/slog"
 	"path/filepath"
-	"strings"
+	"sort"
 	"time"
 
 	"github.com/mmvergara/gosss/internal/model"
 )
 
 func (ls *LocalStorage) PutObject(ctx context.Context, bucket, key string, data io.Reader, size int64, contentType string) (*model.ObjectMetadata, error) {
-	ls.mu.Lock()
-	defer ls.mu.Unlock()
+	return ls.putObject(ctx, bucket, key, data, size, model.ObjectMetadata{ContentType: contentType})
+}
+
+// PutObjectWithMetadata is PutObject with every descriptive field (content
+// type, storage class, tags) taken from template.
+func (ls *LocalStorage) PutObjectWithMetadata(ctx context.Context, bucket, key string, data io.Reader, size int64, template model.ObjectMetadata) (*model.ObjectMetadata, error) {
+	return ls.putObject(ctx, bucket, key, data, size, template)
+}
+
+// putObject stores data under key. Descriptive fields (content type, tags,
+// ...) are taken from template; size, ETag and modification time are derived
+// from the data itself. size is the expected length of data, or -1 if it is
+// unknown; a known size over the bucket's quota is refused before data is
+// read.
+func (ls *LocalStorage) putObject(ctx context.Context, bucket, key string, data io.Reader, size int64, template model.ObjectMetadata) (*model.ObjectMetadata, error) {
+	unlock := ls.lockObject(bucket, key)
+	defer unlock()
 
+	return ls.storeObject(ctx, bucket, key, data, size, template)
+}
+
+// storeObject is putObject for callers already holding the object lock.
+func (ls *LocalStorage) storeObject(ctx context.Context, bucket, key string, data io.Reader, size int64, template model.ObjectMetadata) (*model.ObjectMetadata, error) {
 	// Create full path for object and metadata
-	objectPath := filepath.Join(ls.basePath, bucket, key)
+	objectPath := ls.objectPath(bucket, key)
 	metadataPath := objectPath + ".metadata"
 
+	// Only the key's prefixes are created below; the bucket must exist
+	// already, or writing would create it without its metadata or the name
+	// checks of CreateBucket. The object lock holds the bucket lock, so it
+	// can't be deleted meanwhile
+	if _, err := ls.fs.Stat(filepath.Join(ls.basePath, bucket)); err != nil {
+		if isNotExist(err) {
+			return nil, ErrBucketNotFound
+		}
+		return nil, fmt.Errorf("failed to check bucket")
+	}
+
+	if size >= 0 {
+		if err := ls.checkQuota(bucket, size-ls.objectSize(objectPath)); err != nil {
+			if err == ErrQuotaExceeded {
+				return nil, err
+			}
+			slog.Error("Failed to compute bucket size", "error", err)
+			return nil, fmt.Errorf("failed to compute bucket size")
+		}
+	}
+
+	// Overwrites don't change the number of objects in the bucket
+	stored := false
+	if !ls.objectExists(bucket, key) {
+		if err := ls.reserveObjectSlot(bucket); err != nil {
+			if err == ErrTooManyObjects {
+				return nil, err
+			}
+			slog.Error("Failed to count objects", "error", err)
+			return nil, fmt.Errorf("failed to count objects")
+		}
+		defer func() {
+			if !stored {
+				ls.adjustCount(bucket, -1)
+			}
+		}()
+	}
+
 	// Ensure directory exists
-	if err := os.MkdirAll(filepath.Dir(objectPath), 0755); err != nil {
-		log.Printf("Failed to create directories: %v", err)
+	if err := ls.mkdirAll(filepath.Dir(objectPath)); err != nil {
+		slog.Error("Failed to create directories", "error", err)
 		return nil, fmt.Errorf("failed to create directories")
 	}
 
 	// Create temporary file for object data
-	tempFile, err := os.CreateTemp(filepath.Dir(objectPath), "tmp-")
+	tempFile, err := ls.createTemp(objectPath, "tmp-")
 	if err != nil {
-		log.Printf("Failed to create temporary file: %v", err)
+		slog.Error("Failed to create temporary file", "error", err)
 		return nil, fmt.Errorf("failed to create temporary file")
 	}
 	tempPath := tempFile.Name()
-	defer os.Remove(tempPath) // Clean up temp file in case of error
+	defer ls.fs.Remove(tempPath) // Clean up temp file in case of error
+
+	// In content-addressed mode the blob is named after the SHA-256
+	var contentHash uploadHash
+	if ls.opts.ContentAddressed {
+		contentHash = newUploadHash(sha256.New())
+		defer contentHash.Close()
+	}
 
-	// Calculate ETag (MD5) while copying data
-	hash := md5.New()
-	writer := io.MultiWriter(tempFile, hash)
+	// Calculate ETag (MD5) while copying data. Given spare CPUs the hashes
+	// run on their own goroutines, overlapping with the disk writes
+	hash := newUploadHash(md5.New())
+	defer hash.Close()
+	writers := []io.Writer{tempFile, hash}
+	if contentHash != nil {
+		writers = append(writers, contentHash)
+	}
+	writer := io.MultiWriter(writers...)
 
 	written, err := io.Copy(writer, data)
 	if err != nil {
 		tempFile.Close()
-		log.Printf("Failed to write data: %v", err)
+		slog.Error("Failed to write data", "error", err)
+		return nil, fmt.Errorf("failed to write data")
+	}
+	if err := ls.syncFile(tempFile); err != nil {
+		tempFile.Close()
+		slog.Error("Failed to sync data", "error", err)
 		return nil, fmt.Errorf("failed to write data")
 	}
 	tempFile.Close()
 
+	// Charge the bucket's quota for the bytes added, net of the object being
+	// replaced
+	sizeDelta := written - ls.objectSize(objectPath)
+	if err := ls.reserveBytes(bucket, sizeDelta); err != nil {
+		if err == ErrQuotaExceeded {
+			return nil, err
+		}
+		slog.Error("Failed to compute bucket size", "error", err)
+		return nil, fmt.Errorf("failed to compute bucket size")
+	}
+	defer func() {
+		if !stored {
+			ls.adjustBytes(bucket, -sizeDelta)
+		}
+	}()
+
+	// The metadata being replaced, if any
+	var previous *model.ObjectMetadata
+	if contentHash != nil || ls.opts.ETagHistoryLimit > 0 {
+		previous, _ = ls.readMetadata(metadataPath)
+	}
+
 	// Create metadata
-	metadata := model.ObjectMetadata{
-		Key:          key,
-		Size:         written,
-		LastModified: time.Now().UTC(),
-		ETag:         `"` + hex.EncodeToString(hash.Sum(nil)) + `"`,
-		ContentType:  contentType,
+	metadata := template
+	metadata.Key = key
+	metadata.Size = written
+	metadata.LastModified = time.Now().UTC()
+	metadata.ETag = `"` + hex.EncodeToString(hash.Sum(nil)) + `"`
+	metadata.DeletedAt = nil
+	metadata.SHA256 = ""
+	metadata.History = ls.nextHistory(previous, metadata.LastModified)
+
+	// Share the bytes with any identical object already in the bucket
+	if contentHash != nil {
+		metadata.SHA256 = hex.EncodeToString(contentHash.Sum(nil))
+		if err := ls.linkBlob(bucket, metadata.SHA256, tempPath); err != nil {
+			slog.Error("Failed to link blob", "error", err)
+			return nil, fmt.Errorf("failed to store object data")
+		}
+		defer func() {
+			if !stored {
+				ls.fs.Remove(tempPath)
+				ls.releaseBlob(bucket, metadata.SHA256)
+			}
+		}()
 	}
 
 	// Write metadata to temporary file
-	metadataTempFile, err := os.CreateTemp(filepath.Dir(metadataPath), "tmp-metadata-")
+	metadataTempFile, err := ls.createTemp(metadataPath, "tmp-metadata-")
 	if err != nil {
-		log.Printf("Failed to create temporary metadata file: %v", err)
+		slog.Error("Failed to create temporary metadata file", "error", err)
 		return nil, fmt.Errorf("failed to create temporary metadata file")
 	}
 	metadataTempPath := metadataTempFile.Name()
-	defer os.Remove(metadataTempPath) // Clean up temp metadata file in case of error
+	defer ls.fs.Remove(metadataTempPath) // Clean up temp metadata file in case of error
 
 	if err := json.NewEncoder(metadataTempFile).Encode(metadata); err != nil {
 		metadataTempFile.Close()
-		log.Printf("Failed to write metadata: %v", err)
+		slog.Error("Failed to write metadata", "error", err)
+		return nil, fmt.Errorf("failed to write metadata")
+	}
+	if err := ls.syncFile(metadataTempFile); err != nil {
+		metadataTempFile.Close()
+		slog.Error("Failed to sync metadata", "error", err)
 		return nil, fmt.Errorf("failed to write metadata")
 	}
 	metadataTempFile.Close()
 
-	// Atomically move files into place
-	if err := os.Rename(tempPath, objectPath); err != nil {
-		log.Printf("Failed to move object file: %v", err)
+	// Move both files into place. Each rename is atomic but the pair is not;
+	// it is the object lock, which readers take shared, that keeps anyone
+	// from seeing the new data with the old metadata or the reverse.
+	if err := ls.rename(ctx, tempPath, objectPath); err != nil {
+		slog.Error("Failed to move object file", "error", err)
 		return nil, fmt.Errorf("failed to move object file")
 	}
-	if err := os.Rename(metadataTempPath, metadataPath); err != nil {
+	if err := ls.rename(ctx, metadataTempPath, metadataPath); err != nil {
 		// Try to clean up object file if metadata move fails
-		os.Remove(objectPath)
-		log.Printf("Failed to move metadata file: %v", err)
+		ls.fs.Remove(objectPath)
+		slog.Error("Failed to move metadata file", "error", err)
 		return nil, fmt.Errorf("failed to move metadata file")
 	}
+	if err := ls.syncDir(filepath.Dir(objectPath)); err != nil {
+		slog.Error("Failed to sync object directory", "error", err)
+		return nil, fmt.Errorf("failed to sync object directory")
+	}
+
+	stored = true
+
+	// The replaced object may have been the last link to its blob. With the
+	// same content it dropped a reference all the same
+	if contentHash != nil && previous != nil {
+		if err := ls.releaseBlob(bucket, previous.SHA256); err != nil {
+			slog.Warn("Failed to release blob", "bucket", bucket, "error", err)
+		}
+	}
 
+	// The history is only served by ObjectHistory
+	metadata.History = nil
 	return &metadata, nil
 }
 
 func (ls *LocalStorage) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, *model.ObjectMetadata, error) {
-	ls.mu.RLock()
-	defer ls.mu.RUnlock()
+	unlock := ls.rLockObject(bucket, key)
+	defer unlock()
 
-	objectPath := filepath.Join(ls.basePath, bucket, key)
+	objectPath := ls.objectPath(bucket, key)
 	metadataPath := objectPath + ".metadata"
 
 	// Read metadata first
 	metadata, err := ls.readMetadata(metadataPath)
 	if err != nil {
-		log.Printf("Failed to read metadata: %v", err)
+		slog.Debug("Failed to read metadata", "path", metadataPath, "error", err)
+		if isNotExist(err) {
+			return nil, nil, ls.notFoundError(bucket)
+		}
 		return nil, nil, fmt.Errorf("failed to read metadata")
 	}
 
 	// Open the object file
-	file, err := os.Open(objectPath)
+	file, err := ls.fs.Open(objectPath)
 	if err != nil {
-		log.Printf("Failed to open file: %v", err)
+		slog.Debug("Failed to open file", "path", objectPath, "error", err)
+		if isNotExist(err) {
+			return nil, nil, ErrObjectNotFound
+		}
 		return nil, nil, fmt.Errorf("failed to open file")
 	}
 
 	obj := &model.ObjectMetadata{
-		Key:          metadata.Key,
-		Size:         metadata.Size,
-		LastModified: metadata.LastModified,
-		ETag:         metadata.ETag,
-		ContentType:  metadata.ContentType,
+		Key:              metadata.Key,
+		Size:             metadata.Size,
+		LastModified:     metadata.LastModified,
+		ETag:             metadata.ETag,
+		ContentType:      metadata.ContentType,
+		StorageClass:     metadata.StorageClass,
+		RestoredUntil:    metadata.RestoredUntil,
+		RedirectLocation: metadata.RedirectLocation,
+		CacheControl:     metadata.CacheControl,
+		Tags:             metadata.Tags,
 	}
 
 	return file, obj, nil
 }
 
+// ListObjects returns every object whose key starts with prefix, in key
+// order. It holds the whole listing in memory; use WalkObjects for buckets
+// that may be large.
 func (ls *LocalStorage) ListObjects(ctx context.Context, bucket, prefix string) ([]model.ObjectMetadata, error) {
-	ls.mu.RLock()
-	defer ls.mu.RUnlock()
-
 	var objects []model.ObjectMetadata
-	bucketPath := filepath.Join(ls.basePath, bucket)
-
-	err := filepath.Walk(bucketPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Skip directories and metadata files
-		if info.IsDir() || strings.HasSuffix(path, ".metadata") {
-			return nil
-		}
-
-		// Get relative path and check prefix
-		relPath, _ := filepath.Rel(bucketPath, path)
-		if prefix == "" || strings.HasPrefix(relPath, prefix) {
-			// Read metadata for this object
-			metadata, err := ls.readMetadata(path + ".metadata")
-			if err != nil {
-				// Log error but continue processing other files
-				fmt.Printf("Warning: failed to read metadata for %s: %v\n", relPath, err)
-				return nil
-			}
-
-			objects = append(objects, model.ObjectMetadata{
-				Key:          metadata.Key,
-				Size:         metadata.Size,
-				LastModified: metadata.LastModified,
-				ETag:         metadata.ETag,
-				ContentType:  metadata.ContentType,
-			})
-		}
+	err := ls.WalkObjects(ctx, bucket, prefix, func(obj model.ObjectMetadata) error {
+		objects = append(objects, obj)
 		return nil
 	})
-
 	if err != nil {
-		log.Printf("Failed to list objects: %v", err)
-		return nil, fmt.Errorf("failed to list objects")
+		return nil, err
 	}
 
+	// The walk is lexical per directory, which is not the same as key order
+	// ("a/b" sorts after "a-b" by key but is visited first)
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+
 	return objects, nil
 }
 
 func (ls *LocalStorage) HasObject(ctx context.Context, bucket string) (bool, error) {
-	ls.mu.RLock()
-	defer ls.mu.RUnlock()
-
-	bucketPath := filepath.Join(ls.basePath, bucket)
-
-	// Walk through the bucket directory
-	err := filepath.Walk(bucketPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Skip directories and metadata files
-		if info.IsDir() || strings.HasSuffix(path, ".metadata") {
-			return nil
-		}
-
-		// If we find a file, return true
-		return fmt.Errorf("found object") // This will stop the walk and return true
-	})
+	unlock := ls.rLockBucket(bucket)
+	defer unlock()
 
-	if err != nil && err.Error() != "found object" {
-		// If no object was found, return false with no error
-		if err.Error() == "found object" {
-			return true, nil
-		}
-		log.Printf("Failed to check if object exists: %v", err)
+	found, err := ls.hasObject(bucket)
+	if err != nil {
+		slog.Error("Failed to check if object exists", "error", err)
 		return false, fmt.Errorf("failed to check if object exists")
 	}
-
-	return false, nil // No object found
+	return found, nil
 }
 
 func (ls *LocalStorage) HeadObject(ctx context.Context, bucket, key string) (*model.ObjectMetadata, error) {
-	ls.mu.RLock()
-	defer ls.mu.RUnlock()
+	unlock := ls.rLockObject(bucket, key)
+	defer unlock()
 
-	objectPath := filepath.Join(ls.basePath, bucket, key)
+	objectPath := ls.objectPath(bucket, key)
 	metadataPath := objectPath + ".metadata"
 
-	metadata, err := ls.readMetadata(metadataPath)
+	metadata, err := ls.readHeadMetadata(metadataPath)
 	if err != nil {
-		log.Printf("Failed to read metadata: %v", err)
+		slog.Debug("Failed to read metadata", "path", metadataPath, "error", err)
+		if isNotExist(err) {
+			return nil, ls.notFoundError(bucket)
+		}
 		return nil, fmt.Errorf("failed to read metadata")
 	}
+	return metadata, nil
+}
+
+// StatObject returns an object's size for existence checks. Unlike
+// HeadObject it only stats the object's files instead of decoding its
+// metadata, which grows with tags and ETag history.
+func (ls *LocalStorage) StatObject(ctx context.Context, bucket, key string) (int64, error) {
+	unlock := ls.rLockObject(bucket, key)
+	defer unlock()
+
+	// The metadata file is what makes an object exist for HeadObject too
+	objectPath := ls.objectPath(bucket, key)
+	if _, err := ls.fs.Stat(objectPath + ".metadata"); err != nil {
+		slog.Debug("Failed to stat metadata", "path", objectPath, "error", err)
+		if isNotExist(err) {
+			return 0, ls.notFoundError(bucket)
+		}
+		return 0, fmt.Errorf("failed to stat metadata")
+	}
 
-	return &model.ObjectMetadata{
-		Key:          metadata.Key,
-		Size:         metadata.Size,
-		LastModified: metadata.LastModified,
-		ETag:         metadata.ETag,
-		ContentType:  metadata.ContentType,
-	}, nil
+	info, err := ls.fs.Stat(objectPath)
+	if err != nil {
+		slog.Debug("Failed to stat file", "path", objectPath, "error", err)
+		if isNotExist(err) {
+			return 0, ErrObjectNotFound
+		}
+		return 0, fmt.Errorf("failed to stat file")
+	}
+	return info.Size(), nil
 }
 
 // Helper function to read metadata from file
 func (ls *LocalStorage) readMetadata(path string) (*model.ObjectMetadata, error) {
-	file, err := os.Open(path)
+	var metadata model.ObjectMetadata
+	if err := ls.readJSON(path, &metadata); err != nil {
+		return nil, err
+	}
+
+	return &metadata, nil
+}
+
+// readHeadMetadata reads the metadata HeadObject returns from the metadata
+// file at path. It stops at the ETag history instead of decoding it: the
+// history grows with every overwrite and is written last, after every field
+// returned here, so a HEAD costs the same however often an object changed.
+func (ls *LocalStorage) readHeadMetadata(path string) (*model.ObjectMetadata, error) {
+	file, err := ls.fs.Open(path)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
 	var metadata model.ObjectMetadata
-	if err := json.NewDecoder(file).Decode(&metadata); err != nil {
-		return nil, err
+	fields := map[string]any{
+		"key":              &metadata.Key,
+		"size":             &metadata.Size,
+		"lastModified":     &metadata.LastModified,
+		"etag":             &metadata.ETag,
+		"contentType":      &metadata.ContentType,
+		"storageClass":     &metadata.StorageClass,
+		"restoredUntil":    &metadata.RestoredUntil,
+		"redirectLocation": &metadata.RedirectLocation,
+		"cacheControl":     &metadata.CacheControl,
+		"tags":             &metadata.Tags,
 	}
 
+	dec := json.NewDecoder(file)
+	if tok, err := dec.Token(); err != nil {
+		return nil, err
+	} else if tok != json.Delim('{') {
+		return nil, fmt.Errorf("metadata is not a JSON object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		name, _ := tok.(string)
+		if name == "history" {
+			break
+		}
+		field, ok := fields[name]
+		if !ok {
+			var skip json.RawMessage
+			field = &skip
+		}
+		if err := dec.Decode(field); err != nil {
+			return nil, err
+		}
+	}
 	return &metadata, nil
 }
 
+// readJSON decodes the JSON file at path into v
+func (ls *LocalStorage) readJSON(path string, v any) error {
+	file, err := ls.fs.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return json.NewDecoder(file).Decode(v)
+}
+
+// Helper function to atomically replace the metadata file of an object
+func (ls *LocalStorage) writeMetadata(path string, metadata *model.ObjectMetadata) error {
+	return ls.writeJSON(path, metadata)
+}
+
+// writeJSON atomically replaces the file at path with v encoded as JSON
+func (ls *LocalStorage) writeJSON(path string, v any) error {
+	tempFile, err := ls.createTemp(path, "tmp-metadata-")
+	if err != nil {
+		return err
+	}
+	tempPath := tempFile.Name()
+	defer ls.fs.Remove(tempPath) // Clean up temp metadata file in case of error
+
+	if err := json.NewEncoder(tempFile).Encode(v); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := ls.syncFile(tempFile); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+
+	if err := ls.fs.Rename(tempPath, path); err != nil {
+		return err
+	}
+	return ls.syncDir(filepath.Dir(path))
+}
+
 // DeleteObject should also delete the metadata file
 func (ls *LocalStorage) DeleteObject(ctx context.Context, bucket, key string) error {
-	ls.mu.Lock()
-	defer ls.mu.Unlock()
+	unlock := ls.lockObject(bucket, key)
+	defer unlock()
 
-	objectPath := filepath.Join(ls.basePath, bucket, key)
+	objectPath := ls.objectPath(bucket, key)
 	metadataPath := objectPath + ".metadata"
 
+	size := ls.objectSize(objectPath)
+
+	if ls.opts.SoftDelete {
+		if err := ls.moveToTrash(bucket, key); err != nil {
+			return err
+		}
+		ls.adjustCount(bucket, -1)
+		ls.adjustBytes(bucket, -size)
+		return nil
+	}
+
+	// Needed to release the object's blob in content-addressed mode
+	metadata, _ := ls.readMetadata(metadataPath)
+
 	// Delete both object and metadata files
-	if err := os.Remove(objectPath); err != nil {
-		log.Printf("Failed to delete object: %v", err)
+	if err := ls.fs.Remove(objectPath); err != nil {
+		slog.Debug("Failed to delete object", "path", objectPath, "error", err)
 		return fmt.Errorf("failed to delete object")
 	}
+	ls.adjustCount(bucket, -1)
+	ls.adjustBytes(bucket, -size)
 
 	// Try to delete metadata file, but don't error if it doesn't exist
-	_ = os.Remove(metadataPath)
+	_ = ls.fs.Remove(metadataPath)
+
+	if metadata != nil {
+		if err := ls.releaseBlob(bucket, metadata.SHA256); err != nil {
+			slog.Warn("Failed to release blob", "bucket", bucket, "error", err)
+		}
+	}
 
 	return nil
 }
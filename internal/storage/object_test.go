@@ -0,0 +1,356 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+// bucketFiles lists the regular files in bucket other than its metadata,
+// relative to the bucket directory
+func bucketFiles(t *testing.T, ls *LocalStorage, bucket string) []string {
+	t.Helper()
+	root := filepath.Join(ls.basePath, bucket)
+	var files []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() && info.Name() != bucketMetadataFile {
+			rel, _ := filepath.Rel(root, path)
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestPutObjectDiskFull(t *testing.T) {
+	fs := &faultFS{}
+	ls := newTestStorage(t, Options{FS: fs})
+	fs.writeErr = syscall.ENOSPC
+
+	body := "hello"
+	if _, err := ls.PutObject(context.Background(), "test", "a.txt", strings.NewReader(body), int64(len(body)), "text/plain"); err == nil {
+		t.Fatal("PutObject succeeded with a full disk")
+	}
+	if files := bucketFiles(t, ls, "test"); len(files) != 0 {
+		t.Fatalf("files left behind: %v", files)
+	}
+}
+
+func TestPutObjectMetadataRenameRollsBack(t *testing.T) {
+	fs := &faultFS{}
+	ls := newTestStorage(t, Options{FS: fs})
+	fs.renameErr, fs.renameSuffix = syscall.EIO, ".metadata"
+
+	body := "hello"
+	if _, err := ls.PutObject(context.Background(), "test", "a.txt", strings.NewReader(body), int64(len(body)), "text/plain"); err == nil {
+		t.Fatal("PutObject succeeded although the metadata rename failed")
+	}
+	// The data file was renamed into place first and must be removed again
+	if files := bucketFiles(t, ls, "test"); len(files) != 0 {
+		t.Fatalf("files left behind: %v", files)
+	}
+	if _, err := ls.HeadObject(context.Background(), "test", "a.txt"); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("HeadObject = %v, want ErrObjectNotFound", err)
+	}
+}
+
+func TestPutObjectPermissionDenied(t *testing.T) {
+	fs := &faultFS{}
+	ls := newTestStorage(t, Options{FS: fs})
+	fs.renameErr, fs.renameSuffix = os.ErrPermission, "a.txt"
+
+	body := "hello"
+	_, err := ls.PutObject(context.Background(), "test", "a.txt", strings.NewReader(body), int64(len(body)), "text/plain")
+	if err == nil {
+		t.Fatal("PutObject succeeded although the data rename failed")
+	}
+	if files := bucketFiles(t, ls, "test"); len(files) != 0 {
+		t.Fatalf("files left behind: %v", files)
+	}
+	if _, err := os.Stat(filepath.Join(ls.basePath, "test", "a.txt")); !os.IsNotExist(err) {
+		t.Fatalf("object file exists: %v", err)
+	}
+}
+
+// Concurrent overwrites of one key never let a reader see bytes with another
+// upload's ETag
+func TestConcurrentOverwritesKeepETagsConsistent(t *testing.T) {
+	for name, opts := range map[string]Options{
+		"plain":             {},
+		"content addressed": {ContentAddressed: true},
+		"sharded":           {ShardKeys: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ls := newTestStorage(t, opts)
+			ctx := context.Background()
+			mustPut(t, ls, "test", "hot.txt", "initial")
+
+			const writers, writes, readers = 4, 50, 4
+			var wg sync.WaitGroup
+			done := make(chan struct{})
+			for w := 0; w < writers; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < writes; i++ {
+						// Sizes differ too, so a mismatch can't hide behind equal lengths
+						body := strings.Repeat(fmt.Sprintf("writer %d write %d;", w, i), 1+i%7)
+						if _, err := ls.PutObject(ctx, "test", "hot.txt", strings.NewReader(body), int64(len(body)), "text/plain"); err != nil {
+							t.Errorf("PutObject: %v", err)
+							return
+						}
+					}
+				}()
+			}
+
+			var reads atomic.Int64
+			var readerWg sync.WaitGroup
+			for r := 0; r < readers; r++ {
+				readerWg.Add(1)
+				go func() {
+					defer readerWg.Done()
+					for {
+						select {
+						case <-done:
+							return
+						default:
+						}
+						reader, metadata, err := ls.GetObject(ctx, "test", "hot.txt")
+						if err != nil {
+							t.Errorf("GetObject: %v", err)
+							return
+						}
+						data, err := io.ReadAll(reader)
+						reader.Close()
+						if err != nil {
+							t.Errorf("reading: %v", err)
+							return
+						}
+						sum := md5.Sum(data)
+						if etag := `"` + hex.EncodeToString(sum[:]) + `"`; etag != metadata.ETag || int64(len(data)) != metadata.Size {
+							t.Errorf("served %d bytes hashing to %s with metadata %s, %d bytes", len(data), etag, metadata.ETag, metadata.Size)
+							return
+						}
+						reads.Add(1)
+					}
+				}()
+			}
+
+			wg.Wait()
+			close(done)
+			readerWg.Wait()
+			if reads.Load() == 0 {
+				t.Error("no reads completed")
+			}
+		})
+	}
+}
+
+func TestHeadObjectWithHistory(t *testing.T) {
+	ls := newTestStorage(t, Options{ETagHistoryLimit: 10})
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		mustPut(t, ls, "test", "k", fmt.Sprintf("version %d", i))
+	}
+	template := model.ObjectMetadata{ContentType: "text/html", StorageClass: "GLACIER", RedirectLocation: "/other", CacheControl: "no-cache"}
+	if _, err := ls.PutObjectWithMetadata(ctx, "test", "k", strings.NewReader("latest"), 6, template); err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.PutObjectTagging(ctx, "test", "k", map[string]string{"team": "web"}); err != nil {
+		t.Fatal(err)
+	}
+	until := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	if _, err := ls.RestoreArchivedObject(ctx, "test", "k", until); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, full, err := ls.GetObject(ctx, "test", "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader.Close()
+	head, err := ls.HeadObject(ctx, "test", "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.ETag != etagOf("latest") || head.Size != 6 || !head.LastModified.Equal(full.LastModified) ||
+		head.ContentType != "text/html" || head.StorageClass != "GLACIER" || head.RedirectLocation != "/other" ||
+		head.CacheControl != "no-cache" || head.Tags["team"] != "web" || head.RestoredUntil == nil || !head.RestoredUntil.Equal(until) {
+		t.Errorf("HeadObject = %+v, want the latest version's metadata", head)
+	}
+	if head.History != nil {
+		t.Errorf("HeadObject returned history %+v", head.History)
+	}
+}
+
+// readHeadMetadata stops at the history, so it must be the last field of a
+// metadata file
+func TestHistoryIsLastMetadataField(t *testing.T) {
+	now := time.Now()
+	data, err := json.Marshal(model.ObjectMetadata{
+		Key: "k", Size: 1, LastModified: now, ETag: "e", ContentType: "c", StorageClass: "s",
+		RestoredUntil: &now, RedirectLocation: "/r", CacheControl: "cc", Tags: map[string]string{"t": "v"},
+		SHA256: "h", DeletedAt: &now, History: []model.ETagHistoryEntry{{ETag: "old"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	last := strings.LastIndex(string(data), `"history":`)
+	for name := range fields {
+		if name != "history" && strings.Index(string(data), `"`+name+`":`) > last {
+			t.Errorf("metadata field %s is written after the history", name)
+		}
+	}
+}
+
+// BenchmarkHeadObject reads the headers of an object whose metadata file
+// carries tags and a full ETag history, as HEAD does
+func BenchmarkHeadObject(b *testing.B) {
+	ctx := context.Background()
+	ls := New(b.TempDir(), Options{ETagHistoryLimit: 50})
+	if err := ls.CreateBucket(ctx, "test"); err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i <= 50; i++ {
+		body := fmt.Sprintf("version %d", i)
+		if _, err := ls.PutObject(ctx, "test", "k", strings.NewReader(body), int64(len(body)), "text/plain"); err != nil {
+			b.Fatal(err)
+		}
+	}
+	tags := map[string]string{}
+	for i := 0; i < 10; i++ {
+		tags[fmt.Sprintf("tag%d", i)] = fmt.Sprintf("value%d", i)
+	}
+	if err := ls.PutObjectTagging(ctx, "test", "k", tags); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("HeadObject", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := ls.HeadObject(ctx, "test", "k"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("StatObject", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := ls.StatObject(ctx, "test", "k"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestPutObjectMissingBucket(t *testing.T) {
+	ctx := context.Background()
+	ls := newTestStorage(t, Options{})
+	mustPut(t, ls, "test", "a.txt", "hello")
+
+	_, err := ls.PutObject(ctx, "missing", "a/b.txt", strings.NewReader("hello"), 5, "text/plain")
+	if !errors.Is(err, ErrBucketNotFound) {
+		t.Errorf("PutObject into a missing bucket = %v, want ErrBucketNotFound", err)
+	}
+	_, err = ls.CopyObject(ctx, "test", "a.txt", "missing", "a.txt", nil)
+	if !errors.Is(err, ErrBucketNotFound) {
+		t.Errorf("CopyObject into a missing bucket = %v, want ErrBucketNotFound", err)
+	}
+	if _, err := os.Stat(filepath.Join(ls.basePath, "missing")); !os.IsNotExist(err) {
+		t.Errorf("missing bucket directory created: %v", err)
+	}
+
+	// Key prefixes are still created inside an existing bucket
+	mustPut(t, ls, "test", "deep/nested/key.txt", "nested")
+	if got := readObject(t, ls, "test", "deep/nested/key.txt"); got != "nested" {
+		t.Errorf("nested object = %q, want nested", got)
+	}
+}
+
+// zeroReader reads an endless stream of zero bytes
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// BenchmarkPutObjectLarge stores a 64 MiB object, hashing it for its ETag
+// and, when content-addressed, its blob name, with the hashes overlapped with
+// the disk writes and inline on the copying goroutine. "copy" writes the same
+// bytes to a file without hashing: the most the overlap could ever save. It
+// needs a spare CPU, so compare with -cpu 1,4.
+func BenchmarkPutObjectLarge(b *testing.B) {
+	const size = 64 << 20
+	ctx := context.Background()
+
+	b.Run("copy", func(b *testing.B) {
+		path := filepath.Join(b.TempDir(), "copy")
+		b.SetBytes(size)
+		for i := 0; i < b.N; i++ {
+			f, err := os.Create(path)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := io.Copy(f, io.LimitReader(zeroReader{}, size)); err != nil {
+				b.Fatal(err)
+			}
+			f.Close()
+		}
+	})
+	for _, tc := range []struct {
+		name string
+		opts Options
+	}{
+		{"plain", Options{}},
+		{"content-addressed", Options{ContentAddressed: true}},
+	} {
+		for _, overlap := range []bool{false, true} {
+			name := tc.name + "/inline"
+			if overlap {
+				name = tc.name + "/overlapped"
+			}
+			b.Run(name, func(b *testing.B) {
+				saved := overlapHashing
+				overlapHashing = overlap
+				b.Cleanup(func() { overlapHashing = saved })
+
+				ls := New(b.TempDir(), tc.opts)
+				if err := ls.CreateBucket(ctx, "test"); err != nil {
+					b.Fatal(err)
+				}
+				b.SetBytes(size)
+				for i := 0; i < b.N; i++ {
+					if _, err := ls.PutObject(ctx, "test", "k", io.LimitReader(zeroReader{}, size), size, "application/octet-stream"); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
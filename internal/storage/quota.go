@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// SetBucketQuota caps the total size of a bucket's objects at quota bytes.
+// Zero removes the cap. Objects already over the new quota are kept, but
+// writes that would grow the bucket fail with ErrQuotaExceeded.
+func (ls *LocalStorage) SetBucketQuota(ctx context.Context, bucket string, quota int64) error {
+	err := ls.updateBucketMetadata(bucket, func(meta *bucketMetadata) {
+		meta.Quota = quota
+	})
+	if err != nil {
+		return err
+	}
+
+	ls.usageMu.Lock()
+	ls.quotas[bucket] = quota
+	ls.usageMu.Unlock()
+	return nil
+}
+
+// reserveBytes accounts for a write that changes a bucket's size by delta,
+// failing with ErrQuotaExceeded if that would take a bucket with a quota past
+// it. The size is computed with a single walk the first time a bucket with a
+// quota is written to and then kept up to date. Callers must hold the object
+// lock and undo the reservation with adjustBytes(-delta) if the write fails.
+func (ls *LocalStorage) reserveBytes(bucket string, delta int64) error {
+	return ls.chargeBytes(bucket, delta, true)
+}
+
+// checkQuota fails with ErrQuotaExceeded if growing a bucket by delta would
+// take it past its quota, without reserving anything. Writes of a known size
+// use it to refuse data that can't fit before reading it.
+func (ls *LocalStorage) checkQuota(bucket string, delta int64) error {
+	return ls.chargeBytes(bucket, delta, false)
+}
+
+func (ls *LocalStorage) chargeBytes(bucket string, delta int64, reserve bool) error {
+	unlock := ls.usageLocks.Lock(bucket)
+	defer unlock()
+
+	quota, err := ls.bucketQuota(bucket)
+	if err != nil {
+		return err
+	}
+	if quota == 0 {
+		if reserve {
+			ls.adjustBytes(bucket, delta)
+		}
+		return nil
+	}
+
+	ls.usageMu.Lock()
+	used, ok := ls.usage[bucket]
+	ls.usageMu.Unlock()
+	if !ok {
+		// The walk runs without usageMu, so writes to other buckets go on
+		if used, err = ls.walkBucketSize(bucket); err != nil {
+			return err
+		}
+		ls.usageMu.Lock()
+		ls.usage[bucket] = used
+		ls.usageMu.Unlock()
+	}
+
+	// Shrinking writes are always allowed, even over quota
+	if delta > 0 && used+delta > quota {
+		return ErrQuotaExceeded
+	}
+	if reserve {
+		ls.adjustBytes(bucket, delta)
+	}
+	return nil
+}
+
+// bucketQuota returns a bucket's quota, reading it from the bucket metadata
+// the first time.
+func (ls *LocalStorage) bucketQuota(bucket string) (int64, error) {
+	ls.usageMu.Lock()
+	quota, ok := ls.quotas[bucket]
+	ls.usageMu.Unlock()
+	if ok {
+		return quota, nil
+	}
+
+	var meta bucketMetadata
+	if err := ls.readJSON(filepath.Join(ls.basePath, bucket, bucketMetadataFile), &meta); err != nil && !isNotExist(err) {
+		return 0, err
+	}
+	ls.usageMu.Lock()
+	defer ls.usageMu.Unlock()
+	// SetBucketQuota may have stored a newer value meanwhile
+	if quota, ok := ls.quotas[bucket]; ok {
+		return quota, nil
+	}
+	ls.quotas[bucket] = meta.Quota
+	return meta.Quota, nil
+}
+
+// adjustBytes applies delta to a bucket's cached size, if it has been
+// computed.
+func (ls *LocalStorage) adjustBytes(bucket string, delta int64) {
+	ls.usageMu.Lock()
+	defer ls.usageMu.Unlock()
+
+	if _, ok := ls.usage[bucket]; ok {
+		ls.usage[bucket] += delta
+	}
+}
+
+// resetUsage forgets a bucket's cached size, quota and metadata so they are
+// read again on next use.
+func (ls *LocalStorage) resetUsage(bucket string) {
+	ls.forgetBucketMetadata(bucket)
+
+	ls.usageMu.Lock()
+	defer ls.usageMu.Unlock()
+
+	delete(ls.usage, bucket)
+	delete(ls.quotas, bucket)
+}
+
+// objectSize returns the size of an object's data file, or 0 if it doesn't
+// exist. Callers must hold the object lock.
+func (ls *LocalStorage) objectSize(path string) int64 {
+	info, err := ls.fs.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+func (ls *LocalStorage) walkBucketSize(bucket string) (int64, error) {
+	bucketPath := filepath.Join(ls.basePath, bucket)
+	var n int64
+	err := ls.fs.Walk(bucketPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if isNotExist(err) && path != bucketPath {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			if isInternalDir(bucketPath, path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ls.isMetadataFile(path) {
+			return nil
+		}
+		n += info.Size()
+		return nil
+	})
+	return n, err
+}
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// putSized stores body under key, returning the error
+func putSized(ls *LocalStorage, key, body string) error {
+	_, err := ls.PutObject(context.Background(), "test", key, strings.NewReader(body), int64(len(body)), "text/plain")
+	return err
+}
+
+func withQuota(t *testing.T, ls *LocalStorage, bucket string, quota int64) {
+	t.Helper()
+	if err := ls.SetBucketQuota(context.Background(), bucket, quota); err != nil {
+		t.Fatalf("SetBucketQuota: %v", err)
+	}
+}
+
+func expectQuotaExceeded(t *testing.T, err error) {
+	t.Helper()
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("err = %v, want ErrQuotaExceeded", err)
+	}
+}
+
+func TestQuotaEnforced(t *testing.T) {
+	ls := newTestStorage(t, Options{})
+	withQuota(t, ls, "test", 10)
+
+	if err := putSized(ls, "a", "123456"); err != nil {
+		t.Fatal(err)
+	}
+	expectQuotaExceeded(t, putSized(ls, "b", "123456"))
+	if _, err := ls.HeadObject(context.Background(), "test", "b"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("rejected object stored: %v", err)
+	}
+	if err := putSized(ls, "b", "1234"); err != nil {
+		t.Errorf("upload filling the quota exactly: %v", err)
+	}
+}
+
+// countingReader counts how many times it is read
+type countingReader struct {
+	reads int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.reads++
+	return 0, errors.New("read")
+}
+
+func TestQuotaRefusesKnownSizeBeforeReading(t *testing.T) {
+	ls := newTestStorage(t, Options{})
+	withQuota(t, ls, "test", 10)
+
+	body := &countingReader{}
+	_, err := ls.PutObject(context.Background(), "test", "big", body, 20, "text/plain")
+	expectQuotaExceeded(t, err)
+	if body.reads != 0 {
+		t.Errorf("body read %d times before being refused", body.reads)
+	}
+}
+
+func TestQuotaChargesOverwritesByDifference(t *testing.T) {
+	ls := newTestStorage(t, Options{})
+	withQuota(t, ls, "test", 10)
+
+	if err := putSized(ls, "k", "12345678"); err != nil {
+		t.Fatal(err)
+	}
+	// 8 -> 9 bytes only adds one
+	if err := putSized(ls, "k", "123456789"); err != nil {
+		t.Fatalf("growing overwrite within quota: %v", err)
+	}
+	expectQuotaExceeded(t, putSized(ls, "other", "12"))
+
+	// Shrinking frees the difference
+	if err := putSized(ls, "k", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := putSized(ls, "other", "123456789"); err != nil {
+		t.Errorf("upload after shrinking overwrite: %v", err)
+	}
+}
+
+func TestQuotaTracksDeleteAndRestore(t *testing.T) {
+	ctx := context.Background()
+	ls := newTestStorage(t, Options{SoftDelete: true})
+	withQuota(t, ls, "test", 10)
+
+	if err := putSized(ls, "a", "12345678"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.DeleteObject(ctx, "test", "a"); err != nil {
+		t.Fatal(err)
+	}
+	// Trashed objects don't count
+	if err := putSized(ls, "b", "12345678"); err != nil {
+		t.Fatalf("upload after delete: %v", err)
+	}
+
+	// Restores aren't blocked by the quota, but are charged to it
+	if err := ls.RestoreObject(ctx, "test", "a"); err != nil {
+		t.Fatal(err)
+	}
+	expectQuotaExceeded(t, putSized(ls, "c", "1"))
+	if err := ls.DeleteObject(ctx, "test", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := putSized(ls, "c", "12"); err != nil {
+		t.Errorf("upload after deleting the restored object: %v", err)
+	}
+}
+
+func TestQuotaIgnoresTempFiles(t *testing.T) {
+	ctx := context.Background()
+	ls := newTestStorage(t, Options{})
+	tmp := filepath.Join(ls.basePath, "test", tempDir)
+	if err := os.MkdirAll(tmp, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "tmp-stale"), make([]byte, 100), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(tmp, "tmp-stale"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	withQuota(t, ls, "test", 10)
+
+	if err := putSized(ls, "a", "12345"); err != nil {
+		t.Fatalf("upload with a stray temp file: %v", err)
+	}
+	if n, err := ls.CleanupTempFiles(ctx, "test", time.Minute); err != nil || n != 1 {
+		t.Fatalf("CleanupTempFiles = %d, %v, want 1", n, err)
+	}
+	if err := putSized(ls, "b", "12345"); err != nil {
+		t.Errorf("upload after cleanup: %v", err)
+	}
+	expectQuotaExceeded(t, putSized(ls, "c", "1"))
+}
+
+// Computing one bucket's size doesn't hold up quota checks of another
+func TestQuotaChecksArePerBucket(t *testing.T) {
+	ls := newTestStorage(t, Options{})
+	if err := ls.CreateBucket(context.Background(), "other"); err != nil {
+		t.Fatal(err)
+	}
+	withQuota(t, ls, "test", 10)
+	withQuota(t, ls, "other", 10)
+
+	unlock := ls.usageLocks.Lock("test")
+	defer unlock()
+
+	done := make(chan error)
+	go func() {
+		_, err := ls.PutObject(context.Background(), "other", "k", strings.NewReader("data"), 4, "text/plain")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("upload to another bucket waited for this bucket's quota check")
+	}
+}
+
+func TestQuotaChargesMetadataNamedKeys(t *testing.T) {
+	ls := newTestStorage(t, Options{})
+	if err := putSized(ls, "notes.metadata", "12345678"); err != nil {
+		t.Fatal(err)
+	}
+	// Set afterwards, so the bucket's usage comes from a walk
+	withQuota(t, ls, "test", 10)
+
+	expectQuotaExceeded(t, putSized(ls, "a", "12345"))
+	if err := putSized(ls, "a", "12"); err != nil {
+		t.Errorf("upload filling the quota exactly: %v", err)
+	}
+}
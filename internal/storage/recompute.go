@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+// RecomputeObject re-reads an object's data file and rewrites its metadata
+// with a fresh size, ETag and modification time. This onboards files placed
+// directly into the storage directory. An existing content type and tags are
+// kept; otherwise the content type is guessed from the extension or content.
+func (ls *LocalStorage) RecomputeObject(ctx context.Context, bucket, key string) (*model.ObjectMetadata, error) {
+	unlock := ls.lockObject(bucket, key)
+	defer unlock()
+
+	// The file may have been placed outside the API, so recount lazily
+	ls.resetCount(bucket)
+	ls.resetUsage(bucket)
+
+	return ls.recomputeObject(bucket, key)
+}
+
+// RecomputeBucket runs RecomputeObject on every data file in a bucket and
+// returns the number of objects reconciled.
+func (ls *LocalStorage) RecomputeBucket(ctx context.Context, bucket string) (int, error) {
+	unlock := ls.lockBucket(bucket)
+	defer unlock()
+
+	ls.resetCount(bucket)
+	ls.resetUsage(bucket)
+
+	bucketPath := filepath.Join(ls.basePath, bucket)
+	var keys []string
+	err := ls.fs.Walk(bucketPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if isInternalDir(bucketPath, path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".metadata") {
+			return nil
+		}
+		if key, ok := ls.keyForPath(bucketPath, path); ok {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Failed to walk bucket", "error", err)
+		return 0, fmt.Errorf("failed to walk bucket")
+	}
+
+	for i, key := range keys {
+		if _, err := ls.recomputeObject(bucket, key); err != nil {
+			return i, err
+		}
+	}
+	return len(keys), nil
+}
+
+// recomputeObject does the work of RecomputeObject. Callers must hold the
+// object lock or the bucket lock.
+func (ls *LocalStorage) recomputeObject(bucket, key string) (*model.ObjectMetadata, error) {
+	objectPath := ls.objectPath(bucket, key)
+	metadataPath := objectPath + ".metadata"
+
+	file, err := ls.fs.Open(objectPath)
+	if err != nil {
+		slog.Debug("Failed to open file", "path", objectPath, "error", err)
+		return nil, fmt.Errorf("failed to open file")
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		slog.Error("Failed to stat file", "error", err)
+		return nil, fmt.Errorf("failed to stat file")
+	}
+
+	// Sniff the first bytes while hashing, in case we need a content type
+	hash := md5.New()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		slog.Error("Failed to read file", "error", err)
+		return nil, fmt.Errorf("failed to read file")
+	}
+	head = head[:n]
+	hash.Write(head)
+	written, err := io.Copy(hash, file)
+	if err != nil {
+		slog.Error("Failed to read file", "error", err)
+		return nil, fmt.Errorf("failed to read file")
+	}
+
+	metadata := &model.ObjectMetadata{}
+	if existing, err := ls.readMetadata(metadataPath); err == nil {
+		metadata = existing
+	}
+
+	metadata.Key = key
+	metadata.Size = int64(n) + written
+	metadata.LastModified = info.ModTime().UTC()
+	metadata.ETag = `"` + hex.EncodeToString(hash.Sum(nil)) + `"`
+	if metadata.ContentType == "" {
+		metadata.ContentType = mime.TypeByExtension(filepath.Ext(key))
+	}
+	if metadata.ContentType == "" {
+		metadata.ContentType = http.DetectContentType(head)
+	}
+
+	if err := ls.writeMetadata(metadataPath, metadata); err != nil {
+		slog.Error("Failed to write metadata", "error", err)
+		return nil, fmt.Errorf("failed to write metadata")
+	}
+	return metadata, nil
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+)
+
+// RenameBucket renames a bucket by renaming its directory, which is atomic
+// on a single filesystem. Object keys are bucket-relative, so no metadata has
+// to be rewritten.
+func (ls *LocalStorage) RenameBucket(ctx context.Context, oldName, newName string) error {
+	// Lock both buckets in a fixed order so concurrent renames can't deadlock
+	first, second := oldName, newName
+	if second < first {
+		first, second = second, first
+	}
+	unlockFirst := ls.lockBucket(first)
+	defer unlockFirst()
+	if second != first {
+		unlockSecond := ls.lockBucket(second)
+		defer unlockSecond()
+	}
+
+	oldPath := filepath.Join(ls.basePath, oldName)
+	newPath := filepath.Join(ls.basePath, newName)
+
+	if _, err := ls.fs.Stat(oldPath); err != nil {
+		if isNotExist(err) {
+			return ErrBucketNotFound
+		}
+		slog.Error("Failed to check bucket", "error", err)
+		return fmt.Errorf("failed to check bucket")
+	}
+	if _, err := ls.fs.Stat(newPath); err == nil {
+		return ErrBucketExists
+	} else if !isNotExist(err) {
+		slog.Error("Failed to check bucket", "error", err)
+		return fmt.Errorf("failed to check bucket")
+	}
+
+	if err := ls.rename(ctx, oldPath, newPath); err != nil {
+		slog.Error("Failed to rename bucket", "error", err)
+		return fmt.Errorf("failed to rename bucket")
+	}
+	if err := ls.syncDir(ls.basePath); err != nil {
+		slog.Error("Failed to sync storage directory", "error", err)
+		return fmt.Errorf("failed to rename bucket")
+	}
+
+	ls.resetCount(oldName)
+	ls.resetCount(newName)
+	ls.resetUsage(oldName)
+	ls.resetUsage(newName)
+	return nil
+}
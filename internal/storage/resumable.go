@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+// uploadsDir is the per-bucket directory resumable uploads are staged in
+// until every byte has been received. A staged upload's data is kept at
+// .uploads/<key> and its sidecar at .uploads/.state/<key>; keys can't start
+// with ".", so the two never collide.
+const (
+	uploadsDir     = ".uploads"
+	uploadStateDir = ".state"
+)
+
+// stagedUpload is the sidecar recording a staged upload's progress and the
+// descriptive metadata the object is stored with once it is complete.
+type stagedUpload struct {
+	Size             int64             `json:"size"`
+	ContentType      string            `json:"contentType"`
+	StorageClass     string            `json:"storageClass,omitempty"`
+	RedirectLocation string            `json:"redirectLocation,omitempty"`
+	CacheControl     string            `json:"cacheControl,omitempty"`
+	Received         []model.ByteRange `json:"received"`
+	Initiated        time.Time         `json:"initiated"`
+}
+
+// stagedUploadPaths returns the data and sidecar paths of key's staged upload
+func (ls *LocalStorage) stagedUploadPaths(bucket, key string) (stagedPath, sidecarPath string) {
+	uploads := filepath.Join(ls.basePath, bucket, uploadsDir)
+	return filepath.Join(uploads, key), filepath.Join(uploads, uploadStateDir, key)
+}
+
+// PutObjectRange writes data at offset start of a staged upload of total
+// bytes. Ranges may arrive in any order and may overlap (overlapping bytes
+// are simply rewritten). Once every byte has been received the upload is
+// stored as a regular object, with the descriptive fields of the template
+// the first range came with, and its metadata is returned; until then only
+// the progress is.
+func (ls *LocalStorage) PutObjectRange(ctx context.Context, bucket, key string, start, total int64, data io.Reader, template model.ObjectMetadata) (*model.UploadProgress, *model.ObjectMetadata, error) {
+	unlock := ls.lockObject(bucket, key)
+	defer unlock()
+
+	if _, err := ls.fs.Stat(filepath.Join(ls.basePath, bucket)); err != nil {
+		if isNotExist(err) {
+			return nil, nil, ErrBucketNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to check bucket")
+	}
+
+	stagedPath, sidecarPath := ls.stagedUploadPaths(bucket, key)
+
+	var upload stagedUpload
+	if err := ls.readJSON(sidecarPath, &upload); err != nil {
+		if !isNotExist(err) {
+			slog.Error("Failed to read upload state", "path", sidecarPath, "error", err)
+			return nil, nil, fmt.Errorf("failed to read upload state")
+		}
+		upload = stagedUpload{
+			Size:             total,
+			ContentType:      template.ContentType,
+			StorageClass:     template.StorageClass,
+			RedirectLocation: template.RedirectLocation,
+			CacheControl:     template.CacheControl,
+			Initiated:        time.Now().UTC(),
+		}
+	}
+	if upload.Size != total {
+		return nil, nil, ErrUploadSizeMismatch
+	}
+
+	for _, dir := range []string{filepath.Dir(stagedPath), filepath.Dir(sidecarPath)} {
+		if err := ls.mkdirAll(dir); err != nil {
+			slog.Error("Failed to create directories", "error", err)
+			return nil, nil, fmt.Errorf("failed to create directories")
+		}
+	}
+
+	file, err := ls.fs.OpenFile(stagedPath, os.O_RDWR|os.O_CREATE, ls.opts.FileMode)
+	if err != nil {
+		slog.Error("Failed to open staged upload", "error", err)
+		return nil, nil, fmt.Errorf("failed to open staged upload")
+	}
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		file.Close()
+		slog.Error("Failed to seek staged upload", "error", err)
+		return nil, nil, fmt.Errorf("failed to write data")
+	}
+	// Only the bytes actually written are recorded, so an interrupted
+	// request can simply be resent
+	written, copyErr := io.Copy(file, data)
+	if err := ls.syncFile(file); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	file.Close()
+	if written > 0 {
+		upload.Received = mergeRange(upload.Received, model.ByteRange{Start: start, End: start + written - 1})
+	}
+
+	if err := ls.writeJSON(sidecarPath, &upload); err != nil {
+		slog.Error("Failed to write upload state", "error", err)
+		return nil, nil, fmt.Errorf("failed to write upload state")
+	}
+	if copyErr != nil {
+		slog.Error("Failed to write data", "error", copyErr)
+		return nil, nil, fmt.Errorf("failed to write data")
+	}
+
+	progress := &model.UploadProgress{Key: key, Size: upload.Size, Received: upload.Received}
+	if len(upload.Received) != 1 || upload.Received[0].Start != 0 || upload.Received[0].End != upload.Size-1 {
+		return progress, nil, nil
+	}
+
+	// Every byte is here, store it as a regular object
+	staged, err := ls.fs.Open(stagedPath)
+	if err != nil {
+		slog.Error("Failed to open staged upload", "error", err)
+		return nil, nil, fmt.Errorf("failed to open staged upload")
+	}
+	defer staged.Close()
+
+	metadata, err := ls.storeObject(ctx, bucket, key, io.LimitReader(staged, upload.Size), upload.Size, model.ObjectMetadata{
+		ContentType:      upload.ContentType,
+		StorageClass:     upload.StorageClass,
+		RedirectLocation: upload.RedirectLocation,
+		CacheControl:     upload.CacheControl,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	_ = ls.fs.Remove(stagedPath)
+	_ = ls.fs.Remove(sidecarPath)
+
+	return progress, metadata, nil
+}
+
+// mergeRange adds r to a sorted list of disjoint ranges, coalescing ranges
+// that overlap or touch.
+func mergeRange(ranges []model.ByteRange, r model.ByteRange) []model.ByteRange {
+	ranges = append(ranges, r)
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+
+	merged := ranges[:1]
+	for _, next := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if next.Start <= last.End+1 {
+			if next.End > last.End {
+				last.End = next.End
+			}
+			continue
+		}
+		merged = append(merged, next)
+	}
+	return merged
+}
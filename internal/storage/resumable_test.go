@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+func putRange(t *testing.T, ls *LocalStorage, key string, start, total int64, data string, template model.ObjectMetadata) (*model.UploadProgress, *model.ObjectMetadata) {
+	t.Helper()
+	progress, metadata, err := ls.PutObjectRange(context.Background(), "test", key, start, total, strings.NewReader(data), template)
+	if err != nil {
+		t.Fatalf("PutObjectRange(%s, %d): %v", key, start, err)
+	}
+	return progress, metadata
+}
+
+func TestPutObjectRangeCompletes(t *testing.T) {
+	ls := newTestStorage(t, Options{})
+	template := model.ObjectMetadata{ContentType: "text/plain", StorageClass: "STANDARD_IA", CacheControl: "no-cache"}
+
+	if _, metadata := putRange(t, ls, "a.txt", 5, 10, "world", template); metadata != nil {
+		t.Fatal("upload completed with bytes missing")
+	}
+	_, metadata := putRange(t, ls, "a.txt", 0, 10, "hello", model.ObjectMetadata{})
+	if metadata == nil {
+		t.Fatal("upload not completed")
+	}
+	if metadata.StorageClass != "STANDARD_IA" || metadata.CacheControl != "no-cache" || metadata.ContentType != "text/plain" {
+		t.Fatalf("metadata = %+v, want the first range's descriptive fields", metadata)
+	}
+	if got := readObject(t, ls, "test", "a.txt"); got != "helloworld" {
+		t.Fatalf("object = %q, want helloworld", got)
+	}
+}
+
+// A key ending in the old sidecar extension must not clash with another
+// upload's state
+func TestUploadStateDoesNotCollideWithKeys(t *testing.T) {
+	ls := newTestStorage(t, Options{})
+
+	putRange(t, ls, "k", 0, 10, "abc", model.ObjectMetadata{})
+	putRange(t, ls, "k.upload", 0, 4, "ab", model.ObjectMetadata{})
+
+	uploads, err := ls.ListUploads(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uploads) != 2 || uploads[0].Key != "k" || uploads[1].Key != "k.upload" {
+		t.Fatalf("uploads = %+v, want k and k.upload", uploads)
+	}
+
+	_, metadata := putRange(t, ls, "k.upload", 2, 4, "cd", model.ObjectMetadata{})
+	if metadata == nil {
+		t.Fatal("k.upload not completed")
+	}
+	if got := readObject(t, ls, "test", "k.upload"); got != "abcd" {
+		t.Fatalf("k.upload = %q, want abcd", got)
+	}
+	uploads, _ = ls.ListUploads(context.Background(), "test")
+	if len(uploads) != 1 || uploads[0].Key != "k" || uploads[0].Size != 10 {
+		t.Fatalf("uploads = %+v, want only k", uploads)
+	}
+}
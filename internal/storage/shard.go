@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// With Options.ShardKeys an object's files live under two levels of
+// directories named after the first two bytes of the SHA-256 of its key,
+// e.g. photos/cat.jpg is stored as 3f/a2/photos/cat.jpg. However the keys
+// of a bucket are shaped, no directory then holds more than 256 shards, and
+// the objects are spread evenly over 65536 of them. The key is still the
+// object's path below its shard, so the layout is invisible to the API.
+
+// objectPath returns where the data file of an object is stored. Its
+// metadata sits next to it with a ".metadata" suffix.
+func (ls *LocalStorage) objectPath(bucket, key string) string {
+	if !ls.opts.ShardKeys {
+		return filepath.Join(ls.basePath, bucket, key)
+	}
+	first, second := keyShard(key)
+	return filepath.Join(ls.basePath, bucket, first, second, key)
+}
+
+// keyForPath returns the key of the data file found at path while walking
+// the bucket at bucketPath. With ShardKeys, files that aren't in their key's
+// shard, e.g. copied into the bucket by hand, are not objects.
+func (ls *LocalStorage) keyForPath(bucketPath, path string) (string, bool) {
+	rel, err := filepath.Rel(bucketPath, path)
+	if err != nil {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	if !ls.opts.ShardKeys {
+		return rel, true
+	}
+
+	parts := strings.SplitN(rel, "/", 3)
+	if len(parts) != 3 {
+		return "", false
+	}
+	first, second := keyShard(parts[2])
+	if parts[0] != first || parts[1] != second {
+		return "", false
+	}
+	return parts[2], true
+}
+
+// keyShard returns the two shard directory names for key
+func keyShard(key string) (string, string) {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:1]), hex.EncodeToString(sum[1:2])
+}
+
+// layoutFile records, at the root of the storage directory, which layout
+// its objects are stored in. It isn't a valid bucket name, and ListBuckets
+// only returns directories.
+const layoutFile = ".layout"
+
+type storageLayout struct {
+	ShardKeys bool `json:"shardKeys"`
+}
+
+// errLayoutFound stops the walk of detectLayout at the first object
+var errLayoutFound = errors.New("layout found")
+
+// CheckLayout returns ErrLayoutMismatch when the objects in the storage
+// directory were written with the other setting of Options.ShardKeys, since
+// they would all look missing. The layout is recorded in the directory the
+// first time it is checked; directories from before then are recognized by
+// where their first object is stored.
+func (ls *LocalStorage) CheckLayout() error {
+	path := filepath.Join(ls.basePath, layoutFile)
+	var layout storageLayout
+	err := ls.readJSON(path, &layout)
+	if isNotExist(err) {
+		found, sharded, detectErr := ls.detectLayout()
+		if detectErr != nil {
+			return detectErr
+		}
+		layout.ShardKeys = ls.opts.ShardKeys
+		if found {
+			layout.ShardKeys = sharded
+		}
+		err = ls.writeLayout(path, layout)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read storage layout: %w", err)
+	}
+	if layout.ShardKeys != ls.opts.ShardKeys {
+		return ErrLayoutMismatch
+	}
+	return nil
+}
+
+// detectLayout finds the first object in any bucket and reports whether it
+// is stored in its key's shard
+func (ls *LocalStorage) detectLayout() (found, sharded bool, err error) {
+	buckets, err := ls.ListBuckets(context.Background())
+	if err != nil {
+		return false, false, err
+	}
+	for _, bucket := range buckets {
+		bucketPath := filepath.Join(ls.basePath, bucket)
+		err := ls.fs.Walk(bucketPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if isInternalDir(bucketPath, path) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if filepath.Dir(path) == bucketPath && info.Name() == bucketMetadataFile {
+				return nil
+			}
+			rel, err := filepath.Rel(bucketPath, strings.TrimSuffix(path, ".metadata"))
+			if err != nil {
+				return err
+			}
+			parts := strings.SplitN(filepath.ToSlash(rel), "/", 3)
+			if len(parts) == 3 {
+				first, second := keyShard(parts[2])
+				sharded = parts[0] == first && parts[1] == second
+			}
+			return errLayoutFound
+		})
+		if err == errLayoutFound {
+			return true, sharded, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+	}
+	return false, false, nil
+}
+
+// writeLayout records layout at path. It can't go through writeJSON, whose
+// temp files live inside buckets.
+func (ls *LocalStorage) writeLayout(path string, layout storageLayout) error {
+	if err := ls.mkdirAll(ls.basePath); err != nil {
+		return err
+	}
+	file, err := ls.fs.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, ls.opts.FileMode)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(file).Encode(layout); err != nil {
+		file.Close()
+		ls.fs.Remove(path)
+		return err
+	}
+	if err := ls.syncFile(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
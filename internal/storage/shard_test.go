@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestShardedLayout(t *testing.T) {
+	ls := newTestStorage(t, Options{ShardKeys: true})
+	ctx := context.Background()
+	mustPut(t, ls, "test", "photos/cat.jpg", "meow")
+
+	first, second := keyShard("photos/cat.jpg")
+	if _, err := os.Stat(filepath.Join(ls.basePath, "test", first, second, "photos", "cat.jpg")); err != nil {
+		t.Fatalf("object not stored in its shard: %v", err)
+	}
+	if got := readObject(t, ls, "test", "photos/cat.jpg"); got != "meow" {
+		t.Errorf("read %q, want meow", got)
+	}
+	objects, err := ls.ListObjects(ctx, "test", "photos/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0].Key != "photos/cat.jpg" {
+		t.Errorf("listing = %+v, want the logical key", objects)
+	}
+}
+
+func TestCheckLayout(t *testing.T) {
+	dir := t.TempDir()
+	if err := New(dir, Options{}).CheckLayout(); err != nil {
+		t.Fatalf("new directory: %v", err)
+	}
+	if err := New(dir, Options{}).CheckLayout(); err != nil {
+		t.Errorf("same layout: %v", err)
+	}
+	if err := New(dir, Options{ShardKeys: true}).CheckLayout(); !errors.Is(err, ErrLayoutMismatch) {
+		t.Errorf("other layout: %v, want ErrLayoutMismatch", err)
+	}
+}
+
+// Directories written before the layout was recorded are recognized by
+// where their objects are
+func TestCheckLayoutDetectsUnrecordedLayout(t *testing.T) {
+	for _, sharded := range []bool{false, true} {
+		ls := newTestStorage(t, Options{ShardKeys: sharded})
+		mustPut(t, ls, "test", "a/b/c.txt", "x")
+
+		if err := New(ls.basePath, Options{ShardKeys: !sharded}).CheckLayout(); !errors.Is(err, ErrLayoutMismatch) {
+			t.Errorf("sharded %v opened with the other layout: %v, want ErrLayoutMismatch", sharded, err)
+		}
+		if err := os.Remove(filepath.Join(ls.basePath, layoutFile)); err != nil {
+			t.Fatal(err)
+		}
+		if err := New(ls.basePath, Options{ShardKeys: sharded}).CheckLayout(); err != nil {
+			t.Errorf("sharded %v opened with its own layout: %v", sharded, err)
+		}
+	}
+}
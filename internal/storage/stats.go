@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+// bucketMetadataFile holds bucket-level metadata. Its ".metadata" suffix
+// keeps it out of object walks, and keys can't start with "." so it never
+// belongs to an object.
+const bucketMetadataFile = ".bucket.metadata"
+
+// bucketMetadata is the content of bucketMetadataFile.
+type bucketMetadata struct {
+	CreatedAt time.Time `json:"createdAt"`
+	// Quota caps the bucket's total object size in bytes; zero is unlimited
+	Quota int64 `json:"quota,omitempty"`
+	// DefaultObject is served for GET /{bucket} instead of a listing
+	DefaultObject string `json:"defaultObject,omitempty"`
+	// DefaultCacheControl is sent with objects that have no Cache-Control
+	DefaultCacheControl string `json:"defaultCacheControl,omitempty"`
+	// MaxObjectSize replaces the server-wide upload limit; zero keeps it
+	MaxObjectSize int64 `json:"maxObjectSize,omitempty"`
+}
+
+// BucketStats counts a bucket's objects and their total size with a single
+// walk that only stats files. CreatedAt is zero for buckets created before
+// creation times were recorded.
+func (ls *LocalStorage) BucketStats(ctx context.Context, bucket string) (*model.BucketStats, error) {
+	unlock := ls.rLockBucket(bucket)
+	defer unlock()
+
+	bucketPath := filepath.Join(ls.basePath, bucket)
+	if _, err := ls.fs.Stat(bucketPath); err != nil {
+		if isNotExist(err) {
+			return nil, ErrBucketNotFound
+		}
+		return nil, fmt.Errorf("failed to check bucket")
+	}
+
+	stats := &model.BucketStats{Bucket: bucket}
+
+	var meta bucketMetadata
+	if err := ls.readJSON(filepath.Join(bucketPath, bucketMetadataFile), &meta); err != nil && !isNotExist(err) {
+		slog.Warn("Failed to read bucket metadata", "bucket", bucket, "error", err)
+	}
+	stats.CreatedAt = meta.CreatedAt
+	stats.Quota = meta.Quota
+	stats.MaxObjectSize = meta.MaxObjectSize
+
+	err := ls.fs.Walk(bucketPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if isNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			if isInternalDir(bucketPath, path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ls.isMetadataFile(path) {
+			return nil
+		}
+		stats.ObjectCount++
+		stats.SizeBytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		slog.Error("Failed to compute bucket stats", "bucket", bucket, "error", err)
+		return nil, fmt.Errorf("failed to compute bucket stats")
+	}
+
+	return stats, nil
+}
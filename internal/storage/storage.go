@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/mmvergara/gosss/internal/model"
 )
@@ -13,12 +14,41 @@ type Storage interface {
 	CreateBucket(ctx context.Context, name string) error
 	DeleteBucket(ctx context.Context, name string) error
 	BucketExists(ctx context.Context, name string) (bool, error)
+	ListBuckets(ctx context.Context) ([]string, error)
+	RenameBucket(ctx context.Context, oldName, newName string) error
+	BucketStats(ctx context.Context, bucket string) (*model.BucketStats, error)
+	SetBucketQuota(ctx context.Context, bucket string, quota int64) error
+	SetBucketDefaultObject(ctx context.Context, bucket, key string) error
+	BucketDefaultObject(ctx context.Context, bucket string) (string, error)
+	SetBucketDefaultCacheControl(ctx context.Context, bucket, value string) error
+	BucketDefaultCacheControl(ctx context.Context, bucket string) (string, error)
+	SetBucketMaxObjectSize(ctx context.Context, bucket string, size int64) error
+	BucketMaxObjectSize(ctx context.Context, bucket string) (int64, error)
 
 	// Object operations
 	PutObject(ctx context.Context, bucket, key string, data io.Reader, size int64, contentType string) (*model.ObjectMetadata, error)
+	PutObjectWithMetadata(ctx context.Context, bucket, key string, data io.Reader, size int64, template model.ObjectMetadata) (*model.ObjectMetadata, error)
 	GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, *model.ObjectMetadata, error)
 	DeleteObject(ctx context.Context, bucket, key string) error
 	ListObjects(ctx context.Context, bucket, prefix string) ([]model.ObjectMetadata, error)
+	WalkObjects(ctx context.Context, bucket, prefix string, fn func(model.ObjectMetadata) error) error
+	ListObjectsLimit(ctx context.Context, bucket, prefix, startAfter string, maxKeys int, match func(model.ObjectMetadata) bool) ([]model.ObjectMetadata, bool, error)
 	HasObject(ctx context.Context, bucket string) (bool, error)
 	HeadObject(ctx context.Context, bucket, key string) (*model.ObjectMetadata, error)
+	StatObject(ctx context.Context, bucket, key string) (int64, error)
+	PutObjectRange(ctx context.Context, bucket, key string, start, total int64, data io.Reader, template model.ObjectMetadata) (*model.UploadProgress, *model.ObjectMetadata, error)
+	CopyObject(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string, override *model.ObjectMetadata) (*model.ObjectMetadata, error)
+	RestoreObject(ctx context.Context, bucket, key string) error
+	RestoreArchivedObject(ctx context.Context, bucket, key string, until time.Time) (*model.ObjectMetadata, error)
+	TruncateObject(ctx context.Context, bucket, key string, size int64, extend bool) (*model.ObjectMetadata, error)
+	RecomputeObject(ctx context.Context, bucket, key string) (*model.ObjectMetadata, error)
+	RecomputeBucket(ctx context.Context, bucket string) (int, error)
+	CleanupTempFiles(ctx context.Context, bucket string, olderThan time.Duration) (int, error)
+	ListUploads(ctx context.Context, bucket string) ([]model.UploadInfo, error)
+	ObjectHistory(ctx context.Context, bucket, key string) (*model.ObjectHistory, error)
+
+	// Tagging operations
+	PutObjectTagging(ctx context.Context, bucket, key string, tags map[string]string) error
+	GetObjectTagging(ctx context.Context, bucket, key string) (map[string]string, error)
+	DeleteObjectTagging(ctx context.Context, bucket, key string) error
 }
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+// newTestStorage returns a LocalStorage in a temporary directory with one
+// bucket, "test", already created
+func newTestStorage(t *testing.T, opts Options) *LocalStorage {
+	t.Helper()
+	ls := New(t.TempDir(), opts)
+	if err := ls.CreateBucket(context.Background(), "test"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	return ls
+}
+
+// mustPut stores body under key in bucket
+func mustPut(t *testing.T, ls Storage, bucket, key, body string) {
+	t.Helper()
+	if _, err := ls.PutObject(context.Background(), bucket, key, strings.NewReader(body), int64(len(body)), "text/plain"); err != nil {
+		t.Fatalf("PutObject(%s/%s): %v", bucket, key, err)
+	}
+}
+
+// readObject returns the content of bucket/key
+func readObject(t *testing.T, ls Storage, bucket, key string) string {
+	t.Helper()
+	reader, _, err := ls.GetObject(context.Background(), bucket, key)
+	if err != nil {
+		t.Fatalf("GetObject(%s/%s): %v", bucket, key, err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("reading %s/%s: %v", bucket, key, err)
+	}
+	return string(data)
+}
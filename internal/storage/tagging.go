@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// PutObjectTagging replaces the tag set of an existing object. Tags live in the
+// object's metadata file, so the object data is left untouched.
+func (ls *LocalStorage) PutObjectTagging(ctx context.Context, bucket, key string, tags map[string]string) error {
+	unlock := ls.lockObject(bucket, key)
+	defer unlock()
+
+	metadataPath := ls.objectPath(bucket, key) + ".metadata"
+
+	metadata, err := ls.readMetadata(metadataPath)
+	if err != nil {
+		slog.Debug("Failed to read metadata", "path", metadataPath, "error", err)
+		return fmt.Errorf("failed to read metadata")
+	}
+
+	metadata.Tags = tags
+	if err := ls.writeMetadata(metadataPath, metadata); err != nil {
+		slog.Error("Failed to write metadata", "error", err)
+		return fmt.Errorf("failed to write metadata")
+	}
+	return nil
+}
+
+// GetObjectTagging returns the tag set of an object (empty if it has none).
+func (ls *LocalStorage) GetObjectTagging(ctx context.Context, bucket, key string) (map[string]string, error) {
+	unlock := ls.rLockObject(bucket, key)
+	defer unlock()
+
+	metadataPath := ls.objectPath(bucket, key) + ".metadata"
+
+	metadata, err := ls.readMetadata(metadataPath)
+	if err != nil {
+		slog.Debug("Failed to read metadata", "path", metadataPath, "error", err)
+		return nil, fmt.Errorf("failed to read metadata")
+	}
+
+	if metadata.Tags == nil {
+		return map[string]string{}, nil
+	}
+	return metadata.Tags, nil
+}
+
+// DeleteObjectTagging removes all tags from an object.
+func (ls *LocalStorage) DeleteObjectTagging(ctx context.Context, bucket, key string) error {
+	return ls.PutObjectTagging(ctx, bucket, key, nil)
+}
@@ -0,0 +1,290 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+// trashDir is the per-bucket directory soft-deleted objects are moved into.
+// Object keys cannot start with ".", so it never collides with a real key.
+const trashDir = ".trash"
+
+// trashVersionsDir, inside the trash area, keeps the earlier trashed copies
+// of keys deleted more than once, at <deletion time>/<key>. The trash area
+// itself holds the most recently deleted copy of each key.
+const trashVersionsDir = ".versions"
+
+// isInternalDir reports whether path is one of a bucket's internal areas
+// (trash, staged uploads, blobs, temp files) that must not be treated as
+// objects.
+func isInternalDir(bucketPath, path string) bool {
+	switch path {
+	case filepath.Join(bucketPath, trashDir), filepath.Join(bucketPath, uploadsDir), filepath.Join(bucketPath, blobsDir), filepath.Join(bucketPath, tempDir):
+		return true
+	}
+	return false
+}
+
+// moveToTrash moves an object and its metadata into the bucket's trash area,
+// stamping the metadata with the deletion time. Callers must hold the
+// object lock.
+func (ls *LocalStorage) moveToTrash(bucket, key string) error {
+	objectPath := ls.objectPath(bucket, key)
+	metadataPath := objectPath + ".metadata"
+	trashPath := filepath.Join(ls.basePath, bucket, trashDir, key)
+
+	metadata, err := ls.readMetadata(metadataPath)
+	if err != nil {
+		slog.Debug("Failed to read metadata", "path", metadataPath, "error", err)
+		return fmt.Errorf("failed to delete object")
+	}
+
+	if err := ls.mkdirAll(filepath.Dir(trashPath)); err != nil {
+		slog.Error("Failed to create trash directory", "error", err)
+		return fmt.Errorf("failed to delete object")
+	}
+	if err := ls.keepTrashedVersion(bucket, key); err != nil {
+		slog.Error("Failed to keep earlier trashed copy", "bucket", bucket, "key", key, "error", err)
+		return fmt.Errorf("failed to delete object")
+	}
+
+	if err := ls.fs.Rename(objectPath, trashPath); err != nil {
+		slog.Error("Failed to move object to trash", "error", err)
+		return fmt.Errorf("failed to delete object")
+	}
+
+	deletedAt := time.Now().UTC()
+	metadata.DeletedAt = &deletedAt
+	if err := ls.writeMetadata(trashPath+".metadata", metadata); err != nil {
+		// Put the object back so it isn't left without metadata
+		ls.fs.Rename(trashPath, objectPath)
+		slog.Error("Failed to write trash metadata", "error", err)
+		return fmt.Errorf("failed to delete object")
+	}
+
+	_ = ls.fs.Remove(metadataPath)
+	return nil
+}
+
+// RestoreObject moves a soft-deleted object out of the trash area, replacing
+// any live object stored under the same key.
+func (ls *LocalStorage) RestoreObject(ctx context.Context, bucket, key string) error {
+	unlock := ls.lockObject(bucket, key)
+	defer unlock()
+
+	objectPath := ls.objectPath(bucket, key)
+	trashPath := filepath.Join(ls.basePath, bucket, trashDir, key)
+
+	metadata, err := ls.readMetadata(trashPath + ".metadata")
+	if isNotExist(err) {
+		return ls.notFoundError(bucket)
+	}
+	if err != nil {
+		slog.Error("Failed to read trash metadata", "path", trashPath, "error", err)
+		return fmt.Errorf("failed to read trash metadata")
+	}
+
+	if err := ls.mkdirAll(filepath.Dir(objectPath)); err != nil {
+		slog.Error("Failed to create directories", "error", err)
+		return fmt.Errorf("failed to create directories")
+	}
+
+	isNew := !ls.objectExists(bucket, key)
+	// The live object being replaced, if any
+	var previous *model.ObjectMetadata
+	if ls.opts.ContentAddressed && !isNew {
+		previous, _ = ls.readMetadata(objectPath + ".metadata")
+	}
+	// Restoring isn't blocked by the quota, but the bytes are accounted for
+	sizeDelta := ls.objectSize(trashPath) - ls.objectSize(objectPath)
+	if err := ls.fs.Rename(trashPath, objectPath); err != nil {
+		slog.Error("Failed to restore object file", "error", err)
+		return fmt.Errorf("failed to restore object")
+	}
+	if isNew {
+		ls.adjustCount(bucket, 1)
+	}
+	ls.adjustBytes(bucket, sizeDelta)
+
+	metadata.DeletedAt = nil
+	if err := ls.writeMetadata(objectPath+".metadata", metadata); err != nil {
+		slog.Error("Failed to write metadata", "error", err)
+		return fmt.Errorf("failed to restore object")
+	}
+
+	_ = ls.fs.Remove(trashPath + ".metadata")
+
+	// The replaced object may have been the last link to its blob
+	if previous != nil {
+		if err := ls.releaseBlob(bucket, previous.SHA256); err != nil {
+			slog.Warn("Failed to release blob", "bucket", bucket, "error", err)
+		}
+	}
+
+	// The next restore gets the copy deleted before this one
+	if err := ls.promoteTrashedVersion(bucket, key); err != nil {
+		slog.Warn("Failed to promote earlier trashed copy", "bucket", bucket, "key", key, "error", err)
+	}
+	return nil
+}
+
+// keepTrashedVersion moves the trashed copy of key, if there is one, into
+// the trash versions area so trashing key again doesn't overwrite it.
+// Callers must hold the object lock.
+func (ls *LocalStorage) keepTrashedVersion(bucket, key string) error {
+	trashPath := filepath.Join(ls.basePath, bucket, trashDir, key)
+	previous, err := ls.readMetadata(trashPath + ".metadata")
+	if isNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	deletedAt := time.Now()
+	if previous.DeletedAt != nil {
+		deletedAt = *previous.DeletedAt
+	}
+	versionPath := filepath.Join(ls.basePath, bucket, trashDir, trashVersionsDir, strconv.FormatInt(deletedAt.UnixNano(), 10), key)
+	if err := ls.mkdirAll(filepath.Dir(versionPath)); err != nil {
+		return err
+	}
+	if err := ls.fs.Rename(trashPath, versionPath); err != nil {
+		return err
+	}
+	return ls.fs.Rename(trashPath+".metadata", versionPath+".metadata")
+}
+
+// promoteTrashedVersion moves the most recently deleted earlier copy of key
+// back to its place in the trash area. Callers must hold the object lock.
+func (ls *LocalStorage) promoteTrashedVersion(bucket, key string) error {
+	versionsPath := filepath.Join(ls.basePath, bucket, trashDir, trashVersionsDir)
+	entries, err := ls.fs.ReadDir(versionsPath)
+	if isNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Entries are named by deletion time, so the newest sorts last
+	var stamps []int64
+	for _, entry := range entries {
+		if stamp, err := strconv.ParseInt(entry.Name(), 10, 64); err == nil {
+			stamps = append(stamps, stamp)
+		}
+	}
+	slices.Sort(stamps)
+	for i := len(stamps) - 1; i >= 0; i-- {
+		versionPath := filepath.Join(versionsPath, strconv.FormatInt(stamps[i], 10), key)
+		if _, err := ls.fs.Stat(versionPath + ".metadata"); err != nil {
+			continue
+		}
+		trashPath := filepath.Join(ls.basePath, bucket, trashDir, key)
+		if err := ls.fs.Rename(versionPath, trashPath); err != nil {
+			return err
+		}
+		return ls.fs.Rename(versionPath+".metadata", trashPath+".metadata")
+	}
+	return nil
+}
+
+// PurgeTrash permanently removes trashed objects deleted more than
+// TrashRetention ago, across all buckets. It returns the number purged.
+func (ls *LocalStorage) PurgeTrash(ctx context.Context) (int, error) {
+	buckets, err := ls.fs.ReadDir(ls.basePath)
+	if err != nil {
+		slog.Error("Failed to read storage directory", "error", err)
+		return 0, fmt.Errorf("failed to read storage directory")
+	}
+
+	cutoff := time.Now().Add(-ls.opts.TrashRetention)
+	purged := 0
+	for _, b := range buckets {
+		if !b.IsDir() {
+			continue
+		}
+		n, err := ls.purgeBucketTrash(b.Name(), cutoff)
+		purged += n
+		if err != nil {
+			slog.Error("Failed to purge trash", "bucket", b.Name(), "error", err)
+			return purged, fmt.Errorf("failed to purge trash")
+		}
+	}
+
+	return purged, nil
+}
+
+// purgeBucketTrash removes a single bucket's trashed objects deleted before
+// cutoff, holding the bucket exclusively while it does so.
+func (ls *LocalStorage) purgeBucketTrash(bucket string, cutoff time.Time) (int, error) {
+	unlock := ls.lockBucket(bucket)
+	defer unlock()
+
+	trashPath := filepath.Join(ls.basePath, bucket, trashDir)
+	purged := 0
+	err := ls.fs.Walk(trashPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || strings.HasSuffix(path, ".metadata") {
+			return nil
+		}
+
+		// Fall back to the file's mtime if the metadata is unreadable
+		deletedAt := info.ModTime()
+		metadata, err := ls.readMetadata(path + ".metadata")
+		if err == nil && metadata.DeletedAt != nil {
+			deletedAt = *metadata.DeletedAt
+		}
+		if deletedAt.After(cutoff) {
+			return nil
+		}
+
+		if err := ls.fs.Remove(path); err != nil {
+			return err
+		}
+		_ = ls.fs.Remove(path + ".metadata")
+		if metadata != nil {
+			if err := ls.releaseBlob(bucket, metadata.SHA256); err != nil {
+				slog.Warn("Failed to release blob", "bucket", bucket, "error", err)
+			}
+		}
+		purged++
+		return nil
+	})
+	return purged, err
+}
+
+// RunTrashSweeper calls PurgeTrash every interval until ctx is cancelled.
+func (ls *LocalStorage) RunTrashSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := ls.PurgeTrash(ctx)
+			if err != nil {
+				continue
+			}
+			if purged > 0 {
+				slog.Info("Purged trashed objects", "count", purged)
+			}
+		}
+	}
+}
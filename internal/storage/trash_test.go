@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRestoreObjectNotFound(t *testing.T) {
+	ctx := context.Background()
+	ls := newTestStorage(t, Options{SoftDelete: true})
+
+	if err := ls.RestoreObject(ctx, "test", "missing.txt"); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("RestoreObject(missing key) = %v, want ErrObjectNotFound", err)
+	}
+	if err := ls.RestoreObject(ctx, "nope", "missing.txt"); !errors.Is(err, ErrBucketNotFound) {
+		t.Fatalf("RestoreObject(missing bucket) = %v, want ErrBucketNotFound", err)
+	}
+}
+
+func TestTrashKeepsEveryDeletedCopy(t *testing.T) {
+	ctx := context.Background()
+	ls := newTestStorage(t, Options{SoftDelete: true})
+
+	mustPut(t, ls, "test", "notes.txt", "first")
+	if err := ls.DeleteObject(ctx, "test", "notes.txt"); err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, ls, "test", "notes.txt", "second")
+	if err := ls.DeleteObject(ctx, "test", "notes.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restores come back newest first
+	if err := ls.RestoreObject(ctx, "test", "notes.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readObject(t, ls, "test", "notes.txt"); got != "second" {
+		t.Fatalf("first restore = %q, want second", got)
+	}
+	if err := ls.RestoreObject(ctx, "test", "notes.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readObject(t, ls, "test", "notes.txt"); got != "first" {
+		t.Fatalf("second restore = %q, want first", got)
+	}
+	if err := ls.RestoreObject(ctx, "test", "notes.txt"); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("third restore = %v, want ErrObjectNotFound", err)
+	}
+}
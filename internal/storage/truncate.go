@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+// TruncateObject cuts an object down to its first size bytes, e.g. to drop
+// rotated entries from a log kept in a single object. Growing it, padded with
+// zero bytes, must be asked for with extend and fails with ErrTruncateExtends
+// otherwise. The object is rewritten like an overwrite, so the ETag, size and
+// modification time are recomputed and readers already streaming it keep the
+// old bytes; its content type, tags and other descriptive metadata are kept.
+func (ls *LocalStorage) TruncateObject(ctx context.Context, bucket, key string, size int64, extend bool) (*model.ObjectMetadata, error) {
+	unlock := ls.lockObject(bucket, key)
+	defer unlock()
+
+	objectPath := ls.objectPath(bucket, key)
+	metadataPath := objectPath + ".metadata"
+
+	metadata, err := ls.readMetadata(metadataPath)
+	if err != nil {
+		slog.Debug("Failed to read metadata", "path", metadataPath, "error", err)
+		if isNotExist(err) {
+			return nil, ls.notFoundError(bucket)
+		}
+		return nil, fmt.Errorf("failed to read metadata")
+	}
+	if size > metadata.Size && !extend {
+		return nil, ErrTruncateExtends
+	}
+	if size == metadata.Size {
+		metadata.History = nil
+		return metadata, nil
+	}
+
+	file, err := ls.fs.Open(objectPath)
+	if err != nil {
+		slog.Error("Failed to open file", "path", objectPath, "error", err)
+		return nil, fmt.Errorf("failed to open file")
+	}
+	defer file.Close()
+
+	kept := min(size, metadata.Size)
+	data := io.MultiReader(io.LimitReader(file, kept), io.LimitReader(zeros{}, size-kept))
+	return ls.storeObject(ctx, bucket, key, data, size, *metadata)
+}
+
+// zeros is an endless stream of zero bytes
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
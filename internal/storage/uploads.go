@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/model"
+)
+
+// ListUploads returns the resumable uploads staged in bucket that have not
+// received every byte yet, ordered by key.
+func (ls *LocalStorage) ListUploads(ctx context.Context, bucket string) ([]model.UploadInfo, error) {
+	unlock := ls.rLockBucket(bucket)
+	defer unlock()
+
+	if _, err := ls.fs.Stat(filepath.Join(ls.basePath, bucket)); err != nil {
+		if isNotExist(err) {
+			return nil, ErrBucketNotFound
+		}
+		return nil, fmt.Errorf("failed to check bucket")
+	}
+
+	var uploads []model.UploadInfo
+	err := ls.walkUploads(bucket, func(key, sidecarPath string, info os.FileInfo) error {
+		var upload stagedUpload
+		if err := ls.readJSON(sidecarPath, &upload); err != nil {
+			if isNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		// Uploads staged before the start time was recorded fall back to
+		// their last activity
+		initiated := upload.Initiated
+		if initiated.IsZero() {
+			initiated = info.ModTime()
+		}
+		// File mtimes can be coarser than the clock; never report the last
+		// activity before the start
+		lastModified := info.ModTime()
+		if lastModified.Before(initiated) {
+			lastModified = initiated
+		}
+		uploads = append(uploads, model.UploadInfo{
+			Key:          key,
+			Size:         upload.Size,
+			Received:     upload.Received,
+			Initiated:    initiated.UTC(),
+			LastModified: lastModified.UTC(),
+		})
+		return nil
+	})
+	if err != nil {
+		slog.Error("Failed to list uploads", "bucket", bucket, "error", err)
+		return nil, fmt.Errorf("failed to list uploads")
+	}
+
+	return uploads, nil
+}
+
+// PurgeUploads aborts staged uploads in every bucket that have not received
+// any data for longer than olderThan, and returns how many were removed.
+func (ls *LocalStorage) PurgeUploads(ctx context.Context, olderThan time.Duration) (int, error) {
+	buckets, err := ls.fs.ReadDir(ls.basePath)
+	if err != nil {
+		slog.Error("Failed to read storage directory", "error", err)
+		return 0, fmt.Errorf("failed to read storage directory")
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	purged := 0
+	for _, b := range buckets {
+		if !b.IsDir() {
+			continue
+		}
+		n, err := ls.purgeBucketUploads(b.Name(), cutoff)
+		purged += n
+		if err != nil {
+			slog.Error("Failed to purge uploads", "bucket", b.Name(), "error", err)
+			return purged, fmt.Errorf("failed to purge uploads")
+		}
+	}
+
+	return purged, nil
+}
+
+// purgeBucketUploads removes a single bucket's staged uploads last written
+// before cutoff, holding the bucket exclusively while it does so.
+func (ls *LocalStorage) purgeBucketUploads(bucket string, cutoff time.Time) (int, error) {
+	unlock := ls.lockBucket(bucket)
+	defer unlock()
+
+	purged := 0
+	err := ls.walkUploads(bucket, func(key, sidecarPath string, info os.FileInfo) error {
+		// The sidecar is rewritten after every range, so its mtime is the
+		// upload's last activity
+		if info.ModTime().After(cutoff) {
+			return nil
+		}
+		stagedPath, _ := ls.stagedUploadPaths(bucket, key)
+		if err := ls.fs.Remove(stagedPath); err != nil && !isNotExist(err) {
+			return err
+		}
+		if err := ls.fs.Remove(sidecarPath); err != nil && !isNotExist(err) {
+			return err
+		}
+		purged++
+		return nil
+	})
+	return purged, err
+}
+
+// walkUploads calls fn for the sidecar of every upload staged in bucket.
+func (ls *LocalStorage) walkUploads(bucket string, fn func(key, sidecarPath string, info os.FileInfo) error) error {
+	statePath := filepath.Join(ls.basePath, bucket, uploadsDir, uploadStateDir)
+	return ls.fs.Walk(statePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if isNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(statePath, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		return fn(key, path, info)
+	})
+}
+
+// RunUploadSweeper calls PurgeUploads every interval until ctx is cancelled.
+func (ls *LocalStorage) RunUploadSweeper(ctx context.Context, interval, olderThan time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := ls.PurgeUploads(ctx, olderThan)
+			if err != nil {
+				continue
+			}
+			if purged > 0 {
+				slog.Info("Aborted stale uploads", "count", purged)
+			}
+		}
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !otel
+
+// Package tracing instruments gosss with OpenTelemetry when built with
+// -tags otel. This build has no OpenTelemetry support: nothing is wrapped
+// and Setup fails, so configuring tracing is not silently ignored.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/mmvergara/gosss/internal/storage"
+)
+
+// Setup reports that tracing is unavailable in this build
+func Setup(ctx context.Context) error {
+	return errors.New("tracing requires a server built with -tags otel")
+}
+
+// Middleware returns next unchanged
+func Middleware(next http.Handler) http.Handler {
+	return next
+}
+
+// NewStorage returns next unchanged
+func NewStorage(next storage.Storage) storage.Storage {
+	return next
+}
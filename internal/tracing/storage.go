@@ -0,0 +1,283 @@
+//go:build otel
+
+package tracing
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/mmvergara/gosss/internal/model"
+	"github.com/mmvergara/gosss/internal/storage"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedStorage wraps a Storage and records a span per call, as a child of
+// the request's span. Like MeteredStorage, reads end their span once the
+// object is opened; streaming the body is not included.
+type tracedStorage struct {
+	next storage.Storage
+}
+
+// NewStorage returns a Storage that forwards to next and traces every call
+func NewStorage(next storage.Storage) storage.Storage {
+	return &tracedStorage{next: next}
+}
+
+func startSpan(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("gosss.storage.method", op))
+	return tracer.Start(ctx, "storage."+op, trace.WithAttributes(attrs...))
+}
+
+// endSpan finishes span. It is deferred with a pointer to the named error result
+// so the final error is recorded.
+func endSpan(span trace.Span, err *error) {
+	if *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
+
+// recordSize adds the size of the object an operation returned
+func recordSize(span trace.Span, meta **model.ObjectMetadata) {
+	if *meta != nil {
+		span.SetAttributes(sizeAttr((*meta).Size))
+	}
+}
+
+func bucketAttr(bucket string) attribute.KeyValue { return attribute.String("gosss.bucket", bucket) }
+func keyAttr(key string) attribute.KeyValue       { return attribute.String("gosss.key", key) }
+func sizeAttr(size int64) attribute.KeyValue      { return attribute.Int64("gosss.object.size", size) }
+
+func (t *tracedStorage) CreateBucket(ctx context.Context, name string) (err error) {
+	ctx, span := startSpan(ctx, "CreateBucket", bucketAttr(name))
+	defer endSpan(span, &err)
+	return t.next.CreateBucket(ctx, name)
+}
+
+func (t *tracedStorage) DeleteBucket(ctx context.Context, name string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteBucket", bucketAttr(name))
+	defer endSpan(span, &err)
+	return t.next.DeleteBucket(ctx, name)
+}
+
+func (t *tracedStorage) BucketExists(ctx context.Context, name string) (exists bool, err error) {
+	ctx, span := startSpan(ctx, "BucketExists", bucketAttr(name))
+	defer endSpan(span, &err)
+	return t.next.BucketExists(ctx, name)
+}
+
+func (t *tracedStorage) ListBuckets(ctx context.Context) (buckets []string, err error) {
+	ctx, span := startSpan(ctx, "ListBuckets")
+	defer endSpan(span, &err)
+	return t.next.ListBuckets(ctx)
+}
+
+func (t *tracedStorage) RenameBucket(ctx context.Context, oldName, newName string) (err error) {
+	ctx, span := startSpan(ctx, "RenameBucket", bucketAttr(oldName), attribute.String("gosss.new_bucket", newName))
+	defer endSpan(span, &err)
+	return t.next.RenameBucket(ctx, oldName, newName)
+}
+
+func (t *tracedStorage) BucketStats(ctx context.Context, bucket string) (stats *model.BucketStats, err error) {
+	ctx, span := startSpan(ctx, "BucketStats", bucketAttr(bucket))
+	defer endSpan(span, &err)
+	return t.next.BucketStats(ctx, bucket)
+}
+
+func (t *tracedStorage) SetBucketQuota(ctx context.Context, bucket string, quota int64) (err error) {
+	ctx, span := startSpan(ctx, "SetBucketQuota", bucketAttr(bucket))
+	defer endSpan(span, &err)
+	return t.next.SetBucketQuota(ctx, bucket, quota)
+}
+
+func (t *tracedStorage) SetBucketDefaultObject(ctx context.Context, bucket, key string) (err error) {
+	ctx, span := startSpan(ctx, "SetBucketDefaultObject", bucketAttr(bucket), keyAttr(key))
+	defer endSpan(span, &err)
+	return t.next.SetBucketDefaultObject(ctx, bucket, key)
+}
+
+func (t *tracedStorage) BucketDefaultObject(ctx context.Context, bucket string) (key string, err error) {
+	ctx, span := startSpan(ctx, "BucketDefaultObject", bucketAttr(bucket))
+	defer endSpan(span, &err)
+	return t.next.BucketDefaultObject(ctx, bucket)
+}
+
+func (t *tracedStorage) SetBucketDefaultCacheControl(ctx context.Context, bucket, value string) (err error) {
+	ctx, span := startSpan(ctx, "SetBucketDefaultCacheControl", bucketAttr(bucket))
+	defer endSpan(span, &err)
+	return t.next.SetBucketDefaultCacheControl(ctx, bucket, value)
+}
+
+func (t *tracedStorage) BucketDefaultCacheControl(ctx context.Context, bucket string) (value string, err error) {
+	ctx, span := startSpan(ctx, "BucketDefaultCacheControl", bucketAttr(bucket))
+	defer endSpan(span, &err)
+	return t.next.BucketDefaultCacheControl(ctx, bucket)
+}
+
+func (t *tracedStorage) SetBucketMaxObjectSize(ctx context.Context, bucket string, size int64) (err error) {
+	ctx, span := startSpan(ctx, "SetBucketMaxObjectSize", bucketAttr(bucket))
+	defer endSpan(span, &err)
+	return t.next.SetBucketMaxObjectSize(ctx, bucket, size)
+}
+
+func (t *tracedStorage) BucketMaxObjectSize(ctx context.Context, bucket string) (size int64, err error) {
+	ctx, span := startSpan(ctx, "BucketMaxObjectSize", bucketAttr(bucket))
+	defer endSpan(span, &err)
+	return t.next.BucketMaxObjectSize(ctx, bucket)
+}
+
+func (t *tracedStorage) PutObject(ctx context.Context, bucket, key string, data io.Reader, size int64, contentType string) (meta *model.ObjectMetadata, err error) {
+	ctx, span := startSpan(ctx, "PutObject", bucketAttr(bucket), keyAttr(key), sizeAttr(size))
+	defer endSpan(span, &err)
+	defer recordSize(span, &meta)
+	return t.next.PutObject(ctx, bucket, key, data, size, contentType)
+}
+
+func (t *tracedStorage) PutObjectWithMetadata(ctx context.Context, bucket, key string, data io.Reader, size int64, template model.ObjectMetadata) (meta *model.ObjectMetadata, err error) {
+	ctx, span := startSpan(ctx, "PutObjectWithMetadata", bucketAttr(bucket), keyAttr(key), sizeAttr(size))
+	defer endSpan(span, &err)
+	defer recordSize(span, &meta)
+	return t.next.PutObjectWithMetadata(ctx, bucket, key, data, size, template)
+}
+
+func (t *tracedStorage) GetObject(ctx context.Context, bucket, key string) (body io.ReadCloser, meta *model.ObjectMetadata, err error) {
+	ctx, span := startSpan(ctx, "GetObject", bucketAttr(bucket), keyAttr(key))
+	defer endSpan(span, &err)
+	defer recordSize(span, &meta)
+	return t.next.GetObject(ctx, bucket, key)
+}
+
+func (t *tracedStorage) DeleteObject(ctx context.Context, bucket, key string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteObject", bucketAttr(bucket), keyAttr(key))
+	defer endSpan(span, &err)
+	return t.next.DeleteObject(ctx, bucket, key)
+}
+
+func (t *tracedStorage) ListObjects(ctx context.Context, bucket, prefix string) (objects []model.ObjectMetadata, err error) {
+	ctx, span := startSpan(ctx, "ListObjects", bucketAttr(bucket), attribute.String("gosss.prefix", prefix))
+	defer endSpan(span, &err)
+	return t.next.ListObjects(ctx, bucket, prefix)
+}
+
+// WalkObjects is traced including the callbacks, as in MeteredStorage
+func (t *tracedStorage) WalkObjects(ctx context.Context, bucket, prefix string, fn func(model.ObjectMetadata) error) (err error) {
+	ctx, span := startSpan(ctx, "WalkObjects", bucketAttr(bucket), attribute.String("gosss.prefix", prefix))
+	defer endSpan(span, &err)
+	return t.next.WalkObjects(ctx, bucket, prefix, fn)
+}
+
+func (t *tracedStorage) ListObjectsLimit(ctx context.Context, bucket, prefix, startAfter string, maxKeys int, match func(model.ObjectMetadata) bool) (objects []model.ObjectMetadata, truncated bool, err error) {
+	ctx, span := startSpan(ctx, "ListObjectsLimit", bucketAttr(bucket), attribute.String("gosss.prefix", prefix))
+	defer endSpan(span, &err)
+	return t.next.ListObjectsLimit(ctx, bucket, prefix, startAfter, maxKeys, match)
+}
+
+func (t *tracedStorage) HasObject(ctx context.Context, bucket string) (has bool, err error) {
+	ctx, span := startSpan(ctx, "HasObject", bucketAttr(bucket))
+	defer endSpan(span, &err)
+	return t.next.HasObject(ctx, bucket)
+}
+
+func (t *tracedStorage) HeadObject(ctx context.Context, bucket, key string) (meta *model.ObjectMetadata, err error) {
+	ctx, span := startSpan(ctx, "HeadObject", bucketAttr(bucket), keyAttr(key))
+	defer endSpan(span, &err)
+	defer recordSize(span, &meta)
+	return t.next.HeadObject(ctx, bucket, key)
+}
+
+func (t *tracedStorage) StatObject(ctx context.Context, bucket, key string) (size int64, err error) {
+	ctx, span := startSpan(ctx, "StatObject", bucketAttr(bucket), keyAttr(key))
+	defer endSpan(span, &err)
+	size, err = t.next.StatObject(ctx, bucket, key)
+	if err == nil {
+		span.SetAttributes(sizeAttr(size))
+	}
+	return size, err
+}
+
+func (t *tracedStorage) PutObjectRange(ctx context.Context, bucket, key string, start, total int64, data io.Reader, template model.ObjectMetadata) (progress *model.UploadProgress, meta *model.ObjectMetadata, err error) {
+	ctx, span := startSpan(ctx, "PutObjectRange", bucketAttr(bucket), keyAttr(key))
+	defer endSpan(span, &err)
+	defer recordSize(span, &meta)
+	return t.next.PutObjectRange(ctx, bucket, key, start, total, data, template)
+}
+
+func (t *tracedStorage) CopyObject(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string, override *model.ObjectMetadata) (meta *model.ObjectMetadata, err error) {
+	ctx, span := startSpan(ctx, "CopyObject", bucketAttr(dstBucket), keyAttr(dstKey), attribute.String("gosss.source", srcBucket+"/"+srcKey))
+	defer endSpan(span, &err)
+	defer recordSize(span, &meta)
+	return t.next.CopyObject(ctx, srcBucket, srcKey, dstBucket, dstKey, override)
+}
+
+func (t *tracedStorage) RestoreObject(ctx context.Context, bucket, key string) (err error) {
+	ctx, span := startSpan(ctx, "RestoreObject", bucketAttr(bucket), keyAttr(key))
+	defer endSpan(span, &err)
+	return t.next.RestoreObject(ctx, bucket, key)
+}
+
+func (t *tracedStorage) RestoreArchivedObject(ctx context.Context, bucket, key string, until time.Time) (meta *model.ObjectMetadata, err error) {
+	ctx, span := startSpan(ctx, "RestoreArchivedObject", bucketAttr(bucket), keyAttr(key))
+	defer endSpan(span, &err)
+	defer recordSize(span, &meta)
+	return t.next.RestoreArchivedObject(ctx, bucket, key, until)
+}
+
+func (t *tracedStorage) ObjectHistory(ctx context.Context, bucket, key string) (history *model.ObjectHistory, err error) {
+	ctx, span := startSpan(ctx, "ObjectHistory", bucketAttr(bucket), keyAttr(key))
+	defer endSpan(span, &err)
+	return t.next.ObjectHistory(ctx, bucket, key)
+}
+
+func (t *tracedStorage) TruncateObject(ctx context.Context, bucket, key string, size int64, extend bool) (meta *model.ObjectMetadata, err error) {
+	ctx, span := startSpan(ctx, "TruncateObject", bucketAttr(bucket), keyAttr(key), sizeAttr(size))
+	defer endSpan(span, &err)
+	defer recordSize(span, &meta)
+	return t.next.TruncateObject(ctx, bucket, key, size, extend)
+}
+
+func (t *tracedStorage) RecomputeObject(ctx context.Context, bucket, key string) (meta *model.ObjectMetadata, err error) {
+	ctx, span := startSpan(ctx, "RecomputeObject", bucketAttr(bucket), keyAttr(key))
+	defer endSpan(span, &err)
+	defer recordSize(span, &meta)
+	return t.next.RecomputeObject(ctx, bucket, key)
+}
+
+func (t *tracedStorage) RecomputeBucket(ctx context.Context, bucket string) (count int, err error) {
+	ctx, span := startSpan(ctx, "RecomputeBucket", bucketAttr(bucket))
+	defer endSpan(span, &err)
+	return t.next.RecomputeBucket(ctx, bucket)
+}
+
+func (t *tracedStorage) CleanupTempFiles(ctx context.Context, bucket string, olderThan time.Duration) (removed int, err error) {
+	ctx, span := startSpan(ctx, "CleanupTempFiles", bucketAttr(bucket))
+	defer endSpan(span, &err)
+	return t.next.CleanupTempFiles(ctx, bucket, olderThan)
+}
+
+func (t *tracedStorage) ListUploads(ctx context.Context, bucket string) (uploads []model.UploadInfo, err error) {
+	ctx, span := startSpan(ctx, "ListUploads", bucketAttr(bucket))
+	defer endSpan(span, &err)
+	return t.next.ListUploads(ctx, bucket)
+}
+
+func (t *tracedStorage) PutObjectTagging(ctx context.Context, bucket, key string, tags map[string]string) (err error) {
+	ctx, span := startSpan(ctx, "PutObjectTagging", bucketAttr(bucket), keyAttr(key))
+	defer endSpan(span, &err)
+	return t.next.PutObjectTagging(ctx, bucket, key, tags)
+}
+
+func (t *tracedStorage) GetObjectTagging(ctx context.Context, bucket, key string) (tags map[string]string, err error) {
+	ctx, span := startSpan(ctx, "GetObjectTagging", bucketAttr(bucket), keyAttr(key))
+	defer endSpan(span, &err)
+	return t.next.GetObjectTagging(ctx, bucket, key)
+}
+
+func (t *tracedStorage) DeleteObjectTagging(ctx context.Context, bucket, key string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteObjectTagging", bucketAttr(bucket), keyAttr(key))
+	defer endSpan(span, &err)
+	return t.next.DeleteObjectTagging(ctx, bucket, key)
+}
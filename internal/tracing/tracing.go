@@ -0,0 +1,55 @@
+//go:build otel
+
+// Package tracing instruments gosss with OpenTelemetry: a span per request
+// and a child span per storage operation, exported over OTLP. It is only
+// compiled into builds made with -tags otel; other builds get no-op stubs
+// and refuse to start with tracing configured.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const instrumentationName = "github.com/mmvergara/gosss"
+
+// tracer delegates to the global provider, so spans started before Setup
+// are dropped rather than lost to a stale provider.
+var tracer = otel.Tracer(instrumentationName)
+
+// Setup installs a tracer provider exporting over OTLP/HTTP and the W3C
+// trace context and baggage propagators. The exporter, sampler and resource
+// are configured by the standard OTEL_* environment variables.
+func Setup(ctx context.Context) error {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return err
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the default
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", "gosss")),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	return nil
+}
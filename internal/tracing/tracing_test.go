@@ -0,0 +1,119 @@
+//go:build otel
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mmvergara/gosss/internal/storage"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider that keeps finished spans in
+// memory for the rest of the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(propagator)
+	})
+	return recorder
+}
+
+func attrOf(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func spanNamed(t *testing.T, spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name() == name {
+			return span
+		}
+	}
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	t.Fatalf("no span %q among %v", name, names)
+	return nil
+}
+
+func TestRequestAndStorageSpans(t *testing.T) {
+	recorder := recordSpans(t)
+	store := NewStorage(storage.New(t.TempDir(), storage.Options{}))
+	if err := store.CreateBucket(context.Background(), "photos"); err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/{bucket}/*", func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := store.GetObject(r.Context(), chi.URLParam(r, "bucket"), chi.URLParam(r, "*")); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+	})
+	req := httptest.NewRequest(http.MethodGet, "/photos/cats/a.jpg", nil)
+	req.Header.Set("traceparent", "00-"+parent.TraceID().String()+"-"+parent.SpanID().String()+"-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	server := spanNamed(t, spans, "GET /{bucket}/*")
+	if server.SpanKind() != trace.SpanKindServer {
+		t.Errorf("request span kind = %v, want server", server.SpanKind())
+	}
+	if server.Parent().SpanID() != parent.SpanID() || server.SpanContext().TraceID() != parent.TraceID() {
+		t.Errorf("request span doesn't continue the incoming trace")
+	}
+	for key, want := range map[attribute.Key]string{
+		"http.route":                "/{bucket}/*",
+		"gosss.bucket":              "photos",
+		"gosss.key":                 "cats/a.jpg",
+		"http.request.method":       "GET",
+		"http.response.status_code": "500",
+	} {
+		if got := attrOf(server, key).Emit(); got != want {
+			t.Errorf("request span %s = %q, want %q", key, got, want)
+		}
+	}
+	if server.Status().Code != codes.Error {
+		t.Errorf("request span status = %v, want an error for the 500", server.Status())
+	}
+
+	get := spanNamed(t, spans, "storage.GetObject")
+	if get.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Error("storage span isn't a child of the request span")
+	}
+	if got := attrOf(get, "gosss.key").AsString(); got != "cats/a.jpg" {
+		t.Errorf("storage span key = %q", got)
+	}
+	if get.Status().Code != codes.Error || len(get.Events()) == 0 {
+		t.Errorf("storage span for a missing object has status %v and %d events, want the error recorded", get.Status(), len(get.Events()))
+	}
+}
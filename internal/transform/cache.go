@@ -0,0 +1,84 @@
+package transform
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Variant is a transformed object as it is served.
+type Variant struct {
+	Data        []byte
+	ContentType string
+}
+
+// Cache keeps recently served variants in memory, evicting the least
+// recently used once maxBytes is exceeded. Callers key variants by the
+// source's ETag, so an overwritten object never serves stale variants; they
+// simply age out.
+type Cache struct {
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	size    int64
+}
+
+type cacheEntry struct {
+	key     string
+	variant Variant
+}
+
+// NewCache creates a Cache holding up to maxBytes of variant data. A nil
+// Cache is returned when maxBytes is zero, which disables caching; its
+// methods are safe to call.
+func NewCache(maxBytes int64) *Cache {
+	if maxBytes == 0 {
+		return nil
+	}
+	return &Cache{
+		maxBytes: maxBytes,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// Get returns the variant cached under key.
+func (c *Cache) Get(key string) (Variant, bool) {
+	if c == nil {
+		return Variant{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, found := c.entries[key]
+	if !found {
+		return Variant{}, false
+	}
+	c.lru.MoveToFront(el)
+	return el.Value.(*cacheEntry).variant, true
+}
+
+// Add caches v under key. Variants larger than the whole cache aren't kept.
+func (c *Cache) Add(key string, v Variant) {
+	if c == nil || int64(len(v.Data)) > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, found := c.entries[key]; found {
+		c.size -= int64(len(el.Value.(*cacheEntry).variant.Data))
+		c.lru.Remove(el)
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, variant: v})
+	c.size += int64(len(v.Data))
+
+	for c.size > c.maxBytes {
+		oldest := c.lru.Back()
+		entry := oldest.Value.(*cacheEntry)
+		c.lru.Remove(oldest)
+		delete(c.entries, entry.key)
+		c.size -= int64(len(entry.variant.Data))
+	}
+}
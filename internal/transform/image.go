@@ -0,0 +1,211 @@
+package transform
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"mime"
+)
+
+const (
+	// MaxSourcePixels bounds the images that are decoded, since a small
+	// compressed file can expand to gigabytes of pixels
+	MaxSourcePixels = 50_000_000
+
+	jpegQuality = 85
+)
+
+// ImageResizer scales JPEG, PNG and GIF images. JPEGs stay JPEGs; the others
+// become PNGs, and only the first frame of an animated GIF is kept.
+type ImageResizer struct {
+	// MaxDimension caps a width or height derived from the other one, e.g.
+	// the height of a tall image asked for by width alone. Zero leaves
+	// derived sides uncapped.
+	MaxDimension int
+}
+
+func (ImageResizer) Accepts(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+func (ir ImageResizer) Transform(src io.Reader, opts Options) ([]byte, string, error) {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxSourcePixels {
+		return nil, "", fmt.Errorf("%w: image is %dx%d", ErrUnsupported, cfg.Width, cfg.Height)
+	}
+
+	img, err := decode(format, data)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	resized := resize(img, opts, ir.MaxDimension)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: jpegQuality})
+		return buf.Bytes(), "image/jpeg", err
+	}
+	err = png.Encode(&buf, resized)
+	return buf.Bytes(), "image/png", err
+}
+
+// decode decodes data in the format DecodeConfig detected. The decoders are
+// called directly rather than through image.Decode, which would accept any
+// format some other package happened to register.
+func decode(format string, data []byte) (image.Image, error) {
+	r := bytes.NewReader(data)
+	switch format {
+	case "jpeg":
+		return jpeg.Decode(r)
+	case "png":
+		return png.Decode(r)
+	case "gif":
+		return gif.Decode(r)
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// resize crops and scales img as opts describes. A derived side longer than
+// maxDimension is cut to it and the asked one shrunk to keep the aspect
+// ratio, so a 1x2000 image asked for at w=2048 doesn't become millions of
+// pixels tall.
+func resize(img image.Image, opts Options, maxDimension int) *image.RGBA {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	w, h := opts.Width, opts.Height
+
+	switch {
+	case srcW == 0 || srcH == 0:
+		// Nothing to sample; the result is a blank image of the asked size
+	case h == 0:
+		h = max(1, int(math.Round(float64(w)*float64(srcH)/float64(srcW))))
+		if maxDimension > 0 && h > maxDimension {
+			h = maxDimension
+			w = max(1, int(math.Round(float64(h)*float64(srcW)/float64(srcH))))
+		}
+	case w == 0:
+		w = max(1, int(math.Round(float64(h)*float64(srcW)/float64(srcH))))
+		if maxDimension > 0 && w > maxDimension {
+			w = maxDimension
+			h = max(1, int(math.Round(float64(w)*float64(srcH)/float64(srcW))))
+		}
+	case opts.Fit == FitContain:
+		scale := min(float64(w)/float64(srcW), float64(h)/float64(srcH))
+		w = max(1, int(math.Round(float64(srcW)*scale)))
+		h = max(1, int(math.Round(float64(srcH)*scale)))
+	case opts.Fit == FitCover:
+		// Crop the centre of the source to the box's aspect ratio
+		if srcW*h > w*srcH {
+			cropW := max(1, srcH*w/h)
+			bounds.Min.X += (srcW - cropW) / 2
+			bounds.Max.X = bounds.Min.X + cropW
+		} else {
+			cropH := max(1, srcW*h/w)
+			bounds.Min.Y += (srcH - cropH) / 2
+			bounds.Max.Y = bounds.Min.Y + cropH
+		}
+	}
+	w, h = max(w, 1), max(h, 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if bounds.Empty() {
+		return dst
+	}
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	scale(dst, src)
+	return dst
+}
+
+// contribution is the share of one source pixel in a destination pixel.
+type contribution struct {
+	index  int
+	weight float64
+}
+
+// areaWeights maps each of n destination pixels to the source pixels its
+// span covers out of srcN, weighted by how much of each it covers. This
+// averages when shrinking and repeats pixels when enlarging.
+func areaWeights(srcN, n int) [][]contribution {
+	ratio := float64(srcN) / float64(n)
+	weights := make([][]contribution, n)
+	for i := range weights {
+		start, end := float64(i)*ratio, float64(i+1)*ratio
+		total := end - start
+		for j := int(start); j < srcN && float64(j) < end; j++ {
+			covered := min(end, float64(j+1)) - max(start, float64(j))
+			if covered > 0 {
+				weights[i] = append(weights[i], contribution{j, covered / total})
+			}
+		}
+	}
+	return weights
+}
+
+// scale resamples src onto dst by area averaging, one axis at a time. The
+// pixels are premultiplied, so transparent pixels don't darken edges.
+func scale(dst, src *image.RGBA) {
+	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
+	w, h := dst.Bounds().Dx(), dst.Bounds().Dy()
+	xWeights, yWeights := areaWeights(srcW, w), areaWeights(srcH, h)
+
+	// Each destination row sums a run of horizontally scaled source rows
+	// that starts at or after the last one the previous row used, so only
+	// that last row is kept instead of all srcH of them
+	row := make([]float64, w*4)
+	rowIndex := -1
+	sum := make([]float64, w*4)
+	for y, contribs := range yWeights {
+		clear(sum)
+		for _, c := range contribs {
+			if c.index != rowIndex {
+				scaleRow(row, src.Pix[c.index*src.Stride:], xWeights)
+				rowIndex = c.index
+			}
+			for i, v := range row {
+				sum[i] += v * c.weight
+			}
+		}
+		line := dst.Pix[y*dst.Stride:]
+		for i, v := range sum {
+			line[i] = uint8(min(255, math.Round(v)))
+		}
+	}
+}
+
+// scaleRow resamples one row of source pixels into out, w pixels of four
+// channels, using the horizontal weights.
+func scaleRow(out []float64, line []uint8, xWeights [][]contribution) {
+	clear(out)
+	for x, contribs := range xWeights {
+		px := out[x*4 : x*4+4]
+		for _, c := range contribs {
+			in := line[c.index*4:]
+			for ch := range px {
+				px[ch] += float64(in[ch]) * c.weight
+			}
+		}
+	}
+}
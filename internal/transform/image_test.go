@@ -0,0 +1,126 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// encodePNG draws a w×h PNG whose left half is red and right half blue
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= w/2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func transformPNG(t *testing.T, ir ImageResizer, data []byte, opts Options) image.Image {
+	t.Helper()
+	out, contentType, err := ir.Transform(bytes.NewReader(data), opts)
+	if err != nil {
+		t.Fatalf("Transform(%s): %v", opts, err)
+	}
+	if contentType != "image/png" {
+		t.Fatalf("Transform(%s) content type = %q, want image/png", opts, contentType)
+	}
+	img, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func TestResizeSample(t *testing.T) {
+	data := encodePNG(t, 40, 20)
+	tests := []struct {
+		opts Options
+		w, h int
+	}{
+		{Options{Width: 20, Fit: FitCover}, 20, 10},
+		{Options{Height: 5, Fit: FitCover}, 10, 5},
+		{Options{Width: 10, Height: 10, Fit: FitContain}, 10, 5},
+		{Options{Width: 10, Height: 10, Fit: FitCover}, 10, 10},
+		{Options{Width: 10, Height: 10, Fit: FitFill}, 10, 10},
+		{Options{Width: 80, Fit: FitCover}, 80, 40},
+	}
+	for _, tt := range tests {
+		img := transformPNG(t, ImageResizer{}, data, tt.opts)
+		if b := img.Bounds(); b.Dx() != tt.w || b.Dy() != tt.h {
+			t.Errorf("%s: size %dx%d, want %dx%d", tt.opts, b.Dx(), b.Dy(), tt.w, tt.h)
+			continue
+		}
+		// The halves keep their colours, averaged only along the seam
+		left := color.RGBAModel.Convert(img.At(0, tt.h-1)).(color.RGBA)
+		right := color.RGBAModel.Convert(img.At(tt.w-1, 0)).(color.RGBA)
+		if left != (color.RGBA{R: 255, A: 255}) || right != (color.RGBA{B: 255, A: 255}) {
+			t.Errorf("%s: corners %v and %v, want red and blue", tt.opts, left, right)
+		}
+	}
+}
+
+func TestResizeJPEGStaysJPEG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 32, 32)), nil); err != nil {
+		t.Fatal(err)
+	}
+	out, contentType, err := ImageResizer{}.Transform(&buf, Options{Width: 8, Fit: FitCover})
+	if err != nil || contentType != "image/jpeg" {
+		t.Fatalf("Transform = %q, %v, want image/jpeg", contentType, err)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(out))
+	if err != nil || cfg.Width != 8 || cfg.Height != 8 {
+		t.Errorf("output %dx%d, %v, want 8x8", cfg.Width, cfg.Height, err)
+	}
+}
+
+func TestResizeCapsDerivedSide(t *testing.T) {
+	ir := ImageResizer{MaxDimension: 2048}
+
+	// Uncapped, w=2048 on a 1x2000 image would be 4,096,000 pixels tall
+	img := transformPNG(t, ir, encodePNG(t, 1, 2000), Options{Width: 2048, Fit: FitCover})
+	if b := img.Bounds(); b.Dy() != 2048 || b.Dx() != 1 {
+		t.Errorf("tall image at w=2048: %dx%d, want 1x2048", b.Dx(), b.Dy())
+	}
+	img = transformPNG(t, ir, encodePNG(t, 2000, 1), Options{Height: 2048, Fit: FitCover})
+	if b := img.Bounds(); b.Dx() != 2048 || b.Dy() != 1 {
+		t.Errorf("wide image at h=2048: %dx%d, want 2048x1", b.Dx(), b.Dy())
+	}
+	// Within the cap the asked side is kept
+	img = transformPNG(t, ir, encodePNG(t, 100, 200), Options{Width: 500, Fit: FitCover})
+	if b := img.Bounds(); b.Dx() != 500 || b.Dy() != 1000 {
+		t.Errorf("100x200 at w=500: %dx%d, want 500x1000", b.Dx(), b.Dy())
+	}
+}
+
+func TestResizeRejectsHugeSources(t *testing.T) {
+	// A 1x1 PNG whose header claims 10000x10000: only the header is read
+	// before the source is refused
+	data := encodePNG(t, 1, 1)
+	ihdr := data[12:29]
+	binary.BigEndian.PutUint32(ihdr[4:], 10000)
+	binary.BigEndian.PutUint32(ihdr[8:], 10000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(ihdr))
+
+	_, _, err := ImageResizer{}.Transform(bytes.NewReader(data), Options{Width: 10, Fit: FitCover})
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("100 megapixel source: %v, want ErrUnsupported", err)
+	}
+}
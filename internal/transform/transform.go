@@ -0,0 +1,103 @@
+// Package transform derives variants of objects on the fly, such as
+// thumbnails of stored images, and keeps recently served variants in memory.
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidOptions is returned for transform parameters that can't be
+	// honored, e.g. a width above the configured maximum
+	ErrInvalidOptions = errors.New("invalid transform options")
+	// ErrUnsupported is returned when an object's content can't be decoded
+	// or is too large to transform
+	ErrUnsupported = errors.New("object cannot be transformed")
+)
+
+// Fit says how an image is made to fit a box of both width and height.
+type Fit string
+
+const (
+	// FitCover fills the box, cropping what overflows it. It is the default.
+	FitCover Fit = "cover"
+	// FitContain scales the image to fit inside the box, so the result may
+	// be smaller than it in one dimension
+	FitContain Fit = "contain"
+	// FitFill stretches the image to the box, ignoring its aspect ratio
+	FitFill Fit = "fill"
+)
+
+// Options describes a requested variant. A zero Width or Height is derived
+// from the other one and the source's aspect ratio.
+type Options struct {
+	Width  int
+	Height int
+	Fit    Fit
+}
+
+// String returns a compact form of o, e.g. "w200-h200-cover" or
+// "w200-cover", used to tell variants apart in ETags and cache keys.
+func (o Options) String() string {
+	var b strings.Builder
+	if o.Width > 0 {
+		fmt.Fprintf(&b, "w%d-", o.Width)
+	}
+	if o.Height > 0 {
+		fmt.Fprintf(&b, "h%d-", o.Height)
+	}
+	b.WriteString(string(o.Fit))
+	return b.String()
+}
+
+// Transformer produces variants of objects. Implementations must be safe
+// for concurrent use.
+type Transformer interface {
+	// Accepts reports whether objects of contentType can be transformed
+	Accepts(contentType string) bool
+	// Transform reads an object's content from src and returns the variant
+	// described by opts along with its content type
+	Transform(src io.Reader, opts Options) (data []byte, contentType string, err error)
+}
+
+// ParseOptions reads the w, h and fit query parameters. requested is false
+// when neither w nor h is present, in which case no transform was asked for.
+// Dimensions must be between 1 and maxDimension.
+func ParseOptions(query url.Values, maxDimension int) (opts Options, requested bool, err error) {
+	if !query.Has("w") && !query.Has("h") {
+		return Options{}, false, nil
+	}
+
+	if opts.Width, err = parseDimension(query, "w", maxDimension); err != nil {
+		return Options{}, true, err
+	}
+	if opts.Height, err = parseDimension(query, "h", maxDimension); err != nil {
+		return Options{}, true, err
+	}
+
+	switch fit := Fit(query.Get("fit")); fit {
+	case "":
+		opts.Fit = FitCover
+	case FitCover, FitContain, FitFill:
+		opts.Fit = fit
+	default:
+		return Options{}, true, fmt.Errorf("%w: fit must be cover, contain or fill", ErrInvalidOptions)
+	}
+	return opts, true, nil
+}
+
+func parseDimension(query url.Values, name string, maxDimension int) (int, error) {
+	if !query.Has(name) {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(query.Get(name))
+	if err != nil || n < 1 || n > maxDimension {
+		return 0, fmt.Errorf("%w: %s must be between 1 and %d", ErrInvalidOptions, name, maxDimension)
+	}
+	return n, nil
+}
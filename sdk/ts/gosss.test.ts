@@ -1,8 +1,10 @@
 import { describe, expect, test, mock, beforeEach } from "bun:test";
+import { createHmac } from "node:crypto";
 import {
   GosssS3Client,
   GosssSDKS3,
   GetObjectCommand,
+  getSignedUrl,
   PutObjectCommand,
   ListObjectsCommand,
   GosssError,
@@ -81,6 +83,36 @@ describe("GOSSS", () => {
 
         await expect(client.send(command)).rejects.toThrow(GosssError);
       });
+
+      test("exposes validation details", async () => {
+        const errorResponse = {
+          code: "400",
+          message: "Bucket name must be between 3 and 63 characters",
+          resource: "ab",
+          timestamp: new Date().toISOString(),
+          details: { field: "bucket", rule: "bucket_name_length" },
+        };
+
+        global.fetch = mock(async () => {
+          return new Response(JSON.stringify(errorResponse), {
+            status: 400,
+            headers: { "Content-Type": "application/json" },
+          });
+        });
+
+        const command = new PutObjectCommand({
+          Bucket: "ab",
+          Key: "test.txt",
+          Body: "Hello World",
+        });
+
+        const err = await client.send(command).catch((e) => e);
+        expect(err).toBeInstanceOf(GosssError);
+        expect(err.details).toEqual({
+          field: "bucket",
+          rule: "bucket_name_length",
+        });
+      });
     });
 
     describe("GetObjectCommand", () => {
@@ -303,4 +335,56 @@ describe("GOSSS", () => {
       });
     });
   });
+
+  describe("getSignedUrl", () => {
+    const client = new GosssS3Client(mockOptions);
+    const command = new GetObjectCommand({ Bucket: "test-bucket", Key: "a.txt" });
+
+    const expectedSignature = (hash: string, stringToSign: string) =>
+      createHmac(hash, mockOptions.credentials.secretAccessKey)
+        .update(stringToSign)
+        .digest("hex");
+
+    test("signs and names SHA256 by default", async () => {
+      const url = new URL((await getSignedUrl(client, command, { expiresIn: 60 }))!);
+      const expiration = url.searchParams.get("expiration");
+
+      expect(url.searchParams.get("algorithm")).toBe("SHA256");
+      expect(url.searchParams.get("signature")).toBe(
+        expectedSignature("sha256", `SHA256:GET:${expiration}:test-bucket:a.txt`)
+      );
+    });
+
+    test.each([
+      ["SHA256", "sha256"],
+      ["SHA512", "sha512"],
+    ] as const)("signs the algorithm name with %s", async (algorithm, hash) => {
+      const url = new URL(
+        (await getSignedUrl(client, command, { expiresIn: 60, algorithm }))!
+      );
+      const expiration = url.searchParams.get("expiration");
+
+      expect(url.searchParams.get("algorithm")).toBe(algorithm);
+      expect(url.searchParams.get("signature")).toBe(
+        expectedSignature(hash, `${algorithm}:GET:${expiration}:test-bucket:a.txt`)
+      );
+    });
+
+    test("signs a fresh nonce into one-time URLs", async () => {
+      const first = new URL(
+        (await getSignedUrl(client, command, { expiresIn: 60, oneTime: true }))!
+      );
+      const second = new URL(
+        (await getSignedUrl(client, command, { expiresIn: 60, oneTime: true }))!
+      );
+      const expiration = first.searchParams.get("expiration");
+      const nonce = first.searchParams.get("nonce");
+
+      expect(nonce).toBeTruthy();
+      expect(second.searchParams.get("nonce")).not.toBe(nonce);
+      expect(first.searchParams.get("signature")).toBe(
+        expectedSignature("sha256", `SHA256:GET:${expiration}:test-bucket:a.txt:nonce=${nonce}`)
+      );
+    });
+  });
 });
@@ -6,6 +6,16 @@ export interface GosssErrorResponse {
   message: string;
   resource: string;
   timestamp: string;
+  /**
+   * Sent with validation failures: the input that was rejected ("bucket",
+   * "key", ...) and the rule it broke, e.g. "bucket_name_length"
+   */
+  details?: GosssErrorDetails;
+}
+
+export interface GosssErrorDetails {
+  field: string;
+  rule: string;
 }
 
 /**
@@ -15,13 +25,21 @@ export class GosssError extends Error {
   readonly code: string;
   readonly timestamp: Date;
   readonly resource: string;
-
-  constructor({ message, code, resource, timestamp }: GosssErrorResponse) {
+  readonly details?: GosssErrorDetails;
+
+  constructor({
+    message,
+    code,
+    resource,
+    timestamp,
+    details,
+  }: GosssErrorResponse) {
     super(message);
     this.name = "GosssError";
     this.code = code;
     this.timestamp = new Date(timestamp);
     this.resource = resource;
+    this.details = details;
   }
 }
 
@@ -389,12 +407,32 @@ type GetSignedUrlOptions = {
    * Expiration time in seconds, e.g., 3600 for 1 hour
    **/
   expiresIn: number;
+  /**
+   * HTTP method the URL is valid for. Defaults to DELETE for a
+   * DeleteObjectCommand and GET otherwise; use HEAD for metadata-only links.
+   **/
+  method?: "GET" | "HEAD" | "DELETE";
+  /**
+   * HMAC hash to sign with, SHA256 by default. It is always added to the URL
+   * and signed along with it, so the URL verifies whatever the server's
+   * PRESIGN_ALGORITHM is.
+   **/
+  algorithm?: "SHA256" | "SHA512";
+  /**
+   * Make the URL single-use: a random nonce is added to the URL and signed
+   * along with it, and the server refuses the URL once it has been used.
+   **/
+  oneTime?: boolean;
 };
 export const getSignedUrl = async (
   client: GosssS3Client,
-  command: GetObjectCommand,
+  command: GetObjectCommand | DeleteObjectCommand,
   options: GetSignedUrlOptions
 ): Promise<string | null> => {
+  const method =
+    options.method ??
+    (command instanceof DeleteObjectCommand ? "DELETE" : "GET");
+
   const baseUrl =
     client.options.endpoint +
     "/presign/" +
@@ -404,8 +442,15 @@ export const getSignedUrl = async (
 
   const expiration_unix = Math.floor(Date.now() / 1000) + options.expiresIn;
 
+  const algorithm = options.algorithm ?? "SHA256";
+
   // Create string to sign in same format as server
-  const stringToSign = `${expiration_unix}:${command.input.Bucket}:${command.input.Key}`;
+  let stringToSign = `${algorithm}:${method}:${expiration_unix}:${command.input.Bucket}:${command.input.Key}`;
+  // The nonce is the only extra parameter, and a UUID needs no escaping
+  const nonce = options.oneTime ? crypto.randomUUID() : undefined;
+  if (nonce) {
+    stringToSign += `:nonce=${nonce}`;
+  }
 
   const encoder = new TextEncoder();
   const keyData = encoder.encode(client.options.credentials.secretAccessKey);
@@ -415,7 +460,10 @@ export const getSignedUrl = async (
     const key = await crypto.subtle.importKey(
       "raw",
       keyData,
-      { name: "HMAC", hash: { name: "SHA-256" } },
+      {
+        name: "HMAC",
+        hash: { name: algorithm === "SHA512" ? "SHA-512" : "SHA-256" },
+      },
       false,
       ["sign"]
     );
@@ -429,6 +477,10 @@ export const getSignedUrl = async (
     const url = new URL(baseUrl);
     url.searchParams.append("expiration", expiration_unix.toString());
     url.searchParams.append("signature", signatureHex);
+    url.searchParams.append("algorithm", algorithm);
+    if (nonce) {
+      url.searchParams.append("nonce", nonce);
+    }
 
     return url.toString();
   } catch (error) {